@@ -5,6 +5,7 @@ import (
 	"github.com/golang/protobuf/ptypes/empty"
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
 	"squzy/internal/helpers"
+	monitoring_api "squzy/internal/monitoring-api"
 	"time"
 )
 
@@ -18,7 +19,9 @@ type Handlers interface {
 	RunScheduler(ctx context.Context, id string) error
 	StopScheduler(ctx context.Context, id string) error
 	RemoveScheduler(ctx context.Context, id string) error
-	AddScheduler(ctx context.Context, scheduler *apiPb.AddRequest) (*apiPb.AddResponse, error)
+	AddScheduler(ctx context.Context, scheduler *monitoring_api.AddRequest) (*apiPb.AddResponse, error)
+	UpdateScheduler(ctx context.Context, id string, scheduler *monitoring_api.AddRequest) error
+	GetSchedules(ctx context.Context, ids []string) ([]*monitoring_api.Schedule, error)
 	RegisterApplication(ctx context.Context, rq *apiPb.ApplicationInfo) (*apiPb.InitializeApplicationResponse, error)
 	SaveTransaction(ctx context.Context, rq *apiPb.TransactionInfo) (*empty.Empty, error)
 	GetSchedulerUptime(ctx context.Context, rq *apiPb.GetSchedulerUptimeRequest) (*apiPb.GetSchedulerUptimeResponse, error)
@@ -41,6 +44,7 @@ type handlers struct {
 	monitoringClient            apiPb.SchedulersExecutorClient
 	storageClient               apiPb.StorageClient
 	applicationMonitoringClient apiPb.ApplicationMonitoringClient
+	monitoringExtensionClient   monitoring_api.SchedulersExtensionClient
 }
 
 func (h *handlers) ArchivedApplicationById(ctx context.Context, id string) (*apiPb.Application, error) {
@@ -94,10 +98,32 @@ func (h *handlers) RunScheduler(ctx context.Context, id string) error {
 	return err
 }
 
-func (h *handlers) AddScheduler(ctx context.Context, scheduler *apiPb.AddRequest) (*apiPb.AddResponse, error) {
+func (h *handlers) AddScheduler(ctx context.Context, scheduler *monitoring_api.AddRequest) (*apiPb.AddResponse, error) {
 	c, cancel := helpers.TimeoutContext(ctx, defaultRequestTimeout)
 	defer cancel()
-	return h.monitoringClient.Add(c, scheduler)
+	return h.monitoringExtensionClient.Add(c, scheduler)
+}
+
+func (h *handlers) UpdateScheduler(ctx context.Context, id string, scheduler *monitoring_api.AddRequest) error {
+	c, cancel := helpers.TimeoutContext(ctx, defaultRequestTimeout)
+	defer cancel()
+	_, err := h.monitoringExtensionClient.Update(c, &monitoring_api.UpdateRequest{
+		ID:     id,
+		Config: scheduler,
+	})
+	return err
+}
+
+func (h *handlers) GetSchedules(ctx context.Context, ids []string) ([]*monitoring_api.Schedule, error) {
+	c, cancel := helpers.TimeoutContext(ctx, defaultRequestTimeout)
+	defer cancel()
+	res, err := h.monitoringExtensionClient.GetSchedules(c, &monitoring_api.GetSchedulesRequest{
+		IDs: ids,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res.Schedules, nil
 }
 
 func (h *handlers) StopScheduler(ctx context.Context, id string) error {
@@ -209,11 +235,13 @@ func New(
 	monitoringClient apiPb.SchedulersExecutorClient,
 	storageClient apiPb.StorageClient,
 	applicationMonitoringClient apiPb.ApplicationMonitoringClient,
+	monitoringExtensionClient monitoring_api.SchedulersExtensionClient,
 ) Handlers {
 	return &handlers{
 		agentClient:                 agentClient,
 		monitoringClient:            monitoringClient,
 		storageClient:               storageClient,
 		applicationMonitoringClient: applicationMonitoringClient,
+		monitoringExtensionClient:   monitoringExtensionClient,
 	}
 }
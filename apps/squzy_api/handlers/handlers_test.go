@@ -7,6 +7,7 @@ import (
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	monitoring_api "squzy/internal/monitoring-api"
 	"testing"
 )
 
@@ -266,34 +267,98 @@ func (m mockMonitoringOk) Stop(ctx context.Context, in *apiPb.StopRequest, opts
 	return &apiPb.StopResponse{}, nil
 }
 
+type mockMonitoringExtensionOk struct {
+}
+
+func (m mockMonitoringExtensionOk) Add(ctx context.Context, in *monitoring_api.AddRequest, opts ...grpc.CallOption) (*apiPb.AddResponse, error) {
+	return &apiPb.AddResponse{}, nil
+}
+
+func (m mockMonitoringExtensionOk) Update(ctx context.Context, in *monitoring_api.UpdateRequest, opts ...grpc.CallOption) (*monitoring_api.UpdateResponse, error) {
+	return &monitoring_api.UpdateResponse{}, nil
+}
+
+func (m mockMonitoringExtensionOk) GetSchedules(ctx context.Context, in *monitoring_api.GetSchedulesRequest, opts ...grpc.CallOption) (*monitoring_api.GetSchedulesResponse, error) {
+	return &monitoring_api.GetSchedulesResponse{}, nil
+}
+
+func (m mockMonitoringExtensionOk) GetDiagnostics(ctx context.Context, in *monitoring_api.GetDiagnosticsRequest, opts ...grpc.CallOption) (*monitoring_api.Diagnostics, error) {
+	return &monitoring_api.Diagnostics{}, nil
+}
+
+type mockMonitoringExtensionError struct {
+}
+
+func (m mockMonitoringExtensionError) GetSchedules(ctx context.Context, in *monitoring_api.GetSchedulesRequest, opts ...grpc.CallOption) (*monitoring_api.GetSchedulesResponse, error) {
+	return nil, errors.New("")
+}
+
+func (m mockMonitoringExtensionError) Add(ctx context.Context, in *monitoring_api.AddRequest, opts ...grpc.CallOption) (*apiPb.AddResponse, error) {
+	return nil, errors.New("")
+}
+
+func (m mockMonitoringExtensionError) Update(ctx context.Context, in *monitoring_api.UpdateRequest, opts ...grpc.CallOption) (*monitoring_api.UpdateResponse, error) {
+	return nil, errors.New("")
+}
+
+func (m mockMonitoringExtensionError) GetDiagnostics(ctx context.Context, in *monitoring_api.GetDiagnosticsRequest, opts ...grpc.CallOption) (*monitoring_api.Diagnostics, error) {
+	return nil, errors.New("")
+}
+
 func TestNew(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, nil, nil, nil)
+		s := New(nil, nil, nil, nil, nil)
 		assert.NotNil(t, s)
 	})
 }
 
 func TestHandlers_AddScheduler(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, &mockMonitoringOk{}, nil, nil)
-		_, err := s.AddScheduler(context.Background(), &apiPb.AddRequest{})
+		s := New(nil, nil, nil, nil, &mockMonitoringExtensionOk{})
+		_, err := s.AddScheduler(context.Background(), &monitoring_api.AddRequest{})
+		assert.Nil(t, err)
+	})
+	t.Run("Should: return error", func(t *testing.T) {
+		s := New(nil, nil, nil, nil, &mockMonitoringExtensionError{})
+		_, err := s.AddScheduler(context.Background(), &monitoring_api.AddRequest{})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestHandlers_UpdateScheduler(t *testing.T) {
+	t.Run("Should: not return error", func(t *testing.T) {
+		s := New(nil, nil, nil, nil, &mockMonitoringExtensionOk{})
+		err := s.UpdateScheduler(context.Background(), "id", &monitoring_api.AddRequest{})
+		assert.Nil(t, err)
+	})
+	t.Run("Should: return error", func(t *testing.T) {
+		s := New(nil, nil, nil, nil, &mockMonitoringExtensionError{})
+		err := s.UpdateScheduler(context.Background(), "id", &monitoring_api.AddRequest{})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestHandlers_GetSchedules(t *testing.T) {
+	t.Run("Should: not return error", func(t *testing.T) {
+		s := New(nil, nil, nil, nil, &mockMonitoringExtensionOk{})
+		_, err := s.GetSchedules(context.Background(), []string{"id"})
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, &mockMonitoringError{}, nil, nil)
-		_, err := s.AddScheduler(context.Background(), &apiPb.AddRequest{})
+		s := New(nil, nil, nil, nil, &mockMonitoringExtensionError{})
+		_, err := s.GetSchedules(context.Background(), nil)
 		assert.NotNil(t, err)
 	})
 }
 
 func TestHandlers_GetAgentByID(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(&agentMockOk{}, nil, nil, nil)
+		s := New(&agentMockOk{}, nil, nil, nil, nil)
 		_, err := s.GetAgentByID(context.Background(), "")
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(&agentMockError{}, nil, nil, nil)
+		s := New(&agentMockError{}, nil, nil, nil, nil)
 		_, err := s.GetAgentByID(context.Background(), "")
 		assert.NotNil(t, err)
 	})
@@ -301,12 +366,12 @@ func TestHandlers_GetAgentByID(t *testing.T) {
 
 func TestHandlers_GetAgentList(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(&agentMockOk{}, nil, nil, nil)
+		s := New(&agentMockOk{}, nil, nil, nil, nil)
 		_, err := s.GetAgentList(context.Background())
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(&agentMockError{}, nil, nil, nil)
+		s := New(&agentMockError{}, nil, nil, nil, nil)
 		_, err := s.GetAgentList(context.Background())
 		assert.NotNil(t, err)
 	})
@@ -314,12 +379,12 @@ func TestHandlers_GetAgentList(t *testing.T) {
 
 func TestHandlers_GetAgentHistoryByID(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, nil, &storageMockOk{}, nil)
+		s := New(nil, nil, &storageMockOk{}, nil, nil)
 		_, err := s.GetAgentHistoryByID(context.Background(), nil)
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, nil, &storageMockError{}, nil)
+		s := New(nil, nil, &storageMockError{}, nil, nil)
 		_, err := s.GetAgentHistoryByID(context.Background(), nil)
 		assert.NotNil(t, err)
 	})
@@ -327,12 +392,12 @@ func TestHandlers_GetAgentHistoryByID(t *testing.T) {
 
 func TestHandlers_GetSchedulerHistoryByID(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, nil, &storageMockOk{}, nil)
+		s := New(nil, nil, &storageMockOk{}, nil, nil)
 		_, err := s.GetSchedulerHistoryByID(context.Background(), nil)
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, nil, &storageMockError{}, nil)
+		s := New(nil, nil, &storageMockError{}, nil, nil)
 		_, err := s.GetSchedulerHistoryByID(context.Background(), nil)
 		assert.NotNil(t, err)
 	})
@@ -340,12 +405,12 @@ func TestHandlers_GetSchedulerHistoryByID(t *testing.T) {
 
 func TestHandlers_GetSchedulerByID(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, &mockMonitoringOk{}, nil, nil)
+		s := New(nil, &mockMonitoringOk{}, nil, nil, nil)
 		_, err := s.GetSchedulerByID(context.Background(), "")
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, &mockMonitoringError{}, nil, nil)
+		s := New(nil, &mockMonitoringError{}, nil, nil, nil)
 		_, err := s.GetSchedulerByID(context.Background(), "")
 		assert.NotNil(t, err)
 	})
@@ -353,12 +418,12 @@ func TestHandlers_GetSchedulerByID(t *testing.T) {
 
 func TestHandlers_GetSchedulerList(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, &mockMonitoringOk{}, nil, nil)
+		s := New(nil, &mockMonitoringOk{}, nil, nil, nil)
 		_, err := s.GetSchedulerList(context.Background())
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, &mockMonitoringError{}, nil, nil)
+		s := New(nil, &mockMonitoringError{}, nil, nil, nil)
 		_, err := s.GetSchedulerList(context.Background())
 		assert.NotNil(t, err)
 	})
@@ -366,12 +431,12 @@ func TestHandlers_GetSchedulerList(t *testing.T) {
 
 func TestHandlers_RemoveScheduler(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, &mockMonitoringOk{}, nil, nil)
+		s := New(nil, &mockMonitoringOk{}, nil, nil, nil)
 		err := s.RemoveScheduler(context.Background(), "nil")
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, &mockMonitoringError{}, nil, nil)
+		s := New(nil, &mockMonitoringError{}, nil, nil, nil)
 		err := s.RemoveScheduler(context.Background(), "nil")
 		assert.NotNil(t, err)
 	})
@@ -379,12 +444,12 @@ func TestHandlers_RemoveScheduler(t *testing.T) {
 
 func TestHandlers_RunScheduler(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, &mockMonitoringOk{}, nil, nil)
+		s := New(nil, &mockMonitoringOk{}, nil, nil, nil)
 		err := s.RunScheduler(context.Background(), "nil")
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, &mockMonitoringError{}, nil, nil)
+		s := New(nil, &mockMonitoringError{}, nil, nil, nil)
 		err := s.RunScheduler(context.Background(), "nil")
 		assert.NotNil(t, err)
 	})
@@ -392,12 +457,12 @@ func TestHandlers_RunScheduler(t *testing.T) {
 
 func TestHandlers_StopScheduler(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, &mockMonitoringOk{}, nil, nil)
+		s := New(nil, &mockMonitoringOk{}, nil, nil, nil)
 		err := s.StopScheduler(context.Background(), "nil")
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, &mockMonitoringError{}, nil, nil)
+		s := New(nil, &mockMonitoringError{}, nil, nil, nil)
 		err := s.StopScheduler(context.Background(), "nil")
 		assert.NotNil(t, err)
 	})
@@ -405,12 +470,12 @@ func TestHandlers_StopScheduler(t *testing.T) {
 
 func TestHandlers_GetApplicationById(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, nil, nil, &mockAmOk{})
+		s := New(nil, nil, nil, &mockAmOk{}, nil)
 		_, err := s.GetApplicationById(context.Background(), "nil")
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, nil, nil, &mockAmError{})
+		s := New(nil, nil, nil, &mockAmError{}, nil)
 		_, err := s.GetApplicationById(context.Background(), "nil")
 		assert.NotNil(t, err)
 	})
@@ -418,12 +483,12 @@ func TestHandlers_GetApplicationById(t *testing.T) {
 
 func TestHandlers_GetApplicationList(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, nil, nil, &mockAmOk{})
+		s := New(nil, nil, nil, &mockAmOk{}, nil)
 		_, err := s.GetApplicationList(context.Background())
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, nil, nil, &mockAmError{})
+		s := New(nil, nil, nil, &mockAmError{}, nil)
 		_, err := s.GetApplicationList(context.Background())
 		assert.NotNil(t, err)
 	})
@@ -431,12 +496,12 @@ func TestHandlers_GetApplicationList(t *testing.T) {
 
 func TestHandlers_GetSchedulerUptime(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, nil, &storageMockOk{}, nil)
+		s := New(nil, nil, &storageMockOk{}, nil, nil)
 		_, err := s.GetSchedulerUptime(context.Background(), nil)
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, nil, &storageMockError{}, nil)
+		s := New(nil, nil, &storageMockError{}, nil, nil)
 		_, err := s.GetSchedulerUptime(context.Background(), nil)
 		assert.NotNil(t, err)
 	})
@@ -444,12 +509,12 @@ func TestHandlers_GetSchedulerUptime(t *testing.T) {
 
 func TestHandlers_GetTransactionById(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, nil, &storageMockOk{}, nil)
+		s := New(nil, nil, &storageMockOk{}, nil, nil)
 		_, err := s.GetTransactionById(context.Background(), "")
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, nil, &storageMockError{}, nil)
+		s := New(nil, nil, &storageMockError{}, nil, nil)
 		_, err := s.GetTransactionById(context.Background(), "nil")
 		assert.NotNil(t, err)
 	})
@@ -457,12 +522,12 @@ func TestHandlers_GetTransactionById(t *testing.T) {
 
 func TestHandlers_GetTransactionGroups(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, nil, &storageMockOk{}, nil)
+		s := New(nil, nil, &storageMockOk{}, nil, nil)
 		_, err := s.GetTransactionGroups(context.Background(), nil)
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, nil, &storageMockError{}, nil)
+		s := New(nil, nil, &storageMockError{}, nil, nil)
 		_, err := s.GetTransactionGroups(context.Background(), nil)
 		assert.NotNil(t, err)
 	})
@@ -470,12 +535,12 @@ func TestHandlers_GetTransactionGroups(t *testing.T) {
 
 func TestHandlers_GetTransactionsList(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, nil, &storageMockOk{}, nil)
+		s := New(nil, nil, &storageMockOk{}, nil, nil)
 		_, err := s.GetTransactionsList(context.Background(), nil)
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, nil, &storageMockError{}, nil)
+		s := New(nil, nil, &storageMockError{}, nil, nil)
 		_, err := s.GetTransactionsList(context.Background(), nil)
 		assert.NotNil(t, err)
 	})
@@ -483,12 +548,12 @@ func TestHandlers_GetTransactionsList(t *testing.T) {
 
 func TestHandlers_RegisterApplication(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, nil, nil, &mockAmOk{})
+		s := New(nil, nil, nil, &mockAmOk{}, nil)
 		_, err := s.RegisterApplication(context.Background(), nil)
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, nil, nil, &mockAmError{})
+		s := New(nil, nil, nil, &mockAmError{}, nil)
 		_, err := s.RegisterApplication(context.Background(), nil)
 		assert.NotNil(t, err)
 	})
@@ -496,12 +561,12 @@ func TestHandlers_RegisterApplication(t *testing.T) {
 
 func TestHandlers_SaveTransaction(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, nil, nil, &mockAmOk{})
+		s := New(nil, nil, nil, &mockAmOk{}, nil)
 		_, err := s.SaveTransaction(context.Background(), nil)
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, nil, nil, &mockAmError{})
+		s := New(nil, nil, nil, &mockAmError{}, nil)
 		_, err := s.SaveTransaction(context.Background(), nil)
 		assert.NotNil(t, err)
 	})
@@ -509,12 +574,12 @@ func TestHandlers_SaveTransaction(t *testing.T) {
 
 func TestHandlers_ArchivedApplicationById(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, nil, nil, &mockAmOk{})
+		s := New(nil, nil, nil, &mockAmOk{}, nil)
 		_, err := s.ArchivedApplicationById(context.Background(), "")
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, nil, nil, &mockAmError{})
+		s := New(nil, nil, nil, &mockAmError{}, nil)
 		_, err := s.ArchivedApplicationById(context.Background(), "")
 		assert.NotNil(t, err)
 	})
@@ -522,12 +587,12 @@ func TestHandlers_ArchivedApplicationById(t *testing.T) {
 
 func TestHandlers_DisabledApplicationById(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, nil, nil, &mockAmOk{})
+		s := New(nil, nil, nil, &mockAmOk{}, nil)
 		_, err := s.DisabledApplicationById(context.Background(), "")
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, nil, nil, &mockAmError{})
+		s := New(nil, nil, nil, &mockAmError{}, nil)
 		_, err := s.DisabledApplicationById(context.Background(), "")
 		assert.NotNil(t, err)
 	})
@@ -535,12 +600,12 @@ func TestHandlers_DisabledApplicationById(t *testing.T) {
 
 func TestHandlers_EnabledApplicationById(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(nil, nil, nil, &mockAmOk{})
+		s := New(nil, nil, nil, &mockAmOk{}, nil)
 		_, err := s.EnabledApplicationById(context.Background(), "")
 		assert.Nil(t, err)
 	})
 	t.Run("Should: return error", func(t *testing.T) {
-		s := New(nil, nil, nil, &mockAmError{})
+		s := New(nil, nil, nil, &mockAmError{}, nil)
 		_, err := s.EnabledApplicationById(context.Background(), "")
 		assert.NotNil(t, err)
 	})
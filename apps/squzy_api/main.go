@@ -11,6 +11,7 @@ import (
 	"squzy/apps/squzy_api/router"
 	_ "squzy/apps/squzy_api/version"
 	"squzy/internal/grpctools"
+	monitoring_api "squzy/internal/monitoring-api"
 )
 
 func main() {
@@ -33,6 +34,7 @@ func main() {
 		_ = monitoringConn.Close()
 	}()
 	monitoringClient := apiPb.NewSchedulersExecutorClient(monitoringConn)
+	monitoringExtensionClient := monitoring_api.NewSchedulersExtensionClient(monitoringConn)
 	storageConn, err := tools.GetConnection(cfg.GetStorageServerAddress(), 0, grpc.WithInsecure())
 	if err != nil {
 		log.Fatal(err)
@@ -53,7 +55,7 @@ func main() {
 
 	log.Fatal(
 		router.New(
-			handlers.New(agentServerClient, monitoringClient, storageClient, appMonClient),
+			handlers.New(agentServerClient, monitoringClient, storageClient, appMonClient, monitoringExtensionClient),
 		).GetEngine().Run(fmt.Sprintf(":%d", cfg.GetPort())),
 	)
 }
@@ -10,6 +10,8 @@ import (
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
 	"net/http"
 	"squzy/apps/squzy_api/handlers"
+	"squzy/internal/cron"
+	monitoring_api "squzy/internal/monitoring-api"
 	"strconv"
 	"time"
 )
@@ -17,6 +19,7 @@ import (
 var (
 	errMissingConfig      = errors.New("missing config of scheduler")
 	errNotFoundConfigType = errors.New("not found config type")
+	errMissingSchedule    = errors.New("interval or cron of scheduler is required")
 )
 
 type Router interface {
@@ -47,6 +50,27 @@ type SchedulerHistory struct {
 	SortBy        apiPb.SortSchedulerList `form:"sort_by"`
 }
 
+// Snapshot of scheduler history, latency breakdown of http checks and
+// attempts of checks with retry or flap policy are taken from meta value
+type SchedulerSnapshot struct {
+	*apiPb.SchedulerSnapshot
+	Timings *monitoring_api.HTTPTimings      `json:"timings,omitempty"`
+	Policy  *monitoring_api.CheckPolicyState `json:"policy,omitempty"`
+}
+
+// Scheduler with its schedule, cron expression is not described by squzy_generated
+type SchedulerResponse struct {
+	*apiPb.Scheduler
+	Cron        string     `json:"cron,omitempty"`
+	Timezone    string     `json:"timezone,omitempty"`
+	NextRunTime *time.Time `json:"nextRunTime,omitempty"`
+}
+
+type SchedulerHistoryResponse struct {
+	Snapshots []*SchedulerSnapshot `json:"snapshots,omitempty"`
+	Count     int32                `json:"count,omitempty"`
+}
+
 type AgentHistory struct {
 	Pagination  *PaginationRequest
 	TimeFilters *TimeFilterRequest
@@ -84,15 +108,31 @@ type PaginationRequest struct {
 }
 
 type Scheduler struct {
-	Type            apiPb.SchedulerType        `json:"type"`
-	Interval        int32                      `json:"interval" binding:"required"`
-	Timeout         int32                      `json:"timeout"`
-	Name            string                     `json:"name"`
-	HTTPConfig      *apiPb.HttpConfig          `json:"httpConfig"`
-	TCPConfig       *apiPb.TcpConfig           `json:"tcpConfig"`
-	HTTPValueConfig *apiPb.HttpJsonValueConfig `json:"httpValueConfig"`
-	GRPCConfig      *apiPb.GrpcConfig          `json:"grpcConfig"`
-	SiteMapConfig   *apiPb.SiteMapConfig       `json:"siteMapConfig"`
+	Type             apiPb.SchedulerType              `json:"type"`
+	Interval         int32                            `json:"interval"`
+	Cron             string                           `json:"cron"`
+	Timezone         string                           `json:"timezone"`
+	Timeout          int32                            `json:"timeout"`
+	Name             string                           `json:"name"`
+	HTTPConfig       *monitoring_api.HTTPConfig       `json:"httpConfig"`
+	TCPConfig        *monitoring_api.TCPConfig        `json:"tcpConfig"`
+	HTTPValueConfig  *monitoring_api.HTTPValueConfig  `json:"httpValueConfig"`
+	GRPCConfig       *monitoring_api.GrpcConfig       `json:"grpcConfig"`
+	SiteMapConfig    *monitoring_api.SiteMapConfig    `json:"siteMapConfig"`
+	TLSCertConfig    *monitoring_api.TLSCertConfig    `json:"tlsCertConfig"`
+	DNSConfig        *monitoring_api.DNSConfig        `json:"dnsConfig"`
+	ScenarioConfig   *monitoring_api.ScenarioConfig   `json:"scenarioConfig"`
+	CrawlerConfig    *monitoring_api.CrawlerConfig    `json:"crawlerConfig"`
+	DatabaseConfig   *monitoring_api.DatabaseConfig   `json:"databaseConfig"`
+	UDPConfig        *monitoring_api.UDPConfig        `json:"udpConfig"`
+	WebSocketConfig  *monitoring_api.WebSocketConfig  `json:"webSocketConfig"`
+	PrometheusConfig *monitoring_api.PrometheusConfig `json:"prometheusConfig"`
+	ContentConfig    *monitoring_api.ContentConfig    `json:"contentConfig"`
+	CommandConfig    *monitoring_api.CommandConfig    `json:"commandConfig"`
+	Retry            *monitoring_api.RetryPolicy      `json:"retry"`
+	Flap             *monitoring_api.FlapPolicy       `json:"flap"`
+	Overlap          monitoring_api.OverlapPolicy     `json:"overlap"`
+	Deadline         int32                            `json:"deadline"`
 }
 
 type Application struct {
@@ -410,7 +450,12 @@ func (r *router) GetEngine() *gin.Engine {
 					errWrap(context, http.StatusInternalServerError, err)
 					return
 				}
-				successWrap(context, http.StatusOK, list)
+				schedules, err := r.handlers.GetSchedules(context, nil)
+				if err != nil {
+					errWrap(context, http.StatusInternalServerError, err)
+					return
+				}
+				successWrap(context, http.StatusOK, NewSchedulerListResponse(list, schedules))
 			})
 			schedulers.POST("", func(context *gin.Context) {
 				request := new(Scheduler)
@@ -419,72 +464,11 @@ func (r *router) GetEngine() *gin.Engine {
 					errWrap(context, http.StatusUnprocessableEntity, err)
 					return
 				}
-				var addReq *apiPb.AddRequest
-
-				switch request.Type {
-				case apiPb.SchedulerType_TCP:
-					if request.TCPConfig == nil {
-						errWrap(context, http.StatusUnprocessableEntity, errMissingConfig)
-						return
-					}
-					addReq = &apiPb.AddRequest{
-						Config: &apiPb.AddRequest_Tcp{
-							Tcp: request.TCPConfig,
-						},
-					}
-
-				case apiPb.SchedulerType_GRPC:
-					if request.GRPCConfig == nil {
-						errWrap(context, http.StatusUnprocessableEntity, errMissingConfig)
-						return
-					}
-					addReq = &apiPb.AddRequest{
-						Config: &apiPb.AddRequest_Grpc{
-							Grpc: request.GRPCConfig,
-						},
-					}
-
-				case apiPb.SchedulerType_HTTP:
-					if request.HTTPConfig == nil {
-						errWrap(context, http.StatusUnprocessableEntity, errMissingConfig)
-						return
-					}
-					addReq = &apiPb.AddRequest{
-						Config: &apiPb.AddRequest_Http{
-							Http: request.HTTPConfig,
-						},
-					}
-
-				case apiPb.SchedulerType_SITE_MAP:
-					if request.SiteMapConfig == nil {
-						errWrap(context, http.StatusUnprocessableEntity, errMissingConfig)
-						return
-					}
-					addReq = &apiPb.AddRequest{
-						Config: &apiPb.AddRequest_Sitemap{
-							Sitemap: request.SiteMapConfig,
-						},
-					}
-
-				case apiPb.SchedulerType_HTTP_JSON_VALUE:
-					if request.HTTPValueConfig == nil {
-						errWrap(context, http.StatusUnprocessableEntity, errMissingConfig)
-						return
-					}
-					addReq = &apiPb.AddRequest{
-						Config: &apiPb.AddRequest_HttpValue{
-							HttpValue: request.HTTPValueConfig,
-						},
-					}
-
-				default:
-					errWrap(context, http.StatusUnprocessableEntity, errNotFoundConfigType)
+				addReq, err := NewAddRequest(request)
+				if err != nil {
+					errWrap(context, http.StatusUnprocessableEntity, err)
 					return
 				}
-
-				addReq.Interval = request.Interval
-				addReq.Timeout = request.Timeout
-				addReq.Name = request.Name
 				res, err := r.handlers.AddScheduler(context, addReq)
 				if err != nil {
 					errWrap(context, http.StatusUnprocessableEntity, err)
@@ -502,7 +486,33 @@ func (r *router) GetEngine() *gin.Engine {
 						errWrap(context, http.StatusNotFound, err)
 						return
 					}
-					successWrap(context, http.StatusOK, scheduler)
+					schedules, err := r.handlers.GetSchedules(context, []string{schedulerID})
+					if err != nil {
+						errWrap(context, http.StatusNotFound, err)
+						return
+					}
+					successWrap(context, http.StatusOK, NewSchedulerResponse(scheduler, schedules))
+				})
+				// Update by ID
+				scheduler.PUT("", func(context *gin.Context) {
+					schedulerID := context.Param("schedulerId")
+					request := new(Scheduler)
+					err := context.ShouldBindJSON(request)
+					if err != nil {
+						errWrap(context, http.StatusUnprocessableEntity, err)
+						return
+					}
+					addReq, err := NewAddRequest(request)
+					if err != nil {
+						errWrap(context, http.StatusUnprocessableEntity, err)
+						return
+					}
+					err = r.handlers.UpdateScheduler(context, schedulerID, addReq)
+					if err != nil {
+						errWrap(context, http.StatusUnprocessableEntity, err)
+						return
+					}
+					successWrap(context, http.StatusAccepted, nil)
 				})
 				// Run by ID
 				scheduler.PUT("run", func(context *gin.Context) {
@@ -592,7 +602,7 @@ func (r *router) GetEngine() *gin.Engine {
 						errWrap(context, http.StatusInternalServerError, err)
 						return
 					}
-					successWrap(context, http.StatusOK, res)
+					successWrap(context, http.StatusOK, NewSchedulerHistoryResponse(res))
 				})
 			}
 		}
@@ -601,6 +611,165 @@ func (r *router) GetEngine() *gin.Engine {
 	return engine
 }
 
+func NewSchedulerResponse(scheduler *apiPb.Scheduler, schedules []*monitoring_api.Schedule) *SchedulerResponse {
+	return NewSchedulerListResponse([]*apiPb.Scheduler{scheduler}, schedules)[0]
+}
+
+func NewSchedulerListResponse(list []*apiPb.Scheduler, schedules []*monitoring_api.Schedule) []*SchedulerResponse {
+	byID := map[string]*monitoring_api.Schedule{}
+	for _, schedule := range schedules {
+		byID[schedule.ID] = schedule
+	}
+	result := []*SchedulerResponse{}
+	for _, scheduler := range list {
+		response := &SchedulerResponse{
+			Scheduler: scheduler,
+		}
+		if schedule, ok := byID[scheduler.GetId()]; ok {
+			response.Cron = schedule.Cron
+			response.Timezone = schedule.Timezone
+			response.NextRunTime = schedule.NextRunTime
+		}
+		result = append(result, response)
+	}
+	return result
+}
+
+// Validates scheduler from request body and converts it to request of monitoring, used by add and update
+func NewAddRequest(request *Scheduler) (*monitoring_api.AddRequest, error) {
+	if request.Cron != "" {
+		_, err := cron.Parse(request.Cron, request.Timezone)
+		if err != nil {
+			return nil, err
+		}
+	} else if request.Interval <= 0 {
+		return nil, errMissingSchedule
+	}
+	addReq := &monitoring_api.AddRequest{
+		Type:     request.Type,
+		Interval: request.Interval,
+		Cron:     request.Cron,
+		Timezone: request.Timezone,
+		Timeout:  request.Timeout,
+		Name:     request.Name,
+		Retry:    request.Retry,
+		Flap:     request.Flap,
+		Overlap:  request.Overlap,
+		Deadline: request.Deadline,
+	}
+
+	switch request.Type {
+	case apiPb.SchedulerType_TCP:
+		if request.TCPConfig == nil {
+			return nil, errMissingConfig
+		}
+		addReq.Tcp = request.TCPConfig
+
+	case apiPb.SchedulerType_GRPC:
+		if request.GRPCConfig == nil {
+			return nil, errMissingConfig
+		}
+		addReq.Grpc = request.GRPCConfig
+
+	case apiPb.SchedulerType_HTTP:
+		if request.HTTPConfig == nil {
+			return nil, errMissingConfig
+		}
+		addReq.Http = request.HTTPConfig
+
+	case apiPb.SchedulerType_SITE_MAP:
+		if request.SiteMapConfig == nil {
+			return nil, errMissingConfig
+		}
+		addReq.Sitemap = request.SiteMapConfig
+
+	case apiPb.SchedulerType_HTTP_JSON_VALUE:
+		if request.HTTPValueConfig == nil {
+			return nil, errMissingConfig
+		}
+		addReq.HttpValue = request.HTTPValueConfig
+
+	case monitoring_api.SchedulerTypeTLSCert:
+		if request.TLSCertConfig == nil {
+			return nil, errMissingConfig
+		}
+		addReq.TLSCert = request.TLSCertConfig
+
+	case monitoring_api.SchedulerTypeDNS:
+		if request.DNSConfig == nil {
+			return nil, errMissingConfig
+		}
+		addReq.DNS = request.DNSConfig
+
+	case monitoring_api.SchedulerTypeScenario:
+		if request.ScenarioConfig == nil {
+			return nil, errMissingConfig
+		}
+		addReq.Scenario = request.ScenarioConfig
+
+	case monitoring_api.SchedulerTypeCrawler:
+		if request.CrawlerConfig == nil {
+			return nil, errMissingConfig
+		}
+		addReq.Crawler = request.CrawlerConfig
+
+	case monitoring_api.SchedulerTypePostgres, monitoring_api.SchedulerTypeMySQL, monitoring_api.SchedulerTypeRedis, monitoring_api.SchedulerTypeMongo:
+		if request.DatabaseConfig == nil {
+			return nil, errMissingConfig
+		}
+		addReq.Database = request.DatabaseConfig
+
+	case monitoring_api.SchedulerTypeUDP:
+		if request.UDPConfig == nil {
+			return nil, errMissingConfig
+		}
+		addReq.UDP = request.UDPConfig
+
+	case monitoring_api.SchedulerTypeWebSocket:
+		if request.WebSocketConfig == nil {
+			return nil, errMissingConfig
+		}
+		addReq.WebSocket = request.WebSocketConfig
+
+	case monitoring_api.SchedulerTypePrometheus:
+		if request.PrometheusConfig == nil {
+			return nil, errMissingConfig
+		}
+		addReq.Prometheus = request.PrometheusConfig
+
+	case monitoring_api.SchedulerTypeContent:
+		if request.ContentConfig == nil {
+			return nil, errMissingConfig
+		}
+		addReq.Content = request.ContentConfig
+
+	case monitoring_api.SchedulerTypeCommand:
+		if request.CommandConfig == nil {
+			return nil, errMissingConfig
+		}
+		addReq.Command = request.CommandConfig
+
+	default:
+		return nil, errNotFoundConfigType
+	}
+	return addReq, nil
+}
+
+func NewSchedulerHistoryResponse(res *apiPb.GetSchedulerInformationResponse) *SchedulerHistoryResponse {
+	snapshots := []*SchedulerSnapshot{}
+	for _, snapshot := range res.GetSnapshots() {
+		snapshots = append(snapshots, &SchedulerSnapshot{
+			SchedulerSnapshot: snapshot,
+			Timings:           monitoring_api.HTTPTimingsFromMetaValue(snapshot.GetMeta().GetValue()),
+			Policy:            monitoring_api.CheckPolicyStateFromMetaValue(snapshot.GetMeta().GetValue()),
+		})
+	}
+	return &SchedulerHistoryResponse{
+		Snapshots: snapshots,
+		Count:     res.GetCount(),
+	}
+}
+
 func GetSchedulerListSorting(direction apiPb.SortDirection, sortBy apiPb.SortSchedulerList) *apiPb.SortingSchedulerList {
 	if sortBy == apiPb.SortSchedulerList_SORT_SCHEDULER_LIST_UNSPECIFIED {
 		return nil
@@ -3,14 +3,17 @@ package router
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/empty"
+	structType "github.com/golang/protobuf/ptypes/struct"
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
 	"github.com/stretchr/testify/assert"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	monitoring_api "squzy/internal/monitoring-api"
 	"testing"
 	"time"
 )
@@ -98,10 +101,26 @@ func (m mockOk) RemoveScheduler(ctx context.Context, id string) error {
 	return nil
 }
 
-func (m mockOk) AddScheduler(ctx context.Context, scheduler *apiPb.AddRequest) (*apiPb.AddResponse, error) {
+func (m mockOk) AddScheduler(ctx context.Context, scheduler *monitoring_api.AddRequest) (*apiPb.AddResponse, error) {
 	return &apiPb.AddResponse{}, nil
 }
 
+func (m mockOk) UpdateScheduler(ctx context.Context, id string, scheduler *monitoring_api.AddRequest) error {
+	return nil
+}
+
+func (m mockOk) GetSchedules(ctx context.Context, ids []string) ([]*monitoring_api.Schedule, error) {
+	return []*monitoring_api.Schedule{}, nil
+}
+
+type mockSchedulesError struct {
+	mockOk
+}
+
+func (m mockSchedulesError) GetSchedules(ctx context.Context, ids []string) ([]*monitoring_api.Schedule, error) {
+	return nil, errors.New("")
+}
+
 type mockError struct {
 }
 
@@ -185,7 +204,15 @@ func (m mockError) RemoveScheduler(ctx context.Context, id string) error {
 	return errors.New("")
 }
 
-func (m mockError) AddScheduler(ctx context.Context, scheduler *apiPb.AddRequest) (*apiPb.AddResponse, error) {
+func (m mockError) AddScheduler(ctx context.Context, scheduler *monitoring_api.AddRequest) (*apiPb.AddResponse, error) {
+	return nil, errors.New("")
+}
+
+func (m mockError) UpdateScheduler(ctx context.Context, id string, scheduler *monitoring_api.AddRequest) error {
+	return errors.New("")
+}
+
+func (m mockError) GetSchedules(ctx context.Context, ids []string) ([]*monitoring_api.Schedule, error) {
 	return nil, errors.New("")
 }
 
@@ -246,6 +273,29 @@ func TestRouter_GetEngine(t *testing.T) {
 				Method:       http.MethodPut,
 				ExpectedCode: http.StatusNotFound,
 			},
+			{
+				Path:         "/v1/schedulers/scheduler",
+				Method:       http.MethodPut,
+				ExpectedCode: http.StatusUnprocessableEntity,
+			},
+			{
+				Path:         "/v1/schedulers/scheduler",
+				Method:       http.MethodPut,
+				ExpectedCode: http.StatusUnprocessableEntity,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 30,
+							"timeout": 10,
+							"type": 1,
+							"tcpConfig": {
+								"host": "localhost",
+								"port": 80
+							}
+						}
+					`,
+				)),
+			},
 			{
 				Path:         "/v1/schedulers",
 				Method:       http.MethodPost,
@@ -265,6 +315,51 @@ func TestRouter_GetEngine(t *testing.T) {
 					`,
 				)),
 			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusUnprocessableEntity,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"timeout": 10,
+							"type": 6,
+							"tlsCertConfig": {"host": "squzy.app"}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusUnprocessableEntity,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"cron": "*/5 * * *",
+							"timeout": 10,
+							"type": 6,
+							"tlsCertConfig": {"host": "squzy.app"}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusUnprocessableEntity,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"cron": "0 0 3 * * *",
+							"timezone": "Mars/Olympus",
+							"timeout": 10,
+							"type": 6,
+							"tlsCertConfig": {"host": "squzy.app"}
+						}
+					`,
+				)),
+			},
 			{
 				Path:         "/v1/schedulers",
 				Method:       http.MethodPost,
@@ -337,6 +432,20 @@ func TestRouter_GetEngine(t *testing.T) {
 					`,
 				)),
 			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusUnprocessableEntity,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 10,
+							"timeout": 10,
+							"type": 6
+						}
+					`,
+				)),
+			},
 			{
 				Path:         "/v1/schedulers",
 				Method:       http.MethodPost,
@@ -673,6 +782,55 @@ func TestRouter_GetEngine(t *testing.T) {
 				Method:       http.MethodPut,
 				ExpectedCode: http.StatusAccepted,
 			},
+			{
+				Path:         "/v1/schedulers/scheduler",
+				Method:       http.MethodPut,
+				ExpectedCode: http.StatusAccepted,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 30,
+							"timeout": 10,
+							"type": 1,
+							"tcpConfig": {
+								"host": "localhost",
+								"port": 80
+							}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers/scheduler",
+				Method:       http.MethodPut,
+				ExpectedCode: http.StatusUnprocessableEntity,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 30,
+							"timeout": 10,
+							"type": 1
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers/scheduler",
+				Method:       http.MethodPut,
+				ExpectedCode: http.StatusUnprocessableEntity,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"timeout": 10,
+							"type": 1,
+							"tcpConfig": {
+								"host": "localhost",
+								"port": 80
+							}
+						}
+					`,
+				)),
+			},
 			{
 				Path:         "/v1/schedulers",
 				Method:       http.MethodPost,
@@ -709,6 +867,31 @@ func TestRouter_GetEngine(t *testing.T) {
 					`,
 				)),
 			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 10,
+							"timeout": 10,
+							"type": 2,
+							"grpcConfig": {
+								"host": "localhost",
+								"port": 443,
+								"tls": {"server_name": "squzy.app"},
+								"metadata": {"authorization": "Bearer token"},
+								"call": {
+									"method": "grpc.health.v1.Health/Check",
+									"request": "{}",
+									"selectors": [{"type": 1, "path": "status"}]
+								}
+							}
+						}
+					`,
+				)),
+			},
 			{
 				Path:         "/v1/schedulers",
 				Method:       http.MethodPost,
@@ -727,6 +910,52 @@ func TestRouter_GetEngine(t *testing.T) {
 					`,
 				)),
 			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 10,
+							"timeout": 10,
+							"type": 3,
+							"httpConfig": {
+								"method": "GET",
+								"url": "https://google.ru",
+								"assertions": [
+									{
+										"type": "body_not_contains",
+										"value": "maintenance"
+									}
+								]
+							}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 10,
+							"timeout": 10,
+							"type": 3,
+							"httpConfig": {
+								"method": "POST",
+								"url": "https://squzy.app/graphql",
+								"body": {
+									"type": "json",
+									"content": "{\"query\":\"{ health }\"}"
+								}
+							}
+						}
+					`,
+				)),
+			},
 			{
 				Path:         "/v1/schedulers",
 				Method:       http.MethodPost,
@@ -757,6 +986,331 @@ func TestRouter_GetEngine(t *testing.T) {
 					`,
 				)),
 			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 10,
+							"timeout": 10,
+							"type": 5,
+							"httpValueConfig": {
+								"method": "GET",
+								"url": "https://squzy.app",
+								"selectors": [
+									{
+										"type": 3,
+										"path": "queue.depth",
+										"rule": {
+											"type": "lt",
+											"number": 1000
+										}
+									}
+								]
+							}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 10,
+							"timeout": 10,
+							"type": 5,
+							"httpValueConfig": {
+								"method": "GET",
+								"url": "https://squzy.app/status.xml",
+								"format": "xml",
+								"selectors": [
+									{
+										"type": 1,
+										"path": "//service[@name='billing']/@state",
+										"rule": {
+											"type": "equals",
+											"value": "up"
+										}
+									}
+								]
+							}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 10,
+							"timeout": 10,
+							"type": 6,
+							"tlsCertConfig": {}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 10,
+							"timeout": 10,
+							"type": 7,
+							"dnsConfig": {}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 10,
+							"timeout": 10,
+							"type": 8,
+							"scenarioConfig": {
+								"steps": [
+									{
+										"method": "GET",
+										"url": "https://squzy.app"
+									}
+								]
+							}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 10,
+							"timeout": 10,
+							"type": 9,
+							"crawlerConfig": {
+								"url": "https://squzy.app",
+								"max_depth": 3,
+								"exclude": ["/admin/"]
+							}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 10,
+							"timeout": 10,
+							"type": 10,
+							"databaseConfig": {
+								"host": "localhost",
+								"port": 5432,
+								"user": "squzy",
+								"query": "SELECT pg_is_in_recovery()",
+								"value": {
+									"type": 2,
+									"rule": {"type": "equals", "bool": false}
+								}
+							}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 10,
+							"timeout": 10,
+							"type": 14,
+							"udpConfig": {
+								"host": "localhost",
+								"port": 11211,
+								"send": "stats",
+								"expect": {"type": "prefix", "value": "STAT"}
+							}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 10,
+							"timeout": 10,
+							"type": 1,
+							"tcpConfig": {
+								"host": "localhost",
+								"port": 25,
+								"expect": {"type": "regex", "value": "^220 "},
+								"read_timeout_ms": 500
+							}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 10,
+							"timeout": 10,
+							"type": 15,
+							"webSocketConfig": {
+								"url": "wss://squzy.app/ws",
+								"headers": {"Authorization": "Bearer squzy"},
+								"send": "ping",
+								"expect": {"type": "exact", "value": "pong"}
+							}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 10,
+							"timeout": 10,
+							"type": 16,
+							"prometheusConfig": {
+								"url": "https://squzy.app/metrics",
+								"selectors": [
+									{
+										"name": "queue_size",
+										"labels": [{"name": "queue", "type": "=", "value": "emails"}],
+										"aggregate": "sum",
+										"rule": {"type": "lt", "number": 100}
+									}
+								]
+							}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 3600,
+							"timeout": 10,
+							"type": 17,
+							"contentConfig": {
+								"url": "https://squzy.app/terms",
+								"region": {"type": "xpath", "path": "//div[@id='terms']"},
+								"ignore": ["\\d{4}-\\d{2}-\\d{2}"]
+							}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 60,
+							"timeout": 10,
+							"type": 18,
+							"commandConfig": {
+								"path": "/usr/lib/nagios/plugins/check_disk",
+								"args": ["-w", "20%", "-p", "/"],
+								"env": {"LANG": "C"}
+							}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"cron": "0 */5 9-17 * * MON-FRI; 0 0 0-8,18-23 * * *",
+							"timezone": "Europe/Moscow",
+							"timeout": 10,
+							"type": 6,
+							"tlsCertConfig": {"host": "squzy.app"}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 60,
+							"timeout": 10,
+							"type": 1,
+							"tcpConfig": {"host": "localhost", "port": 5432},
+							"retry": {"attempts": 3, "backoff_ms": 500, "backoff_multiplier": 2},
+							"flap": {"confirm_after": 2, "recover_after": 2}
+						}
+					`,
+				)),
+			},
+			{
+				Path:         "/v1/schedulers",
+				Method:       http.MethodPost,
+				ExpectedCode: http.StatusCreated,
+				Body: bytes.NewBuffer([]byte(
+					`
+						{
+							"interval": 3600,
+							"timeout": 10,
+							"type": 9,
+							"crawlerConfig": {"url": "https://squzy.app"},
+							"overlap": "queue_one",
+							"deadline": 1800
+						}
+					`,
+				)),
+			},
 			{
 				Path:         "/v1/schedulers/schdeduler/history?dateFrom=2020-05-17T19:17:05.899Z&dateTo=2020-05-17T19:17:05.899Z&page=2&limit=4",
 				Method:       http.MethodGet,
@@ -871,6 +1425,52 @@ func TestRouter_GetEngine(t *testing.T) {
 	})
 }
 
+func TestRouter_GetSchedules(t *testing.T) {
+	t.Run("Should: return error because schedules are not received", func(t *testing.T) {
+		r := New(&mockSchedulesError{}).GetEngine()
+		for _, test := range []struct {
+			Path         string
+			ExpectedCode int
+		}{
+			{Path: "/v1/schedulers", ExpectedCode: http.StatusInternalServerError},
+			{Path: "/v1/schedulers/scheduler", ExpectedCode: http.StatusNotFound},
+		} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, test.Path, nil)
+			r.ServeHTTP(w, req)
+			assert.Equal(t, test.ExpectedCode, w.Code, test.Path)
+		}
+	})
+}
+
+func TestNewSchedulerListResponse(t *testing.T) {
+	t.Run("Should: return schedulers with schedules", func(t *testing.T) {
+		next := time.Date(2020, 6, 1, 3, 0, 0, 0, time.UTC)
+		res := NewSchedulerListResponse([]*apiPb.Scheduler{
+			{Id: "cron", Interval: 0},
+			{Id: "interval", Interval: 10},
+		}, []*monitoring_api.Schedule{
+			{ID: "cron", Cron: "0 0 3 * * *", Timezone: "Europe/Moscow", NextRunTime: &next},
+		})
+		assert.Equal(t, "cron", res[0].Id)
+		assert.Equal(t, "0 0 3 * * *", res[0].Cron)
+		assert.Equal(t, "Europe/Moscow", res[0].Timezone)
+		assert.Equal(t, &next, res[0].NextRunTime)
+		assert.Equal(t, int32(10), res[1].Interval)
+		assert.Equal(t, "", res[1].Cron)
+		assert.Nil(t, res[1].NextRunTime)
+	})
+	t.Run("Should: return single scheduler as json", func(t *testing.T) {
+		next := time.Date(2020, 6, 1, 3, 0, 0, 0, time.UTC)
+		res := NewSchedulerResponse(&apiPb.Scheduler{Id: "cron", Name: "daily"}, []*monitoring_api.Schedule{
+			{ID: "cron", Cron: "@daily", NextRunTime: &next},
+		})
+		data, err := json.Marshal(res)
+		assert.Nil(t, err)
+		assert.JSONEq(t, `{"id": "cron", "name": "daily", "Config": null, "cron": "@daily", "nextRunTime": "2020-06-01T03:00:00Z"}`, string(data))
+	})
+}
+
 func TestGetStringValueFromString(t *testing.T) {
 	t.Run("Should: return nil", func(t *testing.T) {
 		assert.Nil(t, GetStringValueFromString(""))
@@ -880,6 +1480,73 @@ func TestGetStringValueFromString(t *testing.T) {
 	})
 }
 
+func TestNewSchedulerHistoryResponse(t *testing.T) {
+	t.Run("Should: return snapshots with timings", func(t *testing.T) {
+		timings := &monitoring_api.HTTPTimings{
+			DNSLookupMs: 1,
+			TotalMs:     10,
+		}
+		res := NewSchedulerHistoryResponse(&apiPb.GetSchedulerInformationResponse{
+			Snapshots: []*apiPb.SchedulerSnapshot{
+				{
+					Type: apiPb.SchedulerType_HTTP,
+					Meta: &apiPb.SchedulerSnapshot_MetaData{
+						Value: &structType.Value{
+							Kind: &structType.Value_StructValue{
+								StructValue: &structType.Struct{
+									Fields: map[string]*structType.Value{
+										monitoring_api.SnapshotTimingsKey: timings.ToValue(),
+									},
+								},
+							},
+						},
+					},
+				},
+				{
+					Type: apiPb.SchedulerType_TCP,
+				},
+			},
+			Count: 2,
+		})
+		assert.Equal(t, int32(2), res.Count)
+		assert.Equal(t, timings, res.Snapshots[0].Timings)
+		assert.Nil(t, res.Snapshots[1].Timings)
+		assert.Equal(t, apiPb.SchedulerType_TCP, res.Snapshots[1].Type)
+	})
+	t.Run("Should: return snapshots with policy state", func(t *testing.T) {
+		policy := &monitoring_api.CheckPolicyState{
+			Attempts: []*monitoring_api.CheckAttempt{
+				{Code: apiPb.SchedulerCode_ERROR, Message: "timeout"},
+			},
+			RawCode:             apiPb.SchedulerCode_ERROR,
+			ConfirmedCode:       apiPb.SchedulerCode_OK,
+			ConsecutiveFailures: 1,
+		}
+		res := NewSchedulerHistoryResponse(&apiPb.GetSchedulerInformationResponse{
+			Snapshots: []*apiPb.SchedulerSnapshot{
+				{
+					Type: apiPb.SchedulerType_TCP,
+					Code: apiPb.SchedulerCode_OK,
+					Meta: &apiPb.SchedulerSnapshot_MetaData{
+						Value: &structType.Value{
+							Kind: &structType.Value_StructValue{
+								StructValue: &structType.Struct{
+									Fields: map[string]*structType.Value{
+										monitoring_api.SnapshotPolicyKey: policy.ToValue(),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			Count: 1,
+		})
+		assert.Equal(t, policy, res.Snapshots[0].Policy)
+		assert.Nil(t, res.Snapshots[0].Timings)
+	})
+}
+
 func TestGetSchedulerListSorting(t *testing.T) {
 	t.Run("Should: return nil", func(t *testing.T) {
 		assert.Nil(t, GetSchedulerListSorting(0, 0))
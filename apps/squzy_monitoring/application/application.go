@@ -10,33 +10,37 @@ import (
 	"net"
 	"os"
 	"squzy/apps/squzy_monitoring/server"
-	"squzy/internal/helpers"
 	job_executor "squzy/internal/job-executor"
-	"squzy/internal/scheduler"
+	monitoring_api "squzy/internal/monitoring-api"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
 	scheduler_storage "squzy/internal/scheduler-storage"
+	"time"
 )
 
 type app struct {
 	schedulerStorage scheduler_storage.SchedulerStorage
 	jobExecutor      job_executor.JobExecutor
 	configStorage    scheduler_config_storage.Storage
+	startJitter      time.Duration
 }
 
 func New(
 	schedulerStorage scheduler_storage.SchedulerStorage,
 	jobExecutor job_executor.JobExecutor,
 	configStorage scheduler_config_storage.Storage,
+	startJitter time.Duration,
 ) *app {
 	return &app{
 		schedulerStorage: schedulerStorage,
 		jobExecutor:      jobExecutor,
 		configStorage:    configStorage,
+		startJitter:      startJitter,
 	}
 }
 
 func (s *app) SyncOne(config *scheduler_config_storage.SchedulerConfig) error {
-	sched, err := scheduler.New(config.ID, helpers.DurationFromSecond(config.Interval), s.jobExecutor)
+	// Synced schedulers are started together, jitter spreads their ticks
+	sched, err := server.NewScheduler(config, s.startJitter, s.jobExecutor)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, fmt.Sprintf("SchedulerId: %s cant synced, error in config", config.ID.Hex()))
 		// @TODO logger here
@@ -98,5 +102,13 @@ func (s *app) Run(port int32) error {
 			s.configStorage,
 		),
 	)
+	monitoring_api.RegisterSchedulersExtensionServer(
+		grpcServer,
+		server.NewExtension(
+			s.schedulerStorage,
+			s.jobExecutor,
+			s.configStorage,
+		),
+	)
 	return grpcServer.Serve(lis)
 }
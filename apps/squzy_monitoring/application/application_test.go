@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"net"
+	monitoring_api "squzy/internal/monitoring-api"
 	"squzy/internal/scheduler"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
 	"testing"
@@ -27,6 +28,10 @@ func (m mockStorageError) Set(scheduler.Scheduler) error {
 	return errors.New("")
 }
 
+func (m mockStorageError) Replace(scheduler.Scheduler) error {
+	panic("implement me")
+}
+
 func (m mockStorageError) Remove(string) error {
 	panic("implement me")
 }
@@ -57,6 +62,10 @@ func (m mockConfigStorageError) Stop(ctx context.Context, schedulerId primitive.
 	panic("implement me")
 }
 
+func (m mockConfigStorageError) Update(ctx context.Context, config *scheduler_config_storage.SchedulerConfig) error {
+	panic("implement me")
+}
+
 func (m mockConfigStorageError) GetAll(ctx context.Context) ([]*scheduler_config_storage.SchedulerConfig, error) {
 	panic("implement me")
 }
@@ -65,6 +74,10 @@ func (m mockConfigStorageError) GetAllForSync(ctx context.Context) ([]*scheduler
 	return nil, errors.New("asf")
 }
 
+func (m mockConfigStorageError) SetContentHash(ctx context.Context, schedulerID primitive.ObjectID, hash string) error {
+	return errors.New("asf")
+}
+
 func (m mockConfigStorageOk) Get(ctx context.Context, schedulerId primitive.ObjectID) (*scheduler_config_storage.SchedulerConfig, error) {
 	panic("implement me")
 }
@@ -85,6 +98,10 @@ func (m mockConfigStorageOk) Stop(ctx context.Context, schedulerId primitive.Obj
 	panic("implement me")
 }
 
+func (m mockConfigStorageOk) Update(ctx context.Context, config *scheduler_config_storage.SchedulerConfig) error {
+	panic("implement me")
+}
+
 func (m mockConfigStorageOk) GetAll(ctx context.Context) ([]*scheduler_config_storage.SchedulerConfig, error) {
 	panic("implement me")
 }
@@ -108,6 +125,10 @@ func (m mockConfigStorageOk) GetAllForSync(ctx context.Context) ([]*scheduler_co
 	}, nil
 }
 
+func (m mockConfigStorageOk) SetContentHash(ctx context.Context, schedulerID primitive.ObjectID, hash string) error {
+	return nil
+}
+
 type mockStorageOk struct {
 }
 
@@ -119,6 +140,10 @@ func (m mockStorageOk) Set(scheduler.Scheduler) error {
 	return nil
 }
 
+func (m mockStorageOk) Replace(scheduler.Scheduler) error {
+	panic("implement me")
+}
+
 func (m mockStorageOk) Remove(string) error {
 	panic("implement me")
 }
@@ -126,16 +151,23 @@ func (m mockStorageOk) Remove(string) error {
 func (m mockExecuter) Execute(schedulerId primitive.ObjectID) {
 }
 
+func (m mockExecuter) Reset(schedulerId primitive.ObjectID) {
+}
+
+func (m mockExecuter) GetDiagnostics() *monitoring_api.Diagnostics {
+	return &monitoring_api.Diagnostics{}
+}
+
 func TestNew(t *testing.T) {
 	t.Run("Should: Create new application", func(t *testing.T) {
-		app := New(nil, nil, nil)
+		app := New(nil, nil, nil, 0)
 		assert.NotEqual(t, nil, app)
 	})
 }
 
 func TestApp_Run(t *testing.T) {
 	t.Run("Should: not return error", func(t *testing.T) {
-		app := New(&mockStorageOk{}, &mockExecuter{}, &mockConfigStorageOk{})
+		app := New(&mockStorageOk{}, &mockExecuter{}, &mockConfigStorageOk{}, 0)
 		go func() {
 			_ = app.Run(11111)
 		}()
@@ -144,11 +176,11 @@ func TestApp_Run(t *testing.T) {
 		assert.Equal(t, nil, err)
 	})
 	t.Run("Should: return error because port is wrong", func(t *testing.T) {
-		app := New(&mockStorageOk{}, &mockExecuter{}, &mockConfigStorageOk{})
+		app := New(&mockStorageOk{}, &mockExecuter{}, &mockConfigStorageOk{}, 0)
 		assert.NotEqual(t, nil, app.Run(1244214))
 	})
 	t.Run("Should: return err because cant sync with DB", func(t *testing.T) {
-		app := New(&mockStorageOk{}, &mockExecuter{}, &mockConfigStorageError{})
+		app := New(&mockStorageOk{}, &mockExecuter{}, &mockConfigStorageError{}, 0)
 		go func() {
 			_ = app.Run(11111)
 		}()
@@ -160,7 +192,7 @@ func TestApp_Run(t *testing.T) {
 
 func TestApp_SyncOne(t *testing.T) {
 	t.Run("Should: return error because config wrong", func(t *testing.T) {
-		app := New(&mockStorageOk{}, &mockExecuter{}, nil)
+		app := New(&mockStorageOk{}, &mockExecuter{}, nil, 0)
 		err := app.SyncOne(&scheduler_config_storage.SchedulerConfig{
 			ID:       primitive.ObjectID{},
 			Type:     0,
@@ -170,8 +202,18 @@ func TestApp_SyncOne(t *testing.T) {
 		})
 		assert.NotEqual(t, nil, err)
 	})
+	t.Run("Should: return error because cron expression wrong", func(t *testing.T) {
+		app := New(&mockStorageOk{}, &mockExecuter{}, nil, 0)
+		err := app.SyncOne(&scheduler_config_storage.SchedulerConfig{
+			ID:      primitive.ObjectID{},
+			Status:  apiPb.SchedulerStatus_RUNNED,
+			Cron:    "*/5 * * *",
+			Timeout: 1,
+		})
+		assert.NotEqual(t, nil, err)
+	})
 	t.Run("Should: return error because cant set in storage", func(t *testing.T) {
-		app := New(&mockStorageError{}, &mockExecuter{}, nil)
+		app := New(&mockStorageError{}, &mockExecuter{}, nil, 0)
 		err := app.SyncOne(&scheduler_config_storage.SchedulerConfig{
 			ID:       primitive.ObjectID{},
 			Type:     0,
@@ -182,7 +224,7 @@ func TestApp_SyncOne(t *testing.T) {
 		assert.NotEqual(t, nil, err)
 	})
 	t.Run("Should: return nil because status stopped", func(t *testing.T) {
-		app := New(&mockStorageOk{}, &mockExecuter{}, nil)
+		app := New(&mockStorageOk{}, &mockExecuter{}, nil, 0)
 		err := app.SyncOne(&scheduler_config_storage.SchedulerConfig{
 			ID:       primitive.ObjectID{},
 			Type:     0,
@@ -193,7 +235,7 @@ func TestApp_SyncOne(t *testing.T) {
 		assert.Equal(t, nil, err)
 	})
 	t.Run("Should: return nil because status runned, ", func(t *testing.T) {
-		app := New(&mockStorageOk{}, &mockExecuter{}, nil)
+		app := New(&mockStorageOk{}, &mockExecuter{}, nil, 0)
 		err := app.SyncOne(&scheduler_config_storage.SchedulerConfig{
 			ID:       primitive.ObjectID{},
 			Type:     0,
@@ -203,4 +245,15 @@ func TestApp_SyncOne(t *testing.T) {
 		})
 		assert.Equal(t, nil, err)
 	})
+	t.Run("Should: return nil because cron scheduler runned", func(t *testing.T) {
+		app := New(&mockStorageOk{}, &mockExecuter{}, nil, 0)
+		err := app.SyncOne(&scheduler_config_storage.SchedulerConfig{
+			ID:       primitive.ObjectID{},
+			Status:   apiPb.SchedulerStatus_RUNNED,
+			Cron:     "0 0 3 * * *",
+			Timezone: "Europe/Moscow",
+			Timeout:  1,
+		})
+		assert.Equal(t, nil, err)
+	})
 }
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"squzy/internal/helpers"
 	"strconv"
 	"time"
@@ -14,11 +15,26 @@ const (
 	ENV_MONGO_URI        = "MONGO_URI"
 	ENV_MONGO_COLLECTION = "MONGO_COLLECTION"
 	ENV_STORAGE_HOST     = "SQUZY_STORAGE_HOST"
+	ENV_SITEMAP_DEPTH    = "SQUZY_SITEMAP_MAX_DEPTH"
+	ENV_SITEMAP_URLS     = "SQUZY_SITEMAP_MAX_URLS"
+	ENV_SITEMAP_TTL      = "SQUZY_SITEMAP_CACHE_TTL"
+	ENV_SITEMAP_SIZE     = "SQUZY_SITEMAP_CACHE_SIZE"
+	ENV_SITEMAP_MAX_SIZE = "SQUZY_SITEMAP_MAX_SIZE"
+	ENV_COMMAND_DIRS     = "SQUZY_COMMAND_DIRS"
+	ENV_START_JITTER     = "SQUZY_START_JITTER"
+	ENV_MAX_JOBS         = "SQUZY_MAX_CONCURRENT_JOBS"
+	ENV_HOST_RATE_LIMIT  = "SQUZY_HOST_RATE_LIMIT"
 
-	defaultPort           int32 = 9090
-	defaultStorageTimeout       = time.Second * 5
-	defaultMongoDb              = "squzy_monitoring"
-	defaultCollection           = "schedulers"
+	defaultPort             int32 = 9090
+	defaultStorageTimeout         = time.Second * 5
+	defaultMongoDb                = "squzy_monitoring"
+	defaultCollection             = "schedulers"
+	defaultSiteMapMaxDepth        = 3
+	defaultSiteMapMaxURLs         = 50000
+	defaultSiteMapCacheTTL        = time.Hour * 24
+	defaultSiteMapCacheSize       = 1000
+	defaultSiteMapMaxSize         = 50 * 1024 * 1024
+	defaultStartJitter            = time.Second * 10
 )
 
 type cfg struct {
@@ -28,6 +44,15 @@ type cfg struct {
 	mongoURI        string
 	mongoDb         string
 	mongoCollection string
+	siteMapMaxDepth int
+	siteMapMaxURLs  int
+	siteMapTTL      time.Duration
+	siteMapSize     int
+	siteMapMaxSize  int
+	commandDirs     []string
+	startJitter     time.Duration
+	maxJobs         int
+	hostRateLimit   float64
 }
 
 func (c *cfg) GetPort() int32 {
@@ -54,6 +79,42 @@ func (c *cfg) GetMongoCollection() string {
 	return c.mongoCollection
 }
 
+func (c *cfg) GetSiteMapMaxDepth() int {
+	return c.siteMapMaxDepth
+}
+
+func (c *cfg) GetSiteMapMaxURLs() int {
+	return c.siteMapMaxURLs
+}
+
+func (c *cfg) GetSiteMapCacheTTL() time.Duration {
+	return c.siteMapTTL
+}
+
+func (c *cfg) GetSiteMapCacheSize() int {
+	return c.siteMapSize
+}
+
+func (c *cfg) GetSiteMapMaxSize() int {
+	return c.siteMapMaxSize
+}
+
+func (c *cfg) GetCommandDirs() []string {
+	return c.commandDirs
+}
+
+func (c *cfg) GetStartJitter() time.Duration {
+	return c.startJitter
+}
+
+func (c *cfg) GetMaxConcurrentJobs() int {
+	return c.maxJobs
+}
+
+func (c *cfg) GetHostRateLimit() float64 {
+	return c.hostRateLimit
+}
+
 type Config interface {
 	GetPort() int32
 	GetClientAddress() string
@@ -61,6 +122,19 @@ type Config interface {
 	GetMongoURI() string
 	GetMongoDb() string
 	GetMongoCollection() string
+	GetSiteMapMaxDepth() int
+	GetSiteMapMaxURLs() int
+	GetSiteMapCacheTTL() time.Duration
+	GetSiteMapCacheSize() int
+	// Max size of uncompressed sitemap in bytes
+	GetSiteMapMaxSize() int
+	GetCommandDirs() []string
+	// Max random offset of first tick of synced schedulers
+	GetStartJitter() time.Duration
+	// Limit of check executions at same time, 0 means unlimited
+	GetMaxConcurrentJobs() int
+	// Check executions per second for one target host, 0 means unlimited
+	GetHostRateLimit() float64
 }
 
 func New() Config {
@@ -90,6 +164,14 @@ func New() Config {
 	if collection == "" {
 		collection = defaultCollection
 	}
+	siteMapTTL := defaultSiteMapCacheTTL
+	if ttl := readInt(ENV_SITEMAP_TTL, 0); ttl > 0 {
+		siteMapTTL = helpers.DurationFromSecond(int32(ttl))
+	}
+	startJitter := defaultStartJitter
+	if jitter := readInt(ENV_START_JITTER, -1); jitter >= 0 {
+		startJitter = helpers.DurationFromSecond(int32(jitter))
+	}
 	return &cfg{
 		clientAddress:   os.Getenv(ENV_STORAGE_HOST),
 		timeout:         timeoutStorage,
@@ -97,5 +179,48 @@ func New() Config {
 		mongoURI:        os.Getenv(ENV_MONGO_URI),
 		mongoDb:         mongoDb,
 		mongoCollection: collection,
+		siteMapMaxDepth: readInt(ENV_SITEMAP_DEPTH, defaultSiteMapMaxDepth),
+		siteMapMaxURLs:  readInt(ENV_SITEMAP_URLS, defaultSiteMapMaxURLs),
+		siteMapTTL:      siteMapTTL,
+		siteMapSize:     readInt(ENV_SITEMAP_SIZE, defaultSiteMapCacheSize),
+		siteMapMaxSize:  readInt(ENV_SITEMAP_MAX_SIZE, defaultSiteMapMaxSize),
+		commandDirs:     readList(ENV_COMMAND_DIRS),
+		startJitter:     startJitter,
+		maxJobs:         readInt(ENV_MAX_JOBS, 0),
+		hostRateLimit:   readFloat(ENV_HOST_RATE_LIMIT, 0),
+	}
+}
+
+func readInt(env string, defaultValue int) int {
+	value := os.Getenv(env)
+	if value == "" {
+		return defaultValue
+	}
+	i, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || i < 0 {
+		return defaultValue
+	}
+	return int(i)
+}
+
+func readFloat(env string, defaultValue float64) float64 {
+	value := os.Getenv(env)
+	if value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		return defaultValue
+	}
+	return f
+}
+
+func readList(env string) []string {
+	result := []string{}
+	for _, value := range filepath.SplitList(os.Getenv(env)) {
+		if value != "" {
+			result = append(result, value)
+		}
 	}
+	return result
 }
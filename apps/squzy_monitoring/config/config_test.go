@@ -16,6 +16,15 @@ func TestNew(t *testing.T) {
 		assert.Equal(t, s.GetMongoDb(), defaultMongoDb)
 		assert.Equal(t, s.GetStorageTimeout(), defaultStorageTimeout)
 		assert.Equal(t, s.GetMongoCollection(), defaultCollection)
+		assert.Equal(t, s.GetSiteMapMaxDepth(), defaultSiteMapMaxDepth)
+		assert.Equal(t, s.GetSiteMapMaxURLs(), defaultSiteMapMaxURLs)
+		assert.Equal(t, s.GetSiteMapCacheTTL(), defaultSiteMapCacheTTL)
+		assert.Equal(t, s.GetSiteMapCacheSize(), defaultSiteMapCacheSize)
+		assert.Equal(t, s.GetSiteMapMaxSize(), defaultSiteMapMaxSize)
+		assert.Equal(t, s.GetCommandDirs(), []string{})
+		assert.Equal(t, s.GetStartJitter(), defaultStartJitter)
+		assert.Equal(t, s.GetMaxConcurrentJobs(), 0)
+		assert.Equal(t, s.GetHostRateLimit(), float64(0))
 	})
 }
 
@@ -61,3 +70,90 @@ func TestCfg_GetMongoUri(t *testing.T) {
 		assert.Equal(t, s.GetMongoURI(), "11124")
 	})
 }
+
+func TestCfg_GetSiteMapMaxDepth(t *testing.T) {
+	t.Run("Should: return from env", func(t *testing.T) {
+		os.Setenv(ENV_SITEMAP_DEPTH, "5")
+		s := New()
+		assert.Equal(t, s.GetSiteMapMaxDepth(), 5)
+	})
+	t.Run("Should: return default because value is invalid", func(t *testing.T) {
+		os.Setenv(ENV_SITEMAP_DEPTH, "-1")
+		s := New()
+		assert.Equal(t, s.GetSiteMapMaxDepth(), defaultSiteMapMaxDepth)
+	})
+}
+
+func TestCfg_GetSiteMapMaxURLs(t *testing.T) {
+	t.Run("Should: return from env", func(t *testing.T) {
+		os.Setenv(ENV_SITEMAP_URLS, "100")
+		s := New()
+		assert.Equal(t, s.GetSiteMapMaxURLs(), 100)
+	})
+}
+
+func TestCfg_GetSiteMapCacheTTL(t *testing.T) {
+	t.Run("Should: return from env", func(t *testing.T) {
+		os.Setenv(ENV_SITEMAP_TTL, "3600")
+		s := New()
+		assert.Equal(t, s.GetSiteMapCacheTTL(), time.Hour)
+	})
+}
+
+func TestCfg_GetSiteMapCacheSize(t *testing.T) {
+	t.Run("Should: return from env", func(t *testing.T) {
+		os.Setenv(ENV_SITEMAP_SIZE, "10")
+		s := New()
+		assert.Equal(t, s.GetSiteMapCacheSize(), 10)
+	})
+}
+
+func TestCfg_GetSiteMapMaxSize(t *testing.T) {
+	t.Run("Should: return from env", func(t *testing.T) {
+		os.Setenv(ENV_SITEMAP_MAX_SIZE, "1024")
+		s := New()
+		assert.Equal(t, s.GetSiteMapMaxSize(), 1024)
+	})
+}
+
+func TestCfg_GetCommandDirs(t *testing.T) {
+	t.Run("Should: return from env", func(t *testing.T) {
+		os.Setenv(ENV_COMMAND_DIRS, "/usr/lib/nagios/plugins"+string(os.PathListSeparator)+string(os.PathListSeparator)+"/opt/checks")
+		defer os.Unsetenv(ENV_COMMAND_DIRS)
+		s := New()
+		assert.Equal(t, s.GetCommandDirs(), []string{"/usr/lib/nagios/plugins", "/opt/checks"})
+	})
+}
+
+func TestCfg_GetStartJitter(t *testing.T) {
+	t.Run("Should: return from env", func(t *testing.T) {
+		os.Setenv(ENV_START_JITTER, "0")
+		defer os.Unsetenv(ENV_START_JITTER)
+		s := New()
+		assert.Equal(t, s.GetStartJitter(), time.Duration(0))
+	})
+}
+
+func TestCfg_GetMaxConcurrentJobs(t *testing.T) {
+	t.Run("Should: return from env", func(t *testing.T) {
+		os.Setenv(ENV_MAX_JOBS, "200")
+		defer os.Unsetenv(ENV_MAX_JOBS)
+		s := New()
+		assert.Equal(t, s.GetMaxConcurrentJobs(), 200)
+	})
+}
+
+func TestCfg_GetHostRateLimit(t *testing.T) {
+	t.Run("Should: return from env", func(t *testing.T) {
+		os.Setenv(ENV_HOST_RATE_LIMIT, "0.5")
+		defer os.Unsetenv(ENV_HOST_RATE_LIMIT)
+		s := New()
+		assert.Equal(t, s.GetHostRateLimit(), 0.5)
+	})
+	t.Run("Should: return default because value is invalid", func(t *testing.T) {
+		os.Setenv(ENV_HOST_RATE_LIMIT, "fast")
+		defer os.Unsetenv(ENV_HOST_RATE_LIMIT)
+		s := New()
+		assert.Equal(t, s.GetHostRateLimit(), float64(0))
+	})
+}
@@ -21,11 +21,6 @@ import (
 	"squzy/internal/semaphore"
 	sitemap_storage "squzy/internal/sitemap-storage"
 	"squzy/internal/storage"
-	"time"
-)
-
-const (
-	day = time.Hour * 24
 )
 
 func main() {
@@ -54,9 +49,12 @@ func main() {
 		grpc.WithInsecure(),
 	)
 	siteMapStorage := sitemap_storage.New(
-		day,
+		cfg.GetSiteMapCacheTTL(),
+		cfg.GetSiteMapCacheSize(),
 		httpPackage,
-		parsers.NewSiteMapParser(),
+		parsers.NewSiteMapParser(int64(cfg.GetSiteMapMaxSize())),
+		cfg.GetSiteMapMaxDepth(),
+		cfg.GetSiteMapMaxURLs(),
 	)
 	configStorage := scheduler_config_storage.New(connector)
 	jobExecutor := job_executor.NewExecutor(
@@ -70,11 +68,24 @@ func main() {
 		job.ExecHTTP,
 		job.ExecSiteMap,
 		job.ExecHTTPValue,
+		job.ExecTLSCert,
+		job.ExecDNS,
+		job.ExecScenario,
+		job.ExecCrawler,
+		job.ExecDatabase,
+		job.ExecUDP,
+		job.ExecWebSocket,
+		job.ExecPrometheus,
+		job.ExecContent,
+		job.NewExecCommand(cfg.GetCommandDirs()),
+		job_executor.NewWorkerPool(cfg.GetMaxConcurrentJobs()),
+		job_executor.NewHostLimiter(cfg.GetHostRateLimit()),
 	)
 	app := application.New(
 		scheduler_storage.New(),
 		jobExecutor,
 		configStorage,
+		cfg.GetStartJitter(),
 	)
 	log.Fatal(app.Run(cfg.GetPort()))
 }
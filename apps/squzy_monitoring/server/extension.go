@@ -0,0 +1,45 @@
+package server
+
+import (
+	"context"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	job_executor "squzy/internal/job-executor"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	scheduler_storage "squzy/internal/scheduler-storage"
+)
+
+// Serves requests which can't be expressed by squzy_generated API
+type extensionServer struct {
+	server *server
+}
+
+func (e *extensionServer) Add(ctx context.Context, rq *monitoring_api.AddRequest) (*apiPb.AddResponse, error) {
+	return e.server.add(ctx, rq)
+}
+
+func (e *extensionServer) Update(ctx context.Context, rq *monitoring_api.UpdateRequest) (*monitoring_api.UpdateResponse, error) {
+	return e.server.update(ctx, rq)
+}
+
+func (e *extensionServer) GetSchedules(ctx context.Context, rq *monitoring_api.GetSchedulesRequest) (*monitoring_api.GetSchedulesResponse, error) {
+	return e.server.getSchedules(ctx, rq)
+}
+
+func (e *extensionServer) GetDiagnostics(ctx context.Context, rq *monitoring_api.GetDiagnosticsRequest) (*monitoring_api.Diagnostics, error) {
+	return e.server.jobExecutor.GetDiagnostics(), nil
+}
+
+func NewExtension(
+	schedulerStorage scheduler_storage.SchedulerStorage,
+	jobExecutor job_executor.JobExecutor,
+	configStorage scheduler_config_storage.Storage,
+) monitoring_api.SchedulersExtensionServer {
+	return &extensionServer{
+		server: &server{
+			schedulerStorage: schedulerStorage,
+			jobExecutor:      jobExecutor,
+			configStorage:    configStorage,
+		},
+	}
+}
@@ -0,0 +1,854 @@
+package server
+
+import (
+	"context"
+	"errors"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	monitoring_api "squzy/internal/monitoring-api"
+	"squzy/internal/scheduler"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	scheduler_storage "squzy/internal/scheduler-storage"
+	"testing"
+	"time"
+)
+
+func TestNewExtension(t *testing.T) {
+	t.Run("Should: implement interface", func(t *testing.T) {
+		s := NewExtension(nil, nil, nil)
+		assert.Implements(t, (*monitoring_api.SchedulersExtensionServer)(nil), s)
+	})
+}
+
+func TestExtensionServer_Add(t *testing.T) {
+	t.Run("Should: return error because wrong interval", func(t *testing.T) {
+		s := NewExtension(nil, nil, nil)
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Type: monitoring_api.SchedulerTypeTLSCert,
+		})
+		assert.NotEqual(t, nil, err)
+	})
+	t.Run("Should: return error because config missing", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeTLSCert,
+		})
+		assert.Equal(t, errMissingConfigError, err)
+	})
+	t.Run("Should: add check with cron expression without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Cron:     "0 0 3 * * *",
+			Timezone: "Europe/Moscow",
+			Type:     monitoring_api.SchedulerTypeTLSCert,
+			TLSCert: &monitoring_api.TLSCertConfig{
+				Host: "squzy.app",
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error because cron expression is wrong", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Cron:     "*/5 * * *",
+			Type:     monitoring_api.SchedulerTypeTLSCert,
+			TLSCert: &monitoring_api.TLSCertConfig{
+				Host: "squzy.app",
+			},
+		})
+		assert.NotEqual(t, nil, err)
+	})
+	t.Run("Should: add tls cert check without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeTLSCert,
+			TLSCert: &monitoring_api.TLSCertConfig{
+				Host:              "squzy.app",
+				Port:              443,
+				ExpiryWarningDays: 14,
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: add dns check without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeDNS,
+			DNS: &monitoring_api.DNSConfig{
+				Host:           "squzy.app",
+				RecordType:     monitoring_api.DNSRecordTypeA,
+				Resolver:       "8.8.8.8",
+				Assertion:      monitoring_api.DNSAssertionContains,
+				ExpectedValues: []string{"127.0.0.1"},
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error because dns resolver missing", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeDNS,
+			DNS: &monitoring_api.DNSConfig{
+				Host:       "squzy.app",
+				RecordType: monitoring_api.DNSRecordTypeA,
+			},
+		})
+		assert.Equal(t, errMissingConfigError, err)
+	})
+	t.Run("Should: add scenario check without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeScenario,
+			Scenario: &monitoring_api.ScenarioConfig{
+				Steps: []*monitoring_api.ScenarioStep{
+					{
+						Method: "GET",
+						URL:    "https://squzy.app",
+					},
+				},
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: add crawler check without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeCrawler,
+			Crawler: &monitoring_api.CrawlerConfig{
+				URL:     "https://squzy.app",
+				Exclude: []string{"/admin/"},
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error because crawler config missing", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeCrawler,
+		})
+		assert.Equal(t, errMissingConfigError, err)
+	})
+	t.Run("Should: add postgres check without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypePostgres,
+			Database: &monitoring_api.DatabaseConfig{
+				Host:     "localhost",
+				Port:     5432,
+				User:     "squzy",
+				Database: "squzy",
+				Query:    "SELECT pg_is_in_recovery()",
+				Value: &monitoring_api.HTTPValueSelector{
+					HttpJsonValueConfig_Selectors: &apiPb.HttpJsonValueConfig_Selectors{
+						Type: apiPb.HttpJsonValueConfig_BOOL,
+					},
+				},
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: add tcp check with expected response without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     apiPb.SchedulerType_TCP,
+			Tcp: &monitoring_api.TCPConfig{
+				TcpConfig: &apiPb.TcpConfig{
+					Host: "localhost",
+					Port: 11211,
+				},
+				Send: "stats\r\n",
+				Expect: &monitoring_api.SocketExpect{
+					Type:  monitoring_api.SocketExpectPrefix,
+					Value: "STAT pid",
+				},
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error because tcp config missing", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     apiPb.SchedulerType_TCP,
+			Tcp:      &monitoring_api.TCPConfig{},
+		})
+		assert.Equal(t, errMissingConfigError, err)
+	})
+	t.Run("Should: add udp check without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeUDP,
+			UDP: &monitoring_api.UDPConfig{
+				Host: "localhost",
+				Port: 514,
+				Send: "<14>squzy: check",
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error because udp config missing", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeUDP,
+		})
+		assert.Equal(t, errMissingConfigError, err)
+	})
+	t.Run("Should: add websocket check without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeWebSocket,
+			WebSocket: &monitoring_api.WebSocketConfig{
+				URL:  "wss://squzy.app/ws",
+				Send: "ping",
+				Expect: &monitoring_api.SocketExpect{
+					Type:  monitoring_api.SocketExpectExact,
+					Value: "pong",
+				},
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error because websocket config missing", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeWebSocket,
+		})
+		assert.Equal(t, errMissingConfigError, err)
+	})
+	t.Run("Should: add prometheus check without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypePrometheus,
+			Prometheus: &monitoring_api.PrometheusConfig{
+				URL: "https://squzy.app/metrics",
+				Selectors: []*monitoring_api.MetricSelector{
+					{
+						Name: "up",
+						Rule: &monitoring_api.SelectorRule{
+							Type:   monitoring_api.SelectorRuleEquals,
+							Number: 1,
+						},
+					},
+				},
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error because prometheus config missing", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypePrometheus,
+		})
+		assert.Equal(t, errMissingConfigError, err)
+	})
+	t.Run("Should: add content check without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeContent,
+			Content: &monitoring_api.ContentConfig{
+				URL: "https://squzy.app/terms",
+				Region: &monitoring_api.ContentRegion{
+					Type: monitoring_api.ContentRegionCSS,
+					Path: "#terms",
+				},
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error because content config missing", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeContent,
+		})
+		assert.Equal(t, errMissingConfigError, err)
+	})
+	t.Run("Should: add command check without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeCommand,
+			Command: &monitoring_api.CommandConfig{
+				Path: "/usr/lib/nagios/plugins/check_disk",
+				Args: []string{"-w", "20%", "-p", "/"},
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error because command config missing", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeCommand,
+		})
+		assert.Equal(t, errMissingConfigError, err)
+	})
+	t.Run("Should: add check with retry and flap policies without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     apiPb.SchedulerType_TCP,
+			Tcp: &monitoring_api.TCPConfig{
+				TcpConfig: &apiPb.TcpConfig{
+					Host: "localhost",
+					Port: 5432,
+				},
+			},
+			Retry: &monitoring_api.RetryPolicy{
+				Attempts:  3,
+				BackoffMs: 500,
+			},
+			Flap: &monitoring_api.FlapPolicy{
+				ConfirmAfter: 2,
+				RecoverAfter: 2,
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error because retry policy invalid", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeCommand,
+			Command: &monitoring_api.CommandConfig{
+				Path: "/usr/lib/nagios/plugins/check_disk",
+			},
+			Retry: &monitoring_api.RetryPolicy{},
+		})
+		assert.Equal(t, errInvalidPolicyError, err)
+	})
+	t.Run("Should: return error because flap policy invalid", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeCommand,
+			Command: &monitoring_api.CommandConfig{
+				Path: "/usr/lib/nagios/plugins/check_disk",
+			},
+			Flap: &monitoring_api.FlapPolicy{
+				ConfirmAfter: 2,
+			},
+		})
+		assert.Equal(t, errInvalidPolicyError, err)
+	})
+	t.Run("Should: return error because content check has retry policy", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeContent,
+			Content: &monitoring_api.ContentConfig{
+				URL: "https://squzy.app/terms",
+			},
+			Retry: &monitoring_api.RetryPolicy{
+				Attempts: 2,
+			},
+		})
+		assert.Equal(t, errInvalidPolicyError, err)
+	})
+	t.Run("Should: add check with overlap policy and deadline without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 60,
+			Type:     monitoring_api.SchedulerTypeCrawler,
+			Crawler: &monitoring_api.CrawlerConfig{
+				URL: "https://squzy.app",
+			},
+			Overlap:  monitoring_api.OverlapQueueOne,
+			Deadline: 300,
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error because overlap policy invalid", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 60,
+			Type:     monitoring_api.SchedulerTypeCrawler,
+			Crawler: &monitoring_api.CrawlerConfig{
+				URL: "https://squzy.app",
+			},
+			Overlap: "wait",
+		})
+		assert.Equal(t, errInvalidOverlap, err)
+	})
+	t.Run("Should: return error because deadline invalid", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 60,
+			Type:     monitoring_api.SchedulerTypeCrawler,
+			Crawler: &monitoring_api.CrawlerConfig{
+				URL: "https://squzy.app",
+			},
+			Deadline: -1,
+		})
+		assert.Equal(t, errInvalidOverlap, err)
+	})
+	t.Run("Should: return error because database config missing", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     monitoring_api.SchedulerTypeRedis,
+		})
+		assert.Equal(t, errMissingConfigError, err)
+	})
+	t.Run("Should: add http check without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     apiPb.SchedulerType_HTTP,
+			Http: &monitoring_api.HTTPConfig{
+				HttpConfig: &apiPb.HttpConfig{
+					Method: "GET",
+					Url:    "https://squzy.app",
+				},
+				Assertions: []*monitoring_api.HTTPAssertion{
+					{
+						Type:  monitoring_api.HTTPAssertionBodyContains,
+						Value: "squzy",
+					},
+				},
+				Body: &monitoring_api.RequestBody{
+					Type: monitoring_api.RequestBodyForm,
+					Form: map[string]string{
+						"user": "squzy",
+					},
+				},
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: add sitemap check with client config without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     apiPb.SchedulerType_SITE_MAP,
+			Sitemap: &monitoring_api.SiteMapConfig{
+				SiteMapConfig: &apiPb.SiteMapConfig{
+					Url: "https://squzy.app/sitemap.xml",
+				},
+				Client: &monitoring_api.HTTPClientConfig{
+					InsecureSkipVerify: true,
+					ProxyURL:           "http://proxy:3128",
+				},
+				MaxFailureRatio: 0.1,
+				SlowThresholdMs: 1000,
+				CacheTTL:        3600,
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error because sitemap config is empty", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     apiPb.SchedulerType_SITE_MAP,
+			Sitemap:  &monitoring_api.SiteMapConfig{},
+		})
+		assert.Equal(t, errMissingConfigError, err)
+	})
+	t.Run("Should: add http value check with rules without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     apiPb.SchedulerType_HTTP_JSON_VALUE,
+			HttpValue: &monitoring_api.HTTPValueConfig{
+				HttpJsonValueConfig: &apiPb.HttpJsonValueConfig{
+					Method: "GET",
+					Url:    "https://squzy.app",
+				},
+				Selectors: []*monitoring_api.HTTPValueSelector{
+					{
+						HttpJsonValueConfig_Selectors: &apiPb.HttpJsonValueConfig_Selectors{
+							Type: apiPb.HttpJsonValueConfig_NUMBER,
+							Path: "queue.depth",
+						},
+						Rule: &monitoring_api.SelectorRule{
+							Type:   monitoring_api.SelectorRuleLess,
+							Number: 1000,
+						},
+					},
+				},
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: add xml value check without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     apiPb.SchedulerType_HTTP_JSON_VALUE,
+			HttpValue: &monitoring_api.HTTPValueConfig{
+				HttpJsonValueConfig: &apiPb.HttpJsonValueConfig{
+					Method: "GET",
+					Url:    "https://squzy.app/status.xml",
+				},
+				Format: monitoring_api.ValueFormatXML,
+				Selectors: []*monitoring_api.HTTPValueSelector{
+					{
+						HttpJsonValueConfig_Selectors: &apiPb.HttpJsonValueConfig_Selectors{
+							Type: apiPb.HttpJsonValueConfig_STRING,
+							Path: "//service[@name='billing']/@state",
+						},
+					},
+				},
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error because http value config missing", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval:  10,
+			Type:      apiPb.SchedulerType_HTTP_JSON_VALUE,
+			HttpValue: &monitoring_api.HTTPValueConfig{},
+		})
+		assert.Equal(t, errMissingConfigError, err)
+	})
+	t.Run("Should: add grpc check with tls, metadata and call without error", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     apiPb.SchedulerType_GRPC,
+			Grpc: &monitoring_api.GrpcConfig{
+				GrpcConfig: &apiPb.GrpcConfig{
+					Host: "localhost",
+					Port: 9090,
+				},
+				TLS:      &monitoring_api.GrpcTLSConfig{ServerName: "squzy.app"},
+				Metadata: map[string]string{"authorization": "Bearer token"},
+				Call: &monitoring_api.GrpcCallConfig{
+					Method:  "grpc.health.v1.Health/Check",
+					Request: `{"service": ""}`,
+				},
+			},
+		})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error because grpc config is empty", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     apiPb.SchedulerType_GRPC,
+			Grpc:     &monitoring_api.GrpcConfig{},
+		})
+		assert.Equal(t, errMissingConfigError, err)
+	})
+	t.Run("Should: return error because http config missing", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Add(context.Background(), &monitoring_api.AddRequest{
+			Interval: 10,
+			Type:     apiPb.SchedulerType_HTTP,
+			Http:     &monitoring_api.HTTPConfig{},
+		})
+		assert.Equal(t, errMissingConfigError, err)
+	})
+}
+
+type mockConfigStorageUpdate struct {
+	mockConfigStorageOk
+	config  *scheduler_config_storage.SchedulerConfig
+	updated *scheduler_config_storage.SchedulerConfig
+	err     error
+}
+
+func (m *mockConfigStorageUpdate) Get(ctx context.Context, schedulerId primitive.ObjectID) (*scheduler_config_storage.SchedulerConfig, error) {
+	return m.config, nil
+}
+
+func (m *mockConfigStorageUpdate) Update(ctx context.Context, config *scheduler_config_storage.SchedulerConfig) error {
+	m.updated = config
+	return m.err
+}
+
+func TestExtensionServer_Update(t *testing.T) {
+	tlsCert := &monitoring_api.TLSCertConfig{
+		Host: "squzy.app",
+		Port: 443,
+	}
+	newConfigStorage := func(status apiPb.SchedulerStatus) *mockConfigStorageUpdate {
+		return &mockConfigStorageUpdate{
+			config: &scheduler_config_storage.SchedulerConfig{
+				ID:       primitive.NewObjectID(),
+				Type:     monitoring_api.SchedulerTypeTLSCert,
+				Status:   status,
+				Interval: 10,
+			},
+		}
+	}
+	t.Run("Should: return error because config missing", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Update(context.Background(), &monitoring_api.UpdateRequest{
+			ID: primitive.NewObjectID().Hex(),
+		})
+		assert.Equal(t, errMissingConfigError, err)
+	})
+	t.Run("Should: return error because id is invalid", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.Update(context.Background(), &monitoring_api.UpdateRequest{
+			ID:     "invalid",
+			Config: &monitoring_api.AddRequest{},
+		})
+		assert.NotEqual(t, nil, err)
+	})
+	t.Run("Should: return error because config storage", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageErrorSingle{})
+		_, err := s.Update(context.Background(), &monitoring_api.UpdateRequest{
+			ID:     primitive.NewObjectID().Hex(),
+			Config: &monitoring_api.AddRequest{},
+		})
+		assert.NotEqual(t, nil, err)
+	})
+	t.Run("Should: return error because scheduler is removed", func(t *testing.T) {
+		configStorage := newConfigStorage(apiPb.SchedulerStatus_REMOVED)
+		s := NewExtension(&mockStorageOk{}, nil, configStorage)
+		_, err := s.Update(context.Background(), &monitoring_api.UpdateRequest{
+			ID: configStorage.config.ID.Hex(),
+			Config: &monitoring_api.AddRequest{
+				Interval: 10,
+				Type:     monitoring_api.SchedulerTypeTLSCert,
+				TLSCert:  tlsCert,
+			},
+		})
+		assert.Equal(t, errRemovedScheduler, err)
+		assert.Nil(t, configStorage.updated)
+	})
+	t.Run("Should: return error because new config is invalid", func(t *testing.T) {
+		configStorage := newConfigStorage(apiPb.SchedulerStatus_RUNNED)
+		s := NewExtension(&mockStorageOk{}, nil, configStorage)
+		_, err := s.Update(context.Background(), &monitoring_api.UpdateRequest{
+			ID: configStorage.config.ID.Hex(),
+			Config: &monitoring_api.AddRequest{
+				Interval: 10,
+				Type:     monitoring_api.SchedulerTypeTLSCert,
+			},
+		})
+		assert.Equal(t, errMissingConfigError, err)
+		assert.Nil(t, configStorage.updated)
+	})
+	t.Run("Should: return error because new schedule is invalid", func(t *testing.T) {
+		configStorage := newConfigStorage(apiPb.SchedulerStatus_RUNNED)
+		s := NewExtension(&mockStorageOk{}, nil, configStorage)
+		_, err := s.Update(context.Background(), &monitoring_api.UpdateRequest{
+			ID: configStorage.config.ID.Hex(),
+			Config: &monitoring_api.AddRequest{
+				Cron:    "*/5 * * *",
+				Type:    monitoring_api.SchedulerTypeTLSCert,
+				TLSCert: tlsCert,
+			},
+		})
+		assert.NotEqual(t, nil, err)
+		assert.Nil(t, configStorage.updated)
+	})
+	t.Run("Should: return error because config is not saved", func(t *testing.T) {
+		configStorage := newConfigStorage(apiPb.SchedulerStatus_RUNNED)
+		configStorage.err = errors.New("")
+		s := NewExtension(&mockStorageOk{}, nil, configStorage)
+		_, err := s.Update(context.Background(), &monitoring_api.UpdateRequest{
+			ID: configStorage.config.ID.Hex(),
+			Config: &monitoring_api.AddRequest{
+				Interval: 10,
+				Type:     monitoring_api.SchedulerTypeTLSCert,
+				TLSCert:  tlsCert,
+			},
+		})
+		assert.NotEqual(t, nil, err)
+	})
+	t.Run("Should: return not found and keep scheduler because scheduler is removed concurrently", func(t *testing.T) {
+		configStorage := newConfigStorage(apiPb.SchedulerStatus_RUNNED)
+		configStorage.err = scheduler_config_storage.ErrSchedulerNotFound
+		storage := scheduler_storage.New()
+		jobExecutor := &mockJobExecutor{}
+		s := NewExtension(storage, jobExecutor, configStorage)
+		_, err := s.Update(context.Background(), &monitoring_api.UpdateRequest{
+			ID: configStorage.config.ID.Hex(),
+			Config: &monitoring_api.AddRequest{
+				Interval: 20,
+				Type:     monitoring_api.SchedulerTypeTLSCert,
+				TLSCert:  tlsCert,
+			},
+		})
+		assert.Equal(t, codes.NotFound, status.Code(err))
+		_, err = storage.Get(configStorage.config.ID.Hex())
+		assert.NotEqual(t, nil, err)
+		assert.Empty(t, jobExecutor.reset)
+	})
+	t.Run("Should: return error because scheduler is not replaced", func(t *testing.T) {
+		configStorage := newConfigStorage(apiPb.SchedulerStatus_RUNNED)
+		s := NewExtension(&mockStorageError{}, &mockJobExecutor{}, configStorage)
+		_, err := s.Update(context.Background(), &monitoring_api.UpdateRequest{
+			ID: configStorage.config.ID.Hex(),
+			Config: &monitoring_api.AddRequest{
+				Interval: 20,
+				Type:     monitoring_api.SchedulerTypeTLSCert,
+				TLSCert:  tlsCert,
+			},
+		})
+		assert.NotEqual(t, nil, err)
+	})
+	t.Run("Should: keep scheduler when schedule is not changed", func(t *testing.T) {
+		configStorage := newConfigStorage(apiPb.SchedulerStatus_RUNNED)
+		storage := scheduler_storage.New()
+		schld, err := scheduler.New(configStorage.config.ID, time.Second*10, 0, nil)
+		assert.Equal(t, nil, err)
+		_ = storage.Set(schld)
+		jobExecutor := &mockJobExecutor{}
+		s := NewExtension(storage, jobExecutor, configStorage)
+		_, err = s.Update(context.Background(), &monitoring_api.UpdateRequest{
+			ID: configStorage.config.ID.Hex(),
+			Config: &monitoring_api.AddRequest{
+				Interval: 10,
+				Name:     "cert",
+				Type:     monitoring_api.SchedulerTypeTLSCert,
+				TLSCert:  tlsCert,
+			},
+		})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, configStorage.config.ID, configStorage.updated.ID)
+		assert.Equal(t, apiPb.SchedulerStatus_RUNNED, configStorage.updated.Status)
+		assert.Equal(t, "cert", configStorage.updated.Name)
+		assert.Equal(t, "squzy.app", configStorage.updated.TLSCertConfig.Host)
+		value, err := storage.Get(configStorage.config.ID.Hex())
+		assert.Equal(t, nil, err)
+		assert.Equal(t, schld, value)
+		assert.Equal(t, []primitive.ObjectID{configStorage.config.ID}, jobExecutor.reset)
+	})
+	t.Run("Should: replace running scheduler when schedule is changed", func(t *testing.T) {
+		configStorage := newConfigStorage(apiPb.SchedulerStatus_RUNNED)
+		storage := scheduler_storage.New()
+		schld, err := scheduler.New(configStorage.config.ID, time.Second*10, 0, &mockJobExecutor{})
+		assert.Equal(t, nil, err)
+		_ = storage.Set(schld)
+		schld.Run()
+		s := NewExtension(storage, &mockJobExecutor{}, configStorage)
+		_, err = s.Update(context.Background(), &monitoring_api.UpdateRequest{
+			ID: configStorage.config.ID.Hex(),
+			Config: &monitoring_api.AddRequest{
+				Cron:    "@daily",
+				Type:    monitoring_api.SchedulerTypeTLSCert,
+				TLSCert: tlsCert,
+			},
+		})
+		assert.Equal(t, nil, err)
+		value, err := storage.Get(configStorage.config.ID.Hex())
+		assert.Equal(t, nil, err)
+		defer value.Stop()
+		assert.NotEqual(t, schld, value)
+		assert.False(t, schld.IsRun())
+		assert.True(t, value.IsRun())
+		assert.Equal(t, configStorage.config.ID.Hex(), value.GetID())
+	})
+	t.Run("Should: replace stopped scheduler without run", func(t *testing.T) {
+		configStorage := newConfigStorage(apiPb.SchedulerStatus_STOPPED)
+		storage := scheduler_storage.New()
+		s := NewExtension(storage, &mockJobExecutor{}, configStorage)
+		_, err := s.Update(context.Background(), &monitoring_api.UpdateRequest{
+			ID: configStorage.config.ID.Hex(),
+			Config: &monitoring_api.AddRequest{
+				Interval: 30,
+				Type:     monitoring_api.SchedulerTypeTLSCert,
+				TLSCert:  tlsCert,
+			},
+		})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, apiPb.SchedulerStatus_STOPPED, configStorage.updated.Status)
+		value, err := storage.Get(configStorage.config.ID.Hex())
+		assert.Equal(t, nil, err)
+		assert.False(t, value.IsRun())
+	})
+}
+
+func TestExtensionServer_GetSchedules(t *testing.T) {
+	t.Run("Should: return schedules of all schedulers", func(t *testing.T) {
+		storage := scheduler_storage.New()
+		schld, err := scheduler.NewCron(successGrpcConfig.ID, "@daily", "", nil)
+		assert.Equal(t, nil, err)
+		_ = storage.Set(schld)
+		schld.Run()
+		defer schld.Stop()
+		s := NewExtension(storage, nil, &mockConfigStorageOk{})
+		res, err := s.GetSchedules(context.Background(), &monitoring_api.GetSchedulesRequest{})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 1, len(res.Schedules))
+		assert.Equal(t, successGrpcConfig.ID.Hex(), res.Schedules[0].ID)
+		assert.True(t, schld.GetNextRunTime().Equal(*res.Schedules[0].NextRunTime))
+	})
+	t.Run("Should: return schedules by ids", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		res, err := s.GetSchedules(context.Background(), &monitoring_api.GetSchedulesRequest{
+			IDs: []string{successTLSCertConfig.ID.Hex()},
+		})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, &monitoring_api.Schedule{
+			ID:       successTLSCertConfig.ID.Hex(),
+			Interval: successTLSCertConfig.Interval,
+		}, res.Schedules[0])
+	})
+	t.Run("Should: return error because id is invalid", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		_, err := s.GetSchedules(context.Background(), &monitoring_api.GetSchedulesRequest{
+			IDs: []string{"invalid"},
+		})
+		assert.NotEqual(t, nil, err)
+	})
+	t.Run("Should: return error from config storage", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, nil, &mockConfigStorageError{})
+		_, err := s.GetSchedules(context.Background(), &monitoring_api.GetSchedulesRequest{})
+		assert.NotEqual(t, nil, err)
+		s = NewExtension(&mockStorageOk{}, nil, &mockConfigStorageErrorSingle{})
+		_, err = s.GetSchedules(context.Background(), &monitoring_api.GetSchedulesRequest{
+			IDs: []string{successTLSCertConfig.ID.Hex()},
+		})
+		assert.NotEqual(t, nil, err)
+	})
+}
+
+type mockJobExecutor struct {
+	reset []primitive.ObjectID
+}
+
+func (m mockJobExecutor) Execute(schedulerID primitive.ObjectID) {
+}
+
+func (m *mockJobExecutor) Reset(schedulerID primitive.ObjectID) {
+	m.reset = append(m.reset, schedulerID)
+}
+
+func (m mockJobExecutor) GetDiagnostics() *monitoring_api.Diagnostics {
+	return &monitoring_api.Diagnostics{
+		QueueDepth:        3,
+		RunningJobs:       10,
+		MaxConcurrentJobs: 10,
+	}
+}
+
+func TestExtensionServer_GetDiagnostics(t *testing.T) {
+	t.Run("Should: return diagnostics of job executor", func(t *testing.T) {
+		s := NewExtension(&mockStorageOk{}, &mockJobExecutor{}, &mockConfigStorageOk{})
+		res, err := s.GetDiagnostics(context.Background(), &monitoring_api.GetDiagnosticsRequest{})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, &monitoring_api.Diagnostics{
+			QueueDepth:        3,
+			RunningJobs:       10,
+			MaxConcurrentJobs: 10,
+		}, res)
+	})
+}
@@ -7,15 +7,24 @@ import (
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"net/http"
 	"squzy/internal/helpers"
 	job_executor "squzy/internal/job-executor"
+	monitoring_api "squzy/internal/monitoring-api"
 	"squzy/internal/scheduler"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
 	scheduler_storage "squzy/internal/scheduler-storage"
+	"time"
 )
 
 var (
-	errInvalidTypeError = errors.New("invalid type of config")
+	errInvalidTypeError   = errors.New("invalid type of config")
+	errMissingConfigError = errors.New("missing config of scheduler")
+	errInvalidPolicyError = errors.New("invalid retry or flap policy")
+	errInvalidOverlap     = errors.New("invalid overlap policy or deadline")
+	errRemovedScheduler   = errors.New("scheduler is removed")
 )
 
 type server struct {
@@ -146,11 +155,97 @@ func (s *server) GetSchedulerById(ctx context.Context, rq *apiPb.GetSchedulerByI
 				},
 			},
 		}, nil
+	case monitoring_api.SchedulerTypeTLSCert, monitoring_api.SchedulerTypeDNS, monitoring_api.SchedulerTypeScenario, monitoring_api.SchedulerTypeCrawler,
+		monitoring_api.SchedulerTypePostgres, monitoring_api.SchedulerTypeMySQL, monitoring_api.SchedulerTypeRedis, monitoring_api.SchedulerTypeMongo,
+		monitoring_api.SchedulerTypeUDP, monitoring_api.SchedulerTypeWebSocket, monitoring_api.SchedulerTypePrometheus, monitoring_api.SchedulerTypeContent,
+		monitoring_api.SchedulerTypeCommand:
+		scheduler := &apiPb.Scheduler{
+			Id:       id,
+			Name:     config.Name,
+			Type:     config.Type,
+			Status:   config.Status,
+			Interval: config.Interval,
+			Timeout:  config.Timeout,
+		}
+		setExtensionConfig(scheduler, config)
+		return scheduler, nil
 	default:
 		return nil, errInvalidTypeError
 	}
 }
 
+// Config of extension types can't be described by squzy_generated, so target of check
+// is returned in closest config of squzy_generated, type of scheduler tells how config is used.
+// Scenario and command have no single target and are returned without config
+func setExtensionConfig(scheduler *apiPb.Scheduler, config *scheduler_config_storage.SchedulerConfig) {
+	switch {
+	case config.TLSCertConfig != nil:
+		scheduler.Config = &apiPb.Scheduler_Tcp{
+			Tcp: &apiPb.TcpConfig{
+				Host: config.TLSCertConfig.Host,
+				Port: config.TLSCertConfig.Port,
+			},
+		}
+	case config.DNSConfig != nil:
+		scheduler.Config = &apiPb.Scheduler_Tcp{
+			Tcp: &apiPb.TcpConfig{
+				Host: config.DNSConfig.Host,
+			},
+		}
+	case config.DatabaseConfig != nil:
+		// Credentials and query are not returned
+		scheduler.Config = &apiPb.Scheduler_Tcp{
+			Tcp: &apiPb.TcpConfig{
+				Host: config.DatabaseConfig.Host,
+				Port: config.DatabaseConfig.Port,
+			},
+		}
+	case config.TCPConfig != nil:
+		// Udp scheduler
+		scheduler.Config = &apiPb.Scheduler_Tcp{
+			Tcp: &apiPb.TcpConfig{
+				Host: config.TCPConfig.Host,
+				Port: config.TCPConfig.Port,
+			},
+		}
+	case config.CrawlerConfig != nil:
+		scheduler.Config = &apiPb.Scheduler_Sitemap{
+			Sitemap: &apiPb.SiteMapConfig{
+				Url:         config.CrawlerConfig.URL,
+				Concurrency: config.CrawlerConfig.Concurrency,
+			},
+		}
+	case config.WebSocketConfig != nil:
+		scheduler.Config = &apiPb.Scheduler_Http{
+			Http: &apiPb.HttpConfig{
+				Method:  http.MethodGet,
+				Url:     config.WebSocketConfig.URL,
+				Headers: config.WebSocketConfig.Headers,
+			},
+		}
+	case config.PrometheusConfig != nil:
+		scheduler.Config = &apiPb.Scheduler_Http{
+			Http: &apiPb.HttpConfig{
+				Method:  http.MethodGet,
+				Url:     config.PrometheusConfig.URL,
+				Headers: config.PrometheusConfig.Headers,
+			},
+		}
+	case config.ContentConfig != nil:
+		method := config.ContentConfig.Method
+		if method == "" {
+			method = http.MethodGet
+		}
+		scheduler.Config = &apiPb.Scheduler_Http{
+			Http: &apiPb.HttpConfig{
+				Method:  method,
+				Url:     config.ContentConfig.URL,
+				Headers: config.ContentConfig.Headers,
+			},
+		}
+	}
+}
+
 func (s *server) Remove(ctx context.Context, rq *apiPb.RemoveRequest) (*apiPb.RemoveResponse, error) {
 	id := rq.Id
 	idBson, err := primitive.ObjectIDFromHex(id)
@@ -161,6 +256,7 @@ func (s *server) Remove(ctx context.Context, rq *apiPb.RemoveRequest) (*apiPb.Re
 	if err != nil {
 		return nil, err
 	}
+	s.jobExecutor.Reset(idBson)
 	err = s.schedulerStorage.Remove(id)
 	if err != nil {
 		return nil, err
@@ -211,89 +307,41 @@ func (s *server) Stop(ctx context.Context, rq *apiPb.StopRequest) (*apiPb.StopRe
 }
 
 func (s *server) Add(ctx context.Context, rq *apiPb.AddRequest) (*apiPb.AddResponse, error) {
-	interval := helpers.DurationFromSecond(rq.Interval)
-	schld, err := scheduler.New(
-		primitive.NewObjectID(),
-		interval,
-		s.jobExecutor,
-	)
+	return s.add(ctx, toExtensionAddRequest(rq))
+}
+
+func validPolicies(schedulerType apiPb.SchedulerType, retry *monitoring_api.RetryPolicy, flap *monitoring_api.FlapPolicy) bool {
+	// Content check saves hash of each run, so repeated run never confirms change
+	if schedulerType == monitoring_api.SchedulerTypeContent && (retry != nil || flap != nil) {
+		return false
+	}
+	if retry != nil && (retry.Attempts < 1 || retry.BackoffMs < 0 || retry.BackoffMultiplier < 0) {
+		return false
+	}
+	if flap != nil && (flap.ConfirmAfter < 1 || flap.RecoverAfter < 1) {
+		return false
+	}
+	return true
+}
+
+func validOverlap(overlap monitoring_api.OverlapPolicy, deadline int32) bool {
+	switch overlap {
+	case "", monitoring_api.OverlapSkip, monitoring_api.OverlapQueueOne, monitoring_api.OverlapAllowParallel:
+		return deadline >= 0
+	default:
+		return false
+	}
+}
+
+func (s *server) add(ctx context.Context, rq *monitoring_api.AddRequest) (*apiPb.AddResponse, error) {
+	schedulerConfig, err := newSchedulerConfig(primitive.NewObjectID(), rq)
 	if err != nil {
 		return nil, err
 	}
-	var schedulerConfig *scheduler_config_storage.SchedulerConfig
-	switch config := rq.Config.(type) {
-	case *apiPb.AddRequest_Tcp:
-		schedulerConfig = &scheduler_config_storage.SchedulerConfig{
-			ID:       schld.GetIDBson(),
-			Name:     rq.Name,
-			Type:     apiPb.SchedulerType_TCP,
-			Status:   apiPb.SchedulerStatus_STOPPED,
-			Interval: rq.Interval,
-			Timeout:  rq.Timeout,
-			TCPConfig: &scheduler_config_storage.TCPConfig{
-				Host: config.Tcp.Host,
-				Port: config.Tcp.Port,
-			},
-		}
-	case *apiPb.AddRequest_Sitemap:
-		schedulerConfig = &scheduler_config_storage.SchedulerConfig{
-			ID:       schld.GetIDBson(),
-			Name:     rq.Name,
-			Type:     apiPb.SchedulerType_SITE_MAP,
-			Status:   apiPb.SchedulerStatus_STOPPED,
-			Interval: rq.Interval,
-			Timeout:  rq.Timeout,
-			SiteMapConfig: &scheduler_config_storage.SiteMapConfig{
-				URL:         config.Sitemap.Url,
-				Concurrency: config.Sitemap.Concurrency,
-			},
-		}
-	case *apiPb.AddRequest_Grpc:
-		schedulerConfig = &scheduler_config_storage.SchedulerConfig{
-			ID:       schld.GetIDBson(),
-			Name:     rq.Name,
-			Type:     apiPb.SchedulerType_GRPC,
-			Status:   apiPb.SchedulerStatus_STOPPED,
-			Interval: rq.Interval,
-			Timeout:  rq.Timeout,
-			GrpcConfig: &scheduler_config_storage.GrpcConfig{
-				Service: config.Grpc.Service,
-				Host:    config.Grpc.Host,
-				Port:    config.Grpc.Port,
-			},
-		}
-	case *apiPb.AddRequest_Http:
-		schedulerConfig = &scheduler_config_storage.SchedulerConfig{
-			ID:       schld.GetIDBson(),
-			Name:     rq.Name,
-			Type:     apiPb.SchedulerType_HTTP,
-			Status:   apiPb.SchedulerStatus_STOPPED,
-			Interval: rq.Interval,
-			Timeout:  rq.Timeout,
-			HTTPConfig: &scheduler_config_storage.HTTPConfig{
-				Method:     config.Http.Method,
-				URL:        config.Http.Url,
-				Headers:    config.Http.Headers,
-				StatusCode: config.Http.StatusCode,
-			},
-		}
-	case *apiPb.AddRequest_HttpValue:
-		schedulerConfig = &scheduler_config_storage.SchedulerConfig{
-			ID:       schld.GetIDBson(),
-			Name:     rq.Name,
-			Type:     apiPb.SchedulerType_HTTP_JSON_VALUE,
-			Status:   apiPb.SchedulerStatus_STOPPED,
-			Interval: rq.Interval,
-			Timeout:  rq.Timeout,
-			HTTPValueConfig: &scheduler_config_storage.HTTPValueConfig{
-				Method:    config.HttpValue.Method,
-				URL:       config.HttpValue.Url,
-				Headers:   config.HttpValue.Headers,
-				Selectors: helpers.SelectorsToDb(config.HttpValue.Selectors),
-			},
-		}
-	default:
-		return nil, errInvalidTypeError
+	// Scheduler added by request is not started together with others
+	schld, err := NewScheduler(schedulerConfig, 0, s.jobExecutor)
+	if err != nil {
+		return nil, err
 	}
 	err = s.configStorage.Add(ctx, schedulerConfig)
 	if err != nil {
@@ -308,6 +356,297 @@ func (s *server) Add(ctx context.Context, rq *apiPb.AddRequest) (*apiPb.AddRespo
 	}, nil
 }
 
+func (s *server) update(ctx context.Context, rq *monitoring_api.UpdateRequest) (*monitoring_api.UpdateResponse, error) {
+	if rq.Config == nil {
+		return nil, errMissingConfigError
+	}
+	idBson, err := primitive.ObjectIDFromHex(rq.ID)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.configStorage.Get(ctx, idBson)
+	if err != nil {
+		return nil, err
+	}
+	if previous.Status == apiPb.SchedulerStatus_REMOVED {
+		return nil, errRemovedScheduler
+	}
+	schedulerConfig, err := newSchedulerConfig(idBson, rq.Config)
+	if err != nil {
+		return nil, err
+	}
+	schedulerConfig.Status = previous.Status
+	// Job executor reads config on each tick, so only changed schedule requires new ticker
+	scheduleChanged := schedulerConfig.Interval != previous.Interval ||
+		schedulerConfig.Cron != previous.Cron ||
+		schedulerConfig.Timezone != previous.Timezone
+	var schld scheduler.Scheduler
+	if scheduleChanged {
+		schld, err = NewScheduler(schedulerConfig, 0, s.jobExecutor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = s.configStorage.Update(ctx, schedulerConfig)
+	// Scheduler which is removed concurrently must not be created again
+	if err == scheduler_config_storage.ErrSchedulerNotFound {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Consecutive runs of previous config must not be counted by new one
+	s.jobExecutor.Reset(idBson)
+	if scheduleChanged {
+		err = s.schedulerStorage.Replace(schld)
+		if err != nil {
+			return nil, err
+		}
+		if schedulerConfig.Status == apiPb.SchedulerStatus_RUNNED {
+			schld.Run()
+		}
+	}
+	return &monitoring_api.UpdateResponse{}, nil
+}
+
+// Validates request and converts it to config which is stored with status STOPPED
+func newSchedulerConfig(id primitive.ObjectID, rq *monitoring_api.AddRequest) (*scheduler_config_storage.SchedulerConfig, error) {
+	schedulerConfig := &scheduler_config_storage.SchedulerConfig{
+		ID:       id,
+		Name:     rq.Name,
+		Type:     rq.Type,
+		Status:   apiPb.SchedulerStatus_STOPPED,
+		Interval: rq.Interval,
+		Cron:     rq.Cron,
+		Timezone: rq.Timezone,
+		Timeout:  rq.Timeout,
+		Retry:    helpers.RetryPolicyToDb(rq.Retry),
+		Flap:     helpers.FlapPolicyToDb(rq.Flap),
+		Overlap:  rq.Overlap,
+		Deadline: rq.Deadline,
+	}
+	if !validPolicies(rq.Type, rq.Retry, rq.Flap) {
+		return nil, errInvalidPolicyError
+	}
+	if !validOverlap(rq.Overlap, rq.Deadline) {
+		return nil, errInvalidOverlap
+	}
+	switch rq.Type {
+	case apiPb.SchedulerType_TCP:
+		if rq.Tcp == nil || rq.Tcp.TcpConfig == nil {
+			return nil, errMissingConfigError
+		}
+		schedulerConfig.TCPConfig = helpers.TCPConfigToDb(rq.Tcp)
+	case apiPb.SchedulerType_SITE_MAP:
+		if rq.Sitemap == nil || rq.Sitemap.SiteMapConfig == nil {
+			return nil, errMissingConfigError
+		}
+		schedulerConfig.SiteMapConfig = &scheduler_config_storage.SiteMapConfig{
+			URL:             rq.Sitemap.Url,
+			Concurrency:     rq.Sitemap.Concurrency,
+			Client:          helpers.HTTPClientConfigToDb(rq.Sitemap.Client),
+			MaxFailureRatio: rq.Sitemap.MaxFailureRatio,
+			SlowThresholdMs: rq.Sitemap.SlowThresholdMs,
+			CacheTTL:        rq.Sitemap.CacheTTL,
+		}
+	case apiPb.SchedulerType_GRPC:
+		if rq.Grpc == nil || rq.Grpc.GrpcConfig == nil {
+			return nil, errMissingConfigError
+		}
+		schedulerConfig.GrpcConfig = &scheduler_config_storage.GrpcConfig{
+			Service:  rq.Grpc.Service,
+			Host:     rq.Grpc.Host,
+			Port:     rq.Grpc.Port,
+			TLS:      helpers.GrpcTLSConfigToDb(rq.Grpc.TLS),
+			Metadata: rq.Grpc.Metadata,
+			Call:     helpers.GrpcCallConfigToDb(rq.Grpc.Call),
+		}
+	case apiPb.SchedulerType_HTTP:
+		if rq.Http == nil || rq.Http.HttpConfig == nil {
+			return nil, errMissingConfigError
+		}
+		schedulerConfig.HTTPConfig = &scheduler_config_storage.HTTPConfig{
+			Method:     rq.Http.Method,
+			URL:        rq.Http.Url,
+			Headers:    rq.Http.Headers,
+			StatusCode: rq.Http.StatusCode,
+			Assertions: helpers.HTTPAssertionsToDb(rq.Http.Assertions),
+			Body:       helpers.RequestBodyToDb(rq.Http.Body),
+			Client:     helpers.HTTPClientConfigToDb(rq.Http.Client),
+		}
+	case apiPb.SchedulerType_HTTP_JSON_VALUE:
+		if rq.HttpValue == nil || rq.HttpValue.HttpJsonValueConfig == nil {
+			return nil, errMissingConfigError
+		}
+		schedulerConfig.HTTPValueConfig = &scheduler_config_storage.HTTPValueConfig{
+			Method:    rq.HttpValue.Method,
+			URL:       rq.HttpValue.Url,
+			Headers:   rq.HttpValue.Headers,
+			Format:    rq.HttpValue.Format,
+			Selectors: helpers.SelectorsToDb(rq.HttpValue.Selectors),
+			Body:      helpers.RequestBodyToDb(rq.HttpValue.Body),
+			Client:    helpers.HTTPClientConfigToDb(rq.HttpValue.Client),
+		}
+	case monitoring_api.SchedulerTypeTLSCert:
+		if rq.TLSCert == nil {
+			return nil, errMissingConfigError
+		}
+		schedulerConfig.TLSCertConfig = &scheduler_config_storage.TLSCertConfig{
+			Host:                rq.TLSCert.Host,
+			Port:                rq.TLSCert.Port,
+			ServerName:          rq.TLSCert.ServerName,
+			ExpiryWarningDays:   rq.TLSCert.ExpiryWarningDays,
+			SkipChainValidation: rq.TLSCert.SkipChainValidation,
+		}
+	case monitoring_api.SchedulerTypeDNS:
+		// Query is sent directly to resolver, so it should be set
+		if rq.DNS == nil || rq.DNS.Resolver == "" {
+			return nil, errMissingConfigError
+		}
+		schedulerConfig.DNSConfig = &scheduler_config_storage.DNSConfig{
+			Host:           rq.DNS.Host,
+			RecordType:     rq.DNS.RecordType,
+			Resolver:       rq.DNS.Resolver,
+			Assertion:      rq.DNS.Assertion,
+			ExpectedValues: rq.DNS.ExpectedValues,
+			MinCount:       rq.DNS.MinCount,
+		}
+	case monitoring_api.SchedulerTypeScenario:
+		if rq.Scenario == nil {
+			return nil, errMissingConfigError
+		}
+		schedulerConfig.ScenarioConfig = helpers.ScenarioToDb(rq.Scenario)
+	case monitoring_api.SchedulerTypeCrawler:
+		if rq.Crawler == nil {
+			return nil, errMissingConfigError
+		}
+		schedulerConfig.CrawlerConfig = &scheduler_config_storage.CrawlerConfig{
+			URL:         rq.Crawler.URL,
+			MaxDepth:    rq.Crawler.MaxDepth,
+			MaxPages:    rq.Crawler.MaxPages,
+			Concurrency: rq.Crawler.Concurrency,
+			Include:     rq.Crawler.Include,
+			Exclude:     rq.Crawler.Exclude,
+			Client:      helpers.HTTPClientConfigToDb(rq.Crawler.Client),
+		}
+	case monitoring_api.SchedulerTypePostgres, monitoring_api.SchedulerTypeMySQL, monitoring_api.SchedulerTypeRedis, monitoring_api.SchedulerTypeMongo:
+		if rq.Database == nil {
+			return nil, errMissingConfigError
+		}
+		schedulerConfig.DatabaseConfig = helpers.DatabaseConfigToDb(rq.Database)
+	case monitoring_api.SchedulerTypeUDP:
+		if rq.UDP == nil {
+			return nil, errMissingConfigError
+		}
+		schedulerConfig.TCPConfig = helpers.UDPConfigToDb(rq.UDP)
+	case monitoring_api.SchedulerTypeWebSocket:
+		if rq.WebSocket == nil {
+			return nil, errMissingConfigError
+		}
+		schedulerConfig.WebSocketConfig = helpers.WebSocketConfigToDb(rq.WebSocket)
+	case monitoring_api.SchedulerTypePrometheus:
+		if rq.Prometheus == nil {
+			return nil, errMissingConfigError
+		}
+		schedulerConfig.PrometheusConfig = helpers.PrometheusConfigToDb(rq.Prometheus)
+	case monitoring_api.SchedulerTypeContent:
+		if rq.Content == nil {
+			return nil, errMissingConfigError
+		}
+		schedulerConfig.ContentConfig = helpers.ContentConfigToDb(rq.Content)
+	case monitoring_api.SchedulerTypeCommand:
+		if rq.Command == nil {
+			return nil, errMissingConfigError
+		}
+		schedulerConfig.CommandConfig = helpers.CommandConfigToDb(rq.Command)
+	default:
+		return nil, errInvalidTypeError
+	}
+	return schedulerConfig, nil
+}
+
+func (s *server) getSchedules(ctx context.Context, rq *monitoring_api.GetSchedulesRequest) (*monitoring_api.GetSchedulesResponse, error) {
+	var configs []*scheduler_config_storage.SchedulerConfig
+	if len(rq.IDs) == 0 {
+		list, err := s.configStorage.GetAll(ctx)
+		if err != nil {
+			return nil, err
+		}
+		configs = list
+	}
+	for _, id := range rq.IDs {
+		idBson, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, err
+		}
+		config, err := s.configStorage.Get(ctx, idBson)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, config)
+	}
+	schedules := []*monitoring_api.Schedule{}
+	for _, config := range configs {
+		schedule := &monitoring_api.Schedule{
+			ID:       config.ID.Hex(),
+			Interval: config.Interval,
+			Cron:     config.Cron,
+			Timezone: config.Timezone,
+		}
+		schld, err := s.schedulerStorage.Get(schedule.ID)
+		if err == nil {
+			if next := schld.GetNextRunTime(); !next.IsZero() {
+				schedule.NextRunTime = &next
+			}
+		}
+		schedules = append(schedules, schedule)
+	}
+	return &monitoring_api.GetSchedulesResponse{
+		Schedules: schedules,
+	}, nil
+}
+
+// Creates scheduler by cron expression of config or by its interval, start jitter is used only by interval
+func NewScheduler(config *scheduler_config_storage.SchedulerConfig, startJitter time.Duration, jobExecutor job_executor.JobExecutor) (scheduler.Scheduler, error) {
+	if config.Cron != "" {
+		return scheduler.NewCron(config.ID, config.Cron, config.Timezone, jobExecutor)
+	}
+	return scheduler.New(config.ID, helpers.DurationFromSecond(config.Interval), startJitter, jobExecutor)
+}
+
+func toExtensionAddRequest(rq *apiPb.AddRequest) *monitoring_api.AddRequest {
+	extRq := &monitoring_api.AddRequest{
+		Interval: rq.Interval,
+		Timeout:  rq.Timeout,
+		Name:     rq.Name,
+	}
+	switch config := rq.Config.(type) {
+	case *apiPb.AddRequest_Tcp:
+		extRq.Type = apiPb.SchedulerType_TCP
+		extRq.Tcp = &monitoring_api.TCPConfig{TcpConfig: config.Tcp}
+	case *apiPb.AddRequest_Sitemap:
+		extRq.Type = apiPb.SchedulerType_SITE_MAP
+		extRq.Sitemap = &monitoring_api.SiteMapConfig{
+			SiteMapConfig: config.Sitemap,
+		}
+	case *apiPb.AddRequest_Grpc:
+		extRq.Type = apiPb.SchedulerType_GRPC
+		extRq.Grpc = &monitoring_api.GrpcConfig{GrpcConfig: config.Grpc}
+	case *apiPb.AddRequest_Http:
+		extRq.Type = apiPb.SchedulerType_HTTP
+		extRq.Http = &monitoring_api.HTTPConfig{
+			HttpConfig: config.Http,
+		}
+	case *apiPb.AddRequest_HttpValue:
+		extRq.Type = apiPb.SchedulerType_HTTP_JSON_VALUE
+		extRq.HttpValue = &monitoring_api.HTTPValueConfig{
+			HttpJsonValueConfig: config.HttpValue,
+			Selectors:           helpers.SelectorsToExtension(config.HttpValue.Selectors),
+		}
+	}
+	return extRq
+}
+
 func New(
 	schedulerStorage scheduler_storage.SchedulerStorage,
 	jobExecutor job_executor.JobExecutor,
@@ -7,9 +7,12 @@ import (
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	monitoring_api "squzy/internal/monitoring-api"
 	"squzy/internal/scheduler"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
 	"testing"
+	"time"
 )
 
 var (
@@ -78,6 +81,18 @@ var (
 		},
 	}
 
+	successTLSCertConfig = &scheduler_config_storage.SchedulerConfig{
+		ID:       primitive.NewObjectID(),
+		Type:     monitoring_api.SchedulerTypeTLSCert,
+		Status:   0,
+		Interval: 0,
+		Timeout:  0,
+		TLSCertConfig: &scheduler_config_storage.TLSCertConfig{
+			Host: "example.com",
+			Port: 443,
+		},
+	}
+
 	errorConfig = &scheduler_config_storage.SchedulerConfig{
 		ID:       primitive.NewObjectID(),
 		Type:     11111,
@@ -92,6 +107,7 @@ var (
 		successHttpConfig.ID:      successHttpConfig,
 		successHttpValueConfig.ID: successHttpValueConfig,
 		successSiteMapConfig.ID:   successSiteMapConfig,
+		successTLSCertConfig.ID:   successTLSCertConfig,
 		errorConfig.ID:            errorConfig,
 	}
 
@@ -182,6 +198,10 @@ func (s schedulerMock) IsRun() bool {
 	panic("implement me")
 }
 
+func (s schedulerMock) GetNextRunTime() time.Time {
+	return time.Time{}
+}
+
 type mockStorageOk struct {
 }
 
@@ -193,6 +213,10 @@ func (m mockStorageOk) Set(scheduler.Scheduler) error {
 	return nil
 }
 
+func (m mockStorageOk) Replace(scheduler.Scheduler) error {
+	return nil
+}
+
 func (m mockStorageOk) Remove(string) error {
 	return nil
 }
@@ -208,6 +232,10 @@ func (m mockStorageError) Set(scheduler.Scheduler) error {
 	return errors.New("")
 }
 
+func (m mockStorageError) Replace(scheduler.Scheduler) error {
+	return errors.New("")
+}
+
 func (m mockStorageError) Remove(string) error {
 	return errors.New("")
 }
@@ -235,6 +263,10 @@ func (m mockConfigStorageOk) Stop(ctx context.Context, schedulerId primitive.Obj
 	return nil
 }
 
+func (m mockConfigStorageOk) Update(ctx context.Context, config *scheduler_config_storage.SchedulerConfig) error {
+	return nil
+}
+
 func (m mockConfigStorageOk) GetAll(ctx context.Context) ([]*scheduler_config_storage.SchedulerConfig, error) {
 	return []*scheduler_config_storage.SchedulerConfig{
 		{
@@ -247,6 +279,10 @@ func (m mockConfigStorageOk) GetAllForSync(ctx context.Context) ([]*scheduler_co
 	panic("implement me")
 }
 
+func (m mockConfigStorageOk) SetContentHash(ctx context.Context, schedulerID primitive.ObjectID, hash string) error {
+	panic("implement me")
+}
+
 type mockConfigStorageErrorSingle struct {
 }
 
@@ -270,6 +306,10 @@ func (m mockConfigStorageErrorSingle) Stop(ctx context.Context, schedulerId prim
 	return errors.New("")
 }
 
+func (m mockConfigStorageErrorSingle) Update(ctx context.Context, config *scheduler_config_storage.SchedulerConfig) error {
+	return errors.New("")
+}
+
 func (m mockConfigStorageErrorSingle) GetAll(ctx context.Context) ([]*scheduler_config_storage.SchedulerConfig, error) {
 	return []*scheduler_config_storage.SchedulerConfig{
 		{
@@ -282,6 +322,10 @@ func (m mockConfigStorageErrorSingle) GetAllForSync(ctx context.Context) ([]*sch
 	panic("implement me")
 }
 
+func (m mockConfigStorageErrorSingle) SetContentHash(ctx context.Context, schedulerID primitive.ObjectID, hash string) error {
+	panic("implement me")
+}
+
 type mockConfigStorageError struct {
 }
 
@@ -305,6 +349,10 @@ func (m mockConfigStorageError) Stop(ctx context.Context, schedulerId primitive.
 	panic("implement me")
 }
 
+func (m mockConfigStorageError) Update(ctx context.Context, config *scheduler_config_storage.SchedulerConfig) error {
+	panic("implement me")
+}
+
 func (m mockConfigStorageError) GetAll(ctx context.Context) ([]*scheduler_config_storage.SchedulerConfig, error) {
 	return nil, errors.New("")
 }
@@ -313,6 +361,10 @@ func (m mockConfigStorageError) GetAllForSync(ctx context.Context) ([]*scheduler
 	panic("implement me")
 }
 
+func (m mockConfigStorageError) SetContentHash(ctx context.Context, schedulerID primitive.ObjectID, hash string) error {
+	panic("implement me")
+}
+
 func TestNew(t *testing.T) {
 	t.Run("Should: implement interface", func(t *testing.T) {
 		s := New(nil, nil, nil)
@@ -388,6 +440,87 @@ func TestServer_GetSchedulerById(t *testing.T) {
 		})
 		assert.NotEqual(t, nil, err)
 	})
+	t.Run("Should: return tls cert scheduler with tcp config", func(t *testing.T) {
+		s := New(nil, nil, &mockConfigStorageOk{})
+		res, err := s.GetSchedulerById(context.Background(), &apiPb.GetSchedulerByIdRequest{
+			Id: successTLSCertConfig.ID.Hex(),
+		})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, monitoring_api.SchedulerTypeTLSCert, res.Type)
+		assert.Equal(t, "example.com", res.GetTcp().Host)
+		assert.EqualValues(t, 443, res.GetTcp().Port)
+	})
+}
+
+func TestSetExtensionConfig(t *testing.T) {
+	t.Run("Should: return database target without credentials", func(t *testing.T) {
+		scheduler := &apiPb.Scheduler{}
+		setExtensionConfig(scheduler, &scheduler_config_storage.SchedulerConfig{
+			Type: monitoring_api.SchedulerTypePostgres,
+			DatabaseConfig: &scheduler_config_storage.DatabaseConfig{
+				Host:     "db",
+				Port:     5432,
+				Password: "secret",
+			},
+		})
+		assert.Equal(t, &apiPb.TcpConfig{Host: "db", Port: 5432}, scheduler.GetTcp())
+	})
+	t.Run("Should: return udp target", func(t *testing.T) {
+		scheduler := &apiPb.Scheduler{}
+		setExtensionConfig(scheduler, &scheduler_config_storage.SchedulerConfig{
+			Type:      monitoring_api.SchedulerTypeUDP,
+			TCPConfig: &scheduler_config_storage.TCPConfig{Host: "host", Port: 53},
+		})
+		assert.Equal(t, &apiPb.TcpConfig{Host: "host", Port: 53}, scheduler.GetTcp())
+	})
+	t.Run("Should: return dns host", func(t *testing.T) {
+		scheduler := &apiPb.Scheduler{}
+		setExtensionConfig(scheduler, &scheduler_config_storage.SchedulerConfig{
+			Type:      monitoring_api.SchedulerTypeDNS,
+			DNSConfig: &scheduler_config_storage.DNSConfig{Host: "example.com", Resolver: "8.8.8.8:53"},
+		})
+		assert.Equal(t, &apiPb.TcpConfig{Host: "example.com"}, scheduler.GetTcp())
+	})
+	t.Run("Should: return crawler as sitemap config", func(t *testing.T) {
+		scheduler := &apiPb.Scheduler{}
+		setExtensionConfig(scheduler, &scheduler_config_storage.SchedulerConfig{
+			Type:          monitoring_api.SchedulerTypeCrawler,
+			CrawlerConfig: &scheduler_config_storage.CrawlerConfig{URL: "http://site", Concurrency: 2},
+		})
+		assert.Equal(t, &apiPb.SiteMapConfig{Url: "http://site", Concurrency: 2}, scheduler.GetSitemap())
+	})
+	t.Run("Should: return websocket as http config", func(t *testing.T) {
+		scheduler := &apiPb.Scheduler{}
+		setExtensionConfig(scheduler, &scheduler_config_storage.SchedulerConfig{
+			Type:            monitoring_api.SchedulerTypeWebSocket,
+			WebSocketConfig: &scheduler_config_storage.WebSocketConfig{URL: "ws://site"},
+		})
+		assert.Equal(t, &apiPb.HttpConfig{Method: http.MethodGet, Url: "ws://site"}, scheduler.GetHttp())
+	})
+	t.Run("Should: return prometheus as http config", func(t *testing.T) {
+		scheduler := &apiPb.Scheduler{}
+		setExtensionConfig(scheduler, &scheduler_config_storage.SchedulerConfig{
+			Type:             monitoring_api.SchedulerTypePrometheus,
+			PrometheusConfig: &scheduler_config_storage.PrometheusConfig{URL: "http://site/metrics"},
+		})
+		assert.Equal(t, &apiPb.HttpConfig{Method: http.MethodGet, Url: "http://site/metrics"}, scheduler.GetHttp())
+	})
+	t.Run("Should: return content as http config with method", func(t *testing.T) {
+		scheduler := &apiPb.Scheduler{}
+		setExtensionConfig(scheduler, &scheduler_config_storage.SchedulerConfig{
+			Type:          monitoring_api.SchedulerTypeContent,
+			ContentConfig: &scheduler_config_storage.ContentConfig{URL: "http://site", Method: http.MethodPost},
+		})
+		assert.Equal(t, &apiPb.HttpConfig{Method: http.MethodPost, Url: "http://site"}, scheduler.GetHttp())
+	})
+	t.Run("Should: return command without config", func(t *testing.T) {
+		scheduler := &apiPb.Scheduler{}
+		setExtensionConfig(scheduler, &scheduler_config_storage.SchedulerConfig{
+			Type:          monitoring_api.SchedulerTypeCommand,
+			CommandConfig: &scheduler_config_storage.CommandConfig{Path: "/bin/true"},
+		})
+		assert.Nil(t, scheduler.Config)
+	})
 }
 
 func TestServer_Run(t *testing.T) {
@@ -468,18 +601,21 @@ func TestServer_Remove(t *testing.T) {
 		assert.NotEqual(t, nil, err)
 	})
 	t.Run("Should: return error because cant find in memory", func(t *testing.T) {
-		s := New(&mockStorageError{}, nil, &mockConfigStorageOk{})
+		s := New(&mockStorageError{}, &mockJobExecutor{}, &mockConfigStorageOk{})
 		_, err := s.Remove(context.Background(), &apiPb.RemoveRequest{
 			Id: primitive.NewObjectID().Hex(),
 		})
 		assert.NotEqual(t, nil, err)
 	})
 	t.Run("Should: not return error", func(t *testing.T) {
-		s := New(&mockStorageOk{}, nil, &mockConfigStorageOk{})
+		jobExecutor := &mockJobExecutor{}
+		s := New(&mockStorageOk{}, jobExecutor, &mockConfigStorageOk{})
+		id := primitive.NewObjectID()
 		_, err := s.Remove(context.Background(), &apiPb.RemoveRequest{
-			Id: primitive.NewObjectID().Hex(),
+			Id: id.Hex(),
 		})
 		assert.Equal(t, nil, err)
+		assert.Equal(t, []primitive.ObjectID{id}, jobExecutor.reset)
 	})
 }
 
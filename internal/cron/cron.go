@@ -0,0 +1,77 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"strings"
+	"time"
+)
+
+var (
+	errEmptyExpression       = errors.New("cron expression is empty")
+	invalidExpressionErrorFn = func(expression string, err error) error {
+		return fmt.Errorf("invalid cron expression `%s`: %s", expression, err.Error())
+	}
+	invalidTimezoneErrorFn = func(timezone string, err error) error {
+		return fmt.Errorf("invalid timezone `%s`: %s", timezone, err.Error())
+	}
+
+	// Seconds field is optional, so expressions of classic cron are accepted too
+	parser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+)
+
+type Schedule interface {
+	// Returns first run time after t, zero time when there is no such time
+	Next(t time.Time) time.Time
+}
+
+// Several expressions joined by `;`, nearest run of them is used
+type schedule []cron.Schedule
+
+// Parses expressions with optional seconds field(`0 */5 9-17 * * MON-FRI`) or descriptors(`@daily`, `@every 1h`),
+// several expressions can be joined by `;`. Time is in UTC when timezone is empty, timezone of expression is replaced by it
+func Parse(spec string, timezone string) (Schedule, error) {
+	location := time.UTC
+	if timezone != "" {
+		var err error
+		location, err = time.LoadLocation(timezone)
+		if err != nil {
+			return nil, invalidTimezoneErrorFn(timezone, err)
+		}
+	}
+	result := schedule{}
+	for _, value := range strings.Split(spec, ";") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		expr, err := parser.Parse(value)
+		if err != nil {
+			return nil, invalidExpressionErrorFn(value, err)
+		}
+		// Interval of `@every` does not depend on location
+		if specSchedule, ok := expr.(*cron.SpecSchedule); ok {
+			specSchedule.Location = location
+		}
+		result = append(result, expr)
+	}
+	if len(result) == 0 {
+		return nil, errEmptyExpression
+	}
+	return result, nil
+}
+
+func (s schedule) Next(t time.Time) time.Time {
+	var result time.Time
+	for _, expr := range s {
+		next := expr.Next(t)
+		if next.IsZero() {
+			continue
+		}
+		if result.IsZero() || next.Before(result) {
+			result = next
+		}
+	}
+	return result
+}
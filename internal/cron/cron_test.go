@@ -0,0 +1,133 @@
+package cron
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func nextRuns(t *testing.T, spec string, timezone string, from time.Time, count int) []time.Time {
+	schedule, err := Parse(spec, timezone)
+	assert.Nil(t, err)
+	result := []time.Time{}
+	for i := 0; i < count; i++ {
+		from = schedule.Next(from)
+		result = append(result, from)
+	}
+	return result
+}
+
+func TestParse(t *testing.T) {
+	t.Run("Should: parse without error", func(t *testing.T) {
+		for _, spec := range []string{
+			"* * * * * *",
+			"*/5 * * * *",
+			"0 */5 9-17 * * MON-FRI",
+			"0 0 0-8,18-23 * * *",
+			"0 0 3 * * ?",
+			"30 15 10 1,15 jan-jun/2 0",
+			"@daily",
+			"@hourly",
+			"@every 1h30m",
+			"0 */5 9-17 * * 1-5; 0 0 0-8,18-23 * * *",
+		} {
+			_, err := Parse(spec, "")
+			assert.Nil(t, err, spec)
+		}
+	})
+	t.Run("Should: return error because expression is invalid", func(t *testing.T) {
+		for _, spec := range []string{
+			"",
+			" ; ",
+			"* * * *",
+			"* * * * * * *",
+			"60 * * * * *",
+			"* * 24 * * *",
+			"* * * 0 * *",
+			"* * * * 13 *",
+			"* * * * * 7",
+			"@Hourly",
+			"* * * * foo *",
+			"*/0 * * * * *",
+			"*/x * * * * *",
+			"10-5 * * * * *",
+			"-5 * * * * *",
+			"@weekly2",
+		} {
+			_, err := Parse(spec, "")
+			assert.NotNil(t, err, spec)
+		}
+	})
+	t.Run("Should: return error because timezone is invalid", func(t *testing.T) {
+		_, err := Parse("@daily", "Mars/Olympus")
+		assert.NotNil(t, err)
+	})
+}
+
+func TestSchedule_Next(t *testing.T) {
+	// Monday
+	from := time.Date(2020, 6, 1, 8, 58, 30, 0, time.UTC)
+	t.Run("Should: return next second", func(t *testing.T) {
+		assert.Equal(t, []time.Time{
+			time.Date(2020, 6, 1, 8, 58, 31, 0, time.UTC),
+			time.Date(2020, 6, 1, 8, 58, 32, 0, time.UTC),
+		}, nextRuns(t, "* * * * * *", "", from.Add(time.Millisecond*300), 2))
+	})
+	t.Run("Should: run every 5 minutes during business hours", func(t *testing.T) {
+		assert.Equal(t, []time.Time{
+			time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC),
+			time.Date(2020, 6, 1, 9, 5, 0, 0, time.UTC),
+		}, nextRuns(t, "0 */5 9-17 * * MON-FRI", "", from, 2))
+		// Friday evening
+		assert.Equal(t, []time.Time{
+			time.Date(2020, 6, 8, 9, 0, 0, 0, time.UTC),
+		}, nextRuns(t, "0 */5 9-17 * * MON-FRI", "", time.Date(2020, 6, 5, 17, 55, 0, 0, time.UTC), 1))
+	})
+	t.Run("Should: use nearest run of several expressions", func(t *testing.T) {
+		assert.Equal(t, []time.Time{
+			time.Date(2020, 6, 1, 17, 55, 0, 0, time.UTC),
+			time.Date(2020, 6, 1, 18, 0, 0, 0, time.UTC),
+			time.Date(2020, 6, 1, 19, 0, 0, 0, time.UTC),
+		}, nextRuns(t, "0 */5 9-17 * * MON-FRI; 0 0 0-8,18-23 * * *", "", time.Date(2020, 6, 1, 17, 50, 0, 0, time.UTC), 3))
+	})
+	t.Run("Should: run daily in timezone", func(t *testing.T) {
+		location, err := time.LoadLocation("Europe/Moscow")
+		assert.Nil(t, err)
+		runs := nextRuns(t, "0 0 3 * * *", "Europe/Moscow", from, 2)
+		assert.True(t, time.Date(2020, 6, 2, 3, 0, 0, 0, location).Equal(runs[0]))
+		assert.True(t, time.Date(2020, 6, 3, 3, 0, 0, 0, location).Equal(runs[1]))
+	})
+	t.Run("Should: skip missing days of month", func(t *testing.T) {
+		assert.Equal(t, []time.Time{
+			time.Date(2020, 7, 31, 0, 0, 0, 0, time.UTC),
+			time.Date(2020, 8, 31, 0, 0, 0, 0, time.UTC),
+			time.Date(2020, 10, 31, 0, 0, 0, 0, time.UTC),
+		}, nextRuns(t, "0 0 0 31 * *", "", from, 3))
+		assert.Equal(t, []time.Time{
+			time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+		}, nextRuns(t, "0 0 0 29 2 *", "", from, 1))
+	})
+	t.Run("Should: match day of month or weekday when both are set", func(t *testing.T) {
+		assert.Equal(t, []time.Time{
+			time.Date(2020, 6, 7, 0, 0, 0, 0, time.UTC),
+			time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+			time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+		}, nextRuns(t, "0 0 0 15 * 0", "", from, 3))
+	})
+	t.Run("Should: skip missing local time and run every hour of repeated local time", func(t *testing.T) {
+		location, err := time.LoadLocation("Europe/Berlin")
+		assert.Nil(t, err)
+		// Clocks are moved forward at 2:00
+		runs := nextRuns(t, "0 30 2 * * *", "Europe/Berlin", time.Date(2020, 3, 28, 12, 0, 0, 0, location), 2)
+		assert.True(t, time.Date(2020, 3, 30, 2, 30, 0, 0, location).Equal(runs[0]))
+		assert.True(t, time.Date(2020, 3, 31, 2, 30, 0, 0, location).Equal(runs[1]))
+		// Clocks are moved back at 3:00
+		runs = nextRuns(t, "0 0 * * * *", "Europe/Berlin", time.Date(2020, 10, 25, 1, 30, 0, 0, location), 3)
+		assert.Equal(t, []int{2, 2, 3}, []int{runs[0].Hour(), runs[1].Hour(), runs[2].Hour()})
+		assert.Equal(t, time.Hour, runs[1].Sub(runs[0]))
+		assert.Equal(t, time.Hour, runs[2].Sub(runs[1]))
+	})
+	t.Run("Should: return zero time", func(t *testing.T) {
+		assert.True(t, nextRuns(t, "0 0 0 30 2 *", "", from, 1)[0].IsZero())
+	})
+}
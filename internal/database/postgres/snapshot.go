@@ -17,6 +17,9 @@ type Snapshot struct {
 	MetaValue     []byte `gorm:"column:metaValue"`
 }
 
+// Code of skipped tick from squzy/internal/monitoring-api
+const schedulerCodeSkipped = 5
+
 type UptimeResult struct {
 	Count   int64  `gorm:"column:count"`
 	Latency string `gorm:"column:latency"`
@@ -25,6 +28,8 @@ type UptimeResult struct {
 var (
 	schedulerIdFilterString   = fmt.Sprintf(`"%s"."schedulerId" = ?`, dbSnapshotCollection)
 	metaStartTimeFilterString = fmt.Sprintf(`"%s"."metaStartTime" BETWEEN ? and ?`, dbSnapshotCollection)
+	// Skipped ticks of scheduler are neither uptime nor downtime
+	notSkippedFilterString = fmt.Sprintf(`"%s"."code" <> '%d'`, dbSnapshotCollection, schedulerCodeSkipped)
 
 	snapOrderMap = map[apiPb.SortSchedulerList]string{
 		apiPb.SortSchedulerList_SORT_SCHEDULER_LIST_UNSPECIFIED: fmt.Sprintf(`"%s"."metaStartTime"`, dbSnapshotCollection),
@@ -90,6 +95,7 @@ func (p *Postgres) GetSnapshotsUptime(request *apiPb.GetSchedulerUptimeRequest)
 	err = p.Db.Table(dbSnapshotCollection).
 		Where(schedulerIdFilterString, request.GetSchedulerId()).
 		Where(metaStartTimeFilterString, timeFrom, timeTo).
+		Where(notSkippedFilterString).
 		Count(&countAll).Error
 
 	selectString := fmt.Sprintf(
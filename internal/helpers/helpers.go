@@ -2,7 +2,11 @@ package helpers
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	monitoring_api "squzy/internal/monitoring-api"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
 	"strings"
 	"time"
@@ -15,6 +19,10 @@ const (
 	defaultTimeoutDuration       = time.Second * defaultTimeout
 )
 
+var (
+	ErrInvalidCACert = errors.New("INVALID_CA_CERTIFICATE")
+)
+
 func GetPortByURL(url string) int32 {
 	if strings.HasPrefix(url, "https") {
 		return httpsPort
@@ -33,12 +41,64 @@ func TimeoutContext(parentCtx context.Context, timeout time.Duration) (context.C
 	return context.WithTimeout(parentCtx, timeout)
 }
 
-func SelectorsToDb(selectors []*apiPb.HttpJsonValueConfig_Selectors) []*scheduler_config_storage.Selectors {
+// Builds client tls config, PEM encoded ca certificates are trusted in addition to system certificates
+func NewTLSConfig(insecureSkipVerify bool, caCert string, clientCert string, clientKey string, serverName string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: insecureSkipVerify, //nolint:gosec
+		ServerName:         serverName,
+	}
+	if caCert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(caCert)) {
+			return nil, ErrInvalidCACert
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if clientCert != "" || clientKey != "" {
+		cert, err := tls.X509KeyPair([]byte(clientCert), []byte(clientKey))
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func SelectorsToDb(selectors []*monitoring_api.HTTPValueSelector) []*scheduler_config_storage.Selectors {
 	arr := []*scheduler_config_storage.Selectors{}
 	for _, v := range selectors {
 		arr = append(arr, &scheduler_config_storage.Selectors{
-			Type: v.Type,
-			Path: v.Path,
+			Type: v.GetType(),
+			Path: v.GetPath(),
+			Rule: selectorRuleToDb(v.Rule),
+		})
+	}
+	return arr
+}
+
+func selectorRuleToDb(rule *monitoring_api.SelectorRule) *scheduler_config_storage.SelectorRule {
+	if rule == nil {
+		return nil
+	}
+	return &scheduler_config_storage.SelectorRule{
+		Type:    rule.Type,
+		Number:  rule.Number,
+		Min:     rule.Min,
+		Max:     rule.Max,
+		Value:   rule.Value,
+		Bool:    rule.Bool,
+		Seconds: rule.Seconds,
+	}
+}
+
+func SelectorsToExtension(selectors []*apiPb.HttpJsonValueConfig_Selectors) []*monitoring_api.HTTPValueSelector {
+	arr := []*monitoring_api.HTTPValueSelector{}
+	for _, v := range selectors {
+		arr = append(arr, &monitoring_api.HTTPValueSelector{
+			HttpJsonValueConfig_Selectors: v,
 		})
 	}
 	return arr
@@ -54,3 +114,253 @@ func SelectorsToProto(selectors []*scheduler_config_storage.Selectors) []*apiPb.
 	}
 	return arr
 }
+
+func HTTPAssertionsToDb(assertions []*monitoring_api.HTTPAssertion) []*scheduler_config_storage.HTTPAssertion {
+	arr := []*scheduler_config_storage.HTTPAssertion{}
+	for _, v := range assertions {
+		arr = append(arr, &scheduler_config_storage.HTTPAssertion{
+			Type:   v.Type,
+			Value:  v.Value,
+			Header: v.Header,
+			Max:    v.Max,
+		})
+	}
+	return arr
+}
+
+func RequestBodyToDb(body *monitoring_api.RequestBody) *scheduler_config_storage.RequestBody {
+	if body == nil {
+		return nil
+	}
+	return &scheduler_config_storage.RequestBody{
+		Type:        body.Type,
+		Content:     body.Content,
+		Form:        body.Form,
+		ContentType: body.ContentType,
+	}
+}
+
+func ScenarioToDb(scenario *monitoring_api.ScenarioConfig) *scheduler_config_storage.ScenarioConfig {
+	steps := []*scheduler_config_storage.ScenarioStep{}
+	for _, step := range scenario.Steps {
+		captures := []*scheduler_config_storage.ScenarioCapture{}
+		for _, capture := range step.Captures {
+			captures = append(captures, &scheduler_config_storage.ScenarioCapture{
+				Variable: capture.Variable,
+				Path:     capture.Path,
+				Header:   capture.Header,
+			})
+		}
+		steps = append(steps, &scheduler_config_storage.ScenarioStep{
+			Name:       step.Name,
+			Method:     step.Method,
+			URL:        step.URL,
+			Headers:    step.Headers,
+			Body:       RequestBodyToDb(step.Body),
+			StatusCode: step.StatusCode,
+			Assertions: HTTPAssertionsToDb(step.Assertions),
+			Captures:   captures,
+		})
+	}
+	return &scheduler_config_storage.ScenarioConfig{
+		Steps:  steps,
+		Client: HTTPClientConfigToDb(scenario.Client),
+	}
+}
+
+func HTTPClientConfigToDb(config *monitoring_api.HTTPClientConfig) *scheduler_config_storage.HTTPClientConfig {
+	if config == nil {
+		return nil
+	}
+	return &scheduler_config_storage.HTTPClientConfig{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		CACert:             config.CACert,
+		ClientCert:         config.ClientCert,
+		ClientKey:          config.ClientKey,
+		ServerName:         config.ServerName,
+		ProxyURL:           config.ProxyURL,
+		DisableRedirects:   config.DisableRedirects,
+		MaxRedirects:       config.MaxRedirects,
+	}
+}
+
+func GrpcTLSConfigToDb(config *monitoring_api.GrpcTLSConfig) *scheduler_config_storage.GrpcTLSConfig {
+	if config == nil {
+		return nil
+	}
+	return &scheduler_config_storage.GrpcTLSConfig{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		CACert:             config.CACert,
+		ClientCert:         config.ClientCert,
+		ClientKey:          config.ClientKey,
+		ServerName:         config.ServerName,
+	}
+}
+
+func GrpcCallConfigToDb(config *monitoring_api.GrpcCallConfig) *scheduler_config_storage.GrpcCallConfig {
+	if config == nil {
+		return nil
+	}
+	return &scheduler_config_storage.GrpcCallConfig{
+		Method:    config.Method,
+		Request:   config.Request,
+		Selectors: SelectorsToDb(config.Selectors),
+	}
+}
+
+func DatabaseConfigToDb(config *monitoring_api.DatabaseConfig) *scheduler_config_storage.DatabaseConfig {
+	if config == nil {
+		return nil
+	}
+	var value *scheduler_config_storage.Selectors
+	if config.Value != nil {
+		value = SelectorsToDb([]*monitoring_api.HTTPValueSelector{config.Value})[0]
+	}
+	return &scheduler_config_storage.DatabaseConfig{
+		Host:     config.Host,
+		Port:     config.Port,
+		User:     config.User,
+		Password: config.Password,
+		Database: config.Database,
+		Query:    config.Query,
+		Command:  config.Command,
+		SSLMode:  config.SSLMode,
+		Value:    value,
+	}
+}
+
+func TCPConfigToDb(config *monitoring_api.TCPConfig) *scheduler_config_storage.TCPConfig {
+	if config == nil || config.TcpConfig == nil {
+		return nil
+	}
+	return &scheduler_config_storage.TCPConfig{
+		Host:          config.Host,
+		Port:          config.Port,
+		Send:          config.Send,
+		Expect:        socketExpectToDb(config.Expect),
+		ReadTimeoutMs: config.ReadTimeoutMs,
+	}
+}
+
+// Udp scheduler is saved with same config as tcp scheduler
+func UDPConfigToDb(config *monitoring_api.UDPConfig) *scheduler_config_storage.TCPConfig {
+	if config == nil {
+		return nil
+	}
+	return &scheduler_config_storage.TCPConfig{
+		Host:          config.Host,
+		Port:          config.Port,
+		Send:          config.Send,
+		Expect:        socketExpectToDb(config.Expect),
+		ReadTimeoutMs: config.ReadTimeoutMs,
+	}
+}
+
+func socketExpectToDb(expect *monitoring_api.SocketExpect) *scheduler_config_storage.SocketExpect {
+	if expect == nil {
+		return nil
+	}
+	return &scheduler_config_storage.SocketExpect{
+		Type:  expect.Type,
+		Value: expect.Value,
+	}
+}
+
+func WebSocketConfigToDb(config *monitoring_api.WebSocketConfig) *scheduler_config_storage.WebSocketConfig {
+	if config == nil {
+		return nil
+	}
+	return &scheduler_config_storage.WebSocketConfig{
+		URL:                config.URL,
+		Headers:            config.Headers,
+		Origin:             config.Origin,
+		Subprotocols:       config.Subprotocols,
+		Send:               config.Send,
+		Expect:             socketExpectToDb(config.Expect),
+		InsecureSkipVerify: config.InsecureSkipVerify,
+	}
+}
+
+func PrometheusConfigToDb(config *monitoring_api.PrometheusConfig) *scheduler_config_storage.PrometheusConfig {
+	if config == nil {
+		return nil
+	}
+	selectors := []*scheduler_config_storage.MetricSelector{}
+	for _, selector := range config.Selectors {
+		labels := []*scheduler_config_storage.MetricLabelMatcher{}
+		for _, label := range selector.Labels {
+			labels = append(labels, &scheduler_config_storage.MetricLabelMatcher{
+				Name:  label.Name,
+				Type:  label.Type,
+				Value: label.Value,
+			})
+		}
+		selectors = append(selectors, &scheduler_config_storage.MetricSelector{
+			Name:      selector.Name,
+			Labels:    labels,
+			Aggregate: selector.Aggregate,
+			Rule:      selectorRuleToDb(selector.Rule),
+		})
+	}
+	return &scheduler_config_storage.PrometheusConfig{
+		URL:       config.URL,
+		Headers:   config.Headers,
+		Selectors: selectors,
+		Client:    HTTPClientConfigToDb(config.Client),
+	}
+}
+
+func ContentConfigToDb(config *monitoring_api.ContentConfig) *scheduler_config_storage.ContentConfig {
+	if config == nil {
+		return nil
+	}
+	var region *scheduler_config_storage.ContentRegion
+	if config.Region != nil {
+		region = &scheduler_config_storage.ContentRegion{
+			Type: config.Region.Type,
+			Path: config.Region.Path,
+		}
+	}
+	return &scheduler_config_storage.ContentConfig{
+		URL:     config.URL,
+		Method:  config.Method,
+		Headers: config.Headers,
+		Region:  region,
+		Ignore:  config.Ignore,
+		Client:  HTTPClientConfigToDb(config.Client),
+	}
+}
+
+func CommandConfigToDb(config *monitoring_api.CommandConfig) *scheduler_config_storage.CommandConfig {
+	if config == nil {
+		return nil
+	}
+	return &scheduler_config_storage.CommandConfig{
+		Path:      config.Path,
+		Args:      config.Args,
+		Env:       config.Env,
+		Dir:       config.Dir,
+		ParseJSON: config.ParseJSON,
+	}
+}
+
+func RetryPolicyToDb(policy *monitoring_api.RetryPolicy) *scheduler_config_storage.RetryPolicy {
+	if policy == nil {
+		return nil
+	}
+	return &scheduler_config_storage.RetryPolicy{
+		Attempts:          policy.Attempts,
+		BackoffMs:         policy.BackoffMs,
+		BackoffMultiplier: policy.BackoffMultiplier,
+	}
+}
+
+func FlapPolicyToDb(policy *monitoring_api.FlapPolicy) *scheduler_config_storage.FlapPolicy {
+	if policy == nil {
+		return nil
+	}
+	return &scheduler_config_storage.FlapPolicy{
+		ConfirmAfter: policy.ConfirmAfter,
+		RecoverAfter: policy.RecoverAfter,
+	}
+}
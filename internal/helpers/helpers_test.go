@@ -4,6 +4,7 @@ import (
 	"context"
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
 	"github.com/stretchr/testify/assert"
+	monitoring_api "squzy/internal/monitoring-api"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
 	"testing"
 	"time"
@@ -46,15 +47,54 @@ func TestSelectorsToDb(t *testing.T) {
 				Type: apiPb.HttpJsonValueConfig_STRING,
 				Path: "select",
 			},
-		}, SelectorsToDb([]*apiPb.HttpJsonValueConfig_Selectors{
+		}, SelectorsToDb([]*monitoring_api.HTTPValueSelector{
 			{
-				Type: apiPb.HttpJsonValueConfig_STRING,
-				Path: "select",
+				HttpJsonValueConfig_Selectors: &apiPb.HttpJsonValueConfig_Selectors{
+					Type: apiPb.HttpJsonValueConfig_STRING,
+					Path: "select",
+				},
+			},
+		}))
+	})
+	t.Run("Should: convert rule", func(t *testing.T) {
+		assert.EqualValues(t, []*scheduler_config_storage.Selectors{
+			{
+				Type: apiPb.HttpJsonValueConfig_NUMBER,
+				Path: "queue.depth",
+				Rule: &scheduler_config_storage.SelectorRule{
+					Type:   monitoring_api.SelectorRuleLess,
+					Number: 1000,
+				},
+			},
+		}, SelectorsToDb([]*monitoring_api.HTTPValueSelector{
+			{
+				HttpJsonValueConfig_Selectors: &apiPb.HttpJsonValueConfig_Selectors{
+					Type: apiPb.HttpJsonValueConfig_NUMBER,
+					Path: "queue.depth",
+				},
+				Rule: &monitoring_api.SelectorRule{
+					Type:   monitoring_api.SelectorRuleLess,
+					Number: 1000,
+				},
 			},
 		}))
 	})
 }
 
+func TestSelectorsToExtension(t *testing.T) {
+	t.Run("Should: convert correct", func(t *testing.T) {
+		selector := &apiPb.HttpJsonValueConfig_Selectors{
+			Type: apiPb.HttpJsonValueConfig_STRING,
+			Path: "select",
+		}
+		assert.EqualValues(t, []*monitoring_api.HTTPValueSelector{
+			{
+				HttpJsonValueConfig_Selectors: selector,
+			},
+		}, SelectorsToExtension([]*apiPb.HttpJsonValueConfig_Selectors{selector}))
+	})
+}
+
 func TestSelectorsToProto(t *testing.T) {
 	t.Run("Should: convert correct", func(t *testing.T) {
 		assert.EqualValues(t, []*apiPb.HttpJsonValueConfig_Selectors{
@@ -70,3 +110,395 @@ func TestSelectorsToProto(t *testing.T) {
 		}))
 	})
 }
+
+func TestHTTPAssertionsToDb(t *testing.T) {
+	t.Run("Should: convert correct", func(t *testing.T) {
+		assert.EqualValues(t, []*scheduler_config_storage.HTTPAssertion{
+			{
+				Type:   monitoring_api.HTTPAssertionHeader,
+				Value:  "application/json",
+				Header: "Content-Type",
+			},
+		}, HTTPAssertionsToDb([]*monitoring_api.HTTPAssertion{
+			{
+				Type:   monitoring_api.HTTPAssertionHeader,
+				Value:  "application/json",
+				Header: "Content-Type",
+			},
+		}))
+	})
+}
+
+func TestRequestBodyToDb(t *testing.T) {
+	t.Run("Should: return nil", func(t *testing.T) {
+		assert.Nil(t, RequestBodyToDb(nil))
+	})
+	t.Run("Should: convert correct", func(t *testing.T) {
+		assert.EqualValues(t, &scheduler_config_storage.RequestBody{
+			Type:        monitoring_api.RequestBodyJSON,
+			Content:     `{"query":"{ health }"}`,
+			ContentType: "application/graphql+json",
+		}, RequestBodyToDb(&monitoring_api.RequestBody{
+			Type:        monitoring_api.RequestBodyJSON,
+			Content:     `{"query":"{ health }"}`,
+			ContentType: "application/graphql+json",
+		}))
+	})
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	t.Run("Should: return config with server name", func(t *testing.T) {
+		config, err := NewTLSConfig(true, "", "", "", "squzy.app")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, true, config.InsecureSkipVerify)
+		assert.Equal(t, "squzy.app", config.ServerName)
+		assert.Nil(t, config.RootCAs)
+	})
+	t.Run("Should: return error because ca is invalid", func(t *testing.T) {
+		_, err := NewTLSConfig(false, "ca", "", "", "")
+		assert.Equal(t, ErrInvalidCACert, err)
+	})
+	t.Run("Should: return error because client certificate is invalid", func(t *testing.T) {
+		_, err := NewTLSConfig(false, "", "cert", "", "")
+		assert.NotEqual(t, nil, err)
+	})
+}
+
+func TestHTTPClientConfigToDb(t *testing.T) {
+	t.Run("Should: return nil", func(t *testing.T) {
+		assert.Nil(t, HTTPClientConfigToDb(nil))
+	})
+	t.Run("Should: convert correct", func(t *testing.T) {
+		assert.EqualValues(t, &scheduler_config_storage.HTTPClientConfig{
+			CACert:       "ca",
+			ClientCert:   "cert",
+			ClientKey:    "key",
+			ServerName:   "squzy.app",
+			ProxyURL:     "http://proxy:3128",
+			MaxRedirects: 3,
+		}, HTTPClientConfigToDb(&monitoring_api.HTTPClientConfig{
+			CACert:       "ca",
+			ClientCert:   "cert",
+			ClientKey:    "key",
+			ServerName:   "squzy.app",
+			ProxyURL:     "http://proxy:3128",
+			MaxRedirects: 3,
+		}))
+	})
+}
+
+func TestScenarioToDb(t *testing.T) {
+	t.Run("Should: convert correct", func(t *testing.T) {
+		assert.EqualValues(t, &scheduler_config_storage.ScenarioConfig{
+			Steps: []*scheduler_config_storage.ScenarioStep{
+				{
+					Name:       "login",
+					Method:     "POST",
+					URL:        "https://squzy.app/login",
+					StatusCode: 201,
+					Assertions: []*scheduler_config_storage.HTTPAssertion{},
+					Captures: []*scheduler_config_storage.ScenarioCapture{
+						{
+							Variable: "token",
+							Path:     "data.token",
+						},
+					},
+				},
+			},
+		}, ScenarioToDb(&monitoring_api.ScenarioConfig{
+			Steps: []*monitoring_api.ScenarioStep{
+				{
+					Name:       "login",
+					Method:     "POST",
+					URL:        "https://squzy.app/login",
+					StatusCode: 201,
+					Captures: []*monitoring_api.ScenarioCapture{
+						{
+							Variable: "token",
+							Path:     "data.token",
+						},
+					},
+				},
+			},
+		}))
+	})
+}
+
+func TestGrpcTLSConfigToDb(t *testing.T) {
+	t.Run("Should: return nil", func(t *testing.T) {
+		assert.Nil(t, GrpcTLSConfigToDb(nil))
+	})
+	t.Run("Should: convert correct", func(t *testing.T) {
+		assert.EqualValues(t, &scheduler_config_storage.GrpcTLSConfig{
+			CACert:     "ca",
+			ServerName: "squzy.app",
+		}, GrpcTLSConfigToDb(&monitoring_api.GrpcTLSConfig{
+			CACert:     "ca",
+			ServerName: "squzy.app",
+		}))
+	})
+}
+
+func TestGrpcCallConfigToDb(t *testing.T) {
+	t.Run("Should: return nil", func(t *testing.T) {
+		assert.Nil(t, GrpcCallConfigToDb(nil))
+	})
+	t.Run("Should: convert correct", func(t *testing.T) {
+		assert.EqualValues(t, &scheduler_config_storage.GrpcCallConfig{
+			Method:  "grpc.health.v1.Health/Check",
+			Request: `{"service":"squzy"}`,
+			Selectors: []*scheduler_config_storage.Selectors{
+				{
+					Type: apiPb.HttpJsonValueConfig_STRING,
+					Path: "status",
+				},
+			},
+		}, GrpcCallConfigToDb(&monitoring_api.GrpcCallConfig{
+			Method:  "grpc.health.v1.Health/Check",
+			Request: `{"service":"squzy"}`,
+			Selectors: []*monitoring_api.HTTPValueSelector{
+				{
+					HttpJsonValueConfig_Selectors: &apiPb.HttpJsonValueConfig_Selectors{
+						Type: apiPb.HttpJsonValueConfig_STRING,
+						Path: "status",
+					},
+				},
+			},
+		}))
+	})
+}
+
+func TestDatabaseConfigToDb(t *testing.T) {
+	t.Run("Should: return nil", func(t *testing.T) {
+		assert.Nil(t, DatabaseConfigToDb(nil))
+	})
+	t.Run("Should: convert correct", func(t *testing.T) {
+		assert.EqualValues(t, &scheduler_config_storage.DatabaseConfig{
+			Host:     "localhost",
+			Port:     5432,
+			User:     "squzy",
+			Password: "password",
+			Database: "squzy",
+			Query:    "SELECT pg_is_in_recovery()",
+			SSLMode:  "verify-full",
+			Value: &scheduler_config_storage.Selectors{
+				Type: apiPb.HttpJsonValueConfig_BOOL,
+				Rule: &scheduler_config_storage.SelectorRule{
+					Type: monitoring_api.SelectorRuleEquals,
+				},
+			},
+		}, DatabaseConfigToDb(&monitoring_api.DatabaseConfig{
+			Host:     "localhost",
+			Port:     5432,
+			User:     "squzy",
+			Password: "password",
+			Database: "squzy",
+			Query:    "SELECT pg_is_in_recovery()",
+			SSLMode:  "verify-full",
+			Value: &monitoring_api.HTTPValueSelector{
+				HttpJsonValueConfig_Selectors: &apiPb.HttpJsonValueConfig_Selectors{
+					Type: apiPb.HttpJsonValueConfig_BOOL,
+				},
+				Rule: &monitoring_api.SelectorRule{
+					Type: monitoring_api.SelectorRuleEquals,
+				},
+			},
+		}))
+	})
+	t.Run("Should: convert without value", func(t *testing.T) {
+		assert.Nil(t, DatabaseConfigToDb(&monitoring_api.DatabaseConfig{Host: "localhost"}).Value)
+	})
+}
+
+func TestTCPConfigToDb(t *testing.T) {
+	t.Run("Should: return nil", func(t *testing.T) {
+		assert.Nil(t, TCPConfigToDb(nil))
+		assert.Nil(t, TCPConfigToDb(&monitoring_api.TCPConfig{}))
+	})
+	t.Run("Should: convert correct", func(t *testing.T) {
+		assert.EqualValues(t, &scheduler_config_storage.TCPConfig{
+			Host:          "localhost",
+			Port:          25,
+			Send:          "EHLO squzy.app\r\n",
+			ReadTimeoutMs: 500,
+			Expect: &scheduler_config_storage.SocketExpect{
+				Type:  monitoring_api.SocketExpectPrefix,
+				Value: "250",
+			},
+		}, TCPConfigToDb(&monitoring_api.TCPConfig{
+			TcpConfig: &apiPb.TcpConfig{
+				Host: "localhost",
+				Port: 25,
+			},
+			Send:          "EHLO squzy.app\r\n",
+			ReadTimeoutMs: 500,
+			Expect: &monitoring_api.SocketExpect{
+				Type:  monitoring_api.SocketExpectPrefix,
+				Value: "250",
+			},
+		}))
+	})
+}
+
+func TestUDPConfigToDb(t *testing.T) {
+	t.Run("Should: return nil", func(t *testing.T) {
+		assert.Nil(t, UDPConfigToDb(nil))
+	})
+	t.Run("Should: convert correct", func(t *testing.T) {
+		assert.EqualValues(t, &scheduler_config_storage.TCPConfig{
+			Host: "localhost",
+			Port: 514,
+			Send: "<14>squzy",
+		}, UDPConfigToDb(&monitoring_api.UDPConfig{
+			Host: "localhost",
+			Port: 514,
+			Send: "<14>squzy",
+		}))
+	})
+}
+
+func TestWebSocketConfigToDb(t *testing.T) {
+	t.Run("Should: return nil", func(t *testing.T) {
+		assert.Nil(t, WebSocketConfigToDb(nil))
+	})
+	t.Run("Should: convert correct", func(t *testing.T) {
+		assert.EqualValues(t, &scheduler_config_storage.WebSocketConfig{
+			URL:          "wss://squzy.app/ws",
+			Headers:      map[string]string{"Authorization": "Bearer squzy"},
+			Subprotocols: []string{"graphql-ws"},
+			Send:         "ping",
+			Expect: &scheduler_config_storage.SocketExpect{
+				Type:  monitoring_api.SocketExpectExact,
+				Value: "pong",
+			},
+		}, WebSocketConfigToDb(&monitoring_api.WebSocketConfig{
+			URL:          "wss://squzy.app/ws",
+			Headers:      map[string]string{"Authorization": "Bearer squzy"},
+			Subprotocols: []string{"graphql-ws"},
+			Send:         "ping",
+			Expect: &monitoring_api.SocketExpect{
+				Type:  monitoring_api.SocketExpectExact,
+				Value: "pong",
+			},
+		}))
+	})
+}
+
+func TestPrometheusConfigToDb(t *testing.T) {
+	t.Run("Should: return nil", func(t *testing.T) {
+		assert.Nil(t, PrometheusConfigToDb(nil))
+	})
+	t.Run("Should: convert correct", func(t *testing.T) {
+		assert.EqualValues(t, &scheduler_config_storage.PrometheusConfig{
+			URL:     "https://squzy.app/metrics",
+			Headers: map[string]string{"Authorization": "Bearer squzy"},
+			Selectors: []*scheduler_config_storage.MetricSelector{
+				{
+					Name: "queue_size",
+					Labels: []*scheduler_config_storage.MetricLabelMatcher{
+						{Name: "queue", Type: monitoring_api.MetricMatchEqual, Value: "emails"},
+					},
+					Aggregate: monitoring_api.MetricAggregationSum,
+					Rule: &scheduler_config_storage.SelectorRule{
+						Type:   monitoring_api.SelectorRuleLess,
+						Number: 100,
+					},
+				},
+			},
+		}, PrometheusConfigToDb(&monitoring_api.PrometheusConfig{
+			URL:     "https://squzy.app/metrics",
+			Headers: map[string]string{"Authorization": "Bearer squzy"},
+			Selectors: []*monitoring_api.MetricSelector{
+				{
+					Name: "queue_size",
+					Labels: []*monitoring_api.MetricLabelMatcher{
+						{Name: "queue", Type: monitoring_api.MetricMatchEqual, Value: "emails"},
+					},
+					Aggregate: monitoring_api.MetricAggregationSum,
+					Rule: &monitoring_api.SelectorRule{
+						Type:   monitoring_api.SelectorRuleLess,
+						Number: 100,
+					},
+				},
+			},
+		}))
+	})
+}
+
+func TestContentConfigToDb(t *testing.T) {
+	t.Run("Should: return nil", func(t *testing.T) {
+		assert.Nil(t, ContentConfigToDb(nil))
+	})
+	t.Run("Should: convert correct", func(t *testing.T) {
+		assert.EqualValues(t, &scheduler_config_storage.ContentConfig{
+			URL:    "https://squzy.app/terms",
+			Method: "GET",
+			Region: &scheduler_config_storage.ContentRegion{
+				Type: monitoring_api.ContentRegionCSS,
+				Path: "#terms",
+			},
+			Ignore: []string{`\d+`},
+		}, ContentConfigToDb(&monitoring_api.ContentConfig{
+			URL:    "https://squzy.app/terms",
+			Method: "GET",
+			Region: &monitoring_api.ContentRegion{
+				Type: monitoring_api.ContentRegionCSS,
+				Path: "#terms",
+			},
+			Ignore: []string{`\d+`},
+		}))
+	})
+}
+
+func TestCommandConfigToDb(t *testing.T) {
+	t.Run("Should: return nil", func(t *testing.T) {
+		assert.Nil(t, CommandConfigToDb(nil))
+	})
+	t.Run("Should: convert correct", func(t *testing.T) {
+		assert.EqualValues(t, &scheduler_config_storage.CommandConfig{
+			Path:      "/usr/lib/nagios/plugins/check_disk",
+			Args:      []string{"-w", "20%"},
+			Env:       map[string]string{"LANG": "C"},
+			Dir:       "/tmp",
+			ParseJSON: true,
+		}, CommandConfigToDb(&monitoring_api.CommandConfig{
+			Path:      "/usr/lib/nagios/plugins/check_disk",
+			Args:      []string{"-w", "20%"},
+			Env:       map[string]string{"LANG": "C"},
+			Dir:       "/tmp",
+			ParseJSON: true,
+		}))
+	})
+}
+
+func TestRetryPolicyToDb(t *testing.T) {
+	t.Run("Should: return nil", func(t *testing.T) {
+		assert.Nil(t, RetryPolicyToDb(nil))
+	})
+	t.Run("Should: convert correct", func(t *testing.T) {
+		assert.EqualValues(t, &scheduler_config_storage.RetryPolicy{
+			Attempts:          3,
+			BackoffMs:         500,
+			BackoffMultiplier: 2,
+		}, RetryPolicyToDb(&monitoring_api.RetryPolicy{
+			Attempts:          3,
+			BackoffMs:         500,
+			BackoffMultiplier: 2,
+		}))
+	})
+}
+
+func TestFlapPolicyToDb(t *testing.T) {
+	t.Run("Should: return nil", func(t *testing.T) {
+		assert.Nil(t, FlapPolicyToDb(nil))
+	})
+	t.Run("Should: convert correct", func(t *testing.T) {
+		assert.EqualValues(t, &scheduler_config_storage.FlapPolicy{
+			ConfirmAfter: 3,
+			RecoverAfter: 2,
+		}, FlapPolicyToDb(&monitoring_api.FlapPolicy{
+			ConfirmAfter: 3,
+			RecoverAfter: 2,
+		}))
+	})
+}
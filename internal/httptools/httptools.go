@@ -1,18 +1,33 @@
 package httptools
 
 import (
+	"bytes"
+	"container/list"
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
 	"squzy/internal/helpers"
+	"sync"
 	"time"
 )
 
 type httpTool struct {
 	userAgent string
 	client    *http.Client
+	clients   map[ClientConfig]*list.Element
+	lru       *list.List
+	mutex     sync.Mutex
+}
+
+type clientItem struct {
+	config ClientConfig
+	client *http.Client
 }
 
 const (
@@ -22,6 +37,14 @@ const (
 	userAgentPrefix                 = "Squzy_monitoring"
 	logHeader                       = "Squzy_scheduler_id"
 	userAgentHeaderKey              = "User-Agent"
+	contentTypeHeaderKey            = "Content-Type"
+	defaultMaxRedirects       int32 = 10
+	// Client is created for every distinct ClientConfig, usually one per scheduler with client settings.
+	// 100 covers such schedulers of most installations, when there are more of them least recently used
+	// client is evicted and only costs new connections on its next request
+	maxClients = 100
+	// Connections of closed client which were in use are closed after that timeout
+	idleConnTimeout = 90 * time.Second
 )
 
 var (
@@ -36,14 +59,62 @@ var (
 			helpers.GetPortByURL(url),
 		)
 	}
-	defaultTimeout = helpers.DurationFromSecond(RequestTimeout)
+	defaultTimeout     = helpers.DurationFromSecond(RequestTimeout)
+	errInvalidCACert   = errors.New("INVALID_CA_CERTIFICATE")
+	tooManyRedirectsFn = func(maxRedirects int32) error {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
 )
 
+type timingsKey struct{}
+
+type clientConfigKey struct{}
+
+type maxBodySizeKey struct{}
+
+// Transport and redirect settings of scheduler, requests with equal settings share connections
+type ClientConfig struct {
+	InsecureSkipVerify bool
+	// PEM encoded certificates which are trusted in addition to system certificates
+	CACert string
+	// PEM encoded certificate and key which are sent for mutual tls
+	ClientCert string
+	ClientKey  string
+	// Overrides name which is sent as SNI and verified against certificate
+	ServerName string
+	// Proxy from environment is used when empty
+	ProxyURL         string
+	DisableRedirects bool
+	// 10 redirects are followed when not set
+	MaxRedirects int32
+}
+
+// Durations of request phases, phases of redirected requests are summed
+type Timings struct {
+	DNSLookup        time.Duration
+	TCPConnect       time.Duration
+	TLSHandshake     time.Duration
+	TimeToFirstByte  time.Duration
+	ContentTransfer  time.Duration
+	Total            time.Duration
+	ReusedConnection bool
+
+	mutex         sync.Mutex
+	start         time.Time
+	dnsStart      time.Time
+	connectStart  time.Time
+	tlsStart      time.Time
+	wroteRequest  time.Time
+	firstResponse time.Time
+}
+
 type HTTPTool interface {
 	SendRequest(req *http.Request) (int, []byte, error)
 	SendRequestTimeout(req *http.Request, timeout time.Duration) (int, []byte, error)
 	SendRequestWithStatusCode(req *http.Request, expectedCode int) (int, []byte, error)
 	SendRequestTimeoutStatusCode(req *http.Request, timeout time.Duration, expectedCode int) (int, []byte, error)
+	SendRequestTimeoutStatusCodeWithHeaders(req *http.Request, timeout time.Duration, expectedCode int) (int, http.Header, []byte, error)
+	SendRequestTimeoutWithHeaders(req *http.Request, timeout time.Duration) (int, http.Header, []byte, error)
 	CreateRequest(method string, url string, headers *map[string]string, schedulerID string) *http.Request
 }
 
@@ -67,12 +138,122 @@ func (h *httpTool) CreateRequest(method string, url string, headers *map[string]
 	return req
 }
 
+// Sets body of request which was created without it, so request can be retried and redirected
+func SetBody(req *http.Request, contentType string, body []byte) {
+	req.ContentLength = int64(len(body))
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	if contentType != "" {
+		req.Header.Set(contentTypeHeaderKey, contentType)
+	}
+}
+
+// Returns request which collects timings of request phases while it is sent
+func WithTimings(req *http.Request) (*http.Request, *Timings) {
+	timings := &Timings{}
+	return req.WithContext(context.WithValue(req.Context(), timingsKey{}, timings)), timings
+}
+
+// Returns request which will be sent by client with settings of scheduler
+func WithClientConfig(req *http.Request, config *ClientConfig) *http.Request {
+	if config == nil {
+		return req
+	}
+	return req.WithContext(context.WithValue(req.Context(), clientConfigKey{}, *config))
+}
+
+// Returns request which body is read up to max+1 bytes, so bigger body is detected without reading all of it
+func WithMaxBodySize(req *http.Request, max int64) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), maxBodySizeKey{}, max))
+}
+
+func timingsFromRequest(req *http.Request) *Timings {
+	timings, _ := req.Context().Value(timingsKey{}).(*Timings)
+	return timings
+}
+
+func (t *Timings) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.dnsStart = time.Now()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.DNSLookup += time.Since(t.dnsStart)
+		},
+		// Connect hooks can be called concurrently when host has several addresses, first connection is counted
+		ConnectStart: func(string, string) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			if t.connectStart.IsZero() {
+				t.connectStart = time.Now()
+			}
+		},
+		ConnectDone: func(string, string, error) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			if !t.connectStart.IsZero() {
+				t.TCPConnect += time.Since(t.connectStart)
+				t.connectStart = time.Time{}
+			}
+		},
+		TLSHandshakeStart: func() {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.TLSHandshake += time.Since(t.tlsStart)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.ReusedConnection = info.Reused
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.wroteRequest = time.Now()
+		},
+		GotFirstResponseByte: func() {
+			t.mutex.Lock()
+			defer t.mutex.Unlock()
+			t.firstResponse = time.Now()
+			t.TimeToFirstByte += t.firstResponse.Sub(t.wroteRequest)
+		},
+	}
+}
+
+func (t *Timings) begin(req *http.Request) *http.Request {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.start = time.Now()
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), t.trace()))
+}
+
+func (t *Timings) finish() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	now := time.Now()
+	if !t.firstResponse.IsZero() {
+		t.ContentTransfer = now.Sub(t.firstResponse)
+	}
+	t.Total = now.Sub(t.start)
+}
+
 func (h *httpTool) SendRequest(req *http.Request) (int, []byte, error) {
-	return sendReq(h.client, req, false, 0)
+	return h.sendRequestTimeout(req, 0, false, 0)
 }
 
 func (h *httpTool) SendRequestWithStatusCode(req *http.Request, expectedCode int) (int, []byte, error) {
-	return sendReq(h.client, req, true, expectedCode)
+	return h.sendRequestTimeout(req, 0, true, expectedCode)
 }
 
 func (h *httpTool) SendRequestTimeout(req *http.Request, timeout time.Duration) (int, []byte, error) {
@@ -83,42 +264,137 @@ func (h *httpTool) SendRequestTimeoutStatusCode(req *http.Request, timeout time.
 	return h.sendRequestTimeout(req, timeout, true, expectedCode)
 }
 
+func (h *httpTool) SendRequestTimeoutStatusCodeWithHeaders(req *http.Request, timeout time.Duration, expectedCode int) (int, http.Header, []byte, error) {
+	return h.sendRequestTimeoutWithHeaders(req, timeout, true, expectedCode)
+}
+
+func (h *httpTool) SendRequestTimeoutWithHeaders(req *http.Request, timeout time.Duration) (int, http.Header, []byte, error) {
+	return h.sendRequestTimeoutWithHeaders(req, timeout, false, 0)
+}
+
 func (h *httpTool) sendRequestTimeout(req *http.Request, timeout time.Duration, checkCode bool, code int) (int, []byte, error) {
-	// If timeout not present will be use method with custom http client
+	statusCode, _, data, err := h.sendRequestTimeoutWithHeaders(req, timeout, checkCode, code)
+	return statusCode, data, err
+}
+
+func (h *httpTool) sendRequestTimeoutWithHeaders(req *http.Request, timeout time.Duration, checkCode bool, code int) (int, http.Header, []byte, error) {
+	client, err := h.getClient(req)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	// If timeout not present will be used default timeout
 	if timeout.Seconds() <= 0 {
-		return sendReq(h.client, req, checkCode, code)
+		timeout = defaultTimeout
 	}
-	ctx, cancel := helpers.TimeoutContext(context.Background(), timeout)
+	ctx, cancel := helpers.TimeoutContext(req.Context(), timeout)
 	defer cancel()
 	reqTimeout := req.WithContext(ctx)
-	return sendReq(http.DefaultClient, reqTimeout, checkCode, code)
+	return sendReqWithHeaders(client, reqTimeout, checkCode, code)
+}
+
+// Returns shared client when request has not settings of scheduler.
+// Clients are kept in lru by their settings, least recently used one is removed when there are more than maxClients
+// and its idle connections are closed
+func (h *httpTool) getClient(req *http.Request) (*http.Client, error) {
+	config, ok := req.Context().Value(clientConfigKey{}).(ClientConfig)
+	if !ok {
+		return h.client, nil
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if element, exist := h.clients[config]; exist {
+		h.lru.MoveToFront(element)
+		return element.Value.(*clientItem).client, nil
+	}
+	client, err := newClient(&config)
+	if err != nil {
+		return nil, err
+	}
+	h.clients[config] = h.lru.PushFront(&clientItem{config: config, client: client})
+	for h.lru.Len() > maxClients {
+		oldest := h.lru.Back()
+		h.lru.Remove(oldest)
+		item := oldest.Value.(*clientItem)
+		delete(h.clients, item.config)
+		item.client.CloseIdleConnections()
+	}
+	return client, nil
+}
+
+func newClient(config *ClientConfig) (*http.Client, error) {
+	tlsConfig, err := helpers.NewTLSConfig(config.InsecureSkipVerify, config.CACert, config.ClientCert, config.ClientKey, config.ServerName)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if config.ProxyURL != "" {
+		proxyURL, err := url.Parse(config.ProxyURL)
+		if err != nil {
+			return nil, err
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	maxRedirects := config.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:               proxy,
+			TLSClientConfig:     tlsConfig,
+			MaxIdleConnsPerHost: MaxIdleConnectionsPerHost,
+			MaxIdleConns:        MaxIdleConnections,
+			IdleConnTimeout:     idleConnTimeout,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if config.DisableRedirects {
+				return http.ErrUseLastResponse
+			}
+			if int32(len(via)) >= maxRedirects {
+				return tooManyRedirectsFn(maxRedirects)
+			}
+			return nil
+		},
+	}, nil
 }
 
-func sendReq(client *http.Client, req *http.Request, checkCode bool, statusCode int) (int, []byte, error) {
+func sendReqWithHeaders(client *http.Client, req *http.Request, checkCode bool, statusCode int) (int, http.Header, []byte, error) {
+	if timings := timingsFromRequest(req); timings != nil {
+		req = timings.begin(req)
+		defer timings.finish()
+	}
+
 	resp, err := client.Do(req)
 
 	if err != nil {
-		return 0, nil, err
+		return 0, nil, nil, err
 	}
 
 	if resp != nil {
 		defer resp.Body.Close()
 	}
 
-	data, err := ioutil.ReadAll(resp.Body)
+	var body io.Reader = resp.Body
+	if max, ok := req.Context().Value(maxBodySizeKey{}).(int64); ok {
+		body = io.LimitReader(resp.Body, max+1)
+	}
+	data, err := ioutil.ReadAll(body)
 
 	if err != nil {
-		return resp.StatusCode, nil, err
+		return resp.StatusCode, resp.Header, nil, err
 	}
 
 	if checkCode {
 		if statusCode != resp.StatusCode {
-			return resp.StatusCode, nil, notExpectedStatusCodeFn(req.URL.String(), resp.StatusCode, statusCode)
+			return resp.StatusCode, resp.Header, nil, notExpectedStatusCodeFn(req.URL.String(), resp.StatusCode, statusCode)
 		}
-		return resp.StatusCode, data, nil
+		return resp.StatusCode, resp.Header, data, nil
 	}
 
-	return resp.StatusCode, data, nil
+	return resp.StatusCode, resp.Header, data, nil
 }
 
 func getUserAgent(version string) string {
@@ -128,12 +404,15 @@ func getUserAgent(version string) string {
 func New(userAgentVersion string) HTTPTool {
 	return &httpTool{
 		userAgent: getUserAgent(userAgentVersion),
+		// Timeout is set by context of request
 		client: &http.Client{
 			Transport: &http.Transport{
+				Proxy:               http.ProxyFromEnvironment,
 				MaxIdleConnsPerHost: MaxIdleConnectionsPerHost,
 				MaxIdleConns:        MaxIdleConnections,
 			},
-			Timeout: defaultTimeout,
 		},
+		clients: make(map[ClientConfig]*list.Element),
+		lru:     list.New(),
 	}
 }
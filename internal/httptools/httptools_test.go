@@ -1,11 +1,21 @@
 package httptools
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"github.com/stretchr/testify/assert"
 	"io"
+	"io/ioutil"
 	"math"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"squzy/internal/helpers"
 	"strings"
 	"testing"
 	"time"
@@ -17,6 +27,14 @@ func TestNew(t *testing.T) {
 		assert.IsType(t, &httpTool{}, j)
 		assert.NotEqual(t, nil, j)
 	})
+	t.Run("Test: Should use proxy from environment", func(t *testing.T) {
+		j := New("")
+		transport := j.(*httpTool).client.Transport.(*http.Transport)
+		assert.NotNil(t, transport.Proxy)
+		client, err := newClient(&ClientConfig{})
+		assert.Nil(t, err)
+		assert.NotNil(t, client.Transport.(*http.Transport).Proxy)
+	})
 }
 
 func newRequest(method string, url string, body io.Reader) *http.Request {
@@ -208,3 +226,285 @@ func TestHttpTool_SendRequestTimeoutStatusCode(t *testing.T) {
 		assert.Equal(t, []uint8([]byte(nil)), body)
 	})
 }
+
+func TestHttpTool_SendRequestTimeoutStatusCodeWithHeaders(t *testing.T) {
+	t.Run("Test: Should return headers and body", func(t *testing.T) {
+		bytes := []byte("Hello, client")
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Custom", "yes")
+			w.WriteHeader(200)
+			_, _ = w.Write(bytes)
+		}))
+		defer ts.Close()
+		j := New("")
+		req := newRequest(http.MethodGet, ts.URL, nil)
+		code, headers, body, err := j.SendRequestTimeoutStatusCodeWithHeaders(req, time.Second*2, http.StatusOK)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "yes", headers.Get("X-Custom"))
+		assert.Equal(t, bytes, body)
+	})
+	t.Run("Test: Should return error because not expected status code", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer ts.Close()
+		j := New("")
+		req := newRequest(http.MethodGet, ts.URL, nil)
+		code, _, body, err := j.SendRequestTimeoutStatusCodeWithHeaders(req, 0, http.StatusOK)
+		assert.NotEqual(t, nil, err)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, []uint8([]byte(nil)), body)
+	})
+}
+
+func TestHttpTool_SendRequestTimeoutWithHeaders(t *testing.T) {
+	t.Run("Test: Should return headers and body without checking status code", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("Not found"))
+		}))
+		defer ts.Close()
+		j := New("")
+		code, headers, body, err := j.SendRequestTimeoutWithHeaders(newRequest(http.MethodGet, ts.URL, nil), time.Second)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, http.StatusNotFound, code)
+		assert.Equal(t, "text/html", headers.Get("Content-Type"))
+		assert.Equal(t, []byte("Not found"), body)
+	})
+}
+
+func TestSetBody(t *testing.T) {
+	t.Run("Test: Should send body with content type", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			if r.Header.Get("Content-Type") != "application/json" || string(body) != `{"query":"{ health }"}` {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer ts.Close()
+		j := New("")
+		req := j.CreateRequest(http.MethodPost, ts.URL, nil, "")
+		SetBody(req, "application/json", []byte(`{"query":"{ health }"}`))
+		code, _, err := j.SendRequestTimeout(req, time.Second)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, http.StatusOK, code)
+	})
+	t.Run("Test: Should keep content type", func(t *testing.T) {
+		req := newRequest(http.MethodPost, "http://localhost", nil)
+		req.Header.Set("Content-Type", "text/plain")
+		SetBody(req, "", []byte("body"))
+		assert.Equal(t, "text/plain", req.Header.Get("Content-Type"))
+		assert.Equal(t, int64(4), req.ContentLength)
+		body, err := req.GetBody()
+		assert.Equal(t, nil, err)
+		data, _ := ioutil.ReadAll(body)
+		assert.Equal(t, "body", string(data))
+	})
+}
+
+func TestWithMaxBodySize(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("0123456789"))
+	}))
+	defer ts.Close()
+	t.Run("Test: Should read body up to max and one byte", func(t *testing.T) {
+		_, data, err := New("").SendRequest(WithMaxBodySize(newRequest(http.MethodGet, ts.URL, nil), 4))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "01234", string(data))
+	})
+	t.Run("Test: Should read whole body which is less than max", func(t *testing.T) {
+		_, data, err := New("").SendRequest(WithMaxBodySize(newRequest(http.MethodGet, ts.URL, nil), 10))
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "0123456789", string(data))
+	})
+}
+
+func TestWithTimings(t *testing.T) {
+	t.Run("Test: Should collect timings of request phases", func(t *testing.T) {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(time.Millisecond * 50)
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("Hello, client"))
+		}))
+		defer ts.Close()
+		j := New("")
+		req, timings := WithTimings(newRequest(http.MethodGet, ts.URL, nil))
+		_, _, _, err := j.SendRequestTimeoutStatusCodeWithHeaders(req, time.Second, http.StatusOK)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, false, timings.ReusedConnection)
+		assert.NotEqual(t, time.Duration(0), timings.TCPConnect)
+		assert.Equal(t, time.Duration(0), timings.TLSHandshake)
+		assert.True(t, timings.TimeToFirstByte >= time.Millisecond*50)
+		assert.True(t, timings.Total >= timings.TimeToFirstByte+timings.TCPConnect)
+	})
+	t.Run("Test: Should collect tls handshake", func(t *testing.T) {
+		ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer ts.Close()
+		req, timings := WithTimings(newRequest(http.MethodGet, ts.URL, nil))
+		code, _, _, err := sendReqWithHeaders(ts.Client(), req, true, http.StatusOK)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, http.StatusOK, code)
+		assert.NotEqual(t, time.Duration(0), timings.TLSHandshake)
+	})
+	t.Run("Test: Should collect total time when request failed", func(t *testing.T) {
+		req, timings := WithTimings(newRequest(http.MethodGet, "http://127.0.0.1:1", nil))
+		_, _, err := New("").SendRequest(req)
+		assert.NotEqual(t, nil, err)
+		assert.NotEqual(t, time.Duration(0), timings.Total)
+		assert.Equal(t, time.Duration(0), timings.TimeToFirstByte)
+	})
+	t.Run("Test: Should not trace request without timings", func(t *testing.T) {
+		req := newRequest(http.MethodGet, "http://localhost", nil)
+		assert.Nil(t, timingsFromRequest(req))
+	})
+}
+
+// Generates self signed certificate and key in PEM
+func selfSignedCert(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Equal(t, nil, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "squzy"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Equal(t, nil, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Equal(t, nil, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+}
+
+func TestWithClientConfig(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	serverCA := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw}))
+	send := func(j HTTPTool, rq *http.Request, config *ClientConfig) (int, error) {
+		code, _, err := j.SendRequestTimeout(WithClientConfig(rq, config), time.Second)
+		return code, err
+	}
+
+	t.Run("Test: Should return error because certificate is not trusted", func(t *testing.T) {
+		_, err := send(New(""), newRequest(http.MethodGet, ts.URL, nil), nil)
+		assert.NotEqual(t, nil, err)
+	})
+	t.Run("Test: Should trust custom ca", func(t *testing.T) {
+		code, err := send(New(""), newRequest(http.MethodGet, ts.URL, nil), &ClientConfig{CACert: serverCA})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, http.StatusOK, code)
+	})
+	t.Run("Test: Should skip verification", func(t *testing.T) {
+		code, err := send(New(""), newRequest(http.MethodGet, ts.URL, nil), &ClientConfig{InsecureSkipVerify: true})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, http.StatusOK, code)
+	})
+	t.Run("Test: Should verify certificate by server name", func(t *testing.T) {
+		_, err := send(New(""), newRequest(http.MethodGet, ts.URL, nil), &ClientConfig{CACert: serverCA, ServerName: "example.com"})
+		assert.Equal(t, nil, err)
+		_, err = send(New(""), newRequest(http.MethodGet, ts.URL, nil), &ClientConfig{CACert: serverCA, ServerName: "squzy.app"})
+		assert.NotEqual(t, nil, err)
+	})
+	t.Run("Test: Should return error because ca is invalid", func(t *testing.T) {
+		_, err := send(New(""), newRequest(http.MethodGet, ts.URL, nil), &ClientConfig{CACert: "ca"})
+		assert.Equal(t, helpers.ErrInvalidCACert, err)
+	})
+	t.Run("Test: Should return error because client certificate is invalid", func(t *testing.T) {
+		_, err := send(New(""), newRequest(http.MethodGet, ts.URL, nil), &ClientConfig{ClientCert: "cert", ClientKey: "key"})
+		assert.NotEqual(t, nil, err)
+	})
+	t.Run("Test: Should return error because proxy url is invalid", func(t *testing.T) {
+		_, err := send(New(""), newRequest(http.MethodGet, ts.URL, nil), &ClientConfig{ProxyURL: "://proxy"})
+		assert.NotEqual(t, nil, err)
+	})
+	t.Run("Test: Should reuse client with the same config", func(t *testing.T) {
+		j := New("")
+		_, _ = send(j, newRequest(http.MethodGet, ts.URL, nil), &ClientConfig{InsecureSkipVerify: true})
+		_, _ = send(j, newRequest(http.MethodGet, ts.URL, nil), &ClientConfig{InsecureSkipVerify: true})
+		_, _ = send(j, newRequest(http.MethodGet, ts.URL, nil), &ClientConfig{CACert: serverCA})
+		assert.Equal(t, 2, len(j.(*httpTool).clients))
+	})
+	t.Run("Test: Should remove least recently used client", func(t *testing.T) {
+		j := New("")
+		_, _ = send(j, newRequest(http.MethodGet, ts.URL, nil), &ClientConfig{InsecureSkipVerify: true})
+		for i := 0; i < maxClients; i++ {
+			_, _ = send(j, newRequest(http.MethodGet, ts.URL, nil), &ClientConfig{InsecureSkipVerify: true, MaxRedirects: int32(i + 1)})
+		}
+		tool := j.(*httpTool)
+		assert.Equal(t, maxClients, len(tool.clients))
+		assert.Equal(t, maxClients, tool.lru.Len())
+		_, exist := tool.clients[ClientConfig{InsecureSkipVerify: true}]
+		assert.False(t, exist)
+	})
+	t.Run("Test: Should send client certificate", func(t *testing.T) {
+		clientCert, clientKey := selfSignedCert(t)
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM([]byte(clientCert))
+		mtls := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		mtls.TLS = &tls.Config{
+			ClientAuth: tls.RequireAndVerifyClientCert,
+			ClientCAs:  pool,
+		}
+		mtls.StartTLS()
+		defer mtls.Close()
+		_, err := send(New(""), newRequest(http.MethodGet, mtls.URL, nil), &ClientConfig{InsecureSkipVerify: true})
+		assert.NotEqual(t, nil, err)
+		code, err := send(New(""), newRequest(http.MethodGet, mtls.URL, nil), &ClientConfig{
+			InsecureSkipVerify: true,
+			ClientCert:         clientCert,
+			ClientKey:          clientKey,
+		})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, http.StatusOK, code)
+	})
+	t.Run("Test: Should send request through proxy", func(t *testing.T) {
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Host != "squzy.invalid" {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer proxy.Close()
+		code, err := send(New(""), newRequest(http.MethodGet, "http://squzy.invalid/", nil), &ClientConfig{ProxyURL: proxy.URL})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, http.StatusAccepted, code)
+	})
+	t.Run("Test: Should follow redirects", func(t *testing.T) {
+		redirects := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/first":
+				http.Redirect(w, r, "/second", http.StatusFound)
+			case "/second":
+				http.Redirect(w, r, "/ok", http.StatusFound)
+			default:
+				w.WriteHeader(http.StatusOK)
+			}
+		}))
+		defer redirects.Close()
+		code, err := send(New(""), newRequest(http.MethodGet, redirects.URL+"/first", nil), &ClientConfig{})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, http.StatusOK, code)
+		code, err = send(New(""), newRequest(http.MethodGet, redirects.URL+"/first", nil), &ClientConfig{DisableRedirects: true})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, http.StatusFound, code)
+		_, err = send(New(""), newRequest(http.MethodGet, redirects.URL+"/first", nil), &ClientConfig{MaxRedirects: 1})
+		assert.NotEqual(t, nil, err)
+	})
+}
@@ -2,15 +2,19 @@ package job_executor
 
 import (
 	"context"
+	"github.com/golang/protobuf/ptypes"
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"google.golang.org/grpc"
 	"squzy/internal/httptools"
 	"squzy/internal/job"
+	monitoring_api "squzy/internal/monitoring-api"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
 	"squzy/internal/semaphore"
 	sitemap_storage "squzy/internal/sitemap-storage"
 	"squzy/internal/storage"
+	"sync"
+	"time"
 )
 
 type HTTPExecutor func(schedulerId string,
@@ -38,6 +42,63 @@ type HTTPValueExecutor func(
 	config *scheduler_config_storage.HTTPValueConfig,
 	httpTool httptools.HTTPTool) job.CheckError
 
+type TLSCertExecutor func(
+	schedulerId string,
+	timeout int32,
+	config *scheduler_config_storage.TLSCertConfig) job.CheckError
+
+type DNSExecutor func(
+	schedulerId string,
+	timeout int32,
+	config *scheduler_config_storage.DNSConfig) job.CheckError
+
+type ScenarioExecutor func(
+	schedulerId string,
+	timeout int32,
+	config *scheduler_config_storage.ScenarioConfig,
+	httpTool httptools.HTTPTool) job.CheckError
+
+type CrawlerExecutor func(
+	schedulerId string,
+	timeout int32,
+	config *scheduler_config_storage.CrawlerConfig,
+	httpTools httptools.HTTPTool,
+	semaphoreFactoryFn func(n int) semaphore.Semaphore) job.CheckError
+
+type DatabaseExecutor func(
+	schedulerId string,
+	timeout int32,
+	schedulerType apiPb.SchedulerType,
+	config *scheduler_config_storage.DatabaseConfig) job.CheckError
+
+type UDPExecutor func(
+	schedulerId string,
+	timeout int32,
+	config *scheduler_config_storage.TCPConfig) job.CheckError
+
+type WebSocketExecutor func(
+	schedulerId string,
+	timeout int32,
+	config *scheduler_config_storage.WebSocketConfig) job.CheckError
+
+type PrometheusExecutor func(
+	schedulerId string,
+	timeout int32,
+	config *scheduler_config_storage.PrometheusConfig,
+	httpTool httptools.HTTPTool) job.CheckError
+
+type ContentExecutor func(
+	schedulerId string,
+	timeout int32,
+	config *scheduler_config_storage.ContentConfig,
+	httpTool httptools.HTTPTool,
+	configStorage scheduler_config_storage.Storage) job.CheckError
+
+type CommandExecutor func(
+	schedulerId string,
+	timeout int32,
+	config *scheduler_config_storage.CommandConfig) job.CheckError
+
 type executor struct {
 	externalStorage    storage.Storage
 	siteMapStorage     sitemap_storage.SiteMapStorage
@@ -49,6 +110,23 @@ type executor struct {
 	execHTTP           HTTPExecutor
 	execSiteMap        SiteMapExecutor
 	execHTTPValue      HTTPValueExecutor
+	execTLSCert        TLSCertExecutor
+	execDNS            DNSExecutor
+	execScenario       ScenarioExecutor
+	execCrawler        CrawlerExecutor
+	execDatabase       DatabaseExecutor
+	execUDP            UDPExecutor
+	execWebSocket      WebSocketExecutor
+	execPrometheus     PrometheusExecutor
+	execContent        ContentExecutor
+	execCommand        CommandExecutor
+	sleep              func(d time.Duration)
+	flapMutex          sync.Mutex
+	flapStates         map[string]*flapState
+	runMutex           sync.Mutex
+	runs               map[primitive.ObjectID]*runState
+	workerPool         WorkerPool
+	hostLimiter        HostLimiter
 }
 
 func (e *executor) Execute(schedulerID primitive.ObjectID) {
@@ -58,29 +136,145 @@ func (e *executor) Execute(schedulerID primitive.ObjectID) {
 		return
 	}
 	id := schedulerID.Hex()
+	var check func() job.CheckError
 	switch config.Type {
 	case apiPb.SchedulerType_TCP:
-		_ = e.externalStorage.Write(e.execTCP(id, config.Timeout, config.TCPConfig))
-		// @TODO logger
+		check = func() job.CheckError {
+			return e.execTCP(id, config.Timeout, config.TCPConfig)
+		}
 	case apiPb.SchedulerType_GRPC:
-		_ = e.externalStorage.Write(e.execGrpc(id, config.Timeout, config.GrpcConfig, grpc.WithInsecure()))
-		// @TODO logger
+		check = func() job.CheckError {
+			return e.execGrpc(id, config.Timeout, config.GrpcConfig)
+		}
 	case apiPb.SchedulerType_HTTP:
-		_ = e.externalStorage.Write(e.execHTTP(id, config.Timeout, config.HTTPConfig, e.httpTool))
-		// @TODO logger
+		check = func() job.CheckError {
+			return e.execHTTP(id, config.Timeout, config.HTTPConfig, e.httpTool)
+		}
 	case apiPb.SchedulerType_SITE_MAP:
-		_ = e.externalStorage.Write(e.execSiteMap(id, config.Timeout, config.SiteMapConfig, e.siteMapStorage, e.httpTool, e.semaphoreFactoryFn))
-		// @TODO logger
+		check = func() job.CheckError {
+			return e.execSiteMap(id, config.Timeout, config.SiteMapConfig, e.siteMapStorage, e.httpTool, e.semaphoreFactoryFn)
+		}
 	case apiPb.SchedulerType_HTTP_JSON_VALUE:
-		_ = e.externalStorage.Write(e.execHTTPValue(id, config.Timeout, config.HTTPValueConfig, e.httpTool))
-		// @TODO logger
+		check = func() job.CheckError {
+			return e.execHTTPValue(id, config.Timeout, config.HTTPValueConfig, e.httpTool)
+		}
+	case monitoring_api.SchedulerTypeTLSCert:
+		check = func() job.CheckError {
+			return e.execTLSCert(id, config.Timeout, config.TLSCertConfig)
+		}
+	case monitoring_api.SchedulerTypeDNS:
+		check = func() job.CheckError {
+			return e.execDNS(id, config.Timeout, config.DNSConfig)
+		}
+	case monitoring_api.SchedulerTypeScenario:
+		check = func() job.CheckError {
+			return e.execScenario(id, config.Timeout, config.ScenarioConfig, e.httpTool)
+		}
+	case monitoring_api.SchedulerTypeCrawler:
+		check = func() job.CheckError {
+			return e.execCrawler(id, config.Timeout, config.CrawlerConfig, e.httpTool, e.semaphoreFactoryFn)
+		}
+	case monitoring_api.SchedulerTypePostgres, monitoring_api.SchedulerTypeMySQL, monitoring_api.SchedulerTypeRedis, monitoring_api.SchedulerTypeMongo:
+		check = func() job.CheckError {
+			return e.execDatabase(id, config.Timeout, config.Type, config.DatabaseConfig)
+		}
+	case monitoring_api.SchedulerTypeUDP:
+		check = func() job.CheckError {
+			return e.execUDP(id, config.Timeout, config.TCPConfig)
+		}
+	case monitoring_api.SchedulerTypeWebSocket:
+		check = func() job.CheckError {
+			return e.execWebSocket(id, config.Timeout, config.WebSocketConfig)
+		}
+	case monitoring_api.SchedulerTypePrometheus:
+		check = func() job.CheckError {
+			return e.execPrometheus(id, config.Timeout, config.PrometheusConfig, e.httpTool)
+		}
+	case monitoring_api.SchedulerTypeContent:
+		check = func() job.CheckError {
+			return e.execContent(id, config.Timeout, config.ContentConfig, e.httpTool, e.configStorage)
+		}
+	case monitoring_api.SchedulerTypeCommand:
+		check = func() job.CheckError {
+			return e.execCommand(id, config.Timeout, config.CommandConfig)
+		}
 	default:
 		// @TODO log incorrect type
+		return
+	}
+	run := func() {
+		ctx := context.Background()
+		attempt := check
+		// Deadline is shared by all attempts of execution
+		if config.Deadline > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(config.Deadline)*time.Second)
+			defer cancel()
+			attempt = withDeadline(ctx, id, config.Type, attempt)
+		}
+		// Waiting for rate limit of host does not hold worker
+		if e.workerPool != nil {
+			attempt = withWorker(e.workerPool, attempt)
+		}
+		if e.hostLimiter != nil {
+			attempt = withHostLimit(e.hostLimiter, targetHost(config), attempt)
+		}
+		_ = e.externalStorage.Write(e.withPolicy(ctx, id, config, attempt))
+		// @TODO logger
+	}
+	if config.Overlap == monitoring_api.OverlapAllowParallel {
+		run()
+		return
+	}
+	e.runExclusive(schedulerID, config.Overlap, run, func() {
+		// Without policy tick is dropped silently, as ticker drops ticks of busy scheduler
+		if config.Overlap == "" {
+			return
+		}
+		_ = e.externalStorage.Write(newExecutionResult(id, config.Type, monitoring_api.SchedulerCodeSkipped, errTickSkipped, ptypes.TimestampNow()))
+		// @TODO logger
+	})
+}
+
+func (e *executor) Reset(schedulerID primitive.ObjectID) {
+	e.flapMutex.Lock()
+	delete(e.flapStates, schedulerID.Hex())
+	e.flapMutex.Unlock()
+	// Running execution is finished with previous config, but its queued tick is dropped
+	e.runMutex.Lock()
+	if state, ok := e.runs[schedulerID]; ok {
+		state.queued = false
+		delete(e.runs, schedulerID)
+	}
+	e.runMutex.Unlock()
+}
+
+func (e *executor) GetDiagnostics() *monitoring_api.Diagnostics {
+	diagnostics := &monitoring_api.Diagnostics{}
+	if e.workerPool != nil {
+		diagnostics.QueueDepth = e.workerPool.GetQueueDepth()
+		diagnostics.RunningJobs = e.workerPool.GetRunning()
+		diagnostics.MaxConcurrentJobs = e.workerPool.GetSize()
+	}
+	if e.hostLimiter != nil {
+		hosts := e.hostLimiter.GetWaiting()
+		for _, count := range hosts {
+			diagnostics.RateLimitedJobs += count
+		}
+		if len(hosts) > 0 {
+			diagnostics.RateLimitedHosts = hosts
+		}
 	}
+	return diagnostics
 }
 
 type JobExecutor interface {
+	// Can be called while previous execution of scheduler is running, overlap policy of scheduler is applied
 	Execute(schedulerID primitive.ObjectID)
+	// Forgets state of scheduler which is kept between executions, called when scheduler is removed or its config is replaced
+	Reset(schedulerID primitive.ObjectID)
+	// Returns current load of executor
+	GetDiagnostics() *monitoring_api.Diagnostics
 }
 
 func NewExecutor(
@@ -94,6 +288,18 @@ func NewExecutor(
 	execHTTP HTTPExecutor,
 	execSiteMap SiteMapExecutor,
 	execHTTPValue HTTPValueExecutor,
+	execTLSCert TLSCertExecutor,
+	execDNS DNSExecutor,
+	execScenario ScenarioExecutor,
+	execCrawler CrawlerExecutor,
+	execDatabase DatabaseExecutor,
+	execUDP UDPExecutor,
+	execWebSocket WebSocketExecutor,
+	execPrometheus PrometheusExecutor,
+	execContent ContentExecutor,
+	execCommand CommandExecutor,
+	workerPool WorkerPool,
+	hostLimiter HostLimiter,
 ) JobExecutor {
 	return &executor{
 		externalStorage:    externalStorage,
@@ -106,5 +312,20 @@ func NewExecutor(
 		execHTTP:           execHTTP,
 		execSiteMap:        execSiteMap,
 		execHTTPValue:      execHTTPValue,
+		execTLSCert:        execTLSCert,
+		execDNS:            execDNS,
+		execScenario:       execScenario,
+		execCrawler:        execCrawler,
+		execDatabase:       execDatabase,
+		execUDP:            execUDP,
+		execWebSocket:      execWebSocket,
+		execPrometheus:     execPrometheus,
+		execContent:        execContent,
+		execCommand:        execCommand,
+		sleep:              time.Sleep,
+		flapStates:         map[string]*flapState{},
+		runs:               map[primitive.ObjectID]*runState{},
+		workerPool:         workerPool,
+		hostLimiter:        hostLimiter,
 	}
 }
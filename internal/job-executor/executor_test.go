@@ -9,6 +9,7 @@ import (
 	"google.golang.org/grpc"
 	"squzy/internal/httptools"
 	"squzy/internal/job"
+	monitoring_api "squzy/internal/monitoring-api"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
 	"squzy/internal/semaphore"
 	sitemap_storage "squzy/internal/sitemap-storage"
@@ -49,6 +50,10 @@ func (c configStorageMockOk) Stop(ctx context.Context, schedulerId primitive.Obj
 	panic("implement me")
 }
 
+func (c configStorageMockOk) Update(ctx context.Context, config *scheduler_config_storage.SchedulerConfig) error {
+	panic("implement me")
+}
+
 func (c configStorageMockOk) GetAll(ctx context.Context) ([]*scheduler_config_storage.SchedulerConfig, error) {
 	panic("implement me")
 }
@@ -57,6 +62,10 @@ func (c configStorageMockOk) GetAllForSync(ctx context.Context) ([]*scheduler_co
 	panic("implement me")
 }
 
+func (c configStorageMockOk) SetContentHash(ctx context.Context, schedulerID primitive.ObjectID, hash string) error {
+	panic("implement me")
+}
+
 type configStorageMockError struct {
 }
 
@@ -80,6 +89,10 @@ func (c configStorageMockError) Stop(ctx context.Context, schedulerId primitive.
 	panic("implement me")
 }
 
+func (c configStorageMockError) Update(ctx context.Context, config *scheduler_config_storage.SchedulerConfig) error {
+	panic("implement me")
+}
+
 func (c configStorageMockError) GetAll(ctx context.Context) ([]*scheduler_config_storage.SchedulerConfig, error) {
 	panic("implement me")
 }
@@ -88,6 +101,10 @@ func (c configStorageMockError) GetAllForSync(ctx context.Context) ([]*scheduler
 	panic("implement me")
 }
 
+func (c configStorageMockError) SetContentHash(ctx context.Context, schedulerID primitive.ObjectID, hash string) error {
+	panic("implement me")
+}
+
 type fnMock struct {
 	executed bool
 }
@@ -112,6 +129,56 @@ func (m *fnMock) HttpMock(schedulerId string, timeout int32, config *scheduler_c
 	return nil
 }
 
+func (m *fnMock) TLSCertMock(schedulerId string, timeout int32, config *scheduler_config_storage.TLSCertConfig) job.CheckError {
+	m.executed = true
+	return nil
+}
+
+func (m *fnMock) DNSMock(schedulerId string, timeout int32, config *scheduler_config_storage.DNSConfig) job.CheckError {
+	m.executed = true
+	return nil
+}
+
+func (m *fnMock) ScenarioMock(schedulerId string, timeout int32, config *scheduler_config_storage.ScenarioConfig, httpTool httptools.HTTPTool) job.CheckError {
+	m.executed = true
+	return nil
+}
+
+func (m *fnMock) CrawlerMock(schedulerId string, timeout int32, config *scheduler_config_storage.CrawlerConfig, httpTools httptools.HTTPTool, semaphoreFactoryFn func(n int) semaphore.Semaphore) job.CheckError {
+	m.executed = true
+	return nil
+}
+
+func (m *fnMock) DatabaseMock(schedulerId string, timeout int32, schedulerType apiPb.SchedulerType, config *scheduler_config_storage.DatabaseConfig) job.CheckError {
+	m.executed = true
+	return nil
+}
+
+func (m *fnMock) UDPMock(schedulerId string, timeout int32, config *scheduler_config_storage.TCPConfig) job.CheckError {
+	m.executed = true
+	return nil
+}
+
+func (m *fnMock) WebSocketMock(schedulerId string, timeout int32, config *scheduler_config_storage.WebSocketConfig) job.CheckError {
+	m.executed = true
+	return nil
+}
+
+func (m *fnMock) PrometheusMock(schedulerId string, timeout int32, config *scheduler_config_storage.PrometheusConfig, httpTool httptools.HTTPTool) job.CheckError {
+	m.executed = true
+	return nil
+}
+
+func (m *fnMock) ContentMock(schedulerId string, timeout int32, config *scheduler_config_storage.ContentConfig, httpTool httptools.HTTPTool, configStorage scheduler_config_storage.Storage) job.CheckError {
+	m.executed = true
+	return nil
+}
+
+func (m *fnMock) CommandMock(schedulerId string, timeout int32, config *scheduler_config_storage.CommandConfig) job.CheckError {
+	m.executed = true
+	return nil
+}
+
 func (m *fnMock) HttpValueMock(schedulerId string, timeout int32, config *scheduler_config_storage.HTTPValueConfig, httpTool httptools.HTTPTool) job.CheckError {
 	m.executed = true
 	return nil
@@ -130,6 +197,18 @@ func TestNewExecutor(t *testing.T) {
 			nil,
 			nil,
 			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
 		)
 		assert.Implements(t, (*JobExecutor)(nil), s)
 	})
@@ -149,6 +228,18 @@ func TestExecutor_Execute(t *testing.T) {
 			fnMock.HttpMock,
 			fnMock.SiteMapMock,
 			fnMock.HttpValueMock,
+			fnMock.TLSCertMock,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
 		)
 		s.Execute(primitive.NewObjectID())
 		assert.Equal(t, false, fnMock.executed)
@@ -168,6 +259,18 @@ func TestExecutor_Execute(t *testing.T) {
 			nil,
 			nil,
 			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
 		)
 		s.Execute(primitive.NewObjectID())
 		assert.Equal(t, true, fnMock.executed)
@@ -187,6 +290,18 @@ func TestExecutor_Execute(t *testing.T) {
 			nil,
 			nil,
 			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
 		)
 		s.Execute(primitive.NewObjectID())
 		assert.Equal(t, true, fnMock.executed)
@@ -206,6 +321,18 @@ func TestExecutor_Execute(t *testing.T) {
 			fnMock.HttpMock,
 			nil,
 			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
 		)
 		s.Execute(primitive.NewObjectID())
 		assert.Equal(t, true, fnMock.executed)
@@ -225,6 +352,18 @@ func TestExecutor_Execute(t *testing.T) {
 			nil,
 			fnMock.SiteMapMock,
 			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
 		)
 		s.Execute(primitive.NewObjectID())
 		assert.Equal(t, true, fnMock.executed)
@@ -244,6 +383,328 @@ func TestExecutor_Execute(t *testing.T) {
 			nil,
 			nil,
 			fnMock.HttpValueMock,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+		)
+		s.Execute(primitive.NewObjectID())
+		assert.Equal(t, true, fnMock.executed)
+	})
+	t.Run("Should: execute tls cert mock", func(t *testing.T) {
+		fnMock := &fnMock{}
+		s := NewExecutor(
+			&externalStorageMock{},
+			nil,
+			nil,
+			nil,
+			&configStorageMockOk{
+				monitoring_api.SchedulerTypeTLSCert,
+			},
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			fnMock.TLSCertMock,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+		)
+		s.Execute(primitive.NewObjectID())
+		assert.Equal(t, true, fnMock.executed)
+	})
+	t.Run("Should: execute dns mock", func(t *testing.T) {
+		fnMock := &fnMock{}
+		s := NewExecutor(
+			&externalStorageMock{},
+			nil,
+			nil,
+			nil,
+			&configStorageMockOk{
+				monitoring_api.SchedulerTypeDNS,
+			},
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			fnMock.DNSMock,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+		)
+		s.Execute(primitive.NewObjectID())
+		assert.Equal(t, true, fnMock.executed)
+	})
+	t.Run("Should: execute scenario mock", func(t *testing.T) {
+		fnMock := &fnMock{}
+		s := NewExecutor(
+			&externalStorageMock{},
+			nil,
+			nil,
+			nil,
+			&configStorageMockOk{
+				monitoring_api.SchedulerTypeScenario,
+			},
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			fnMock.ScenarioMock,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+		)
+		s.Execute(primitive.NewObjectID())
+		assert.Equal(t, true, fnMock.executed)
+	})
+	t.Run("Should: execute crawler mock", func(t *testing.T) {
+		fnMock := &fnMock{}
+		s := NewExecutor(
+			&externalStorageMock{},
+			nil,
+			nil,
+			nil,
+			&configStorageMockOk{
+				monitoring_api.SchedulerTypeCrawler,
+			},
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			fnMock.CrawlerMock,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+		)
+		s.Execute(primitive.NewObjectID())
+		assert.Equal(t, true, fnMock.executed)
+	})
+	t.Run("Should: execute database mock", func(t *testing.T) {
+		fnMock := &fnMock{}
+		s := NewExecutor(
+			&externalStorageMock{},
+			nil,
+			nil,
+			nil,
+			&configStorageMockOk{
+				monitoring_api.SchedulerTypeRedis,
+			},
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			fnMock.DatabaseMock,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+		)
+		s.Execute(primitive.NewObjectID())
+		assert.Equal(t, true, fnMock.executed)
+	})
+	t.Run("Should: execute udp mock", func(t *testing.T) {
+		fnMock := &fnMock{}
+		s := NewExecutor(
+			&externalStorageMock{},
+			nil,
+			nil,
+			nil,
+			&configStorageMockOk{
+				monitoring_api.SchedulerTypeUDP,
+			},
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			fnMock.UDPMock,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+		)
+		s.Execute(primitive.NewObjectID())
+		assert.Equal(t, true, fnMock.executed)
+	})
+	t.Run("Should: execute websocket mock", func(t *testing.T) {
+		fnMock := &fnMock{}
+		s := NewExecutor(
+			&externalStorageMock{},
+			nil,
+			nil,
+			nil,
+			&configStorageMockOk{
+				monitoring_api.SchedulerTypeWebSocket,
+			},
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			fnMock.WebSocketMock,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+		)
+		s.Execute(primitive.NewObjectID())
+		assert.Equal(t, true, fnMock.executed)
+	})
+	t.Run("Should: execute prometheus mock", func(t *testing.T) {
+		fnMock := &fnMock{}
+		s := NewExecutor(
+			&externalStorageMock{},
+			nil,
+			nil,
+			nil,
+			&configStorageMockOk{
+				monitoring_api.SchedulerTypePrometheus,
+			},
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			fnMock.PrometheusMock,
+			nil,
+			nil,
+			nil,
+			nil,
+		)
+		s.Execute(primitive.NewObjectID())
+		assert.Equal(t, true, fnMock.executed)
+	})
+	t.Run("Should: execute content mock", func(t *testing.T) {
+		fnMock := &fnMock{}
+		s := NewExecutor(
+			&externalStorageMock{},
+			nil,
+			nil,
+			nil,
+			&configStorageMockOk{
+				monitoring_api.SchedulerTypeContent,
+			},
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			fnMock.ContentMock,
+			nil,
+			nil,
+			nil,
+		)
+		s.Execute(primitive.NewObjectID())
+		assert.Equal(t, true, fnMock.executed)
+	})
+	t.Run("Should: execute command mock", func(t *testing.T) {
+		fnMock := &fnMock{}
+		s := NewExecutor(
+			&externalStorageMock{},
+			nil,
+			nil,
+			nil,
+			&configStorageMockOk{
+				monitoring_api.SchedulerTypeCommand,
+			},
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			fnMock.CommandMock,
+			nil,
+			nil,
 		)
 		s.Execute(primitive.NewObjectID())
 		assert.Equal(t, true, fnMock.executed)
@@ -263,8 +724,44 @@ func TestExecutor_Execute(t *testing.T) {
 			nil,
 			nil,
 			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
 		)
 		s.Execute(primitive.NewObjectID())
 		assert.Equal(t, false, fnMock.executed)
 	})
 }
+
+func TestExecutor_GetDiagnostics(t *testing.T) {
+	t.Run("Should: return empty diagnostics without limits", func(t *testing.T) {
+		e := &executor{}
+		assert.Equal(t, &monitoring_api.Diagnostics{}, e.GetDiagnostics())
+	})
+	t.Run("Should: return load of worker pool and host limiter", func(t *testing.T) {
+		pool := NewWorkerPool(5)
+		pool.Acquire()
+		defer pool.Release()
+		limiter := NewHostLimiter(1).(*hostLimiter)
+		limiter.waiting["squzy.app"] = 2
+		e := &executor{
+			workerPool:  pool,
+			hostLimiter: limiter,
+		}
+		assert.Equal(t, &monitoring_api.Diagnostics{
+			RunningJobs:       1,
+			MaxConcurrentJobs: 5,
+			RateLimitedJobs:   2,
+			RateLimitedHosts:  map[string]int32{"squzy.app": 2},
+		}, e.GetDiagnostics())
+	})
+}
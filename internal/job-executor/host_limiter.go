@@ -0,0 +1,182 @@
+package job_executor
+
+import (
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"net"
+	"net/url"
+	"squzy/internal/job"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limits rate of check executions for one target host
+type HostLimiter interface {
+	// Blocks until execution for host is allowed
+	Wait(host string)
+	// Executions which wait for rate limit by hosts
+	GetWaiting() map[string]int32
+}
+
+// Hosts which were not executed for that time are removed from limiter
+const hostLimiterCleanupInterval = time.Minute
+
+type hostLimiter struct {
+	mutex     sync.Mutex
+	gap       time.Duration
+	next      map[string]time.Time
+	waiting   map[string]int32
+	cleanupAt time.Time
+	sleep     func(d time.Duration)
+}
+
+// Executions of one host are spread evenly, limiter with rate 0 does not limit them
+func NewHostLimiter(rate float64) HostLimiter {
+	var gap time.Duration
+	if rate > 0 {
+		gap = time.Duration(float64(time.Second) / rate)
+	}
+	return &hostLimiter{
+		gap:     gap,
+		next:    map[string]time.Time{},
+		waiting: map[string]int32{},
+		sleep:   time.Sleep,
+	}
+}
+
+func (l *hostLimiter) Wait(host string) {
+	if host == "" || l.gap == 0 {
+		return
+	}
+	l.mutex.Lock()
+	now := time.Now()
+	l.cleanup(now)
+	slot := l.next[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next[host] = slot.Add(l.gap)
+	if !slot.After(now) {
+		l.mutex.Unlock()
+		return
+	}
+	l.waiting[host]++
+	l.mutex.Unlock()
+
+	l.sleep(slot.Sub(now))
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.waiting[host]--
+	if l.waiting[host] == 0 {
+		delete(l.waiting, host)
+	}
+}
+
+// Host with passed slot is not limited, so it is same as host without entry
+func (l *hostLimiter) cleanup(now time.Time) {
+	if now.Before(l.cleanupAt) {
+		return
+	}
+	l.cleanupAt = now.Add(hostLimiterCleanupInterval)
+	for host, slot := range l.next {
+		if slot.Before(now) {
+			delete(l.next, host)
+		}
+	}
+}
+
+func (l *hostLimiter) GetWaiting() map[string]int32 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	waiting := map[string]int32{}
+	for host, count := range l.waiting {
+		waiting[host] = count
+	}
+	return waiting
+}
+
+// Check waits for rate limit of host before each execution
+func withHostLimit(limiter HostLimiter, host string, check func() job.CheckError) func() job.CheckError {
+	return func() job.CheckError {
+		limiter.Wait(host)
+		return check()
+	}
+}
+
+// Host which is requested by check, empty when check has no remote target
+func targetHost(config *scheduler_config_storage.SchedulerConfig) string {
+	switch config.Type {
+	case apiPb.SchedulerType_TCP, monitoring_api.SchedulerTypeUDP:
+		if config.TCPConfig != nil {
+			return hostKey(config.TCPConfig.Host)
+		}
+	case apiPb.SchedulerType_GRPC:
+		if config.GrpcConfig != nil {
+			return hostKey(config.GrpcConfig.Host)
+		}
+	case apiPb.SchedulerType_HTTP:
+		if config.HTTPConfig != nil {
+			return hostKey(config.HTTPConfig.URL)
+		}
+	case apiPb.SchedulerType_SITE_MAP:
+		if config.SiteMapConfig != nil {
+			return hostKey(config.SiteMapConfig.URL)
+		}
+	case apiPb.SchedulerType_HTTP_JSON_VALUE:
+		if config.HTTPValueConfig != nil {
+			return hostKey(config.HTTPValueConfig.URL)
+		}
+	case monitoring_api.SchedulerTypeTLSCert:
+		if config.TLSCertConfig != nil {
+			return hostKey(config.TLSCertConfig.Host)
+		}
+	case monitoring_api.SchedulerTypeDNS:
+		// Queries are sent to resolver, system resolver is not limited
+		if config.DNSConfig != nil {
+			return hostKey(config.DNSConfig.Resolver)
+		}
+	case monitoring_api.SchedulerTypeScenario:
+		if config.ScenarioConfig != nil && len(config.ScenarioConfig.Steps) > 0 {
+			return hostKey(config.ScenarioConfig.Steps[0].URL)
+		}
+	case monitoring_api.SchedulerTypeCrawler:
+		if config.CrawlerConfig != nil {
+			return hostKey(config.CrawlerConfig.URL)
+		}
+	case monitoring_api.SchedulerTypePostgres, monitoring_api.SchedulerTypeMySQL, monitoring_api.SchedulerTypeRedis, monitoring_api.SchedulerTypeMongo:
+		if config.DatabaseConfig != nil {
+			return hostKey(config.DatabaseConfig.Host)
+		}
+	case monitoring_api.SchedulerTypeWebSocket:
+		if config.WebSocketConfig != nil {
+			return hostKey(config.WebSocketConfig.URL)
+		}
+	case monitoring_api.SchedulerTypePrometheus:
+		if config.PrometheusConfig != nil {
+			return hostKey(config.PrometheusConfig.URL)
+		}
+	case monitoring_api.SchedulerTypeContent:
+		if config.ContentConfig != nil {
+			return hostKey(config.ContentConfig.URL)
+		}
+	}
+	return ""
+}
+
+// Same host gets same key whether it is written as url, host with port or host
+func hostKey(address string) string {
+	if strings.Contains(address, "://") {
+		u, err := url.Parse(address)
+		if err != nil {
+			return ""
+		}
+		address = u.Host
+	}
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+	return strings.TrimSuffix(strings.ToLower(strings.Trim(address, "[]")), ".")
+}
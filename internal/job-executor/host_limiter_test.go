@@ -0,0 +1,186 @@
+package job_executor
+
+import (
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/stretchr/testify/assert"
+	"squzy/internal/job"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewHostLimiter(t *testing.T) {
+	t.Run("Should: implement interface", func(t *testing.T) {
+		assert.Implements(t, (*HostLimiter)(nil), NewHostLimiter(1))
+	})
+}
+
+func TestHostLimiter_Wait(t *testing.T) {
+	t.Run("Should: not wait without rate limit", func(t *testing.T) {
+		limiter := NewHostLimiter(0).(*hostLimiter)
+		limiter.sleep = func(d time.Duration) {
+			assert.Fail(t, "should not wait")
+		}
+		limiter.Wait("squzy.app")
+		limiter.Wait("squzy.app")
+	})
+	t.Run("Should: not wait for empty host", func(t *testing.T) {
+		limiter := NewHostLimiter(1).(*hostLimiter)
+		limiter.sleep = func(d time.Duration) {
+			assert.Fail(t, "should not wait")
+		}
+		limiter.Wait("")
+		limiter.Wait("")
+	})
+	t.Run("Should: spread executions of one host", func(t *testing.T) {
+		limiter := NewHostLimiter(2).(*hostLimiter)
+		mutex := sync.Mutex{}
+		sleeps := []time.Duration{}
+		limiter.sleep = func(d time.Duration) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			sleeps = append(sleeps, d)
+		}
+		limiter.Wait("squzy.app")
+		limiter.Wait("squzy.app")
+		limiter.Wait("squzy.app")
+		limiter.Wait("google.com")
+		assert.Len(t, sleeps, 2)
+		assert.InDelta(t, float64(time.Millisecond*500), float64(sleeps[0]), float64(time.Millisecond*50))
+		assert.InDelta(t, float64(time.Second), float64(sleeps[1]), float64(time.Millisecond*50))
+	})
+	t.Run("Should: remove hosts with passed slots", func(t *testing.T) {
+		limiter := NewHostLimiter(2).(*hostLimiter)
+		limiter.sleep = func(d time.Duration) {}
+		limiter.next["old.squzy.app"] = time.Now().Add(-time.Second)
+		limiter.next["busy.squzy.app"] = time.Now().Add(time.Hour)
+		limiter.Wait("squzy.app")
+		assert.Len(t, limiter.next, 2)
+		assert.Contains(t, limiter.next, "busy.squzy.app")
+		assert.Contains(t, limiter.next, "squzy.app")
+		limiter.next["old.squzy.app"] = time.Now().Add(-time.Second)
+		limiter.Wait("squzy.app")
+		assert.Contains(t, limiter.next, "old.squzy.app")
+	})
+	t.Run("Should: count waiting executions by host", func(t *testing.T) {
+		limiter := NewHostLimiter(1).(*hostLimiter)
+		release := make(chan bool)
+		limiter.sleep = func(d time.Duration) {
+			<-release
+		}
+		limiter.Wait("squzy.app")
+		done := make(chan bool)
+		go func() {
+			limiter.Wait("squzy.app")
+			done <- true
+		}()
+		assert.Eventually(t, func() bool {
+			return limiter.GetWaiting()["squzy.app"] == 1
+		}, time.Second, time.Millisecond)
+		release <- true
+		<-done
+		assert.Empty(t, limiter.GetWaiting())
+	})
+}
+
+func TestWithHostLimit(t *testing.T) {
+	t.Run("Should: wait for rate limit before check", func(t *testing.T) {
+		limiter := NewHostLimiter(1).(*hostLimiter)
+		waited := false
+		limiter.sleep = func(d time.Duration) {
+			waited = true
+		}
+		check := withHostLimit(limiter, "squzy.app", func() job.CheckError {
+			return checkOk
+		})
+		assert.Equal(t, checkOk, check())
+		assert.Equal(t, checkOk, check())
+		assert.True(t, waited)
+	})
+}
+
+func TestTargetHost(t *testing.T) {
+	t.Run("Should: return host of check", func(t *testing.T) {
+		tests := []struct {
+			config   *scheduler_config_storage.SchedulerConfig
+			expected string
+		}{
+			{
+				config: &scheduler_config_storage.SchedulerConfig{
+					Type:       apiPb.SchedulerType_HTTP,
+					HTTPConfig: &scheduler_config_storage.HTTPConfig{URL: "https://Squzy.App:8080/path"},
+				},
+				expected: "squzy.app",
+			},
+			{
+				config: &scheduler_config_storage.SchedulerConfig{
+					Type:      monitoring_api.SchedulerTypeUDP,
+					TCPConfig: &scheduler_config_storage.TCPConfig{Host: "localhost", Port: 53},
+				},
+				expected: "localhost",
+			},
+			{
+				config: &scheduler_config_storage.SchedulerConfig{
+					Type:      monitoring_api.SchedulerTypeDNS,
+					DNSConfig: &scheduler_config_storage.DNSConfig{Host: "squzy.app", Resolver: "[2001:4860:4860::8888]:53"},
+				},
+				expected: "2001:4860:4860::8888",
+			},
+			{
+				config: &scheduler_config_storage.SchedulerConfig{
+					Type:      monitoring_api.SchedulerTypeDNS,
+					DNSConfig: &scheduler_config_storage.DNSConfig{Host: "squzy.app", Resolver: "8.8.8.8"},
+				},
+				expected: "8.8.8.8",
+			},
+			{
+				config: &scheduler_config_storage.SchedulerConfig{
+					Type: monitoring_api.SchedulerTypeScenario,
+					ScenarioConfig: &scheduler_config_storage.ScenarioConfig{
+						Steps: []*scheduler_config_storage.ScenarioStep{
+							{URL: "https://api.squzy.app/login"},
+						},
+					},
+				},
+				expected: "api.squzy.app",
+			},
+			{
+				config: &scheduler_config_storage.SchedulerConfig{
+					Type:           monitoring_api.SchedulerTypeRedis,
+					DatabaseConfig: &scheduler_config_storage.DatabaseConfig{Host: "redis.local."},
+				},
+				expected: "redis.local",
+			},
+			{
+				config: &scheduler_config_storage.SchedulerConfig{
+					Type:          monitoring_api.SchedulerTypeCommand,
+					CommandConfig: &scheduler_config_storage.CommandConfig{Path: "/usr/lib/nagios/plugins/check_disk"},
+				},
+				expected: "",
+			},
+			{
+				config: &scheduler_config_storage.SchedulerConfig{
+					Type: apiPb.SchedulerType_HTTP,
+				},
+				expected: "",
+			},
+		}
+		for _, test := range tests {
+			assert.Equal(t, test.expected, targetHost(test.config))
+		}
+	})
+}
+
+func TestHostKey(t *testing.T) {
+	t.Run("Should: return same key for different forms of host", func(t *testing.T) {
+		for _, address := range []string{"squzy.app", "Squzy.App.", "squzy.app:443", "https://squzy.app/path", "wss://SQUZY.app:8443", "[squzy.app]"} {
+			assert.Equal(t, "squzy.app", hostKey(address), address)
+		}
+		for _, address := range []string{"::1", "[::1]:53", "http://[::1]:8080/"} {
+			assert.Equal(t, "::1", hostKey(address), address)
+		}
+		assert.Equal(t, "", hostKey("http://%zz"))
+	})
+}
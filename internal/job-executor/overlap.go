@@ -0,0 +1,97 @@
+package job_executor
+
+import (
+	"context"
+	"errors"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"squzy/internal/job"
+	monitoring_api "squzy/internal/monitoring-api"
+)
+
+var (
+	errDeadlineExceeded = errors.New("execution deadline exceeded")
+	errTickSkipped      = errors.New("tick skipped because previous execution is still running")
+)
+
+// Running execution of scheduler
+type runState struct {
+	queued bool
+}
+
+// Executes run when scheduler has no running execution, otherwise tick is queued or skipped by overlap policy.
+// Queued tick is executed right after running execution
+func (e *executor) runExclusive(schedulerID primitive.ObjectID, policy monitoring_api.OverlapPolicy, run func(), skip func()) {
+	e.runMutex.Lock()
+	state, ok := e.runs[schedulerID]
+	if ok {
+		if policy == monitoring_api.OverlapQueueOne && !state.queued {
+			state.queued = true
+			e.runMutex.Unlock()
+			return
+		}
+		e.runMutex.Unlock()
+		skip()
+		return
+	}
+	state = &runState{}
+	e.runs[schedulerID] = state
+	e.runMutex.Unlock()
+	for {
+		run()
+		e.runMutex.Lock()
+		if !state.queued {
+			// Execution which is started after reset of scheduler owns its own state
+			if e.runs[schedulerID] == state {
+				delete(e.runs, schedulerID)
+			}
+			e.runMutex.Unlock()
+			return
+		}
+		state.queued = false
+		e.runMutex.Unlock()
+	}
+}
+
+// Returns timeout snapshot when check is not finished before deadline of context, check which starts after deadline
+// is not executed. Check is not interrupted and its result is ignored, abandoned check holds neither worker nor overlap slot
+func withDeadline(ctx context.Context, schedulerID string, schedulerType apiPb.SchedulerType, check func() job.CheckError) func() job.CheckError {
+	return func() job.CheckError {
+		startTime := ptypes.TimestampNow()
+		if ctx.Err() != nil {
+			return newExecutionResult(schedulerID, schedulerType, apiPb.SchedulerCode_ERROR, errDeadlineExceeded, startTime)
+		}
+		// Buffered, so abandoned check is not blocked when it is finished
+		done := make(chan job.CheckError, 1)
+		go func() {
+			done <- check()
+		}()
+		select {
+		case res := <-done:
+			return res
+		case <-ctx.Done():
+			return newExecutionResult(schedulerID, schedulerType, apiPb.SchedulerCode_ERROR, errDeadlineExceeded, startTime)
+		}
+	}
+}
+
+func newExecutionResult(schedulerID string, schedulerType apiPb.SchedulerType, code apiPb.SchedulerCode, err error, startTime *timestamp.Timestamp) job.CheckError {
+	return &executionResult{
+		response: &apiPb.SchedulerResponse{
+			SchedulerId: schedulerID,
+			Snapshot: &apiPb.SchedulerSnapshot{
+				Code: code,
+				Error: &apiPb.SchedulerSnapshot_Error{
+					Message: err.Error(),
+				},
+				Type: schedulerType,
+				Meta: &apiPb.SchedulerSnapshot_MetaData{
+					StartTime: startTime,
+					EndTime:   ptypes.TimestampNow(),
+				},
+			},
+		},
+	}
+}
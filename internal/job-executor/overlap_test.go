@@ -0,0 +1,373 @@
+package job_executor
+
+import (
+	"context"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"squzy/internal/job"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"sync"
+	"testing"
+	"time"
+)
+
+type externalStorageMockWrites struct {
+	mutex  sync.Mutex
+	writes []*apiPb.SchedulerResponse
+}
+
+func (e *externalStorageMockWrites) Write(log job.CheckError) error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.writes = append(e.writes, log.GetLogData())
+	return nil
+}
+
+type configStorageMockOverlap struct {
+	configStorageMockOk
+	overlap  monitoring_api.OverlapPolicy
+	deadline int32
+	retry    *scheduler_config_storage.RetryPolicy
+}
+
+func (c configStorageMockOverlap) Get(ctx context.Context, schedulerId primitive.ObjectID) (*scheduler_config_storage.SchedulerConfig, error) {
+	return &scheduler_config_storage.SchedulerConfig{
+		ID:       schedulerId,
+		Type:     monitoring_api.SchedulerTypeCommand,
+		Overlap:  c.overlap,
+		Deadline: c.deadline,
+		Retry:    c.retry,
+	}, nil
+}
+
+// Run which is blocked until it is released
+type blockingRun struct {
+	mutex   sync.Mutex
+	count   int
+	started chan bool
+	release chan bool
+}
+
+func newBlockingRun() *blockingRun {
+	return &blockingRun{
+		started: make(chan bool, 10),
+		release: make(chan bool),
+	}
+}
+
+func (b *blockingRun) run() {
+	b.mutex.Lock()
+	b.count++
+	b.mutex.Unlock()
+	b.started <- true
+	<-b.release
+}
+
+func (b *blockingRun) getCount() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.count
+}
+
+func TestExecutor_runExclusive(t *testing.T) {
+	t.Run("Should: skip tick while execution is running", func(t *testing.T) {
+		e := &executor{runs: map[primitive.ObjectID]*runState{}}
+		id := primitive.NewObjectID()
+		blocking := newBlockingRun()
+		done := make(chan bool)
+		go func() {
+			e.runExclusive(id, monitoring_api.OverlapSkip, blocking.run, func() {})
+			done <- true
+		}()
+		<-blocking.started
+		skipped := false
+		e.runExclusive(id, monitoring_api.OverlapSkip, blocking.run, func() {
+			skipped = true
+		})
+		assert.True(t, skipped)
+		blocking.release <- true
+		<-done
+		assert.Equal(t, 1, blocking.getCount())
+		assert.Empty(t, e.runs)
+	})
+	t.Run("Should: execute one queued tick after running execution", func(t *testing.T) {
+		e := &executor{runs: map[primitive.ObjectID]*runState{}}
+		id := primitive.NewObjectID()
+		blocking := newBlockingRun()
+		done := make(chan bool)
+		go func() {
+			e.runExclusive(id, monitoring_api.OverlapQueueOne, blocking.run, func() {})
+			done <- true
+		}()
+		<-blocking.started
+		skipped := 0
+		skip := func() {
+			skipped++
+		}
+		e.runExclusive(id, monitoring_api.OverlapQueueOne, blocking.run, skip)
+		e.runExclusive(id, monitoring_api.OverlapQueueOne, blocking.run, skip)
+		assert.Equal(t, 1, skipped)
+		blocking.release <- true
+		<-blocking.started
+		blocking.release <- true
+		<-done
+		assert.Equal(t, 2, blocking.getCount())
+		assert.Empty(t, e.runs)
+	})
+	t.Run("Should: drop queued tick and run next tick because scheduler is reset", func(t *testing.T) {
+		e := &executor{
+			runs:       map[primitive.ObjectID]*runState{},
+			flapStates: map[string]*flapState{},
+		}
+		id := primitive.NewObjectID()
+		blocking := newBlockingRun()
+		done := make(chan bool)
+		go func() {
+			e.runExclusive(id, monitoring_api.OverlapQueueOne, blocking.run, func() {})
+			done <- true
+		}()
+		<-blocking.started
+		e.runExclusive(id, monitoring_api.OverlapQueueOne, blocking.run, func() {})
+		e.Reset(id)
+		go func() {
+			e.runExclusive(id, monitoring_api.OverlapQueueOne, blocking.run, func() {})
+			done <- true
+		}()
+		<-blocking.started
+		blocking.release <- true
+		blocking.release <- true
+		<-done
+		<-done
+		assert.Equal(t, 2, blocking.getCount())
+		assert.Empty(t, e.runs)
+	})
+	t.Run("Should: execute ticks of different schedulers in parallel", func(t *testing.T) {
+		e := &executor{runs: map[primitive.ObjectID]*runState{}}
+		blocking := newBlockingRun()
+		done := make(chan bool)
+		for i := 0; i < 2; i++ {
+			go func() {
+				e.runExclusive(primitive.NewObjectID(), monitoring_api.OverlapSkip, blocking.run, func() {})
+				done <- true
+			}()
+		}
+		<-blocking.started
+		<-blocking.started
+		blocking.release <- true
+		blocking.release <- true
+		<-done
+		<-done
+		assert.Equal(t, 2, blocking.getCount())
+	})
+}
+
+func TestWithDeadline(t *testing.T) {
+	t.Run("Should: return check result before deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		res := withDeadline(ctx, "id", apiPb.SchedulerType_HTTP, func() job.CheckError {
+			return checkOk
+		})()
+		assert.Equal(t, checkOk, res)
+	})
+	t.Run("Should: return timeout snapshot because deadline exceeded", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancel()
+		release := make(chan bool)
+		res := withDeadline(ctx, "id", apiPb.SchedulerType_HTTP, func() job.CheckError {
+			<-release
+			return checkOk
+		})().GetLogData()
+		close(release)
+		assert.Equal(t, "id", res.SchedulerId)
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, res.Snapshot.Code)
+		assert.Equal(t, apiPb.SchedulerType_HTTP, res.Snapshot.Type)
+		assert.Equal(t, errDeadlineExceeded.Error(), res.Snapshot.Error.Message)
+		assert.NotNil(t, res.Snapshot.Meta.StartTime)
+	})
+	t.Run("Should: not execute check after deadline", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		executed := false
+		res := withDeadline(ctx, "id", apiPb.SchedulerType_HTTP, func() job.CheckError {
+			executed = true
+			return checkOk
+		})().GetLogData()
+		assert.False(t, executed)
+		assert.Equal(t, errDeadlineExceeded.Error(), res.Snapshot.Error.Message)
+	})
+}
+
+func TestExecutor_ExecuteOverlap(t *testing.T) {
+	newExecutorWithDeadline := func(storage *externalStorageMockWrites, overlap monitoring_api.OverlapPolicy, deadline int32, blocking *blockingRun) JobExecutor {
+		return NewExecutor(
+			storage,
+			nil,
+			nil,
+			nil,
+			&configStorageMockOverlap{overlap: overlap, deadline: deadline},
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			func(schedulerId string, timeout int32, config *scheduler_config_storage.CommandConfig) job.CheckError {
+				blocking.run()
+				return checkOk
+			},
+			nil,
+			nil,
+		)
+	}
+	newExecutor := func(storage *externalStorageMockWrites, overlap monitoring_api.OverlapPolicy, blocking *blockingRun) JobExecutor {
+		return newExecutorWithDeadline(storage, overlap, 0, blocking)
+	}
+	t.Run("Should: execute next tick while check abandoned by deadline is running", func(t *testing.T) {
+		storage := &externalStorageMockWrites{}
+		blocking := newBlockingRun()
+		s := newExecutorWithDeadline(storage, monitoring_api.OverlapSkip, 1, blocking)
+		id := primitive.NewObjectID()
+		s.Execute(id)
+		<-blocking.started
+		done := make(chan bool)
+		go func() {
+			s.Execute(id)
+			done <- true
+		}()
+		<-blocking.started
+		// Abandoned and running checks are released together
+		blocking.release <- true
+		blocking.release <- true
+		<-done
+		assert.Len(t, storage.writes, 2)
+		assert.Equal(t, errDeadlineExceeded.Error(), storage.writes[0].Snapshot.Error.Message)
+		assert.Equal(t, checkOk.GetLogData(), storage.writes[1])
+		assert.Equal(t, 2, blocking.getCount())
+	})
+	t.Run("Should: save skipped snapshot", func(t *testing.T) {
+		storage := &externalStorageMockWrites{}
+		blocking := newBlockingRun()
+		s := newExecutor(storage, monitoring_api.OverlapSkip, blocking)
+		id := primitive.NewObjectID()
+		done := make(chan bool)
+		go func() {
+			s.Execute(id)
+			done <- true
+		}()
+		<-blocking.started
+		s.Execute(id)
+		blocking.release <- true
+		<-done
+		assert.Len(t, storage.writes, 2)
+		assert.Equal(t, monitoring_api.SchedulerCodeSkipped, storage.writes[0].Snapshot.Code)
+		assert.Equal(t, monitoring_api.SchedulerTypeCommand, storage.writes[0].Snapshot.Type)
+		assert.Equal(t, errTickSkipped.Error(), storage.writes[0].Snapshot.Error.Message)
+		assert.Equal(t, checkOk.GetLogData(), storage.writes[1])
+	})
+	t.Run("Should: drop tick without snapshot because policy is not set", func(t *testing.T) {
+		storage := &externalStorageMockWrites{}
+		blocking := newBlockingRun()
+		s := newExecutor(storage, "", blocking)
+		id := primitive.NewObjectID()
+		done := make(chan bool)
+		go func() {
+			s.Execute(id)
+			done <- true
+		}()
+		<-blocking.started
+		s.Execute(id)
+		blocking.release <- true
+		<-done
+		assert.Len(t, storage.writes, 1)
+		assert.Equal(t, checkOk.GetLogData(), storage.writes[0])
+		assert.Equal(t, 1, blocking.getCount())
+	})
+	t.Run("Should: execute ticks in parallel", func(t *testing.T) {
+		storage := &externalStorageMockWrites{}
+		blocking := newBlockingRun()
+		s := newExecutor(storage, monitoring_api.OverlapAllowParallel, blocking)
+		id := primitive.NewObjectID()
+		done := make(chan bool)
+		for i := 0; i < 2; i++ {
+			go func() {
+				s.Execute(id)
+				done <- true
+			}()
+		}
+		<-blocking.started
+		<-blocking.started
+		blocking.release <- true
+		blocking.release <- true
+		<-done
+		<-done
+		assert.Len(t, storage.writes, 2)
+		assert.Equal(t, apiPb.SchedulerCode_OK, storage.writes[0].Snapshot.Code)
+		assert.Equal(t, apiPb.SchedulerCode_OK, storage.writes[1].Snapshot.Code)
+	})
+}
+
+func TestExecutor_ExecuteDeadline(t *testing.T) {
+	t.Run("Should: share deadline between retry attempts", func(t *testing.T) {
+		storage := &externalStorageMockWrites{}
+		var mutex sync.Mutex
+		calls := 0
+		s := NewExecutor(
+			storage,
+			nil,
+			nil,
+			nil,
+			&configStorageMockOverlap{
+				overlap:  monitoring_api.OverlapSkip,
+				deadline: 1,
+				retry: &scheduler_config_storage.RetryPolicy{
+					Attempts: 3,
+				},
+			},
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			nil,
+			func(schedulerId string, timeout int32, config *scheduler_config_storage.CommandConfig) job.CheckError {
+				mutex.Lock()
+				calls++
+				mutex.Unlock()
+				time.Sleep(time.Millisecond * 700)
+				return checkFailed
+			},
+			nil,
+			nil,
+		)
+		startTime := time.Now()
+		s.Execute(primitive.NewObjectID())
+		// Three attempts with own deadline would take more than two seconds
+		assert.True(t, time.Since(startTime) < time.Millisecond*1500)
+		assert.Len(t, storage.writes, 1)
+		assert.Equal(t, errDeadlineExceeded.Error(), storage.writes[0].Snapshot.Error.Message)
+		state := monitoring_api.CheckPolicyStateFromMetaValue(storage.writes[0].Snapshot.Meta.Value)
+		assert.Len(t, state.Attempts, 2)
+		mutex.Lock()
+		defer mutex.Unlock()
+		assert.Equal(t, 2, calls)
+	})
+}
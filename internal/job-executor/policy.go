@@ -0,0 +1,143 @@
+package job_executor
+
+import (
+	"context"
+	"github.com/golang/protobuf/proto"
+	structType "github.com/golang/protobuf/ptypes/struct"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"math"
+	"squzy/internal/job"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"time"
+)
+
+const policyValueKey = "value"
+
+// Consecutive runs of scheduler, state is kept in memory of monitoring
+type flapState struct {
+	confirmedCode        apiPb.SchedulerCode
+	confirmedMessage     string
+	consecutiveFailures  int32
+	consecutiveSuccesses int32
+}
+
+// Snapshot which is made by executor instead of check
+type executionResult struct {
+	response *apiPb.SchedulerResponse
+}
+
+func (r *executionResult) GetLogData() *apiPb.SchedulerResponse {
+	return r.response
+}
+
+// Repeats failed check by retry policy and confirms code of run by flap policy,
+// check result is returned as is when scheduler has not policies. Check is not repeated when its backoff ends after deadline of context.
+// Policies are not applied to content check, its repeated run does not see change which is saved by previous run
+func (e *executor) withPolicy(ctx context.Context, schedulerID string, config *scheduler_config_storage.SchedulerConfig, check func() job.CheckError) job.CheckError {
+	if (config.Retry == nil && config.Flap == nil) || config.Type == monitoring_api.SchedulerTypeContent {
+		return check()
+	}
+	attempts := []*monitoring_api.CheckAttempt{}
+	var last *apiPb.SchedulerResponse
+	for i := 0; ; i++ {
+		last = check().GetLogData()
+		attempts = append(attempts, &monitoring_api.CheckAttempt{
+			Code:    last.GetSnapshot().GetCode(),
+			Message: last.GetSnapshot().GetError().GetMessage(),
+		})
+		if last.GetSnapshot().GetCode() == apiPb.SchedulerCode_OK || config.Retry == nil || int32(i+1) >= config.Retry.Attempts {
+			break
+		}
+		backoff := retryBackoff(config.Retry, i)
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(backoff).After(deadline) {
+			break
+		}
+		e.sleep(backoff)
+	}
+	state, message := e.confirm(schedulerID, config.Flap, last.GetSnapshot())
+	state.Attempts = attempts
+
+	response := proto.Clone(last).(*apiPb.SchedulerResponse)
+	if response.Snapshot == nil {
+		response.Snapshot = &apiPb.SchedulerSnapshot{}
+	}
+	response.Snapshot.Code = state.ConfirmedCode
+	response.Snapshot.Error = nil
+	if state.ConfirmedCode != apiPb.SchedulerCode_OK {
+		response.Snapshot.Error = &apiPb.SchedulerSnapshot_Error{
+			Message: message,
+		}
+	}
+	if response.Snapshot.Meta == nil {
+		response.Snapshot.Meta = &apiPb.SchedulerSnapshot_MetaData{}
+	}
+	response.Snapshot.Meta.Value = withPolicyState(response.Snapshot.Meta.Value, state)
+	return &executionResult{
+		response: response,
+	}
+}
+
+func retryBackoff(policy *scheduler_config_storage.RetryPolicy, attempt int) time.Duration {
+	multiplier := policy.BackoffMultiplier
+	if multiplier <= 0 {
+		multiplier = 1
+	}
+	return time.Duration(float64(policy.BackoffMs)*math.Pow(multiplier, float64(attempt))) * time.Millisecond
+}
+
+// Updates consecutive runs of scheduler and returns confirmed state with message of confirmed failure,
+// code of run is confirmed as is without flap policy
+func (e *executor) confirm(schedulerID string, policy *scheduler_config_storage.FlapPolicy, snapshot *apiPb.SchedulerSnapshot) (*monitoring_api.CheckPolicyState, string) {
+	e.flapMutex.Lock()
+	defer e.flapMutex.Unlock()
+	state, ok := e.flapStates[schedulerID]
+	if !ok {
+		state = &flapState{
+			confirmedCode: apiPb.SchedulerCode_OK,
+		}
+		e.flapStates[schedulerID] = state
+	}
+	code := snapshot.GetCode()
+	if code == apiPb.SchedulerCode_OK {
+		state.consecutiveFailures = 0
+		state.consecutiveSuccesses++
+		if policy == nil || state.consecutiveSuccesses >= policy.RecoverAfter {
+			state.confirmedCode = code
+			state.confirmedMessage = ""
+		}
+	} else {
+		state.consecutiveSuccesses = 0
+		state.consecutiveFailures++
+		if policy == nil || state.consecutiveFailures >= policy.ConfirmAfter || state.confirmedCode != apiPb.SchedulerCode_OK {
+			state.confirmedCode = code
+			state.confirmedMessage = snapshot.GetError().GetMessage()
+		}
+	}
+	return &monitoring_api.CheckPolicyState{
+		RawCode:              code,
+		ConfirmedCode:        state.confirmedCode,
+		ConsecutiveFailures:  state.consecutiveFailures,
+		ConsecutiveSuccesses: state.consecutiveSuccesses,
+	}, state.confirmedMessage
+}
+
+// Adds policy state to struct value, other values are wrapped
+func withPolicyState(value *structType.Value, state *monitoring_api.CheckPolicyState) *structType.Value {
+	fields := map[string]*structType.Value{}
+	if value.GetStructValue() != nil {
+		for key, field := range value.GetStructValue().GetFields() {
+			fields[key] = field
+		}
+	} else if value != nil {
+		fields[policyValueKey] = value
+	}
+	fields[monitoring_api.SnapshotPolicyKey] = state.ToValue()
+	return &structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{
+				Fields: fields,
+			},
+		},
+	}
+}
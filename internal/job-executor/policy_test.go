@@ -0,0 +1,242 @@
+package job_executor
+
+import (
+	"context"
+	structType "github.com/golang/protobuf/ptypes/struct"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"squzy/internal/job"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"testing"
+	"time"
+)
+
+type checkResultMock struct {
+	code    apiPb.SchedulerCode
+	message string
+}
+
+func (m *checkResultMock) GetLogData() *apiPb.SchedulerResponse {
+	var err *apiPb.SchedulerSnapshot_Error
+	if m.code != apiPb.SchedulerCode_OK {
+		err = &apiPb.SchedulerSnapshot_Error{
+			Message: m.message,
+		}
+	}
+	return &apiPb.SchedulerResponse{
+		SchedulerId: "id",
+		Snapshot: &apiPb.SchedulerSnapshot{
+			Code:  m.code,
+			Error: err,
+			Meta: &apiPb.SchedulerSnapshot_MetaData{
+				Value: &structType.Value{
+					Kind: &structType.Value_NumberValue{NumberValue: 1},
+				},
+			},
+		},
+	}
+}
+
+// Returns check which results are taken in order, last result is repeated
+func checkSequence(calls *int, results ...*checkResultMock) func() job.CheckError {
+	return func() job.CheckError {
+		result := results[len(results)-1]
+		if *calls < len(results) {
+			result = results[*calls]
+		}
+		*calls++
+		return result
+	}
+}
+
+func newPolicyExecutor(sleeps *[]time.Duration) *executor {
+	return &executor{
+		sleep: func(d time.Duration) {
+			*sleeps = append(*sleeps, d)
+		},
+		flapStates: map[string]*flapState{},
+	}
+}
+
+var (
+	checkOk     = &checkResultMock{code: apiPb.SchedulerCode_OK}
+	checkFailed = &checkResultMock{code: apiPb.SchedulerCode_ERROR, message: "timeout"}
+)
+
+func TestExecutor_withPolicy(t *testing.T) {
+	t.Run("Should: return check result as is without policies", func(t *testing.T) {
+		sleeps := []time.Duration{}
+		calls := 0
+		e := newPolicyExecutor(&sleeps)
+		res := e.withPolicy(context.Background(), "id", &scheduler_config_storage.SchedulerConfig{}, checkSequence(&calls, checkFailed))
+		assert.Equal(t, checkFailed, res)
+		assert.Equal(t, 1, calls)
+	})
+	t.Run("Should: return result of content check as is", func(t *testing.T) {
+		sleeps := []time.Duration{}
+		calls := 0
+		e := newPolicyExecutor(&sleeps)
+		res := e.withPolicy(context.Background(), "id", &scheduler_config_storage.SchedulerConfig{
+			Type: monitoring_api.SchedulerTypeContent,
+			Retry: &scheduler_config_storage.RetryPolicy{
+				Attempts: 3,
+			},
+			Flap: &scheduler_config_storage.FlapPolicy{
+				ConfirmAfter: 2,
+				RecoverAfter: 1,
+			},
+		}, checkSequence(&calls, checkFailed, checkOk))
+		assert.Equal(t, checkFailed, res)
+		assert.Equal(t, 1, calls)
+	})
+	t.Run("Should: retry failed check with backoff until success", func(t *testing.T) {
+		sleeps := []time.Duration{}
+		calls := 0
+		e := newPolicyExecutor(&sleeps)
+		res := e.withPolicy(context.Background(), "id", &scheduler_config_storage.SchedulerConfig{
+			Retry: &scheduler_config_storage.RetryPolicy{
+				Attempts:          4,
+				BackoffMs:         100,
+				BackoffMultiplier: 2,
+			},
+		}, checkSequence(&calls, checkFailed, checkFailed, checkOk))
+		snapshot := res.GetLogData().GetSnapshot()
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, sleeps)
+		assert.Equal(t, apiPb.SchedulerCode_OK, snapshot.Code)
+		assert.Nil(t, snapshot.Error)
+		assert.Equal(t, &monitoring_api.CheckPolicyState{
+			Attempts: []*monitoring_api.CheckAttempt{
+				{Code: apiPb.SchedulerCode_ERROR, Message: "timeout"},
+				{Code: apiPb.SchedulerCode_ERROR, Message: "timeout"},
+				{Code: apiPb.SchedulerCode_OK},
+			},
+			RawCode:              apiPb.SchedulerCode_OK,
+			ConfirmedCode:        apiPb.SchedulerCode_OK,
+			ConsecutiveSuccesses: 1,
+		}, monitoring_api.CheckPolicyStateFromMetaValue(snapshot.Meta.Value))
+		assert.Equal(t, float64(1), snapshot.Meta.Value.GetStructValue().Fields[policyValueKey].GetNumberValue())
+	})
+	t.Run("Should: return error after all attempts failed", func(t *testing.T) {
+		sleeps := []time.Duration{}
+		calls := 0
+		e := newPolicyExecutor(&sleeps)
+		res := e.withPolicy(context.Background(), "id", &scheduler_config_storage.SchedulerConfig{
+			Retry: &scheduler_config_storage.RetryPolicy{
+				Attempts:  2,
+				BackoffMs: 100,
+			},
+		}, checkSequence(&calls, checkFailed))
+		snapshot := res.GetLogData().GetSnapshot()
+		assert.Equal(t, 2, calls)
+		assert.Equal(t, []time.Duration{100 * time.Millisecond}, sleeps)
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+		assert.Equal(t, "timeout", snapshot.Error.Message)
+	})
+	t.Run("Should: confirm failure after consecutive failed runs", func(t *testing.T) {
+		sleeps := []time.Duration{}
+		calls := 0
+		e := newPolicyExecutor(&sleeps)
+		config := &scheduler_config_storage.SchedulerConfig{
+			Flap: &scheduler_config_storage.FlapPolicy{
+				ConfirmAfter: 2,
+				RecoverAfter: 2,
+			},
+		}
+		check := checkSequence(&calls, checkFailed, checkFailed, checkOk, checkOk)
+
+		first := e.withPolicy(context.Background(), "id", config, check).GetLogData().GetSnapshot()
+		assert.Equal(t, apiPb.SchedulerCode_OK, first.Code)
+		assert.Nil(t, first.Error)
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, monitoring_api.CheckPolicyStateFromMetaValue(first.Meta.Value).RawCode)
+
+		second := e.withPolicy(context.Background(), "id", config, check).GetLogData().GetSnapshot()
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, second.Code)
+		assert.Equal(t, "timeout", second.Error.Message)
+		assert.EqualValues(t, 2, monitoring_api.CheckPolicyStateFromMetaValue(second.Meta.Value).ConsecutiveFailures)
+
+		third := e.withPolicy(context.Background(), "id", config, check).GetLogData().GetSnapshot()
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, third.Code)
+		assert.Equal(t, "timeout", third.Error.Message)
+
+		fourth := e.withPolicy(context.Background(), "id", config, check).GetLogData().GetSnapshot()
+		assert.Equal(t, apiPb.SchedulerCode_OK, fourth.Code)
+		assert.Nil(t, fourth.Error)
+	})
+	t.Run("Should: keep state of schedulers separately", func(t *testing.T) {
+		sleeps := []time.Duration{}
+		calls := 0
+		e := newPolicyExecutor(&sleeps)
+		config := &scheduler_config_storage.SchedulerConfig{
+			Flap: &scheduler_config_storage.FlapPolicy{
+				ConfirmAfter: 2,
+				RecoverAfter: 1,
+			},
+		}
+		check := checkSequence(&calls, checkFailed)
+		_ = e.withPolicy(context.Background(), "first", config, check)
+		snapshot := e.withPolicy(context.Background(), "second", config, check).GetLogData().GetSnapshot()
+		assert.Equal(t, apiPb.SchedulerCode_OK, snapshot.Code)
+	})
+	t.Run("Should: not retry when backoff ends after deadline", func(t *testing.T) {
+		sleeps := []time.Duration{}
+		calls := 0
+		e := newPolicyExecutor(&sleeps)
+		config := &scheduler_config_storage.SchedulerConfig{
+			Retry: &scheduler_config_storage.RetryPolicy{
+				Attempts:  3,
+				BackoffMs: 1000,
+			},
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*500)
+		defer cancel()
+		res := e.withPolicy(ctx, "id", config, checkSequence(&calls, checkFailed)).GetLogData().GetSnapshot()
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, res.Code)
+		assert.Equal(t, 1, calls)
+		assert.Empty(t, sleeps)
+	})
+	t.Run("Should: forget state of reset scheduler", func(t *testing.T) {
+		sleeps := []time.Duration{}
+		calls := 0
+		e := newPolicyExecutor(&sleeps)
+		config := &scheduler_config_storage.SchedulerConfig{
+			Flap: &scheduler_config_storage.FlapPolicy{
+				ConfirmAfter: 2,
+				RecoverAfter: 1,
+			},
+		}
+		id := primitive.NewObjectID()
+		check := checkSequence(&calls, checkFailed)
+		_ = e.withPolicy(context.Background(), id.Hex(), config, check)
+		e.Reset(id)
+		assert.Empty(t, e.flapStates)
+		snapshot := e.withPolicy(context.Background(), id.Hex(), config, check).GetLogData().GetSnapshot()
+		assert.Equal(t, apiPb.SchedulerCode_OK, snapshot.Code)
+	})
+}
+
+func TestWithPolicyState(t *testing.T) {
+	state := &monitoring_api.CheckPolicyState{
+		Attempts: []*monitoring_api.CheckAttempt{},
+	}
+	t.Run("Should: add policy state to struct value", func(t *testing.T) {
+		value := withPolicyState(&structType.Value{
+			Kind: &structType.Value_StructValue{
+				StructValue: &structType.Struct{
+					Fields: map[string]*structType.Value{
+						"exitCode": {Kind: &structType.Value_NumberValue{NumberValue: 0}},
+					},
+				},
+			},
+		}, state)
+		assert.NotNil(t, value.GetStructValue().Fields["exitCode"])
+		assert.Equal(t, state, monitoring_api.CheckPolicyStateFromMetaValue(value))
+	})
+	t.Run("Should: return policy state without value", func(t *testing.T) {
+		value := withPolicyState(nil, state)
+		assert.Len(t, value.GetStructValue().Fields, 1)
+		assert.Equal(t, state, monitoring_api.CheckPolicyStateFromMetaValue(value))
+	})
+}
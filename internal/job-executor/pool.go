@@ -0,0 +1,86 @@
+package job_executor
+
+import (
+	"squzy/internal/job"
+	"sync"
+)
+
+// Process-wide limit of check executions at same time
+type WorkerPool interface {
+	// Blocks until worker is free
+	Acquire()
+	Release()
+	// Executions which wait for free worker
+	GetQueueDepth() int32
+	GetRunning() int32
+	// 0 when executions are not limited
+	GetSize() int32
+}
+
+type workerPool struct {
+	mutex   sync.Mutex
+	size    int32
+	running int32
+	waiting int32
+	workers chan struct{}
+}
+
+// Pool with size 0 does not limit executions, but counts them
+func NewWorkerPool(size int) WorkerPool {
+	pool := &workerPool{
+		size: int32(size),
+	}
+	if size > 0 {
+		pool.workers = make(chan struct{}, size)
+	}
+	return pool
+}
+
+func (p *workerPool) Acquire() {
+	if p.workers != nil {
+		p.mutex.Lock()
+		p.waiting++
+		p.mutex.Unlock()
+		p.workers <- struct{}{}
+		p.mutex.Lock()
+		p.waiting--
+		p.mutex.Unlock()
+	}
+	p.mutex.Lock()
+	p.running++
+	p.mutex.Unlock()
+}
+
+func (p *workerPool) Release() {
+	p.mutex.Lock()
+	p.running--
+	p.mutex.Unlock()
+	if p.workers != nil {
+		<-p.workers
+	}
+}
+
+func (p *workerPool) GetQueueDepth() int32 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.waiting
+}
+
+func (p *workerPool) GetRunning() int32 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.running
+}
+
+func (p *workerPool) GetSize() int32 {
+	return p.size
+}
+
+// Check holds worker of pool while it is executed
+func withWorker(pool WorkerPool, check func() job.CheckError) func() job.CheckError {
+	return func() job.CheckError {
+		pool.Acquire()
+		defer pool.Release()
+		return check()
+	}
+}
@@ -0,0 +1,73 @@
+package job_executor
+
+import (
+	"context"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/stretchr/testify/assert"
+	"squzy/internal/job"
+	"testing"
+	"time"
+)
+
+func TestNewWorkerPool(t *testing.T) {
+	t.Run("Should: implement interface", func(t *testing.T) {
+		assert.Implements(t, (*WorkerPool)(nil), NewWorkerPool(1))
+	})
+}
+
+func TestWorkerPool_Acquire(t *testing.T) {
+	t.Run("Should: count running executions without limit", func(t *testing.T) {
+		pool := NewWorkerPool(0)
+		pool.Acquire()
+		pool.Acquire()
+		assert.Equal(t, int32(2), pool.GetRunning())
+		assert.Equal(t, int32(0), pool.GetQueueDepth())
+		assert.Equal(t, int32(0), pool.GetSize())
+		pool.Release()
+		pool.Release()
+		assert.Equal(t, int32(0), pool.GetRunning())
+	})
+	t.Run("Should: wait for free worker", func(t *testing.T) {
+		pool := NewWorkerPool(1)
+		pool.Acquire()
+		acquired := make(chan bool)
+		go func() {
+			pool.Acquire()
+			acquired <- true
+		}()
+		assert.Eventually(t, func() bool {
+			return pool.GetQueueDepth() == 1
+		}, time.Second, time.Millisecond)
+		assert.Equal(t, int32(1), pool.GetRunning())
+		pool.Release()
+		<-acquired
+		assert.Equal(t, int32(0), pool.GetQueueDepth())
+		assert.Equal(t, int32(1), pool.GetRunning())
+		pool.Release()
+	})
+}
+
+func TestWithWorker(t *testing.T) {
+	t.Run("Should: hold worker while check is executed", func(t *testing.T) {
+		pool := NewWorkerPool(1)
+		res := withWorker(pool, func() job.CheckError {
+			assert.Equal(t, int32(1), pool.GetRunning())
+			return checkOk
+		})()
+		assert.Equal(t, checkOk, res)
+		assert.Equal(t, int32(0), pool.GetRunning())
+	})
+	t.Run("Should: release worker when check is abandoned by deadline", func(t *testing.T) {
+		pool := NewWorkerPool(1)
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+		defer cancel()
+		release := make(chan bool)
+		res := withWorker(pool, withDeadline(ctx, "id", apiPb.SchedulerType_HTTP, func() job.CheckError {
+			<-release
+			return checkOk
+		}))()
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, res.GetLogData().Snapshot.Code)
+		assert.Equal(t, int32(0), pool.GetRunning())
+		close(release)
+	})
+}
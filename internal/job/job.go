@@ -2,6 +2,8 @@ package job
 
 import (
 	"errors"
+	"fmt"
+	structType "github.com/golang/protobuf/ptypes/struct"
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
 )
 
@@ -9,8 +11,44 @@ var (
 	errGrpcNotServing          = errors.New("STATUS_NOT_SERVING")
 	errConnTimeoutError        = errors.New("CONNECTION_TIMEOUT")
 	errWrongConnectConfigError = errors.New("WRONG_CONNECTION_CONFIGURATION")
+	invalidPatternErrorFn      = func(pattern string, err error) error {
+		return fmt.Errorf("invalid pattern %s: %s", pattern, err.Error())
+	}
 )
 
 type CheckError interface {
 	GetLogData() *apiPb.SchedulerResponse
 }
+
+func stringValue(value string) *structType.Value {
+	return &structType.Value{
+		Kind: &structType.Value_StringValue{
+			StringValue: value,
+		},
+	}
+}
+
+func numberValue(value float64) *structType.Value {
+	return &structType.Value{
+		Kind: &structType.Value_NumberValue{
+			NumberValue: value,
+		},
+	}
+}
+
+func boolValue(value bool) *structType.Value {
+	return &structType.Value{
+		Kind: &structType.Value_BoolValue{
+			BoolValue: value,
+		},
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	structType "github.com/golang/protobuf/ptypes/struct"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"squzy/internal/helpers"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"strings"
+)
+
+const (
+	// Output is not saved further than that size
+	maxCommandOutputSize = 64 * 1024
+)
+
+var (
+	errCommandNotAllowed    = errors.New("COMMAND_NOT_ALLOWED")
+	errCommandEnvNotAllowed = errors.New("COMMAND_ENV_NOT_ALLOWED")
+	errCommandDirNotAllowed = errors.New("COMMAND_DIR_NOT_ALLOWED")
+	errCommandTimeout       = errors.New("command timed out")
+	commandExitErrorFn      = func(code int) error {
+		return fmt.Errorf("command exited with code %d", code)
+	}
+	commandInvalidJSONErrorFn = func(err error) error {
+		return fmt.Errorf("invalid json output: %s", err.Error())
+	}
+)
+
+type commandError struct {
+	schedulerID string
+	startTime   *timestamp.Timestamp
+	endTime     *timestamp.Timestamp
+	code        apiPb.SchedulerCode
+	description string
+	value       *structType.Value
+}
+
+func (e *commandError) GetLogData() *apiPb.SchedulerResponse {
+	var err *apiPb.SchedulerSnapshot_Error
+	if e.code != apiPb.SchedulerCode_OK {
+		err = &apiPb.SchedulerSnapshot_Error{
+			Message: e.description,
+		}
+	}
+	return &apiPb.SchedulerResponse{
+		SchedulerId: e.schedulerID,
+		Snapshot: &apiPb.SchedulerSnapshot{
+			Code:  e.code,
+			Error: err,
+			Type:  monitoring_api.SchedulerTypeCommand,
+			Meta: &apiPb.SchedulerSnapshot_MetaData{
+				StartTime: e.startTime,
+				EndTime:   e.endTime,
+				Value:     e.value,
+			},
+		},
+	}
+}
+
+func newCommandError(schedulerID string, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string, value *structType.Value) CheckError {
+	return &commandError{
+		schedulerID: schedulerID,
+		startTime:   startTime,
+		endTime:     endTime,
+		code:        code,
+		description: description,
+		value:       value,
+	}
+}
+
+// Output buffer which drops everything after limit
+type limitedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if rest := maxCommandOutputSize - b.Len(); rest > 0 {
+		if len(p) > rest {
+			_, _ = b.Buffer.Write(p[:rest])
+		} else {
+			_, _ = b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// Returns command check which runs only executables inside of allowed directories,
+// commands are disabled when directories are not set
+func NewExecCommand(allowedDirs []string) func(schedulerID string, timeout int32, config *scheduler_config_storage.CommandConfig) CheckError {
+	return func(schedulerID string, timeout int32, config *scheduler_config_storage.CommandConfig) CheckError {
+		return execCommand(schedulerID, timeout, config, allowedDirs)
+	}
+}
+
+func execCommand(schedulerID string, timeout int32, config *scheduler_config_storage.CommandConfig, allowedDirs []string) CheckError {
+	startTime := ptypes.TimestampNow()
+	if !commandAllowed(config.Path, allowedDirs) {
+		return newCommandError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errCommandNotAllowed.Error(), nil)
+	}
+	if !commandEnvAllowed(config.Env) {
+		return newCommandError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errCommandEnvNotAllowed.Error(), nil)
+	}
+	if config.Dir != "" && !commandDirAllowed(config.Dir, allowedDirs) {
+		return newCommandError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errCommandDirNotAllowed.Error(), nil)
+	}
+
+	ctx, cancel := helpers.TimeoutContext(context.Background(), helpers.DurationFromSecond(timeout))
+	defer cancel()
+	cmd := exec.CommandContext(ctx, config.Path, config.Args...)
+	cmd.Dir = config.Dir
+	// Environment of squzy_monitoring has credentials, so only PATH is inherited
+	cmd.Env = []string{"PATH=" + os.Getenv("PATH")}
+	for name, value := range config.Env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	stdout, stderr := &limitedBuffer{}, &limitedBuffer{}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return newCommandError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errCommandTimeout.Error(), nil)
+	}
+	exitCode := 0
+	if err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			return newCommandError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), nil)
+		}
+		exitCode = exitErr.ExitCode()
+	}
+
+	output := stringValue(stdout.String())
+	if config.ParseJSON {
+		output = &structType.Value{}
+		err = protojson.Unmarshal(stdout.Bytes(), output)
+		if err != nil {
+			return newCommandError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, commandInvalidJSONErrorFn(err).Error(), nil)
+		}
+	}
+	value := &structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{
+				Fields: map[string]*structType.Value{
+					"exitCode": numberValue(float64(exitCode)),
+					"output":   output,
+				},
+			},
+		},
+	}
+	code := commandCode(exitCode)
+	if code == apiPb.SchedulerCode_OK {
+		return newCommandError(schedulerID, startTime, ptypes.TimestampNow(), code, "", value)
+	}
+	return newCommandError(schedulerID, startTime, ptypes.TimestampNow(), code, commandMessage(exitCode, stdout.String(), stderr.String()), value)
+}
+
+// Symlinks are resolved, so link inside of allowed directory can not point outside of it
+func commandAllowed(path string, allowedDirs []string) bool {
+	return insideAllowedDirs(path, allowedDirs, false)
+}
+
+// Working directory is allowed to be one of allowed directories or inside of them
+func commandDirAllowed(dir string, allowedDirs []string) bool {
+	return insideAllowedDirs(dir, allowedDirs, true)
+}
+
+// Loader variables and PATH could replace executable or its libraries
+func commandEnvAllowed(env map[string]string) bool {
+	for name := range env {
+		upperName := strings.ToUpper(name)
+		if upperName == "PATH" || strings.HasPrefix(upperName, "LD_") {
+			return false
+		}
+	}
+	return true
+}
+
+func insideAllowedDirs(path string, allowedDirs []string, allowSame bool) bool {
+	if !filepath.IsAbs(path) {
+		return false
+	}
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	for _, dir := range allowedDirs {
+		if dir == "" {
+			continue
+		}
+		dir, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if rel != "." || allowSame {
+			return true
+		}
+	}
+	return false
+}
+
+func commandCode(exitCode int) apiPb.SchedulerCode {
+	switch exitCode {
+	case 0:
+		return apiPb.SchedulerCode_OK
+	case 1:
+		return monitoring_api.SchedulerCodeWarning
+	case 2:
+		return apiPb.SchedulerCode_ERROR
+	default:
+		return monitoring_api.SchedulerCodeUnknown
+	}
+}
+
+// Stderr is used as message, nagios plugins write status to first line of stdout instead
+func commandMessage(exitCode int, stdout string, stderr string) string {
+	if message := strings.TrimSpace(stderr); message != "" {
+		return message
+	}
+	if message := strings.TrimSpace(strings.SplitN(stdout, "\n", 2)[0]); message != "" {
+		return message
+	}
+	return commandExitErrorFn(exitCode).Error()
+}
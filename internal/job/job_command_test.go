@@ -0,0 +1,116 @@
+package job
+
+import (
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"strings"
+	"testing"
+)
+
+const commandScript = `#!/bin/sh
+case "$1" in
+ok) echo "OK - disk 42% used"; exit 0 ;;
+warning) echo "WARNING - disk 85% used"; exit 1 ;;
+critical) echo "CRITICAL - disk 99% used" >&2; exit 2 ;;
+unknown) exit 3 ;;
+json) echo '{"used": 42, "mount": "/"}'; exit 0 ;;
+env) echo "$CHECK_MOUNT"; exit 0 ;;
+sleep) exec sleep 5 ;;
+esac
+`
+
+func TestNewExecCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "squzy_command")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "check_disk")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(commandScript), 0700))
+	exec := NewExecCommand([]string{dir})
+
+	t.Run("Should: map exit code to scheduler code", func(t *testing.T) {
+		for arg, code := range map[string]apiPb.SchedulerCode{
+			"ok":       apiPb.SchedulerCode_OK,
+			"warning":  monitoring_api.SchedulerCodeWarning,
+			"critical": apiPb.SchedulerCode_ERROR,
+			"unknown":  monitoring_api.SchedulerCodeUnknown,
+		} {
+			job := exec("", 5, &scheduler_config_storage.CommandConfig{Path: path, Args: []string{arg}})
+			assert.Equal(t, code, job.GetLogData().Snapshot.Code, arg)
+			assert.Equal(t, monitoring_api.SchedulerTypeCommand, job.GetLogData().Snapshot.Type)
+		}
+	})
+	t.Run("Should: return output and exit code", func(t *testing.T) {
+		job := exec("", 5, &scheduler_config_storage.CommandConfig{Path: path, Args: []string{"ok"}})
+		fields := job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields
+		assert.Equal(t, float64(0), fields["exitCode"].GetNumberValue())
+		assert.Equal(t, "OK - disk 42% used\n", fields["output"].GetStringValue())
+		assert.Nil(t, job.GetLogData().Snapshot.Error)
+	})
+	t.Run("Should: return message from stderr or first line of stdout", func(t *testing.T) {
+		job := exec("", 5, &scheduler_config_storage.CommandConfig{Path: path, Args: []string{"critical"}})
+		assert.Equal(t, "CRITICAL - disk 99% used", job.GetLogData().Snapshot.Error.Message)
+		job = exec("", 5, &scheduler_config_storage.CommandConfig{Path: path, Args: []string{"warning"}})
+		assert.Equal(t, "WARNING - disk 85% used", job.GetLogData().Snapshot.Error.Message)
+		job = exec("", 5, &scheduler_config_storage.CommandConfig{Path: path, Args: []string{"unknown"}})
+		assert.Equal(t, commandExitErrorFn(3).Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: parse json output", func(t *testing.T) {
+		job := exec("", 5, &scheduler_config_storage.CommandConfig{Path: path, Args: []string{"json"}, ParseJSON: true})
+		output := job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields["output"].GetStructValue()
+		assert.Equal(t, float64(42), output.Fields["used"].GetNumberValue())
+		assert.Equal(t, "/", output.Fields["mount"].GetStringValue())
+	})
+	t.Run("Should: return error because output is not json", func(t *testing.T) {
+		job := exec("", 5, &scheduler_config_storage.CommandConfig{Path: path, Args: []string{"ok"}, ParseJSON: true})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		assert.True(t, strings.HasPrefix(job.GetLogData().Snapshot.Error.Message, "invalid json output"))
+	})
+	t.Run("Should: pass env to command", func(t *testing.T) {
+		job := exec("", 5, &scheduler_config_storage.CommandConfig{Path: path, Args: []string{"env"}, Env: map[string]string{"CHECK_MOUNT": "/var"}})
+		fields := job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields
+		assert.Equal(t, "/var\n", fields["output"].GetStringValue())
+	})
+	t.Run("Should: return error because command timed out", func(t *testing.T) {
+		job := exec("", 1, &scheduler_config_storage.CommandConfig{Path: path, Args: []string{"sleep"}})
+		assert.Equal(t, errCommandTimeout.Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: return error because command is not allowed", func(t *testing.T) {
+		for _, commandPath := range []string{"check_disk", "/bin/sh", dir, filepath.Join(dir, "..", "check_disk")} {
+			job := exec("", 5, &scheduler_config_storage.CommandConfig{Path: commandPath})
+			assert.Equal(t, errCommandNotAllowed.Error(), job.GetLogData().Snapshot.Error.Message, commandPath)
+		}
+		job := NewExecCommand([]string{})("", 5, &scheduler_config_storage.CommandConfig{Path: path, Args: []string{"ok"}})
+		assert.Equal(t, errCommandNotAllowed.Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: return error because command is symlink outside of allowed directory", func(t *testing.T) {
+		link := filepath.Join(dir, "check_shell")
+		assert.Nil(t, os.Symlink("/bin/sh", link))
+		job := exec("", 5, &scheduler_config_storage.CommandConfig{Path: link})
+		assert.Equal(t, errCommandNotAllowed.Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: return error because env overrides path or loader", func(t *testing.T) {
+		for _, name := range []string{"PATH", "LD_PRELOAD", "LD_LIBRARY_PATH", "ld_preload"} {
+			job := exec("", 5, &scheduler_config_storage.CommandConfig{Path: path, Args: []string{"ok"}, Env: map[string]string{name: dir}})
+			assert.Equal(t, errCommandEnvNotAllowed.Error(), job.GetLogData().Snapshot.Error.Message, name)
+		}
+	})
+	t.Run("Should: run command in allowed working directory", func(t *testing.T) {
+		job := exec("", 5, &scheduler_config_storage.CommandConfig{Path: path, Args: []string{"ok"}, Dir: dir})
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+	})
+	t.Run("Should: return error because working directory is not allowed", func(t *testing.T) {
+		for _, commandDir := range []string{"/", "tmp", filepath.Join(dir, "..")} {
+			job := exec("", 5, &scheduler_config_storage.CommandConfig{Path: path, Args: []string{"ok"}, Dir: commandDir})
+			assert.Equal(t, errCommandDirNotAllowed.Error(), job.GetLogData().Snapshot.Error.Message, commandDir)
+		}
+	})
+	t.Run("Should: return error because command not exist", func(t *testing.T) {
+		job := exec("", 5, &scheduler_config_storage.CommandConfig{Path: filepath.Join(dir, "missing")})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+	})
+}
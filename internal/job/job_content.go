@@ -0,0 +1,188 @@
+package job
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	structType "github.com/golang/protobuf/ptypes/struct"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/tidwall/gjson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/net/html"
+	"net/http"
+	"regexp"
+	"squzy/internal/helpers"
+	"squzy/internal/httptools"
+	monitoring_api "squzy/internal/monitoring-api"
+	"squzy/internal/parsers"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"strings"
+)
+
+var (
+	errInvalidContentRegionType  = errors.New("INVALID_CONTENT_REGION_TYPE")
+	errContentChanged            = errors.New("content changed")
+	contentRegionNotExistErrorFn = func(path string) error {
+		return fmt.Errorf("content region by path=`%s` not exist", path)
+	}
+	contentHashSaveErrorFn = func(err error) error {
+		return fmt.Errorf("failed to save hash of content: %s", err.Error())
+	}
+)
+
+type contentError struct {
+	schedulerID string
+	startTime   *timestamp.Timestamp
+	endTime     *timestamp.Timestamp
+	code        apiPb.SchedulerCode
+	description string
+	value       *structType.Value
+}
+
+func (e *contentError) GetLogData() *apiPb.SchedulerResponse {
+	var err *apiPb.SchedulerSnapshot_Error
+	if e.code == apiPb.SchedulerCode_ERROR {
+		err = &apiPb.SchedulerSnapshot_Error{
+			Message: e.description,
+		}
+	}
+	return &apiPb.SchedulerResponse{
+		SchedulerId: e.schedulerID,
+		Snapshot: &apiPb.SchedulerSnapshot{
+			Code:  e.code,
+			Error: err,
+			Type:  monitoring_api.SchedulerTypeContent,
+			Meta: &apiPb.SchedulerSnapshot_MetaData{
+				StartTime: e.startTime,
+				EndTime:   e.endTime,
+				Value:     e.value,
+			},
+		},
+	}
+}
+
+func newContentError(schedulerID string, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string, value *structType.Value) CheckError {
+	return &contentError{
+		schedulerID: schedulerID,
+		startTime:   startTime,
+		endTime:     endTime,
+		code:        code,
+		description: description,
+		value:       value,
+	}
+}
+
+// Hashes content and compares it with hash of previous run, new hash is saved to config storage.
+// Check fails once when content is changed, first run only remembers hash
+func ExecContent(schedulerID string, timeout int32, config *scheduler_config_storage.ContentConfig, httpTool httptools.HTTPTool, configStorage scheduler_config_storage.Storage) CheckError {
+	startTime := ptypes.TimestampNow()
+	ignore := []*regexp.Regexp{}
+	for _, pattern := range config.Ignore {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return newContentError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, invalidPatternErrorFn(pattern, err).Error(), nil)
+		}
+		ignore = append(ignore, re)
+	}
+
+	req := httpTool.CreateRequest(config.Method, config.URL, &config.Headers, schedulerID)
+	req, timings := httptools.WithTimings(httptools.WithClientConfig(req, httpClientConfig(config.Client)))
+	_, data, err := httpTool.SendRequestTimeoutStatusCode(req, helpers.DurationFromSecond(timeout), http.StatusOK)
+	if err != nil {
+		return newContentError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), httpMetaValue(nil, timings))
+	}
+
+	content, err := contentRegion(config.Region, data)
+	if err != nil {
+		return newContentError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), httpMetaValue(nil, timings))
+	}
+	for _, re := range ignore {
+		content = re.ReplaceAllString(content, "")
+	}
+	sum := sha256.Sum256([]byte(content))
+	hash := hex.EncodeToString(sum[:])
+
+	status := monitoring_api.ContentStatusUnchanged
+	if config.LastHash != "" && config.LastHash != hash {
+		status = monitoring_api.ContentStatusChanged
+	}
+	value := httpMetaValue(&structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{
+				Fields: map[string]*structType.Value{
+					"status":       stringValue(string(status)),
+					"hash":         stringValue(hash),
+					"previousHash": stringValue(config.LastHash),
+				},
+			},
+		},
+	}, timings)
+
+	if hash != config.LastHash {
+		err = saveContentHash(schedulerID, timeout, hash, configStorage)
+		if err != nil {
+			return newContentError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, contentHashSaveErrorFn(err).Error(), value)
+		}
+	}
+	if status == monitoring_api.ContentStatusChanged {
+		return newContentError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errContentChanged.Error(), value)
+	}
+	return newContentError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", value)
+}
+
+// Returns markup of selected html elements, text of selected attributes or raw json of selected value
+func contentRegion(region *scheduler_config_storage.ContentRegion, data []byte) (string, error) {
+	if region == nil {
+		return string(data), nil
+	}
+	var nodes []*html.Node
+	switch region.Type {
+	case monitoring_api.ContentRegionJSON:
+		res := gjson.GetBytes(data, region.Path)
+		if !res.Exists() {
+			return "", contentRegionNotExistErrorFn(region.Path)
+		}
+		return res.Raw, nil
+	case monitoring_api.ContentRegionCSS, monitoring_api.ContentRegionXPath:
+		root, err := parsers.ParseHTML(data)
+		if err != nil {
+			return "", err
+		}
+		if region.Type == monitoring_api.ContentRegionCSS {
+			nodes, err = parsers.SelectCSS(root, region.Path)
+		} else {
+			nodes, err = parsers.SelectXPath(root, region.Path)
+		}
+		if err != nil {
+			return "", err
+		}
+	default:
+		return "", errInvalidContentRegionType
+	}
+	if len(nodes) == 0 {
+		return "", contentRegionNotExistErrorFn(region.Path)
+	}
+	parts := []string{}
+	for _, node := range nodes {
+		part, err := parsers.RenderNode(node)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+func saveContentHash(schedulerID string, timeout int32, hash string, configStorage scheduler_config_storage.Storage) error {
+	id, err := primitive.ObjectIDFromHex(schedulerID)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := helpers.TimeoutContext(context.Background(), helpers.DurationFromSecond(timeout))
+	defer cancel()
+	return configStorage.SetContentHash(ctx, id, hash)
+}
@@ -0,0 +1,167 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/http/httptest"
+	"squzy/internal/httptools"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"testing"
+)
+
+type contentStorageMock struct {
+	scheduler_config_storage.Storage
+	hashes map[primitive.ObjectID]string
+	err    error
+}
+
+func (m *contentStorageMock) SetContentHash(ctx context.Context, schedulerID primitive.ObjectID, hash string) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.hashes[schedulerID] = hash
+	return nil
+}
+
+func newContentStorageMock() *contentStorageMock {
+	return &contentStorageMock{hashes: map[primitive.ObjectID]string{}}
+}
+
+const contentFixture = `<html><body>
+<div id="terms"><h1>Terms</h1><p class="updated">Rendered at 2020-06-01 10:00:00</p><a href="/privacy">Privacy</a></div>
+<footer>Request id 7f3a</footer>
+</body></html>`
+
+func contentStatus(job CheckError) string {
+	value := job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields[httpMetaValueKey]
+	return value.GetStructValue().Fields["status"].GetStringValue()
+}
+
+func contentHash(job CheckError) string {
+	value := job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields[httpMetaValueKey]
+	return value.GetStructValue().Fields["hash"].GetStringValue()
+}
+
+func TestExecContent(t *testing.T) {
+	page := contentFixture
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/json" {
+			_, _ = fmt.Fprint(w, `{"version": 3, "terms": {"text": "Be nice"}}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, page)
+	}))
+	defer server.Close()
+
+	t.Run("Should: remember hash on first run and detect change on next run", func(t *testing.T) {
+		page = contentFixture
+		storage := newContentStorageMock()
+		id := primitive.NewObjectID()
+		config := &scheduler_config_storage.ContentConfig{URL: server.URL}
+
+		job := ExecContent(id.Hex(), 1, config, httptools.New(""), storage)
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		assert.Equal(t, monitoring_api.SchedulerTypeContent, job.GetLogData().Snapshot.Type)
+		assert.Equal(t, string(monitoring_api.ContentStatusUnchanged), contentStatus(job))
+		assert.Equal(t, contentHash(job), storage.hashes[id])
+
+		config.LastHash = storage.hashes[id]
+		job = ExecContent(id.Hex(), 1, config, httptools.New(""), storage)
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		assert.Equal(t, string(monitoring_api.ContentStatusUnchanged), contentStatus(job))
+
+		page = contentFixture + "<script>defaced()</script>"
+		job = ExecContent(id.Hex(), 1, config, httptools.New(""), storage)
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		assert.Equal(t, errContentChanged.Error(), job.GetLogData().Snapshot.Error.Message)
+		assert.Equal(t, string(monitoring_api.ContentStatusChanged), contentStatus(job))
+		assert.NotEqual(t, config.LastHash, storage.hashes[id])
+	})
+	t.Run("Should: hash only region without ignored fragments", func(t *testing.T) {
+		for _, region := range []*scheduler_config_storage.ContentRegion{
+			{Type: monitoring_api.ContentRegionCSS, Path: "#terms"},
+			{Type: monitoring_api.ContentRegionXPath, Path: "//div[@id='terms']"},
+		} {
+			page = contentFixture
+			config := &scheduler_config_storage.ContentConfig{
+				URL:    server.URL,
+				Region: region,
+				Ignore: []string{`\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}`},
+			}
+			job := ExecContent(primitive.NewObjectID().Hex(), 1, config, httptools.New(""), newContentStorageMock())
+			config.LastHash = contentHash(job)
+
+			page = `<html><body>
+<div id="terms"><h1>Terms</h1><p class="updated">Rendered at 2020-06-02 11:30:00</p><a href="/privacy">Privacy</a></div>
+<footer>Request id 9c1b</footer>
+</body></html>`
+			job = ExecContent(primitive.NewObjectID().Hex(), 1, config, httptools.New(""), newContentStorageMock())
+			assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+
+			page = `<html><body>
+<div id="terms"><h1>Terms</h1><p class="updated">Rendered at 2020-06-02 11:30:00</p><a href="https://evil.com">Privacy</a></div>
+</body></html>`
+			job = ExecContent(primitive.NewObjectID().Hex(), 1, config, httptools.New(""), newContentStorageMock())
+			assert.Equal(t, string(monitoring_api.ContentStatusChanged), contentStatus(job))
+		}
+	})
+	t.Run("Should: hash json region", func(t *testing.T) {
+		job := ExecContent(primitive.NewObjectID().Hex(), 1, &scheduler_config_storage.ContentConfig{
+			URL:    server.URL + "/json",
+			Region: &scheduler_config_storage.ContentRegion{Type: monitoring_api.ContentRegionJSON, Path: "terms"},
+		}, httptools.New(""), newContentStorageMock())
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+	})
+	t.Run("Should: return error because region not exist", func(t *testing.T) {
+		for _, region := range []*scheduler_config_storage.ContentRegion{
+			{Type: monitoring_api.ContentRegionCSS, Path: "#missing"},
+			{Type: monitoring_api.ContentRegionJSON, Path: "missing"},
+		} {
+			page = contentFixture
+			job := ExecContent(primitive.NewObjectID().Hex(), 1, &scheduler_config_storage.ContentConfig{
+				URL:    server.URL,
+				Region: region,
+			}, httptools.New(""), newContentStorageMock())
+			assert.Equal(t, contentRegionNotExistErrorFn(region.Path).Error(), job.GetLogData().Snapshot.Error.Message)
+		}
+	})
+	t.Run("Should: return error because region is invalid", func(t *testing.T) {
+		job := ExecContent(primitive.NewObjectID().Hex(), 1, &scheduler_config_storage.ContentConfig{
+			URL:    server.URL,
+			Region: &scheduler_config_storage.ContentRegion{Type: "yaml", Path: "terms"},
+		}, httptools.New(""), newContentStorageMock())
+		assert.Equal(t, errInvalidContentRegionType.Error(), job.GetLogData().Snapshot.Error.Message)
+		job = ExecContent(primitive.NewObjectID().Hex(), 1, &scheduler_config_storage.ContentConfig{
+			URL:    server.URL,
+			Region: &scheduler_config_storage.ContentRegion{Type: monitoring_api.ContentRegionXPath, Path: "//div["},
+		}, httptools.New(""), newContentStorageMock())
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+	})
+	t.Run("Should: return error because ignore pattern is invalid", func(t *testing.T) {
+		job := ExecContent(primitive.NewObjectID().Hex(), 1, &scheduler_config_storage.ContentConfig{
+			URL:    server.URL,
+			Ignore: []string{"("},
+		}, httptools.New(""), newContentStorageMock())
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+	})
+	t.Run("Should: return error because hash is not saved", func(t *testing.T) {
+		storage := newContentStorageMock()
+		storage.err = errors.New("storage")
+		job := ExecContent(primitive.NewObjectID().Hex(), 1, &scheduler_config_storage.ContentConfig{
+			URL: server.URL,
+		}, httptools.New(""), storage)
+		assert.Equal(t, contentHashSaveErrorFn(storage.err).Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: return error because request failed", func(t *testing.T) {
+		job := ExecContent(primitive.NewObjectID().Hex(), 1, &scheduler_config_storage.ContentConfig{
+			URL: "http://localhost:1",
+		}, httptools.New(""), newContentStorageMock())
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+	})
+}
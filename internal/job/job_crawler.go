@@ -0,0 +1,339 @@
+package job
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	structType "github.com/golang/protobuf/ptypes/struct"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"golang.org/x/net/html"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"squzy/internal/helpers"
+	"squzy/internal/httptools"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"squzy/internal/semaphore"
+	"sync"
+)
+
+const (
+	defaultCrawlerMaxDepth = 2
+	defaultCrawlerMaxPages = 100
+	// Page is read up to that size, links after it are not followed
+	crawlerMaxBodySize int64 = 5 << 20
+)
+
+var (
+	errCrawlerInvalidURL    = errors.New("INVALID_CRAWLER_URL")
+	crawlerInvalidPatternFn = func(pattern string, err error) error {
+		return fmt.Errorf("invalid pattern %s: %s", pattern, err.Error())
+	}
+	crawlerBrokenLinksErrorFn = func(broken int, pages int) error {
+		return fmt.Errorf("%d of %d pages are broken", broken, pages)
+	}
+	crawlerWrongCodeErrorFn = func(code int) error {
+		return fmt.Errorf("wrong status code %d", code)
+	}
+)
+
+type crawlerError struct {
+	schedulerID string
+	startTime   *timestamp.Timestamp
+	endTime     *timestamp.Timestamp
+	code        apiPb.SchedulerCode
+	description string
+	value       *structType.Value
+}
+
+func (e *crawlerError) GetLogData() *apiPb.SchedulerResponse {
+	var err *apiPb.SchedulerSnapshot_Error
+	if e.code == apiPb.SchedulerCode_ERROR {
+		err = &apiPb.SchedulerSnapshot_Error{
+			Message: e.description,
+		}
+	}
+	return &apiPb.SchedulerResponse{
+		SchedulerId: e.schedulerID,
+		Snapshot: &apiPb.SchedulerSnapshot{
+			Code:  e.code,
+			Error: err,
+			Type:  monitoring_api.SchedulerTypeCrawler,
+			Meta: &apiPb.SchedulerSnapshot_MetaData{
+				StartTime: e.startTime,
+				EndTime:   e.endTime,
+				Value:     e.value,
+			},
+		},
+	}
+}
+
+func newCrawlerError(schedulerID string, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string, value *structType.Value) CheckError {
+	return &crawlerError{
+		schedulerID: schedulerID,
+		startTime:   startTime,
+		endTime:     endTime,
+		code:        code,
+		description: description,
+		value:       value,
+	}
+}
+
+// Page which should be requested, referrer is page where link was found
+type crawlerPage struct {
+	location string
+	referrer string
+}
+
+type crawlerBrokenLink struct {
+	location string
+	referrer string
+	code     int
+	err      string
+}
+
+type crawlerFilter struct {
+	origin  string
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+func ExecCrawler(schedulerID string, timeout int32, config *scheduler_config_storage.CrawlerConfig, httpTools httptools.HTTPTool, semaphoreFactoryFn func(n int) semaphore.Semaphore) CheckError {
+	startTime := ptypes.TimestampNow()
+
+	filter, err := newCrawlerFilter(config)
+	if err != nil {
+		return newCrawlerError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), nil)
+	}
+
+	maxDepth := int(config.MaxDepth)
+	if maxDepth <= 0 {
+		maxDepth = defaultCrawlerMaxDepth
+	}
+	maxPages := int(config.MaxPages)
+	if maxPages <= 0 {
+		maxPages = defaultCrawlerMaxPages
+	}
+	concurrency := int(config.Concurrency)
+	if concurrency <= 0 {
+		concurrency = maxPages
+	}
+
+	sem := semaphoreFactoryFn(concurrency)
+	clientConfig := httpClientConfig(config.Client)
+
+	visited := map[string]bool{config.URL: true}
+	level := []*crawlerPage{{location: config.URL}}
+	requested := 1
+	broken := []*crawlerBrokenLink{}
+
+	var mutex sync.Mutex
+
+	for depth := 0; len(level) > 0; depth++ {
+		next := []*crawlerPage{}
+		var wg sync.WaitGroup
+		for _, p := range level {
+			page := p
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				links, link := crawlPage(schedulerID, timeout, page, httpTools, clientConfig, sem, depth < maxDepth)
+
+				mutex.Lock()
+				defer mutex.Unlock()
+				if link != nil {
+					broken = append(broken, link)
+				}
+				for _, location := range links {
+					if requested >= maxPages || visited[location] || !filter.match(location) {
+						continue
+					}
+					visited[location] = true
+					requested++
+					next = append(next, &crawlerPage{location: location, referrer: page.location})
+				}
+			}()
+		}
+		wg.Wait()
+		level = next
+	}
+
+	value := crawlerMetaValue(requested, broken)
+	if len(broken) > 0 {
+		return newCrawlerError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, crawlerBrokenLinksErrorFn(len(broken), requested).Error(), value)
+	}
+	return newCrawlerError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", value)
+}
+
+// Requests page, returns links of page when they should be followed or broken link when page is not available
+func crawlPage(schedulerID string, timeout int32, page *crawlerPage, httpTools httptools.HTTPTool, clientConfig *httptools.ClientConfig, sem semaphore.Semaphore, follow bool) ([]string, *crawlerBrokenLink) {
+	err := sem.Acquire(context.Background())
+	if err != nil {
+		return nil, &crawlerBrokenLink{location: page.location, referrer: page.referrer, err: err.Error()}
+	}
+	defer sem.Release()
+
+	rq := httptools.WithClientConfig(httpTools.CreateRequest(http.MethodGet, page.location, nil, schedulerID), clientConfig)
+	// Body is not needed when links of page are not followed
+	maxBodySize := int64(0)
+	if follow {
+		maxBodySize = crawlerMaxBodySize
+	}
+	rq = httptools.WithMaxBodySize(rq, maxBodySize)
+	code, headers, body, err := httpTools.SendRequestTimeoutWithHeaders(rq, helpers.DurationFromSecond(timeout))
+	if err != nil {
+		return nil, &crawlerBrokenLink{location: page.location, referrer: page.referrer, code: code, err: err.Error()}
+	}
+	if code >= http.StatusBadRequest {
+		return nil, &crawlerBrokenLink{location: page.location, referrer: page.referrer, code: code, err: crawlerWrongCodeErrorFn(code).Error()}
+	}
+	if !follow || !isHTML(headers) {
+		return nil, nil
+	}
+	return pageLinks(page.location, body), nil
+}
+
+// Links are extracted only from html pages, images, feeds and other files are only checked for availability
+func isHTML(headers http.Header) bool {
+	mediaType, _, err := mime.ParseMediaType(headers.Get("Content-Type"))
+	return err == nil && mediaType == "text/html"
+}
+
+// Absolute urls of <a href> without fragment
+func pageLinks(location string, body []byte) []string {
+	base, err := url.Parse(location)
+	if err != nil {
+		return nil
+	}
+	links := []string{}
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	for {
+		tokenType := tokenizer.Next()
+		if tokenType == html.ErrorToken {
+			return links
+		}
+		if tokenType != html.StartTagToken && tokenType != html.SelfClosingTagToken {
+			continue
+		}
+		token := tokenizer.Token()
+		if token.Data == "base" {
+			for _, attr := range token.Attr {
+				if attr.Key == "href" {
+					if href, err := base.Parse(attr.Val); err == nil {
+						base = href
+					}
+				}
+			}
+			continue
+		}
+		if token.Data != "a" {
+			continue
+		}
+		for _, attr := range token.Attr {
+			if attr.Key != "href" {
+				continue
+			}
+			link, err := base.Parse(attr.Val)
+			if err != nil {
+				continue
+			}
+			link.Fragment = ""
+			links = append(links, link.String())
+		}
+	}
+}
+
+func newCrawlerFilter(config *scheduler_config_storage.CrawlerConfig) (*crawlerFilter, error) {
+	seed, err := url.Parse(config.URL)
+	if err != nil || seed.Host == "" || (seed.Scheme != "http" && seed.Scheme != "https") {
+		return nil, errCrawlerInvalidURL
+	}
+	filter := &crawlerFilter{
+		origin: origin(seed),
+	}
+	filter.include, err = compilePatterns(config.Include)
+	if err != nil {
+		return nil, err
+	}
+	filter.exclude, err = compilePatterns(config.Exclude)
+	if err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// Links to other origins are not followed, excluded urls are ignored same as ignored urls of sitemap
+func (f *crawlerFilter) match(location string) bool {
+	link, err := url.Parse(location)
+	if err != nil || origin(link) != f.origin {
+		return false
+	}
+	for _, pattern := range f.exclude {
+		if pattern.MatchString(location) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, pattern := range f.include {
+		if pattern.MatchString(location) {
+			return true
+		}
+	}
+	return false
+}
+
+func origin(u *url.URL) string {
+	return fmt.Sprintf("%s://%s", u.Scheme, u.Host)
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	result := []*regexp.Regexp{}
+	for _, pattern := range patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, crawlerInvalidPatternFn(pattern, err)
+		}
+		result = append(result, compiled)
+	}
+	return result, nil
+}
+
+func crawlerMetaValue(pages int, broken []*crawlerBrokenLink) *structType.Value {
+	links := []*structType.Value{}
+	for _, link := range broken {
+		fields := map[string]*structType.Value{
+			"url":      stringValue(link.location),
+			"referrer": stringValue(link.referrer),
+			"error":    stringValue(link.err),
+		}
+		if link.code != 0 {
+			fields["code"] = numberValue(float64(link.code))
+		}
+		links = append(links, &structType.Value{
+			Kind: &structType.Value_StructValue{
+				StructValue: &structType.Struct{Fields: fields},
+			},
+		})
+	}
+	return &structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{
+				Fields: map[string]*structType.Value{
+					"pages": numberValue(float64(pages)),
+					"broken": {
+						Kind: &structType.Value_ListValue{
+							ListValue: &structType.ListValue{Values: links},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,153 @@
+package job
+
+import (
+	"fmt"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"squzy/internal/httptools"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"strings"
+	"testing"
+)
+
+func newCrawlerSite() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = fmt.Fprint(w, `<html><body>
+			<a href="/about">About</a>
+			<a href="/blog#top">Blog</a>
+			<a href="https://external.squzy.app/">External</a>
+			<a href="/admin/panel">Admin</a>
+		</body></html>`)
+	})
+	mux.HandleFunc("/about", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `<a href="/">Home</a><a href="/missing">Missing</a>`)
+	})
+	mux.HandleFunc("/blog", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `<a href="blog/post/1">Post</a>`)
+	})
+	mux.HandleFunc("/blog/post/1", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `<a href="/deep">Deep</a>`)
+	})
+	mux.HandleFunc("/admin/panel", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = fmt.Fprint(w, `<rss><a href="/missing">Missing</a></rss>`)
+	})
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = fmt.Fprint(w, `<a href="/about">About</a>`, strings.Repeat(" ", int(crawlerMaxBodySize)), `<a href="/missing">Missing</a>`)
+	})
+	return httptest.NewServer(mux)
+}
+
+func brokenLinks(job CheckError) map[string]string {
+	result := map[string]string{}
+	value := job.GetLogData().Snapshot.Meta.Value
+	for _, link := range value.GetStructValue().Fields["broken"].GetListValue().Values {
+		fields := link.GetStructValue().Fields
+		result[fields["url"].GetStringValue()] = fields["referrer"].GetStringValue()
+	}
+	return result
+}
+
+func TestExecCrawler(t *testing.T) {
+	site := newCrawlerSite()
+	defer site.Close()
+
+	t.Run("Should: report broken links with referring page", func(t *testing.T) {
+		job := ExecCrawler("", 1, &scheduler_config_storage.CrawlerConfig{
+			URL:         site.URL + "/",
+			Concurrency: 2,
+		}, httptools.New(""), successFactory)
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		assert.Equal(t, map[string]string{
+			site.URL + "/missing":     site.URL + "/about",
+			site.URL + "/admin/panel": site.URL + "/",
+		}, brokenLinks(job))
+		assert.Equal(t, float64(6), job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields["pages"].GetNumberValue())
+	})
+	t.Run("Should: not request excluded links", func(t *testing.T) {
+		job := ExecCrawler("", 1, &scheduler_config_storage.CrawlerConfig{
+			URL:      site.URL + "/",
+			MaxDepth: 1,
+			Exclude:  []string{"/admin/"},
+		}, httptools.New(""), successFactory)
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		assert.Equal(t, float64(3), job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields["pages"].GetNumberValue())
+	})
+	t.Run("Should: request only included links", func(t *testing.T) {
+		job := ExecCrawler("", 1, &scheduler_config_storage.CrawlerConfig{
+			URL:      site.URL + "/",
+			MaxDepth: 3,
+			Include:  []string{"/blog"},
+		}, httptools.New(""), successFactory)
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		assert.Equal(t, float64(3), job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields["pages"].GetNumberValue())
+	})
+	t.Run("Should: stop after max pages", func(t *testing.T) {
+		job := ExecCrawler("", 1, &scheduler_config_storage.CrawlerConfig{
+			URL:      site.URL + "/",
+			MaxPages: 2,
+		}, httptools.New(""), successFactory)
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		assert.Equal(t, float64(2), job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields["pages"].GetNumberValue())
+	})
+	t.Run("Should: not follow links of page which is not html", func(t *testing.T) {
+		job := ExecCrawler("", 1, &scheduler_config_storage.CrawlerConfig{
+			URL: site.URL + "/feed.xml",
+		}, httptools.New(""), successFactory)
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		assert.Equal(t, float64(1), job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields["pages"].GetNumberValue())
+	})
+	t.Run("Should: not follow links after max body size", func(t *testing.T) {
+		job := ExecCrawler("", 1, &scheduler_config_storage.CrawlerConfig{
+			URL:      site.URL + "/large",
+			MaxDepth: 1,
+		}, httptools.New(""), successFactory)
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		assert.Equal(t, float64(2), job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields["pages"].GetNumberValue())
+	})
+	t.Run("Should: report seed url when it is broken", func(t *testing.T) {
+		job := ExecCrawler("", 1, &scheduler_config_storage.CrawlerConfig{
+			URL: site.URL + "/missing",
+		}, httptools.New(""), successFactory)
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		assert.Equal(t, map[string]string{site.URL + "/missing": ""}, brokenLinks(job))
+	})
+	t.Run("Should: return error because semaphore can't be acquired", func(t *testing.T) {
+		job := ExecCrawler("", 1, &scheduler_config_storage.CrawlerConfig{
+			URL: site.URL + "/",
+		}, httptools.New(""), errorFactory)
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+	})
+	t.Run("Should: return error because url is invalid", func(t *testing.T) {
+		job := ExecCrawler("", 1, &scheduler_config_storage.CrawlerConfig{
+			URL: "squzy.app",
+		}, httptools.New(""), successFactory)
+		assert.Equal(t, errCrawlerInvalidURL.Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: return error because pattern is invalid", func(t *testing.T) {
+		job := ExecCrawler("", 1, &scheduler_config_storage.CrawlerConfig{
+			URL:     site.URL,
+			Exclude: []string{"("},
+		}, httptools.New(""), successFactory)
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		assert.Contains(t, job.GetLogData().Snapshot.Error.Message, "invalid pattern (")
+	})
+}
+
+func TestPageLinks(t *testing.T) {
+	t.Run("Should: resolve links against base", func(t *testing.T) {
+		links := pageLinks("https://squzy.app/blog/", []byte(`<base href="https://squzy.app/docs/"><a href="start#intro">Start</a><a>Empty</a>`))
+		assert.Equal(t, []string{"https://squzy.app/docs/start"}, links)
+	})
+}
@@ -0,0 +1,314 @@
+package job
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-redis/redis/v7"
+	"github.com/go-sql-driver/mysql"
+	"github.com/golang/protobuf/ptypes"
+	structType "github.com/golang/protobuf/ptypes/struct"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	_ "github.com/lib/pq" // postgres driver for database/sql
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/tidwall/gjson"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net"
+	"net/url"
+	"squzy/internal/helpers"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultSQLQuery       = "SELECT 1"
+	defaultRedisCommand   = "PING"
+	defaultPostgresSSL    = "disable"
+	defaultMongoCommand   = `{"hello": 1}`
+	defaultMongoDatabase  = "admin"
+	defaultMongoValuePath = "ok"
+)
+
+var (
+	errDatabaseNoRows            = errors.New("QUERY_RETURNED_NO_ROWS")
+	errDatabaseUnsupportedType   = errors.New("UNSUPPORTED_DATABASE_TYPE")
+	errDatabaseInvalidRedisIndex = errors.New("INVALID_REDIS_DATABASE")
+	databaseInvalidCommandFn     = func(err error) error {
+		return fmt.Errorf("invalid command: %s", err.Error())
+	}
+	// Replaced in tests to check queries without real database
+	sqlOpen = sql.Open
+)
+
+type databaseError struct {
+	schedulerID   string
+	schedulerType apiPb.SchedulerType
+	startTime     *timestamp.Timestamp
+	endTime       *timestamp.Timestamp
+	code          apiPb.SchedulerCode
+	description   string
+	value         *structType.Value
+}
+
+func (e *databaseError) GetLogData() *apiPb.SchedulerResponse {
+	var err *apiPb.SchedulerSnapshot_Error
+	if e.code == apiPb.SchedulerCode_ERROR {
+		err = &apiPb.SchedulerSnapshot_Error{
+			Message: e.description,
+		}
+	}
+	return &apiPb.SchedulerResponse{
+		SchedulerId: e.schedulerID,
+		Snapshot: &apiPb.SchedulerSnapshot{
+			Code:  e.code,
+			Error: err,
+			Type:  e.schedulerType,
+			Meta: &apiPb.SchedulerSnapshot_MetaData{
+				StartTime: e.startTime,
+				EndTime:   e.endTime,
+				Value:     e.value,
+			},
+		},
+	}
+}
+
+func newDatabaseError(schedulerID string, schedulerType apiPb.SchedulerType, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string, value *structType.Value) CheckError {
+	return &databaseError{
+		schedulerID:   schedulerID,
+		schedulerType: schedulerType,
+		startTime:     startTime,
+		endTime:       endTime,
+		code:          code,
+		description:   description,
+		value:         value,
+	}
+}
+
+// Connects to postgres, mysql, redis or mongo, runs probe and checks value returned by it
+func ExecDatabase(schedulerID string, timeout int32, schedulerType apiPb.SchedulerType, config *scheduler_config_storage.DatabaseConfig) CheckError {
+	startTime := ptypes.TimestampNow()
+
+	ctx, cancel := helpers.TimeoutContext(context.Background(), helpers.DurationFromSecond(timeout))
+	defer cancel()
+
+	var result gjson.Result
+	var err error
+	switch schedulerType {
+	case monitoring_api.SchedulerTypePostgres:
+		result, err = querySQL(ctx, "postgres", postgresDSN(config, timeout), config.Query, config.Value != nil)
+	case monitoring_api.SchedulerTypeMySQL:
+		result, err = querySQL(ctx, "mysql", mysqlDSN(config, timeout), config.Query, config.Value != nil)
+	case monitoring_api.SchedulerTypeRedis:
+		result, err = queryRedis(ctx, config)
+	case monitoring_api.SchedulerTypeMongo:
+		result, err = queryMongo(ctx, config)
+	default:
+		err = errDatabaseUnsupportedType
+	}
+	if err != nil {
+		return newDatabaseError(schedulerID, schedulerType, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), nil)
+	}
+
+	if config.Value == nil {
+		return newDatabaseError(schedulerID, schedulerType, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", nil)
+	}
+	value := selectorValue(config.Value, result)
+	if config.Value.Rule != nil {
+		err = checkSelectorRule(config.Value, result)
+		if err != nil {
+			return newDatabaseError(schedulerID, schedulerType, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), value)
+		}
+	}
+	return newDatabaseError(schedulerID, schedulerType, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", value)
+}
+
+// Runs query and returns first column of first row
+func querySQL(ctx context.Context, driver string, dsn string, query string, needValue bool) (gjson.Result, error) {
+	if query == "" {
+		query = defaultSQLQuery
+	}
+	db, err := sqlOpen(driver, dsn)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	defer func() {
+		_ = db.Close()
+	}()
+	db.SetMaxOpenConns(1)
+
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+	if !rows.Next() {
+		if rows.Err() != nil {
+			return gjson.Result{}, rows.Err()
+		}
+		if needValue {
+			return gjson.Result{}, errDatabaseNoRows
+		}
+		return gjson.Result{}, nil
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	err = rows.Scan(pointers...)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	return scalarResult(values[0]), nil
+}
+
+func queryRedis(ctx context.Context, config *scheduler_config_storage.DatabaseConfig) (gjson.Result, error) {
+	db := 0
+	if config.Database != "" {
+		index, err := strconv.Atoi(config.Database)
+		if err != nil {
+			return gjson.Result{}, errDatabaseInvalidRedisIndex
+		}
+		db = index
+	}
+	// Arguments are passed as is, so they can contain spaces
+	args := []interface{}{defaultRedisCommand}
+	if len(config.Command) > 0 {
+		args = []interface{}{}
+		for _, arg := range config.Command {
+			args = append(args, arg)
+		}
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:       net.JoinHostPort(config.Host, strconv.Itoa(int(config.Port))),
+		Username:   config.User,
+		Password:   config.Password,
+		DB:         db,
+		MaxRetries: -1,
+		PoolSize:   1,
+	})
+	defer func() {
+		_ = client.Close()
+	}()
+
+	reply, err := client.WithContext(ctx).Do(args...).Result()
+	if err != nil && err != redis.Nil {
+		return gjson.Result{}, err
+	}
+	return scalarResult(reply), nil
+}
+
+// Runs command and returns value from its result by path, ok field by default
+func queryMongo(ctx context.Context, config *scheduler_config_storage.DatabaseConfig) (gjson.Result, error) {
+	command := config.Query
+	if command == "" {
+		command = defaultMongoCommand
+	}
+	doc := bson.D{}
+	err := bson.UnmarshalExtJSON([]byte(command), false, &doc)
+	if err != nil {
+		return gjson.Result{}, databaseInvalidCommandFn(err)
+	}
+
+	database := config.Database
+	if database == "" {
+		database = defaultMongoDatabase
+	}
+	opts := options.Client().
+		SetHosts([]string{net.JoinHostPort(config.Host, strconv.Itoa(int(config.Port)))}).
+		SetDirect(true)
+	if deadline, ok := ctx.Deadline(); ok {
+		opts.SetServerSelectionTimeout(time.Until(deadline)).SetConnectTimeout(time.Until(deadline))
+	}
+	if config.User != "" {
+		opts.SetAuth(options.Credential{
+			Username:   config.User,
+			Password:   config.Password,
+			AuthSource: database,
+		})
+	}
+	client, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	defer func() {
+		_ = client.Disconnect(context.Background())
+	}()
+
+	raw, err := client.Database(database).RunCommand(ctx, doc).DecodeBytes()
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	data, err := bson.MarshalExtJSON(raw, false, false)
+	if err != nil {
+		return gjson.Result{}, err
+	}
+	path := defaultMongoValuePath
+	if config.Value != nil && config.Value.Path != "" {
+		path = config.Value.Path
+	}
+	return gjson.GetBytes(data, path), nil
+}
+
+// Value of query as json, so selectors can be applied to it
+func scalarResult(value interface{}) gjson.Result {
+	switch v := value.(type) {
+	case []byte:
+		value = string(v)
+	case time.Time:
+		value = v.Format(time.RFC3339Nano)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return gjson.Parse(strconv.Quote(fmt.Sprintf("%v", value)))
+	}
+	return gjson.ParseBytes(data)
+}
+
+func postgresDSN(config *scheduler_config_storage.DatabaseConfig, timeout int32) string {
+	dsn := &url.URL{
+		Scheme: "postgres",
+		Host:   net.JoinHostPort(config.Host, strconv.Itoa(int(config.Port))),
+		Path:   "/" + config.Database,
+	}
+	if config.Password != "" {
+		dsn.User = url.UserPassword(config.User, config.Password)
+	} else if config.User != "" {
+		dsn.User = url.User(config.User)
+	}
+	query := url.Values{}
+	sslMode := config.SSLMode
+	if sslMode == "" {
+		sslMode = defaultPostgresSSL
+	}
+	query.Set("sslmode", sslMode)
+	if timeout > 0 {
+		query.Set("connect_timeout", strconv.Itoa(int(timeout)))
+	}
+	dsn.RawQuery = query.Encode()
+	return dsn.String()
+}
+
+func mysqlDSN(config *scheduler_config_storage.DatabaseConfig, timeout int32) string {
+	mysqlConfig := mysql.NewConfig()
+	mysqlConfig.Net = "tcp"
+	mysqlConfig.Addr = net.JoinHostPort(config.Host, strconv.Itoa(int(config.Port)))
+	mysqlConfig.User = config.User
+	mysqlConfig.Passwd = config.Password
+	mysqlConfig.DBName = config.Database
+	mysqlConfig.Timeout = helpers.DurationFromSecond(timeout)
+	return mysqlConfig.FormatDSN()
+}
@@ -0,0 +1,401 @@
+package job
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	structType "github.com/golang/protobuf/ptypes/struct"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"golang.org/x/net/dns/dnsmessage"
+	"io"
+	"net"
+	"sort"
+	"squzy/internal/helpers"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"strings"
+	"time"
+)
+
+const (
+	dnsDefaultPort = "53"
+	// Size of udp payload which we advertise by EDNS0, enough for most TXT answers
+	dnsUDPPayloadSize = 4096
+)
+
+var (
+	errDNSInvalidRecordType = errors.New("INVALID_DNS_RECORD_TYPE")
+	errDNSInvalidAssertion  = errors.New("INVALID_DNS_ASSERTION")
+	errDNSTruncatedResponse = errors.New("DNS_RESPONSE_TRUNCATED")
+	errDNSWrongResponse     = errors.New("DNS_WRONG_RESPONSE")
+	errDNSMissingResolver   = errors.New("DNS_RESOLVER_MISSING")
+	dnsResponseCodeErrorFn  = func(code dnsmessage.RCode) error {
+		return fmt.Errorf("dns query failed with code %s", code.String())
+	}
+	dnsExactAssertionErrorFn = func(expected []string, actual []string) error {
+		return fmt.Errorf("expected records %v, but got %v", expected, actual)
+	}
+	dnsContainsAssertionErrorFn = func(missing []string, actual []string) error {
+		return fmt.Errorf("records %v are missing in %v", missing, actual)
+	}
+	dnsMinCountAssertionErrorFn = func(minCount int32, actual []string) error {
+		return fmt.Errorf("expected at least %d records, but got %d", minCount, len(actual))
+	}
+	dnsQueryTypes = map[monitoring_api.DNSRecordType]dnsmessage.Type{
+		monitoring_api.DNSRecordTypeA:     dnsmessage.TypeA,
+		monitoring_api.DNSRecordTypeAAAA:  dnsmessage.TypeAAAA,
+		monitoring_api.DNSRecordTypeCNAME: dnsmessage.TypeCNAME,
+		monitoring_api.DNSRecordTypeMX:    dnsmessage.TypeMX,
+		monitoring_api.DNSRecordTypeTXT:   dnsmessage.TypeTXT,
+	}
+)
+
+type dnsError struct {
+	schedulerID string
+	startTime   *timestamp.Timestamp
+	endTime     *timestamp.Timestamp
+	code        apiPb.SchedulerCode
+	description string
+	value       *structType.Value
+}
+
+func (e *dnsError) GetLogData() *apiPb.SchedulerResponse {
+	var err *apiPb.SchedulerSnapshot_Error
+	if e.code == apiPb.SchedulerCode_ERROR {
+		err = &apiPb.SchedulerSnapshot_Error{
+			Message: e.description,
+		}
+	}
+	return &apiPb.SchedulerResponse{
+		SchedulerId: e.schedulerID,
+		Snapshot: &apiPb.SchedulerSnapshot{
+			Code:  e.code,
+			Error: err,
+			Type:  monitoring_api.SchedulerTypeDNS,
+			Meta: &apiPb.SchedulerSnapshot_MetaData{
+				StartTime: e.startTime,
+				EndTime:   e.endTime,
+				Value:     e.value,
+			},
+		},
+	}
+}
+
+func newDNSError(schedulerID string, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string, value *structType.Value) CheckError {
+	return &dnsError{
+		schedulerID: schedulerID,
+		startTime:   startTime,
+		endTime:     endTime,
+		code:        code,
+		description: description,
+		value:       value,
+	}
+}
+
+func ExecDNS(schedulerID string, timeout int32, config *scheduler_config_storage.DNSConfig) CheckError {
+	startTime := ptypes.TimestampNow()
+
+	queryType, ok := dnsQueryTypes[config.RecordType]
+	if !ok {
+		return newDNSError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errDNSInvalidRecordType.Error(), nil)
+	}
+
+	if config.Resolver == "" {
+		return newDNSError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errDNSMissingResolver.Error(), nil)
+	}
+
+	ctx, cancel := helpers.TimeoutContext(context.Background(), helpers.DurationFromSecond(timeout))
+	defer cancel()
+
+	lookupStart := time.Now()
+	values, err := dnsLookup(ctx, resolverAddress(config.Resolver), config.Host, queryType)
+	latency := time.Since(lookupStart)
+	if err != nil {
+		return newDNSError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), nil)
+	}
+
+	value := dnsResultToValue(values, latency)
+
+	err = assertDNSValues(config, values)
+	if err != nil {
+		return newDNSError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), value)
+	}
+
+	return newDNSError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", value)
+}
+
+func resolverAddress(resolver string) string {
+	if _, _, err := net.SplitHostPort(resolver); err == nil {
+		return resolver
+	}
+	return net.JoinHostPort(strings.Trim(resolver, "[]"), dnsDefaultPort)
+}
+
+func dnsLookup(ctx context.Context, resolver string, host string, queryType dnsmessage.Type) ([]string, error) {
+	// Name should be fully qualified, otherwise it can't be packed
+	if !strings.HasSuffix(host, ".") {
+		host += "."
+	}
+	name, err := dnsmessage.NewName(host)
+	if err != nil {
+		return nil, err
+	}
+	id, err := dnsQueryID()
+	if err != nil {
+		return nil, err
+	}
+	query, err := buildDNSQuery(id, name, queryType)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := dnsExchangeUDP(ctx, resolver, id, query)
+	if err != nil {
+		return nil, err
+	}
+	// Answer which doesn't fit into udp payload is requested again over tcp, same as resolvers do
+	if msg.Truncated {
+		msg, err = dnsExchangeTCP(ctx, resolver, id, query)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return parseDNSAnswer(msg, queryType)
+}
+
+func dnsExchangeUDP(ctx context.Context, resolver string, id uint16, query []byte) (*dnsmessage.Message, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", resolver)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	_, err = conn.Write(query)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, dnsUDPPayloadSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		var msg dnsmessage.Message
+		// Responses which are not related to our query are skipped
+		if msg.Unpack(buf[:n]) != nil || msg.ID != id || !msg.Response {
+			continue
+		}
+		return &msg, nil
+	}
+}
+
+// Messages over tcp are prefixed by their length
+func dnsExchangeTCP(ctx context.Context, resolver string, id uint16, query []byte) (*dnsmessage.Message, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", resolver)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	packet := make([]byte, 2+len(query))
+	binary.BigEndian.PutUint16(packet, uint16(len(query)))
+	copy(packet[2:], query)
+	_, err = conn.Write(packet)
+	if err != nil {
+		return nil, err
+	}
+
+	length := make([]byte, 2)
+	_, err = io.ReadFull(conn, length)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(length))
+	_, err = io.ReadFull(conn, buf)
+	if err != nil {
+		return nil, err
+	}
+	var msg dnsmessage.Message
+	if msg.Unpack(buf) != nil || msg.ID != id || !msg.Response {
+		return nil, errDNSWrongResponse
+	}
+	return &msg, nil
+}
+
+func dnsQueryID() (uint16, error) {
+	b := make([]byte, 2)
+	_, err := rand.Read(b)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(b), nil
+}
+
+func buildDNSQuery(id uint16, name dnsmessage.Name, queryType dnsmessage.Type) ([]byte, error) {
+	var opt dnsmessage.ResourceHeader
+	err := opt.SetEDNS0(dnsUDPPayloadSize, dnsmessage.RCodeSuccess, false)
+	if err != nil {
+		return nil, err
+	}
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:               id,
+			RecursionDesired: true,
+		},
+		Questions: []dnsmessage.Question{
+			{
+				Name:  name,
+				Type:  queryType,
+				Class: dnsmessage.ClassINET,
+			},
+		},
+		Additionals: []dnsmessage.Resource{
+			{
+				Header: opt,
+				Body:   &dnsmessage.OPTResource{},
+			},
+		},
+	}
+	return msg.Pack()
+}
+
+func parseDNSAnswer(msg *dnsmessage.Message, queryType dnsmessage.Type) ([]string, error) {
+	if msg.Truncated {
+		return nil, errDNSTruncatedResponse
+	}
+	if msg.RCode != dnsmessage.RCodeSuccess {
+		return nil, dnsResponseCodeErrorFn(msg.RCode)
+	}
+	values := []string{}
+	for _, answer := range msg.Answers {
+		// Answer can contain CNAME chain before requested records, records of other types are not values of check,
+		// so assertion fails when there are no requested records
+		if answer.Header.Type != queryType {
+			continue
+		}
+		switch body := answer.Body.(type) {
+		case *dnsmessage.AResource:
+			values = append(values, net.IP(body.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			values = append(values, net.IP(body.AAAA[:]).String())
+		case *dnsmessage.CNAMEResource:
+			values = append(values, normalizeDNSName(body.CNAME.String()))
+		case *dnsmessage.MXResource:
+			values = append(values, normalizeDNSName(body.MX.String()))
+		case *dnsmessage.TXTResource:
+			values = append(values, strings.Join(body.TXT, ""))
+		}
+	}
+	sort.Strings(values)
+	return values, nil
+}
+
+func normalizeDNSName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+func normalizeExpectedDNSValue(recordType monitoring_api.DNSRecordType, value string) string {
+	switch recordType {
+	case monitoring_api.DNSRecordTypeA, monitoring_api.DNSRecordTypeAAAA:
+		if ip := net.ParseIP(value); ip != nil {
+			return ip.String()
+		}
+		return value
+	case monitoring_api.DNSRecordTypeCNAME, monitoring_api.DNSRecordTypeMX:
+		return normalizeDNSName(value)
+	default:
+		return value
+	}
+}
+
+func assertDNSValues(config *scheduler_config_storage.DNSConfig, values []string) error {
+	expected := []string{}
+	for _, value := range config.ExpectedValues {
+		expected = append(expected, normalizeExpectedDNSValue(config.RecordType, value))
+	}
+	sort.Strings(expected)
+
+	switch config.Assertion {
+	case monitoring_api.DNSAssertionExact:
+		if !sameDNSValues(expected, values) {
+			return dnsExactAssertionErrorFn(expected, values)
+		}
+		return nil
+	case monitoring_api.DNSAssertionContains:
+		missing := []string{}
+		for _, value := range expected {
+			if !containsString(values, value) {
+				missing = append(missing, value)
+			}
+		}
+		if len(missing) != 0 {
+			return dnsContainsAssertionErrorFn(missing, values)
+		}
+		return nil
+	case monitoring_api.DNSAssertionMinCount, "":
+		minCount := config.MinCount
+		if minCount <= 0 {
+			minCount = 1
+		}
+		if int32(len(values)) < minCount {
+			return dnsMinCountAssertionErrorFn(minCount, values)
+		}
+		return nil
+	default:
+		return errDNSInvalidAssertion
+	}
+}
+
+func sameDNSValues(expected []string, actual []string) bool {
+	for _, value := range expected {
+		if !containsString(actual, value) {
+			return false
+		}
+	}
+	for _, value := range actual {
+		if !containsString(expected, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func dnsResultToValue(values []string, latency time.Duration) *structType.Value {
+	list := []*structType.Value{}
+	for _, value := range values {
+		list = append(list, stringValue(value))
+	}
+	return &structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{
+				Fields: map[string]*structType.Value{
+					"values": {
+						Kind: &structType.Value_ListValue{
+							ListValue: &structType.ListValue{
+								Values: list,
+							},
+						},
+					},
+					"latencyMs": numberValue(float64(latency) / float64(time.Millisecond)),
+				},
+			},
+		},
+	}
+}
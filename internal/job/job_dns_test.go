@@ -0,0 +1,310 @@
+package job
+
+import (
+	"encoding/binary"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/dns/dnsmessage"
+	"io"
+	"net"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"strings"
+	"testing"
+)
+
+func stubDNSAnswers(question dnsmessage.Question) ([]dnsmessage.Resource, dnsmessage.RCode) {
+	header := dnsmessage.ResourceHeader{
+		Name:  question.Name,
+		Type:  question.Type,
+		Class: dnsmessage.ClassINET,
+		TTL:   60,
+	}
+	switch question.Name.String() {
+	case "example.com.":
+	case "large.example.com.":
+		txt := []dnsmessage.Resource{}
+		for i := 0; i < 100; i++ {
+			txt = append(txt, dnsmessage.Resource{Header: header, Body: &dnsmessage.TXTResource{TXT: []string{strings.Repeat("x", 100)}}})
+		}
+		return txt, dnsmessage.RCodeSuccess
+	case "alias.example.com.":
+		header.Type = dnsmessage.TypeCNAME
+		return []dnsmessage.Resource{
+			{Header: header, Body: &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("example.com.")}},
+		}, dnsmessage.RCodeSuccess
+	default:
+		return nil, dnsmessage.RCodeNameError
+	}
+	switch question.Type {
+	case dnsmessage.TypeA:
+		return []dnsmessage.Resource{
+			{Header: header, Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 2}}},
+			{Header: header, Body: &dnsmessage.AResource{A: [4]byte{10, 0, 0, 1}}},
+		}, dnsmessage.RCodeSuccess
+	case dnsmessage.TypeAAAA:
+		return []dnsmessage.Resource{
+			{Header: header, Body: &dnsmessage.AAAAResource{AAAA: [16]byte{15: 1}}},
+		}, dnsmessage.RCodeSuccess
+	case dnsmessage.TypeCNAME:
+		return []dnsmessage.Resource{
+			{Header: header, Body: &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName("Target.example.org.")}},
+		}, dnsmessage.RCodeSuccess
+	case dnsmessage.TypeMX:
+		return []dnsmessage.Resource{
+			{Header: header, Body: &dnsmessage.MXResource{Pref: 10, MX: dnsmessage.MustNewName("mail.example.com.")}},
+		}, dnsmessage.RCodeSuccess
+	case dnsmessage.TypeTXT:
+		return []dnsmessage.Resource{
+			{Header: header, Body: &dnsmessage.TXTResource{TXT: []string{"v=spf1 ", "-all"}}},
+		}, dnsmessage.RCodeSuccess
+	default:
+		return nil, dnsmessage.RCodeSuccess
+	}
+}
+
+func stubDNSResponse(rq *dnsmessage.Message, maxSize int) ([]byte, error) {
+	answers, code := stubDNSAnswers(rq.Questions[0])
+	rs := dnsmessage.Message{
+		Header: dnsmessage.Header{
+			ID:       rq.ID,
+			Response: true,
+			RCode:    code,
+		},
+		Questions: rq.Questions,
+		Answers:   answers,
+	}
+	packed, err := rs.Pack()
+	if err != nil || len(packed) <= maxSize {
+		return packed, err
+	}
+	rs.Truncated = true
+	rs.Answers = nil
+	return rs.Pack()
+}
+
+// Starts dns server which knows only about example.com, answers which don't fit into 512 bytes are sent only over tcp
+func startStubDNSServer(t *testing.T) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Equal(t, nil, err)
+	listener, err := net.Listen("tcp", conn.LocalAddr().String())
+	assert.Equal(t, nil, err)
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				_ = listener.Close()
+				return
+			}
+			var rq dnsmessage.Message
+			if rq.Unpack(buf[:n]) != nil || len(rq.Questions) != 1 {
+				continue
+			}
+			packed, err := stubDNSResponse(&rq, 512)
+			if err != nil {
+				continue
+			}
+			_, _ = conn.WriteTo(packed, addr)
+		}
+	}()
+	go func() {
+		for {
+			tcpConn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			length := make([]byte, 2)
+			_, _ = io.ReadFull(tcpConn, length)
+			buf := make([]byte, binary.BigEndian.Uint16(length))
+			_, _ = io.ReadFull(tcpConn, buf)
+			var rq dnsmessage.Message
+			if rq.Unpack(buf) == nil && len(rq.Questions) == 1 {
+				packed, err := stubDNSResponse(&rq, 65535)
+				if err == nil {
+					binary.BigEndian.PutUint16(length, uint16(len(packed)))
+					_, _ = tcpConn.Write(append(length, packed...))
+				}
+			}
+			_ = tcpConn.Close()
+		}
+	}()
+	return conn
+}
+
+func TestExecDNS(t *testing.T) {
+	t.Run("Test: Testing dns check:", func(t *testing.T) {
+		server := startStubDNSServer(t)
+		defer func() {
+			_ = server.Close()
+		}()
+		resolver := server.LocalAddr().String()
+
+		t.Run("Should: return ok with resolved values", func(t *testing.T) {
+			job := ExecDNS("", 1, &scheduler_config_storage.DNSConfig{
+				Host:       "example.com",
+				RecordType: monitoring_api.DNSRecordTypeA,
+				Resolver:   resolver,
+			})
+			snapshot := job.GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_OK, snapshot.Code)
+			assert.Equal(t, monitoring_api.SchedulerTypeDNS, snapshot.Type)
+			fields := snapshot.Meta.Value.GetStructValue().Fields
+			values := fields["values"].GetListValue().Values
+			assert.Equal(t, 2, len(values))
+			assert.Equal(t, "10.0.0.1", values[0].GetStringValue())
+			assert.NotNil(t, fields["latencyMs"])
+		})
+		t.Run("Should: return ok because exact match", func(t *testing.T) {
+			job := ExecDNS("", 1, &scheduler_config_storage.DNSConfig{
+				Host:           "example.com",
+				RecordType:     monitoring_api.DNSRecordTypeA,
+				Resolver:       resolver,
+				Assertion:      monitoring_api.DNSAssertionExact,
+				ExpectedValues: []string{"10.0.0.2", "10.0.0.1"},
+			})
+			assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		})
+		t.Run("Should: return error because exact not match", func(t *testing.T) {
+			job := ExecDNS("", 1, &scheduler_config_storage.DNSConfig{
+				Host:           "example.com",
+				RecordType:     monitoring_api.DNSRecordTypeA,
+				Resolver:       resolver,
+				Assertion:      monitoring_api.DNSAssertionExact,
+				ExpectedValues: []string{"10.0.0.1"},
+			})
+			snapshot := job.GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.NotNil(t, snapshot.Meta.Value)
+		})
+		t.Run("Should: return ok because contains", func(t *testing.T) {
+			job := ExecDNS("", 1, &scheduler_config_storage.DNSConfig{
+				Host:           "example.com",
+				RecordType:     monitoring_api.DNSRecordTypeCNAME,
+				Resolver:       resolver,
+				Assertion:      monitoring_api.DNSAssertionContains,
+				ExpectedValues: []string{"target.example.org."},
+			})
+			assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		})
+		t.Run("Should: return error because not contains", func(t *testing.T) {
+			job := ExecDNS("", 1, &scheduler_config_storage.DNSConfig{
+				Host:           "example.com",
+				RecordType:     monitoring_api.DNSRecordTypeMX,
+				Resolver:       resolver,
+				Assertion:      monitoring_api.DNSAssertionContains,
+				ExpectedValues: []string{"mail.example.com", "backup.example.com"},
+			})
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		})
+		t.Run("Should: return ok because txt record match", func(t *testing.T) {
+			job := ExecDNS("", 1, &scheduler_config_storage.DNSConfig{
+				Host:           "example.com",
+				RecordType:     monitoring_api.DNSRecordTypeTXT,
+				Resolver:       resolver,
+				Assertion:      monitoring_api.DNSAssertionExact,
+				ExpectedValues: []string{"v=spf1 -all"},
+			})
+			assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		})
+		t.Run("Should: return ok because aaaa record match", func(t *testing.T) {
+			job := ExecDNS("", 1, &scheduler_config_storage.DNSConfig{
+				Host:           "example.com",
+				RecordType:     monitoring_api.DNSRecordTypeAAAA,
+				Resolver:       resolver,
+				Assertion:      monitoring_api.DNSAssertionExact,
+				ExpectedValues: []string{"0:0:0:0:0:0:0:1"},
+			})
+			assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		})
+		t.Run("Should: return ok because truncated answer is requested over tcp", func(t *testing.T) {
+			job := ExecDNS("", 1, &scheduler_config_storage.DNSConfig{
+				Host:       "large.example.com",
+				RecordType: monitoring_api.DNSRecordTypeTXT,
+				Resolver:   resolver,
+				MinCount:   100,
+			})
+			snapshot := job.GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_OK, snapshot.Code)
+			assert.Equal(t, 100, len(snapshot.Meta.Value.GetStructValue().Fields["values"].GetListValue().Values))
+		})
+		t.Run("Should: return error because answer has not records of requested type", func(t *testing.T) {
+			job := ExecDNS("", 1, &scheduler_config_storage.DNSConfig{
+				Host:       "alias.example.com",
+				RecordType: monitoring_api.DNSRecordTypeA,
+				Resolver:   resolver,
+			})
+			snapshot := job.GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Equal(t, dnsMinCountAssertionErrorFn(1, []string{}).Error(), snapshot.Error.Message)
+		})
+		t.Run("Should: return error because less than min count", func(t *testing.T) {
+			job := ExecDNS("", 1, &scheduler_config_storage.DNSConfig{
+				Host:       "example.com",
+				RecordType: monitoring_api.DNSRecordTypeA,
+				Resolver:   resolver,
+				Assertion:  monitoring_api.DNSAssertionMinCount,
+				MinCount:   3,
+			})
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		})
+		t.Run("Should: return error because domain not exist", func(t *testing.T) {
+			job := ExecDNS("", 1, &scheduler_config_storage.DNSConfig{
+				Host:       "unknown.com",
+				RecordType: monitoring_api.DNSRecordTypeA,
+				Resolver:   resolver,
+			})
+			snapshot := job.GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Equal(t, dnsResponseCodeErrorFn(dnsmessage.RCodeNameError).Error(), snapshot.Error.Message)
+		})
+		t.Run("Should: return error because invalid record type", func(t *testing.T) {
+			job := ExecDNS("", 1, &scheduler_config_storage.DNSConfig{
+				Host:       "example.com",
+				RecordType: "SRV",
+				Resolver:   resolver,
+			})
+			snapshot := job.GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Equal(t, errDNSInvalidRecordType.Error(), snapshot.Error.Message)
+		})
+		t.Run("Should: return error because resolver missing", func(t *testing.T) {
+			job := ExecDNS("", 1, &scheduler_config_storage.DNSConfig{
+				Host:       "example.com",
+				RecordType: monitoring_api.DNSRecordTypeA,
+			})
+			snapshot := job.GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Equal(t, errDNSMissingResolver.Error(), snapshot.Error.Message)
+		})
+		t.Run("Should: return error because invalid assertion", func(t *testing.T) {
+			job := ExecDNS("", 1, &scheduler_config_storage.DNSConfig{
+				Host:       "example.com",
+				RecordType: monitoring_api.DNSRecordTypeA,
+				Resolver:   resolver,
+				Assertion:  "regexp",
+			})
+			snapshot := job.GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Equal(t, errDNSInvalidAssertion.Error(), snapshot.Error.Message)
+		})
+	})
+	t.Run("Should: return error because resolver not respond", func(t *testing.T) {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.Equal(t, nil, err)
+		defer func() {
+			_ = conn.Close()
+		}()
+		job := ExecDNS("", 1, &scheduler_config_storage.DNSConfig{
+			Host:       "example.com",
+			RecordType: monitoring_api.DNSRecordTypeA,
+			Resolver:   conn.LocalAddr().String(),
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+	})
+	t.Run("Should: use default port of resolver", func(t *testing.T) {
+		assert.Equal(t, "8.8.8.8:53", resolverAddress("8.8.8.8"))
+		assert.Equal(t, "[::1]:53", resolverAddress("::1"))
+		assert.Equal(t, "127.0.0.1:5353", resolverAddress("127.0.0.1:5353"))
+	})
+}
@@ -2,27 +2,61 @@ package job
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/golang/protobuf/ptypes"
+	structType "github.com/golang/protobuf/ptypes/struct"
 	"github.com/golang/protobuf/ptypes/timestamp"
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/tidwall/gjson"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	health_check "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
+	reflectionPb "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
 	"squzy/internal/helpers"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"strings"
 )
 
 const (
 	logMetaData = "squzy_scheduler_id"
 )
 
+var (
+	errGrpcStreamingMethod    = errors.New("STREAMING_METHOD_NOT_SUPPORTED")
+	errGrpcReflectionResponse = errors.New("WRONG_REFLECTION_RESPONSE")
+	grpcInvalidMethodErrorFn  = func(method string) error {
+		return fmt.Errorf("invalid method %s, expected package.Service/Method", method)
+	}
+	grpcMethodNotFoundErrorFn = func(method string) error {
+		return fmt.Errorf("method %s not found", method)
+	}
+	grpcFileNotFoundErrorFn = func(file string) error {
+		return fmt.Errorf("descriptor of %s not found", file)
+	}
+	grpcReflectionErrorFn = func(message string) error {
+		return fmt.Errorf("reflection failed: %s", message)
+	}
+	grpcInvalidRequestErrorFn = func(err error) error {
+		return fmt.Errorf("invalid request: %s", err.Error())
+	}
+)
+
 type grpcError struct {
 	schedulerID string
 	startTime   *timestamp.Timestamp
 	endTime     *timestamp.Timestamp
 	code        apiPb.SchedulerCode
 	description string
+	value       *structType.Value
 }
 
 func (s *grpcError) GetLogData() *apiPb.SchedulerResponse {
@@ -41,18 +75,20 @@ func (s *grpcError) GetLogData() *apiPb.SchedulerResponse {
 			Meta: &apiPb.SchedulerSnapshot_MetaData{
 				StartTime: s.startTime,
 				EndTime:   s.endTime,
+				Value:     s.value,
 			},
 		},
 	}
 }
 
-func newGrpcError(schedulerID string, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string) CheckError {
+func newGrpcError(schedulerID string, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string, value *structType.Value) CheckError {
 	return &grpcError{
 		schedulerID: schedulerID,
 		startTime:   startTime,
 		endTime:     endTime,
 		code:        code,
 		description: description,
+		value:       value,
 	}
 }
 
@@ -63,30 +99,248 @@ func ExecGrpc(schedulerID string, timeout int32, config *scheduler_config_storag
 
 	defer cancel()
 
-	conn, err := grpc.DialContext(ctx, fmt.Sprintf("%s:%d", config.Host, config.Port), opts...)
+	transportOpt, err := grpcTransportOption(config.TLS)
+
+	if err != nil {
+		return newGrpcError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), nil)
+	}
+
+	conn, err := grpc.DialContext(ctx, fmt.Sprintf("%s:%d", config.Host, config.Port), append([]grpc.DialOption{transportOpt}, opts...)...)
 
 	if err != nil {
-		return newGrpcError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errWrongConnectConfigError.Error())
+		return newGrpcError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errWrongConnectConfigError.Error(), nil)
 	}
 
 	defer func() {
 		_ = conn.Close()
 	}()
 
+	md := metadata.New(config.Metadata)
+	md.Set(logMetaData, schedulerID)
+	ctx = metadata.NewOutgoingContext(ctx, md)
+
+	if config.Call != nil {
+		return execGrpcCall(ctx, schedulerID, startTime, conn, config.Call)
+	}
+
 	client := health_check.NewHealthClient(conn)
 
-	md := metadata.New(map[string]string{
-		logMetaData: schedulerID,
+	res, err := client.Check(ctx, &health_check.HealthCheckRequest{Service: config.Service})
+
+	if err != nil {
+		return newGrpcError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errConnTimeoutError.Error(), nil)
+	}
+
+	if res.Status != health_check.HealthCheckResponse_SERVING {
+		return newGrpcError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errGrpcNotServing.Error(), nil)
+	}
+	return newGrpcError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", nil)
+}
+
+// Connection is insecure when tls is not configured
+func grpcTransportOption(config *scheduler_config_storage.GrpcTLSConfig) (grpc.DialOption, error) {
+	if config == nil {
+		return grpc.WithInsecure(), nil
+	}
+	tlsConfig, err := helpers.NewTLSConfig(config.InsecureSkipVerify, config.CACert, config.ClientCert, config.ClientKey, config.ServerName)
+	if err != nil {
+		return nil, err
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)), nil
+}
+
+// Calls unary method with request from json, values are selected from json of response
+func execGrpcCall(ctx context.Context, schedulerID string, startTime *timestamp.Timestamp, conn *grpc.ClientConn, config *scheduler_config_storage.GrpcCallConfig) CheckError {
+	method, err := resolveGrpcMethod(ctx, conn, config.Method)
+	if err != nil {
+		return newGrpcError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), nil)
+	}
+
+	request := dynamicpb.NewMessage(method.Input())
+	if config.Request != "" {
+		err = protojson.Unmarshal([]byte(config.Request), request)
+		if err != nil {
+			return newGrpcError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, grpcInvalidRequestErrorFn(err).Error(), nil)
+		}
+	}
+	response := dynamicpb.NewMessage(method.Output())
+
+	err = conn.Invoke(ctx, fmt.Sprintf("/%s/%s", method.Parent().FullName(), method.Name()), request, response)
+	if err != nil {
+		return newGrpcError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), nil)
+	}
+
+	data, err := protojson.Marshal(response)
+	if err != nil {
+		return newGrpcError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), nil)
+	}
+
+	values := []*structType.Value{}
+	var ruleErr error
+	for _, selector := range config.Selectors {
+		res := gjson.GetBytes(data, selector.Path)
+		if !res.Exists() {
+			return newGrpcError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, valueNotExistErrorFn(selector.Path).Error(), nil)
+		}
+		if v := selectorValue(selector, res); v != nil {
+			values = append(values, v)
+		}
+		// Values of all selectors are saved even when first of them does not satisfy rule
+		if ruleErr == nil && selector.Rule != nil {
+			ruleErr = checkSelectorRule(selector, res)
+		}
+	}
+
+	value := &structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{
+				Fields: map[string]*structType.Value{
+					"values": {
+						Kind: &structType.Value_ListValue{
+							ListValue: &structType.ListValue{
+								Values: values,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if ruleErr != nil {
+		return newGrpcError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, ruleErr.Error(), value)
+	}
+	return newGrpcError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", value)
+}
+
+// Finds descriptor of unary method by server reflection, name can be package.Service/Method or package.Service.Method
+func resolveGrpcMethod(ctx context.Context, conn *grpc.ClientConn, name string) (protoreflect.MethodDescriptor, error) {
+	name = strings.TrimPrefix(name, "/")
+	separator := strings.LastIndex(name, "/")
+	if separator == -1 {
+		separator = strings.LastIndex(name, ".")
+	}
+	if separator <= 0 || separator == len(name)-1 {
+		return nil, grpcInvalidMethodErrorFn(name)
+	}
+	serviceName := name[:separator]
+	methodName := name[separator+1:]
+
+	stream, err := reflectionPb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = stream.CloseSend()
+	}()
+
+	resolver := &grpcFileResolver{
+		stream: stream,
+		protos: map[string]*descriptorpb.FileDescriptorProto{},
+		files:  &protoregistry.Files{},
+	}
+	fileName, err := resolver.fetch(&reflectionPb.ServerReflectionRequest{
+		MessageRequest: &reflectionPb.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: serviceName,
+		},
 	})
+	if err != nil {
+		return nil, err
+	}
+	_, err = resolver.build(fileName)
+	if err != nil {
+		return nil, err
+	}
 
-	res, err := client.Check(metadata.NewOutgoingContext(ctx, md), &health_check.HealthCheckRequest{Service: config.Service})
+	descriptor, err := resolver.files.FindDescriptorByName(protoreflect.FullName(serviceName))
+	if err != nil {
+		return nil, grpcMethodNotFoundErrorFn(name)
+	}
+	service, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, grpcMethodNotFoundErrorFn(name)
+	}
+	method := service.Methods().ByName(protoreflect.Name(methodName))
+	if method == nil {
+		return nil, grpcMethodNotFoundErrorFn(name)
+	}
+	if method.IsStreamingClient() || method.IsStreamingServer() {
+		return nil, errGrpcStreamingMethod
+	}
+	return method, nil
+}
 
+type grpcFileResolver struct {
+	stream reflectionPb.ServerReflection_ServerReflectionInfoClient
+	protos map[string]*descriptorpb.FileDescriptorProto
+	files  *protoregistry.Files
+}
+
+// Requests files by reflection, returns name of first file in response which contains requested symbol or file
+func (r *grpcFileResolver) fetch(rq *reflectionPb.ServerReflectionRequest) (string, error) {
+	err := r.stream.Send(rq)
+	if err != nil {
+		return "", err
+	}
+	res, err := r.stream.Recv()
 	if err != nil {
-		return newGrpcError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errConnTimeoutError.Error())
+		return "", err
 	}
+	switch response := res.MessageResponse.(type) {
+	case *reflectionPb.ServerReflectionResponse_FileDescriptorResponse:
+		name := ""
+		for _, data := range response.FileDescriptorResponse.FileDescriptorProto {
+			fileProto := &descriptorpb.FileDescriptorProto{}
+			err = proto.Unmarshal(data, fileProto)
+			if err != nil {
+				return "", err
+			}
+			if name == "" {
+				name = fileProto.GetName()
+			}
+			r.protos[fileProto.GetName()] = fileProto
+		}
+		if name == "" {
+			return "", errGrpcReflectionResponse
+		}
+		return name, nil
+	case *reflectionPb.ServerReflectionResponse_ErrorResponse:
+		return "", grpcReflectionErrorFn(response.ErrorResponse.ErrorMessage)
+	default:
+		return "", errGrpcReflectionResponse
+	}
+}
 
-	if res.Status != health_check.HealthCheckResponse_SERVING {
-		return newGrpcError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errGrpcNotServing.Error())
+// Builds file with its dependencies, dependencies which server does not know are taken from linked files
+func (r *grpcFileResolver) build(name string) (protoreflect.FileDescriptor, error) {
+	if file, err := r.files.FindFileByPath(name); err == nil {
+		return file, nil
+	}
+	fileProto, ok := r.protos[name]
+	if !ok {
+		_, err := r.fetch(&reflectionPb.ServerReflectionRequest{
+			MessageRequest: &reflectionPb.ServerReflectionRequest_FileByFilename{
+				FileByFilename: name,
+			},
+		})
+		fileProto, ok = r.protos[name]
+		if err != nil || !ok {
+			file, globalErr := protoregistry.GlobalFiles.FindFileByPath(name)
+			if globalErr != nil {
+				return nil, grpcFileNotFoundErrorFn(name)
+			}
+			return file, r.files.RegisterFile(file)
+		}
+	}
+	for _, dependency := range fileProto.GetDependency() {
+		_, err := r.build(dependency)
+		if err != nil {
+			return nil, err
+		}
+	}
+	file, err := protodesc.NewFile(fileProto, r.files)
+	if err != nil {
+		return nil, err
 	}
-	return newGrpcError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "")
+	return file, r.files.RegisterFile(file)
 }
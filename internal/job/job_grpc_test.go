@@ -2,13 +2,26 @@ package job
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"errors"
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
 	health_check "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"math/big"
 	"net"
+	"squzy/internal/helpers"
+	monitoring_api "squzy/internal/monitoring-api"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
 	"testing"
 	"time"
@@ -136,3 +149,212 @@ func TestExecGrpc(t *testing.T) {
 		})
 	})
 }
+
+// Starts health server with reflection on random port
+func startReflectionServer(t *testing.T, opts ...grpc.ServerOption) (*grpc.Server, int32) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equal(t, nil, err)
+	grpcServer := grpc.NewServer(opts...)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("squzy", health_check.HealthCheckResponse_NOT_SERVING)
+	health_check.RegisterHealthServer(grpcServer, healthServer)
+	reflection.Register(grpcServer)
+	go func() {
+		_ = grpcServer.Serve(lis)
+	}()
+	return grpcServer, int32(lis.Addr().(*net.TCPAddr).Port)
+}
+
+// Generates self signed certificate for localhost and key in PEM
+func localhostCert(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Equal(t, nil, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.Equal(t, nil, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Equal(t, nil, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}))
+}
+
+func TestExecGrpcCall(t *testing.T) {
+	grpcServer, port := startReflectionServer(t)
+	defer grpcServer.Stop()
+
+	t.Run("Should: call method and select values from response", func(t *testing.T) {
+		job := ExecGrpc("", 1, &scheduler_config_storage.GrpcConfig{
+			Host: "localhost",
+			Port: port,
+			Call: &scheduler_config_storage.GrpcCallConfig{
+				Method:  "grpc.health.v1.Health/Check",
+				Request: `{"service": ""}`,
+				Selectors: []*scheduler_config_storage.Selectors{
+					{
+						Type: apiPb.HttpJsonValueConfig_STRING,
+						Path: "status",
+						Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleEquals, Value: "SERVING"},
+					},
+				},
+			},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		values := job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields["values"].GetListValue().Values
+		assert.Equal(t, "SERVING", values[0].GetStringValue())
+	})
+	t.Run("Should: return error because value does not satisfy rule", func(t *testing.T) {
+		job := ExecGrpc("", 1, &scheduler_config_storage.GrpcConfig{
+			Host: "localhost",
+			Port: port,
+			Call: &scheduler_config_storage.GrpcCallConfig{
+				Method:  "grpc.health.v1.Health.Check",
+				Request: `{"service": "squzy"}`,
+				Selectors: []*scheduler_config_storage.Selectors{
+					{
+						Type: apiPb.HttpJsonValueConfig_STRING,
+						Path: "status",
+						Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleEquals, Value: "SERVING"},
+					},
+				},
+			},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		values := job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields["values"].GetListValue().Values
+		assert.Equal(t, "NOT_SERVING", values[0].GetStringValue())
+	})
+	t.Run("Should: return error because method is streaming", func(t *testing.T) {
+		job := ExecGrpc("", 1, &scheduler_config_storage.GrpcConfig{
+			Host: "localhost",
+			Port: port,
+			Call: &scheduler_config_storage.GrpcCallConfig{Method: "grpc.health.v1.Health/Watch"},
+		})
+		assert.Equal(t, errGrpcStreamingMethod.Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: return error because method does not exist", func(t *testing.T) {
+		job := ExecGrpc("", 1, &scheduler_config_storage.GrpcConfig{
+			Host: "localhost",
+			Port: port,
+			Call: &scheduler_config_storage.GrpcCallConfig{Method: "grpc.health.v1.Health/Get"},
+		})
+		assert.Equal(t, grpcMethodNotFoundErrorFn("grpc.health.v1.Health/Get").Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: return error because method name is invalid", func(t *testing.T) {
+		job := ExecGrpc("", 1, &scheduler_config_storage.GrpcConfig{
+			Host: "localhost",
+			Port: port,
+			Call: &scheduler_config_storage.GrpcCallConfig{Method: "Check"},
+		})
+		assert.Equal(t, grpcInvalidMethodErrorFn("Check").Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: return error because service is unknown", func(t *testing.T) {
+		job := ExecGrpc("", 1, &scheduler_config_storage.GrpcConfig{
+			Host: "localhost",
+			Port: port,
+			Call: &scheduler_config_storage.GrpcCallConfig{Method: "squzy.Unknown/Check"},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+	})
+	t.Run("Should: return error because request is invalid", func(t *testing.T) {
+		job := ExecGrpc("", 1, &scheduler_config_storage.GrpcConfig{
+			Host: "localhost",
+			Port: port,
+			Call: &scheduler_config_storage.GrpcCallConfig{
+				Method:  "grpc.health.v1.Health/Check",
+				Request: `{"unknown": 1}`,
+			},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		assert.Contains(t, job.GetLogData().Snapshot.Error.Message, "invalid request")
+	})
+}
+
+func TestExecGrpcTLS(t *testing.T) {
+	certPEM, keyPEM := localhostCert(t)
+	cert, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	assert.Equal(t, nil, err)
+	grpcServer, port := startReflectionServer(t, grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+	defer grpcServer.Stop()
+
+	t.Run("Should: check health over tls with trusted certificate", func(t *testing.T) {
+		job := ExecGrpc("", 1, &scheduler_config_storage.GrpcConfig{
+			Host: "localhost",
+			Port: port,
+			TLS:  &scheduler_config_storage.GrpcTLSConfig{CACert: certPEM},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+	})
+	t.Run("Should: check health over tls without verification", func(t *testing.T) {
+		job := ExecGrpc("", 1, &scheduler_config_storage.GrpcConfig{
+			Host: "localhost",
+			Port: port,
+			TLS:  &scheduler_config_storage.GrpcTLSConfig{InsecureSkipVerify: true},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+	})
+	t.Run("Should: return error because certificate is not trusted", func(t *testing.T) {
+		job := ExecGrpc("", 1, &scheduler_config_storage.GrpcConfig{
+			Host: "localhost",
+			Port: port,
+			TLS:  &scheduler_config_storage.GrpcTLSConfig{},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+	})
+	t.Run("Should: return error because ca certificate is invalid", func(t *testing.T) {
+		job := ExecGrpc("", 1, &scheduler_config_storage.GrpcConfig{
+			Host: "localhost",
+			Port: port,
+			TLS:  &scheduler_config_storage.GrpcTLSConfig{CACert: "invalid"},
+		})
+		assert.Equal(t, helpers.ErrInvalidCACert.Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+}
+
+type metadataServer struct {
+	server
+}
+
+func (s metadataServer) Check(ctx context.Context, rq *health_check.HealthCheckRequest) (*health_check.HealthCheckResponse, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if len(md.Get("authorization")) == 0 || md.Get("authorization")[0] != "Bearer token" {
+		return nil, errors.New("Unauthorized")
+	}
+	return s.server.Check(ctx, rq)
+}
+
+func TestExecGrpcMetadata(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Equal(t, nil, err)
+	grpcServer := grpc.NewServer()
+	health_check.RegisterHealthServer(grpcServer, &metadataServer{})
+	go func() {
+		_ = grpcServer.Serve(lis)
+	}()
+	defer grpcServer.Stop()
+	port := int32(lis.Addr().(*net.TCPAddr).Port)
+
+	t.Run("Should: send metadata with request", func(t *testing.T) {
+		job := ExecGrpc("id", 1, &scheduler_config_storage.GrpcConfig{
+			Host:     "localhost",
+			Port:     port,
+			Metadata: map[string]string{"authorization": "Bearer token"},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+	})
+	t.Run("Should: return error because metadata is missing", func(t *testing.T) {
+		job := ExecGrpc("id", 1, &scheduler_config_storage.GrpcConfig{
+			Host: "localhost",
+			Port: port,
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+	})
+}
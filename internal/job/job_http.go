@@ -1,12 +1,40 @@
 package job
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/golang/protobuf/ptypes"
+	structType "github.com/golang/protobuf/ptypes/struct"
 	"github.com/golang/protobuf/ptypes/timestamp"
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"net/http"
+	"net/url"
+	"regexp"
 	"squzy/internal/helpers"
 	"squzy/internal/httptools"
+	monitoring_api "squzy/internal/monitoring-api"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"strings"
+	"time"
+)
+
+const (
+	contentTypeText = "text/plain; charset=utf-8"
+	contentTypeJSON = "application/json"
+	contentTypeForm = "application/x-www-form-urlencoded"
+	// Key of meta value under which value of check is saved next to timings
+	httpMetaValueKey = "value"
+)
+
+var (
+	errInvalidRequestBodyType = errors.New("INVALID_REQUEST_BODY_TYPE")
+	errInvalidJSONBody        = errors.New("INVALID_JSON_REQUEST_BODY")
+	errHTTPInvalidAssertion   = errors.New("INVALID_ASSERTION_TYPE")
+	httpAssertionErrorFn      = func(assertionType monitoring_api.HTTPAssertionType, err error) error {
+		return fmt.Errorf("assertion %s failed: %s", assertionType, err.Error())
+	}
 )
 
 type httpError struct {
@@ -15,6 +43,7 @@ type httpError struct {
 	endTime     *timestamp.Timestamp
 	code        apiPb.SchedulerCode
 	description string
+	value       *structType.Value
 }
 
 func (e *httpError) GetLogData() *apiPb.SchedulerResponse {
@@ -33,18 +62,20 @@ func (e *httpError) GetLogData() *apiPb.SchedulerResponse {
 			Meta: &apiPb.SchedulerSnapshot_MetaData{
 				StartTime: e.startTime,
 				EndTime:   e.endTime,
+				Value:     e.value,
 			},
 		},
 	}
 }
 
-func newHTTPError(schedulerID string, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string) CheckError {
+func newHTTPError(schedulerID string, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string, value *structType.Value) CheckError {
 	return &httpError{
 		schedulerID: schedulerID,
 		startTime:   startTime,
 		endTime:     endTime,
 		code:        code,
 		description: description,
+		value:       value,
 	}
 }
 
@@ -52,7 +83,26 @@ func ExecHTTP(schedulerID string, timeout int32, config *scheduler_config_storag
 	startTime := ptypes.TimestampNow()
 	req := httpTool.CreateRequest(config.Method, config.URL, &config.Headers, schedulerID)
 
-	_, _, err := httpTool.SendRequestTimeoutStatusCode(req, helpers.DurationFromSecond(timeout), int(config.StatusCode))
+	err := setRequestBody(req, config.Body)
+	if err != nil {
+		return newHTTPError(
+			schedulerID,
+			startTime,
+			ptypes.TimestampNow(),
+			apiPb.SchedulerCode_ERROR,
+			err.Error(),
+			nil,
+		)
+	}
+
+	req, timings := httptools.WithTimings(httptools.WithClientConfig(req, httpClientConfig(config.Client)))
+	if max, ok := maxBodySize(config.Assertions); ok {
+		req = httptools.WithMaxBodySize(req, max)
+	}
+	requestStart := time.Now()
+	_, headers, body, err := httpTool.SendRequestTimeoutStatusCodeWithHeaders(req, helpers.DurationFromSecond(timeout), int(config.StatusCode))
+	latency := time.Since(requestStart)
+	value := httpMetaValue(nil, timings)
 
 	if err != nil {
 		return newHTTPError(
@@ -61,14 +111,170 @@ func ExecHTTP(schedulerID string, timeout int32, config *scheduler_config_storag
 			ptypes.TimestampNow(),
 			apiPb.SchedulerCode_ERROR,
 			err.Error(),
+			value,
 		)
 	}
 
+	for _, assertion := range config.Assertions {
+		err = checkHTTPAssertion(assertion, headers, body, latency)
+		if err != nil {
+			return newHTTPError(
+				schedulerID,
+				startTime,
+				ptypes.TimestampNow(),
+				apiPb.SchedulerCode_ERROR,
+				httpAssertionErrorFn(assertion.Type, err).Error(),
+				value,
+			)
+		}
+	}
+
 	return newHTTPError(
 		schedulerID,
 		startTime,
 		ptypes.TimestampNow(),
 		apiPb.SchedulerCode_OK,
 		"",
+		value,
 	)
 }
+
+// Body is encoded by type, content type from config has priority over content type from headers
+func setRequestBody(req *http.Request, body *scheduler_config_storage.RequestBody) error {
+	if body == nil {
+		return nil
+	}
+	var data []byte
+	var contentType string
+	switch body.Type {
+	case monitoring_api.RequestBodyRaw:
+		data = []byte(body.Content)
+		contentType = contentTypeText
+	case monitoring_api.RequestBodyJSON:
+		if !json.Valid([]byte(body.Content)) {
+			return errInvalidJSONBody
+		}
+		data = []byte(body.Content)
+		contentType = contentTypeJSON
+	case monitoring_api.RequestBodyForm:
+		form := url.Values{}
+		for k, v := range body.Form {
+			form.Set(k, v)
+		}
+		data = []byte(form.Encode())
+		contentType = contentTypeForm
+	default:
+		return errInvalidRequestBodyType
+	}
+	if req.Header.Get("Content-Type") != "" {
+		contentType = ""
+	}
+	if body.ContentType != "" {
+		contentType = body.ContentType
+	}
+	httptools.SetBody(req, contentType, data)
+	return nil
+}
+
+func checkHTTPAssertion(assertion *scheduler_config_storage.HTTPAssertion, headers http.Header, body []byte, latency time.Duration) error {
+	switch assertion.Type {
+	case monitoring_api.HTTPAssertionBodyContains:
+		if !bytes.Contains(body, []byte(assertion.Value)) {
+			return fmt.Errorf("body does not contain %q", assertion.Value)
+		}
+	case monitoring_api.HTTPAssertionBodyNotContains:
+		if bytes.Contains(body, []byte(assertion.Value)) {
+			return fmt.Errorf("body contains %q", assertion.Value)
+		}
+	case monitoring_api.HTTPAssertionBodyRegex:
+		re, err := regexp.Compile(assertion.Value)
+		if err != nil {
+			return err
+		}
+		if !re.Match(body) {
+			return fmt.Errorf("body does not match %q", assertion.Value)
+		}
+	case monitoring_api.HTTPAssertionMaxSize:
+		if int64(len(body)) > assertion.Max {
+			return fmt.Errorf("body size is more than %d bytes", assertion.Max)
+		}
+	case monitoring_api.HTTPAssertionHeader:
+		values, ok := headers[http.CanonicalHeaderKey(assertion.Header)]
+		if !ok {
+			return fmt.Errorf("header %s is missing", assertion.Header)
+		}
+		if assertion.Value != "" && !containsString(values, assertion.Value) {
+			return fmt.Errorf("header %s is %q, expected %q", assertion.Header, strings.Join(values, ", "), assertion.Value)
+		}
+	case monitoring_api.HTTPAssertionMaxLatency:
+		if latency > time.Duration(assertion.Max)*time.Millisecond {
+			return fmt.Errorf("latency %dms is more than %dms", latency.Milliseconds(), assertion.Max)
+		}
+	default:
+		return errHTTPInvalidAssertion
+	}
+	return nil
+}
+
+// Body is not read further than the smallest max size, bigger body fails check anyway
+func maxBodySize(assertions []*scheduler_config_storage.HTTPAssertion) (int64, bool) {
+	max, ok := int64(0), false
+	for _, assertion := range assertions {
+		if assertion.Type != monitoring_api.HTTPAssertionMaxSize || assertion.Max < 0 {
+			continue
+		}
+		if !ok || assertion.Max < max {
+			max, ok = assertion.Max, true
+		}
+	}
+	return max, ok
+}
+
+// Meta value of http checks, value of check is saved next to latency breakdown of request
+func httpMetaValue(value *structType.Value, timings *httptools.Timings) *structType.Value {
+	fields := map[string]*structType.Value{
+		monitoring_api.SnapshotTimingsKey: httpTimings(timings).ToValue(),
+	}
+	if value != nil {
+		fields[httpMetaValueKey] = value
+	}
+	return &structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{
+				Fields: fields,
+			},
+		},
+	}
+}
+
+func httpTimings(timings *httptools.Timings) *monitoring_api.HTTPTimings {
+	return &monitoring_api.HTTPTimings{
+		DNSLookupMs:       durationToMs(timings.DNSLookup),
+		TCPConnectMs:      durationToMs(timings.TCPConnect),
+		TLSHandshakeMs:    durationToMs(timings.TLSHandshake),
+		TimeToFirstByteMs: durationToMs(timings.TimeToFirstByte),
+		ContentTransferMs: durationToMs(timings.ContentTransfer),
+		TotalMs:           durationToMs(timings.Total),
+		ReusedConnection:  timings.ReusedConnection,
+	}
+}
+
+func durationToMs(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
+
+func httpClientConfig(config *scheduler_config_storage.HTTPClientConfig) *httptools.ClientConfig {
+	if config == nil {
+		return nil
+	}
+	return &httptools.ClientConfig{
+		InsecureSkipVerify: config.InsecureSkipVerify,
+		CACert:             config.CACert,
+		ClientCert:         config.ClientCert,
+		ClientKey:          config.ClientKey,
+		ServerName:         config.ServerName,
+		ProxyURL:           config.ProxyURL,
+		DisableRedirects:   config.DisableRedirects,
+		MaxRedirects:       config.MaxRedirects,
+	}
+}
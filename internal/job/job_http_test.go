@@ -4,7 +4,12 @@ import (
 	"errors"
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
 	"github.com/stretchr/testify/assert"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"squzy/internal/httptools"
+	monitoring_api "squzy/internal/monitoring-api"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
 	"testing"
 	"time"
@@ -17,6 +22,14 @@ func (h httpToolsMock) SendRequestTimeoutStatusCode(req *http.Request, timeout t
 	return 0, nil, nil
 }
 
+func (h httpToolsMock) SendRequestTimeoutStatusCodeWithHeaders(req *http.Request, timeout time.Duration, expectedCode int) (int, http.Header, []byte, error) {
+	return 0, nil, nil, nil
+}
+
+func (h httpToolsMock) SendRequestTimeoutWithHeaders(req *http.Request, timeout time.Duration) (int, http.Header, []byte, error) {
+	return 0, nil, nil, nil
+}
+
 func (h httpToolsMock) SendRequestTimeout(req *http.Request, timeout time.Duration) (int, []byte, error) {
 	panic("implement me")
 }
@@ -33,6 +46,14 @@ func (h httpToolsMockError) SendRequestTimeoutStatusCode(req *http.Request, time
 	return 0, nil, errors.New("safsaf")
 }
 
+func (h httpToolsMockError) SendRequestTimeoutStatusCodeWithHeaders(req *http.Request, timeout time.Duration, expectedCode int) (int, http.Header, []byte, error) {
+	return 0, nil, nil, errors.New("safsaf")
+}
+
+func (h httpToolsMockError) SendRequestTimeoutWithHeaders(req *http.Request, timeout time.Duration) (int, http.Header, []byte, error) {
+	return 0, nil, nil, errors.New("safsaf")
+}
+
 func (h httpToolsMockError) SendRequestTimeout(req *http.Request, timeout time.Duration) (int, []byte, error) {
 	panic("implement me")
 }
@@ -86,3 +107,196 @@ func TestExecHttp(t *testing.T) {
 		assert.Equal(t, apiPb.SchedulerCode_ERROR, s.GetLogData().Snapshot.Code)
 	})
 }
+
+func TestExecHttpAssertions(t *testing.T) {
+	t.Run("Test: Testing http assertions:", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("<h1>Site is under maintenance</h1>"))
+		}))
+		defer server.Close()
+		httpTool := httptools.New("")
+		exec := func(assertions ...*scheduler_config_storage.HTTPAssertion) *apiPb.SchedulerSnapshot {
+			return ExecHTTP("", 1, &scheduler_config_storage.HTTPConfig{
+				Method:     http.MethodGet,
+				URL:        server.URL,
+				StatusCode: http.StatusOK,
+				Assertions: assertions,
+			}, httpTool).GetLogData().Snapshot
+		}
+
+		t.Run("Should: not return error because all assertions passed", func(t *testing.T) {
+			snapshot := exec(
+				&scheduler_config_storage.HTTPAssertion{Type: monitoring_api.HTTPAssertionBodyContains, Value: "<h1>"},
+				&scheduler_config_storage.HTTPAssertion{Type: monitoring_api.HTTPAssertionBodyNotContains, Value: "error"},
+				&scheduler_config_storage.HTTPAssertion{Type: monitoring_api.HTTPAssertionBodyRegex, Value: "under \\w+"},
+				&scheduler_config_storage.HTTPAssertion{Type: monitoring_api.HTTPAssertionMaxSize, Max: 1024},
+				&scheduler_config_storage.HTTPAssertion{Type: monitoring_api.HTTPAssertionHeader, Header: "content-type", Value: "text/html"},
+				&scheduler_config_storage.HTTPAssertion{Type: monitoring_api.HTTPAssertionHeader, Header: "Date"},
+				&scheduler_config_storage.HTTPAssertion{Type: monitoring_api.HTTPAssertionMaxLatency, Max: 1000},
+			)
+			assert.Equal(t, apiPb.SchedulerCode_OK, snapshot.Code)
+		})
+		t.Run("Should: save timings of request", func(t *testing.T) {
+			timings := monitoring_api.HTTPTimingsFromMetaValue(exec().Meta.Value)
+			assert.NotNil(t, timings)
+			assert.NotEqual(t, float64(0), timings.TotalMs)
+		})
+		t.Run("Should: return error because body contains maintenance", func(t *testing.T) {
+			snapshot := exec(&scheduler_config_storage.HTTPAssertion{Type: monitoring_api.HTTPAssertionBodyNotContains, Value: "maintenance"})
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Equal(t, `assertion body_not_contains failed: body contains "maintenance"`, snapshot.Error.Message)
+		})
+		t.Run("Should: return error because body not contains", func(t *testing.T) {
+			snapshot := exec(&scheduler_config_storage.HTTPAssertion{Type: monitoring_api.HTTPAssertionBodyContains, Value: "Welcome"})
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Equal(t, `assertion body_contains failed: body does not contain "Welcome"`, snapshot.Error.Message)
+		})
+		t.Run("Should: return error because body not match regex", func(t *testing.T) {
+			snapshot := exec(&scheduler_config_storage.HTTPAssertion{Type: monitoring_api.HTTPAssertionBodyRegex, Value: "^Welcome"})
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+		})
+		t.Run("Should: return error because invalid regex", func(t *testing.T) {
+			snapshot := exec(&scheduler_config_storage.HTTPAssertion{Type: monitoring_api.HTTPAssertionBodyRegex, Value: "("})
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+		})
+		t.Run("Should: return error because body too big", func(t *testing.T) {
+			snapshot := exec(&scheduler_config_storage.HTTPAssertion{Type: monitoring_api.HTTPAssertionMaxSize, Max: 10})
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Equal(t, "assertion max_size failed: body size is more than 10 bytes", snapshot.Error.Message)
+		})
+		t.Run("Should: use the smallest max size", func(t *testing.T) {
+			max, ok := maxBodySize([]*scheduler_config_storage.HTTPAssertion{
+				{Type: monitoring_api.HTTPAssertionMaxLatency, Max: 5},
+				{Type: monitoring_api.HTTPAssertionMaxSize, Max: 1024},
+				{Type: monitoring_api.HTTPAssertionMaxSize, Max: 10},
+			})
+			assert.True(t, ok)
+			assert.Equal(t, int64(10), max)
+			_, ok = maxBodySize([]*scheduler_config_storage.HTTPAssertion{{Type: monitoring_api.HTTPAssertionMaxLatency, Max: 5}})
+			assert.False(t, ok)
+		})
+		t.Run("Should: return error because header missing", func(t *testing.T) {
+			snapshot := exec(&scheduler_config_storage.HTTPAssertion{Type: monitoring_api.HTTPAssertionHeader, Header: "X-Version"})
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Equal(t, "assertion header failed: header X-Version is missing", snapshot.Error.Message)
+		})
+		t.Run("Should: return error because header has another value", func(t *testing.T) {
+			snapshot := exec(&scheduler_config_storage.HTTPAssertion{Type: monitoring_api.HTTPAssertionHeader, Header: "Content-Type", Value: "application/json"})
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+		})
+		t.Run("Should: return error because latency too big", func(t *testing.T) {
+			snapshot := exec(&scheduler_config_storage.HTTPAssertion{Type: monitoring_api.HTTPAssertionMaxLatency, Max: -1})
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+		})
+		t.Run("Should: return error because unknown assertion", func(t *testing.T) {
+			snapshot := exec(&scheduler_config_storage.HTTPAssertion{Type: "body_json"})
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Equal(t, httpAssertionErrorFn("body_json", errHTTPInvalidAssertion).Error(), snapshot.Error.Message)
+		})
+	})
+}
+
+func TestExecHttpBody(t *testing.T) {
+	t.Run("Test: Testing http request body:", func(t *testing.T) {
+		// Server responds with content type and body of request
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			w.Header().Set("X-Request-Content-Type", r.Header.Get("Content-Type"))
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(body)
+		}))
+		defer server.Close()
+		httpTool := httptools.New("")
+		exec := func(headers map[string]string, body *scheduler_config_storage.RequestBody, contentType string, content string) *apiPb.SchedulerSnapshot {
+			return ExecHTTP("", 1, &scheduler_config_storage.HTTPConfig{
+				Method:     http.MethodPost,
+				URL:        server.URL,
+				Headers:    headers,
+				StatusCode: http.StatusOK,
+				Body:       body,
+				Assertions: []*scheduler_config_storage.HTTPAssertion{
+					{Type: monitoring_api.HTTPAssertionHeader, Header: "X-Request-Content-Type", Value: contentType},
+					{Type: monitoring_api.HTTPAssertionBodyRegex, Value: "^" + regexp.QuoteMeta(content) + "$"},
+				},
+			}, httpTool).GetLogData().Snapshot
+		}
+
+		t.Run("Should: send json body", func(t *testing.T) {
+			snapshot := exec(nil, &scheduler_config_storage.RequestBody{
+				Type:    monitoring_api.RequestBodyJSON,
+				Content: `{"query":"{ health }"}`,
+			}, "application/json", `{"query":"{ health }"}`)
+			assert.Equal(t, apiPb.SchedulerCode_OK, snapshot.Code)
+		})
+		t.Run("Should: send form body", func(t *testing.T) {
+			snapshot := exec(nil, &scheduler_config_storage.RequestBody{
+				Type: monitoring_api.RequestBodyForm,
+				Form: map[string]string{
+					"user": "squzy",
+					"pass": "a&b",
+				},
+			}, "application/x-www-form-urlencoded", "pass=a%26b&user=squzy")
+			assert.Equal(t, apiPb.SchedulerCode_OK, snapshot.Code)
+		})
+		t.Run("Should: send raw body with content type from headers", func(t *testing.T) {
+			snapshot := exec(map[string]string{
+				"Content-Type": "application/xml",
+			}, &scheduler_config_storage.RequestBody{
+				Type:    monitoring_api.RequestBodyRaw,
+				Content: "<health/>",
+			}, "application/xml", "<health/>")
+			assert.Equal(t, apiPb.SchedulerCode_OK, snapshot.Code)
+		})
+		t.Run("Should: send raw body with content type from config", func(t *testing.T) {
+			snapshot := exec(map[string]string{
+				"Content-Type": "application/xml",
+			}, &scheduler_config_storage.RequestBody{
+				Type:        monitoring_api.RequestBodyRaw,
+				Content:     "query { health }",
+				ContentType: "application/graphql",
+			}, "application/graphql", "query { health }")
+			assert.Equal(t, apiPb.SchedulerCode_OK, snapshot.Code)
+		})
+		t.Run("Should: return error because json invalid", func(t *testing.T) {
+			snapshot := exec(nil, &scheduler_config_storage.RequestBody{
+				Type:    monitoring_api.RequestBodyJSON,
+				Content: `{"query":`,
+			}, "", "")
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Equal(t, errInvalidJSONBody.Error(), snapshot.Error.Message)
+		})
+		t.Run("Should: return error because body type invalid", func(t *testing.T) {
+			snapshot := exec(nil, &scheduler_config_storage.RequestBody{
+				Type: "multipart",
+			}, "", "")
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Equal(t, errInvalidRequestBodyType.Error(), snapshot.Error.Message)
+		})
+	})
+}
+
+func TestExecHttpClient(t *testing.T) {
+	t.Run("Test: Testing http client config:", func(t *testing.T) {
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+		httpTool := httptools.New("")
+		exec := func(client *scheduler_config_storage.HTTPClientConfig) *apiPb.SchedulerSnapshot {
+			return ExecHTTP("", 1, &scheduler_config_storage.HTTPConfig{
+				Method:     http.MethodGet,
+				URL:        server.URL,
+				StatusCode: http.StatusOK,
+				Client:     client,
+			}, httpTool).GetLogData().Snapshot
+		}
+		t.Run("Should: return error because certificate is not trusted", func(t *testing.T) {
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, exec(nil).Code)
+		})
+		t.Run("Should: not return error because verification is skipped", func(t *testing.T) {
+			assert.Equal(t, apiPb.SchedulerCode_OK, exec(&scheduler_config_storage.HTTPClientConfig{InsecureSkipVerify: true}).Code)
+		})
+	})
+}
@@ -1,15 +1,21 @@
 package job
 
 import (
+	"errors"
 	"fmt"
 	"github.com/golang/protobuf/ptypes"
 	structType "github.com/golang/protobuf/ptypes/struct"
 	"github.com/golang/protobuf/ptypes/timestamp"
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
 	"github.com/tidwall/gjson"
+	"golang.org/x/net/html"
+	"regexp"
 	"squzy/internal/helpers"
 	"squzy/internal/httptools"
+	monitoring_api "squzy/internal/monitoring-api"
+	"squzy/internal/parsers"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"strings"
 	"time"
 )
 
@@ -26,6 +32,11 @@ var (
 	valueNotExistErrorFn = func(path string) error {
 		return fmt.Errorf("value by path=`%s` not exist", path)
 	}
+	errInvalidSelectorRule = errors.New("INVALID_RULE_FOR_VALUE_TYPE")
+	errInvalidValueFormat  = errors.New("INVALID_VALUE_FORMAT")
+	selectorRuleErrorFn    = func(path string, ruleType monitoring_api.SelectorRuleType, err error) error {
+		return fmt.Errorf("rule %s failed for value by path=`%s`: %s", ruleType, path, err.Error())
+	}
 )
 
 func (e *jsonHTTPError) GetLogData() *apiPb.SchedulerResponse {
@@ -54,6 +65,20 @@ func ExecHTTPValue(schedulerID string, timeout int32, config *scheduler_config_s
 	startTime := ptypes.TimestampNow()
 	req := httpTool.CreateRequest(config.Method, config.URL, &config.Headers, schedulerID)
 
+	err := setRequestBody(req, config.Body)
+	if err != nil {
+		return newJSONHTTPError(
+			schedulerID,
+			startTime,
+			ptypes.TimestampNow(),
+			apiPb.SchedulerCode_ERROR,
+			err.Error(),
+			nil,
+		)
+	}
+
+	// Meta value keeps raw value of selectors, so latency breakdown is not saved for this check
+	req = httptools.WithClientConfig(req, httpClientConfig(config.Client))
 	_, data, err := httpTool.SendRequestTimeout(req, helpers.DurationFromSecond(timeout))
 
 	if err != nil {
@@ -67,8 +92,6 @@ func ExecHTTPValue(schedulerID string, timeout int32, config *scheduler_config_s
 		)
 	}
 
-	jsonString := string(data)
-
 	results := []*structType.Value{}
 
 	if len(config.Selectors) == 0 {
@@ -82,8 +105,31 @@ func ExecHTTPValue(schedulerID string, timeout int32, config *scheduler_config_s
 		)
 	}
 
+	lookup, err := valueLookup(config.Format, data)
+	if err != nil {
+		return newJSONHTTPError(
+			schedulerID,
+			startTime,
+			ptypes.TimestampNow(),
+			apiPb.SchedulerCode_ERROR,
+			err.Error(),
+			nil,
+		)
+	}
+
+	var ruleErr error
 	for _, value := range config.Selectors {
-		res := gjson.Get(jsonString, value.Path)
+		res, err := lookup(value.Path)
+		if err != nil {
+			return newJSONHTTPError(
+				schedulerID,
+				startTime,
+				ptypes.TimestampNow(),
+				apiPb.SchedulerCode_ERROR,
+				err.Error(),
+				nil,
+			)
+		}
 		if !res.Exists() {
 			return newJSONHTTPError(
 				schedulerID,
@@ -94,44 +140,20 @@ func ExecHTTPValue(schedulerID string, timeout int32, config *scheduler_config_s
 				nil,
 			)
 		}
-		switch value.Type {
-		case apiPb.HttpJsonValueConfig_STRING:
-			results = append(results, &structType.Value{
-				Kind: &structType.Value_StringValue{
-					StringValue: res.String(),
-				},
-			})
-		case apiPb.HttpJsonValueConfig_BOOL:
-			results = append(results, &structType.Value{
-				Kind: &structType.Value_BoolValue{
-					BoolValue: res.Bool(),
-				},
-			})
-		case apiPb.HttpJsonValueConfig_NUMBER:
-			results = append(results, &structType.Value{
-				Kind: &structType.Value_NumberValue{
-					NumberValue: res.Float(),
-				},
-			})
-		case apiPb.HttpJsonValueConfig_TIME:
-			results = append(results, &structType.Value{
-				Kind: &structType.Value_StringValue{
-					StringValue: res.Time().Format(time.RFC3339),
-				},
-			})
-		case apiPb.HttpJsonValueConfig_ANY:
-			results = append(results, &structType.Value{
-				Kind: &structType.Value_StringValue{
-					StringValue: fmt.Sprintf("%v", res.Value()),
-				},
-			})
-		case apiPb.HttpJsonValueConfig_RAW:
-			results = append(results, &structType.Value{
-				Kind: &structType.Value_StringValue{
-					StringValue: res.Raw,
-				},
-			})
+		if v := selectorValue(value, res); v != nil {
+			results = append(results, v)
 		}
+		// Values of all selectors are saved even when first of them does not satisfy rule
+		if ruleErr == nil && value.Rule != nil {
+			ruleErr = checkSelectorRule(value, res)
+		}
+	}
+
+	code := apiPb.SchedulerCode_OK
+	description := ""
+	if ruleErr != nil {
+		code = apiPb.SchedulerCode_ERROR
+		description = ruleErr.Error()
 	}
 
 	if len(config.Selectors) == 1 {
@@ -139,8 +161,8 @@ func ExecHTTPValue(schedulerID string, timeout int32, config *scheduler_config_s
 			schedulerID,
 			startTime,
 			ptypes.TimestampNow(),
-			apiPb.SchedulerCode_OK,
-			"",
+			code,
+			description,
 			results[0],
 		)
 	}
@@ -149,8 +171,8 @@ func ExecHTTPValue(schedulerID string, timeout int32, config *scheduler_config_s
 		schedulerID,
 		startTime,
 		ptypes.TimestampNow(),
-		apiPb.SchedulerCode_OK,
-		"",
+		code,
+		description,
 		&structType.Value{
 			Kind: &structType.Value_ListValue{
 				ListValue: &structType.ListValue{
@@ -161,6 +183,186 @@ func ExecHTTPValue(schedulerID string, timeout int32, config *scheduler_config_s
 	)
 }
 
+// Returns function which finds value by path in response of given format.
+// Text of first matched xml or html node is typed by same rules as json string, raw value is markup of node
+func valueLookup(format monitoring_api.ValueFormat, data []byte) (func(path string) (gjson.Result, error), error) {
+	var root *html.Node
+	var err error
+	render := parsers.RenderNode
+	switch format {
+	case "", monitoring_api.ValueFormatJSON:
+		return func(path string) (gjson.Result, error) {
+			return gjson.GetBytes(data, path), nil
+		}, nil
+	case monitoring_api.ValueFormatXML:
+		return xmlValueLookup(data)
+	case monitoring_api.ValueFormatHTML, monitoring_api.ValueFormatHTMLCSS:
+		root, err = parsers.ParseHTML(data)
+	default:
+		return nil, errInvalidValueFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func(path string) (gjson.Result, error) {
+		var nodes []*html.Node
+		var err error
+		if format == monitoring_api.ValueFormatHTMLCSS {
+			nodes, err = parsers.SelectCSS(root, path)
+		} else {
+			nodes, err = parsers.SelectXPath(root, path)
+		}
+		if err != nil || len(nodes) == 0 {
+			return gjson.Result{}, err
+		}
+		raw, err := render(nodes[0])
+		if err != nil {
+			return gjson.Result{}, err
+		}
+		return gjson.Result{
+			Type: gjson.String,
+			Str:  strings.TrimSpace(parsers.NodeText(nodes[0])),
+			Raw:  raw,
+		}, nil
+	}, nil
+}
+
+func xmlValueLookup(data []byte) (func(path string) (gjson.Result, error), error) {
+	root, err := parsers.ParseXML(data)
+	if err != nil {
+		return nil, err
+	}
+	return func(path string) (gjson.Result, error) {
+		nodes, err := parsers.SelectXMLXPath(root, path)
+		if err != nil || len(nodes) == 0 {
+			return gjson.Result{}, err
+		}
+		return gjson.Result{
+			Type: gjson.String,
+			Str:  strings.TrimSpace(nodes[0].InnerText()),
+			Raw:  parsers.RenderXMLNode(nodes[0]),
+		}, nil
+	}, nil
+}
+
+func selectorValue(selector *scheduler_config_storage.Selectors, res gjson.Result) *structType.Value {
+	switch selector.Type {
+	case apiPb.HttpJsonValueConfig_STRING:
+		return &structType.Value{
+			Kind: &structType.Value_StringValue{
+				StringValue: res.String(),
+			},
+		}
+	case apiPb.HttpJsonValueConfig_BOOL:
+		return &structType.Value{
+			Kind: &structType.Value_BoolValue{
+				BoolValue: res.Bool(),
+			},
+		}
+	case apiPb.HttpJsonValueConfig_NUMBER:
+		return &structType.Value{
+			Kind: &structType.Value_NumberValue{
+				NumberValue: res.Float(),
+			},
+		}
+	case apiPb.HttpJsonValueConfig_TIME:
+		return &structType.Value{
+			Kind: &structType.Value_StringValue{
+				StringValue: res.Time().Format(time.RFC3339),
+			},
+		}
+	case apiPb.HttpJsonValueConfig_ANY:
+		return &structType.Value{
+			Kind: &structType.Value_StringValue{
+				StringValue: fmt.Sprintf("%v", res.Value()),
+			},
+		}
+	case apiPb.HttpJsonValueConfig_RAW:
+		return &structType.Value{
+			Kind: &structType.Value_StringValue{
+				StringValue: res.Raw,
+			},
+		}
+	}
+	return nil
+}
+
+func checkSelectorRule(selector *scheduler_config_storage.Selectors, res gjson.Result) error {
+	rule := selector.Rule
+	var err error
+	switch {
+	case selector.Type == apiPb.HttpJsonValueConfig_NUMBER:
+		err = checkNumberRule(rule, res.Float())
+	case selector.Type == apiPb.HttpJsonValueConfig_BOOL && rule.Type == monitoring_api.SelectorRuleEquals:
+		if res.Bool() != rule.Bool {
+			err = fmt.Errorf("%t is not equal to %t", res.Bool(), rule.Bool)
+		}
+	case selector.Type == apiPb.HttpJsonValueConfig_TIME && rule.Type == monitoring_api.SelectorRuleNotOlder:
+		age := time.Since(res.Time())
+		if age.Seconds() > float64(rule.Seconds) {
+			err = fmt.Errorf("%s is older than %d seconds", res.Time().Format(time.RFC3339), rule.Seconds)
+		}
+	case selector.Type == apiPb.HttpJsonValueConfig_STRING ||
+		selector.Type == apiPb.HttpJsonValueConfig_ANY ||
+		selector.Type == apiPb.HttpJsonValueConfig_RAW:
+		value := res.String()
+		if selector.Type == apiPb.HttpJsonValueConfig_RAW {
+			value = res.Raw
+		}
+		err = checkStringRule(rule, value)
+	default:
+		err = errInvalidSelectorRule
+	}
+	if err != nil {
+		return selectorRuleErrorFn(selector.Path, rule.Type, err)
+	}
+	return nil
+}
+
+func checkNumberRule(rule *scheduler_config_storage.SelectorRule, value float64) error {
+	switch rule.Type {
+	case monitoring_api.SelectorRuleGreater:
+		if value <= rule.Number {
+			return fmt.Errorf("%v is not greater than %v", value, rule.Number)
+		}
+	case monitoring_api.SelectorRuleLess:
+		if value >= rule.Number {
+			return fmt.Errorf("%v is not less than %v", value, rule.Number)
+		}
+	case monitoring_api.SelectorRuleBetween:
+		if value < rule.Min || value > rule.Max {
+			return fmt.Errorf("%v is not between %v and %v", value, rule.Min, rule.Max)
+		}
+	case monitoring_api.SelectorRuleEquals:
+		if value != rule.Number {
+			return fmt.Errorf("%v is not equal to %v", value, rule.Number)
+		}
+	default:
+		return errInvalidSelectorRule
+	}
+	return nil
+}
+
+func checkStringRule(rule *scheduler_config_storage.SelectorRule, value string) error {
+	switch rule.Type {
+	case monitoring_api.SelectorRuleEquals:
+		if value != rule.Value {
+			return fmt.Errorf("%q is not equal to %q", value, rule.Value)
+		}
+	case monitoring_api.SelectorRuleRegex:
+		re, err := regexp.Compile(rule.Value)
+		if err != nil {
+			return err
+		}
+		if !re.MatchString(value) {
+			return fmt.Errorf("%q does not match %q", value, rule.Value)
+		}
+	default:
+		return errInvalidSelectorRule
+	}
+	return nil
+}
+
 func newJSONHTTPError(schedulerID string, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string, value *structType.Value) CheckError {
 	return &jsonHTTPError{
 		schedulerID: schedulerID,
@@ -6,6 +6,7 @@ import (
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	monitoring_api "squzy/internal/monitoring-api"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
 	"testing"
 	"time"
@@ -41,6 +42,14 @@ func (m mockSuccess) SendRequestWithStatusCode(req *http.Request, expectedCode i
 	panic("implement me")
 }
 
+func (m mockSuccess) SendRequestTimeoutStatusCodeWithHeaders(req *http.Request, timeout time.Duration, expectedCode int) (int, http.Header, []byte, error) {
+	panic("implement me")
+}
+
+func (m mockSuccess) SendRequestTimeoutWithHeaders(req *http.Request, timeout time.Duration) (int, http.Header, []byte, error) {
+	panic("implement me")
+}
+
 func (m mockSuccess) CreateRequest(method string, url string, headers *map[string]string, logId string) *http.Request {
 	req, _ := http.NewRequest(method, url, nil)
 	return req
@@ -54,6 +63,14 @@ func (m mockError) SendRequestWithStatusCode(req *http.Request, expectedCode int
 	panic("implement me")
 }
 
+func (m mockError) SendRequestTimeoutStatusCodeWithHeaders(req *http.Request, timeout time.Duration, expectedCode int) (int, http.Header, []byte, error) {
+	panic("implement me")
+}
+
+func (m mockError) SendRequestTimeoutWithHeaders(req *http.Request, timeout time.Duration) (int, http.Header, []byte, error) {
+	panic("implement me")
+}
+
 func (m mockError) CreateRequest(method string, url string, headers *map[string]string, logId string) *http.Request {
 	req, _ := http.NewRequest(method, url, nil)
 	return req
@@ -74,6 +91,21 @@ func TestExecHttpValue(t *testing.T) {
 		assert.Equal(t, apiPb.SchedulerCode_ERROR, s.GetLogData().Snapshot.Code)
 		assert.Equal(t, "", s.GetLogData().Snapshot.Meta.Value.GetStringValue())
 	})
+	t.Run("Should: return error because request body invalid", func(t *testing.T) {
+		s := ExecHTTPValue("", 0, &scheduler_config_storage.HTTPValueConfig{Method: http.MethodPost, Headers: map[string]string{}, Body: &scheduler_config_storage.RequestBody{
+			Type:    monitoring_api.RequestBodyJSON,
+			Content: "{",
+		}}, &mockSuccess{})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, s.GetLogData().Snapshot.Code)
+		assert.Equal(t, errInvalidJSONBody.Error(), s.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: not return error with request body", func(t *testing.T) {
+		s := ExecHTTPValue("", 0, &scheduler_config_storage.HTTPValueConfig{Method: http.MethodPost, Headers: map[string]string{}, Body: &scheduler_config_storage.RequestBody{
+			Type:    monitoring_api.RequestBodyJSON,
+			Content: `{"query":"{ health }"}`,
+		}}, &mockSuccess{})
+		assert.Equal(t, apiPb.SchedulerCode_OK, s.GetLogData().Snapshot.Code)
+	})
 	t.Run("Should: not return error because selectors is missing", func(t *testing.T) {
 		s := ExecHTTPValue("", 0, &scheduler_config_storage.HTTPValueConfig{Method: http.MethodGet, Headers: map[string]string{}}, &mockSuccess{})
 		assert.Equal(t, apiPb.SchedulerCode_OK, s.GetLogData().Snapshot.Code)
@@ -138,6 +170,16 @@ func TestExecHttpValue(t *testing.T) {
 		assert.Equal(t, apiPb.SchedulerCode_OK, s.GetLogData().Snapshot.Code)
 		assert.Equal(t, "2012-04-23T18:25:43Z", s.GetLogData().Snapshot.Meta.Value.GetStringValue())
 	})
+	t.Run("Should: save value without timings", func(t *testing.T) {
+		s := ExecHTTPValue("", 0, &scheduler_config_storage.HTTPValueConfig{Method: http.MethodGet, Headers: map[string]string{}, Selectors: []*scheduler_config_storage.Selectors{
+			{
+				Type: apiPb.HttpJsonValueConfig_NUMBER,
+				Path: "age",
+			},
+		}}, &mockSuccess{})
+		assert.Equal(t, float64(31), s.GetLogData().Snapshot.Meta.Value.GetNumberValue())
+		assert.Nil(t, monitoring_api.HTTPTimingsFromMetaValue(s.GetLogData().Snapshot.Meta.Value))
+	})
 	t.Run("Should: parse multipile value", func(t *testing.T) {
 		s := ExecHTTPValue("", 0, &scheduler_config_storage.HTTPValueConfig{Method: http.MethodGet, Headers: map[string]string{}, Selectors: []*scheduler_config_storage.Selectors{
 			{
@@ -166,3 +208,219 @@ func TestExecHttpValue(t *testing.T) {
 		}, s.GetLogData().Snapshot.Meta.Value.GetListValue())
 	})
 }
+
+func TestExecHttpValueRules(t *testing.T) {
+	exec := func(selectors ...*scheduler_config_storage.Selectors) *apiPb.SchedulerSnapshot {
+		return ExecHTTPValue("", 0, &scheduler_config_storage.HTTPValueConfig{
+			Method:    http.MethodGet,
+			Headers:   map[string]string{},
+			Selectors: selectors,
+		}, &mockSuccess{}).GetLogData().Snapshot
+	}
+	number := func(rule *scheduler_config_storage.SelectorRule) *scheduler_config_storage.Selectors {
+		return &scheduler_config_storage.Selectors{Type: apiPb.HttpJsonValueConfig_NUMBER, Path: "age", Rule: rule}
+	}
+	t.Run("Should: not return error because all rules satisfied", func(t *testing.T) {
+		snapshot := exec(
+			number(&scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleGreater, Number: 30}),
+			number(&scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleLess, Number: 32}),
+			number(&scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleBetween, Min: 31, Max: 31}),
+			number(&scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleEquals, Number: 31}),
+			&scheduler_config_storage.Selectors{
+				Type: apiPb.HttpJsonValueConfig_STRING,
+				Path: "name",
+				Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleEquals, Value: "John"},
+			},
+			&scheduler_config_storage.Selectors{
+				Type: apiPb.HttpJsonValueConfig_STRING,
+				Path: "city",
+				Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleRegex, Value: "^New"},
+			},
+			&scheduler_config_storage.Selectors{
+				Type: apiPb.HttpJsonValueConfig_RAW,
+				Path: "raw",
+				Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleRegex, Value: "ahha"},
+			},
+			&scheduler_config_storage.Selectors{
+				Type: apiPb.HttpJsonValueConfig_BOOL,
+				Path: "success",
+				Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleEquals, Bool: true},
+			},
+			&scheduler_config_storage.Selectors{
+				Type: apiPb.HttpJsonValueConfig_TIME,
+				Path: "time",
+				Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleNotOlder, Seconds: 1 << 40},
+			},
+		)
+		assert.Equal(t, apiPb.SchedulerCode_OK, snapshot.Code)
+	})
+	t.Run("Should: return error with values because number is greater", func(t *testing.T) {
+		snapshot := exec(
+			&scheduler_config_storage.Selectors{Type: apiPb.HttpJsonValueConfig_STRING, Path: "name"},
+			number(&scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleLess, Number: 18}),
+		)
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+		assert.Equal(t, "rule lt failed for value by path=`age`: 31 is not less than 18", snapshot.Error.Message)
+		assert.Equal(t, 2, len(snapshot.Meta.Value.GetListValue().Values))
+	})
+	t.Run("Should: return error because number is less", func(t *testing.T) {
+		snapshot := exec(number(&scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleGreater, Number: 31}))
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+		assert.Equal(t, float64(31), snapshot.Meta.Value.GetNumberValue())
+	})
+	t.Run("Should: return error because number not between", func(t *testing.T) {
+		snapshot := exec(number(&scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleBetween, Min: 0, Max: 30}))
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+	})
+	t.Run("Should: return error because number not equal", func(t *testing.T) {
+		snapshot := exec(number(&scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleEquals, Number: 30}))
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+	})
+	t.Run("Should: return error because string not equal", func(t *testing.T) {
+		snapshot := exec(&scheduler_config_storage.Selectors{
+			Type: apiPb.HttpJsonValueConfig_STRING,
+			Path: "name",
+			Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleEquals, Value: "Bob"},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+	})
+	t.Run("Should: return error because string not match", func(t *testing.T) {
+		snapshot := exec(&scheduler_config_storage.Selectors{
+			Type: apiPb.HttpJsonValueConfig_STRING,
+			Path: "name",
+			Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleRegex, Value: "^B"},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+	})
+	t.Run("Should: return error because regex invalid", func(t *testing.T) {
+		snapshot := exec(&scheduler_config_storage.Selectors{
+			Type: apiPb.HttpJsonValueConfig_STRING,
+			Path: "name",
+			Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleRegex, Value: "("},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+	})
+	t.Run("Should: return error because bool not equal", func(t *testing.T) {
+		snapshot := exec(&scheduler_config_storage.Selectors{
+			Type: apiPb.HttpJsonValueConfig_BOOL,
+			Path: "success",
+			Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleEquals, Bool: false},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+	})
+	t.Run("Should: return error because time is stale", func(t *testing.T) {
+		snapshot := exec(&scheduler_config_storage.Selectors{
+			Type: apiPb.HttpJsonValueConfig_TIME,
+			Path: "time",
+			Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleNotOlder, Seconds: 60},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+	})
+	t.Run("Should: return error because rule not supported by type", func(t *testing.T) {
+		snapshot := exec(&scheduler_config_storage.Selectors{
+			Type: apiPb.HttpJsonValueConfig_BOOL,
+			Path: "success",
+			Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleGreater},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+		assert.Equal(t, selectorRuleErrorFn("success", monitoring_api.SelectorRuleGreater, errInvalidSelectorRule).Error(), snapshot.Error.Message)
+	})
+	t.Run("Should: return error because number rule unknown", func(t *testing.T) {
+		snapshot := exec(number(&scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleRegex}))
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+	})
+}
+
+type mockMarkup struct {
+	mockSuccess
+	body string
+}
+
+func (m mockMarkup) SendRequestTimeout(req *http.Request, timeout time.Duration) (int, []byte, error) {
+	return 0, []byte(m.body), nil
+}
+
+func TestExecHttpValueMarkup(t *testing.T) {
+	xmlBody := `<?xml version="1.0"?>
+<rss version="2.0"><channel>
+	<title>Status</title>
+	<item><title>All systems operational</title><pubDate>2020-06-01T10:00:00Z</pubDate><open>false</open><count>3</count></item>
+</channel></rss>`
+	htmlBody := `<html><body><div class="status"><span id="state">operational</span><span class="incidents"> 0 </span></div></body></html>`
+	exec := func(format monitoring_api.ValueFormat, body string, selectors ...*scheduler_config_storage.Selectors) *apiPb.SchedulerSnapshot {
+		return ExecHTTPValue("", 0, &scheduler_config_storage.HTTPValueConfig{
+			Method:    http.MethodGet,
+			Headers:   map[string]string{},
+			Format:    format,
+			Selectors: selectors,
+		}, &mockMarkup{body: body}).GetLogData().Snapshot
+	}
+	t.Run("Should: parse values from xml by xpath", func(t *testing.T) {
+		snapshot := exec(monitoring_api.ValueFormatXML, xmlBody,
+			&scheduler_config_storage.Selectors{Type: apiPb.HttpJsonValueConfig_STRING, Path: "//item/title"},
+			&scheduler_config_storage.Selectors{Type: apiPb.HttpJsonValueConfig_TIME, Path: "//item/pubDate"},
+			&scheduler_config_storage.Selectors{Type: apiPb.HttpJsonValueConfig_BOOL, Path: "//item/open"},
+			&scheduler_config_storage.Selectors{
+				Type: apiPb.HttpJsonValueConfig_NUMBER,
+				Path: "//item/count",
+				Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleLess, Number: 5},
+			},
+			&scheduler_config_storage.Selectors{Type: apiPb.HttpJsonValueConfig_RAW, Path: "/rss/channel/title"},
+			&scheduler_config_storage.Selectors{Type: apiPb.HttpJsonValueConfig_ANY, Path: "/rss/@version"},
+		)
+		assert.Equal(t, apiPb.SchedulerCode_OK, snapshot.Code)
+		values := snapshot.Meta.Value.GetListValue().Values
+		assert.Equal(t, "All systems operational", values[0].GetStringValue())
+		assert.Equal(t, "2020-06-01T10:00:00Z", values[1].GetStringValue())
+		assert.Equal(t, false, values[2].GetBoolValue())
+		assert.Equal(t, float64(3), values[3].GetNumberValue())
+		assert.Equal(t, "<title>Status</title>", values[4].GetStringValue())
+		assert.Equal(t, "2.0", values[5].GetStringValue())
+	})
+	t.Run("Should: parse values from xml with namespaces by xpath with prefixes", func(t *testing.T) {
+		body := `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><s:Status xmlns:s="urn:status">ok</s:Status></soap:Body></soap:Envelope>`
+		snapshot := exec(monitoring_api.ValueFormatXML, body,
+			&scheduler_config_storage.Selectors{Type: apiPb.HttpJsonValueConfig_STRING, Path: "/soap:Envelope/soap:Body/s:Status"},
+			&scheduler_config_storage.Selectors{Type: apiPb.HttpJsonValueConfig_RAW, Path: "//soap:Body"},
+		)
+		assert.Equal(t, apiPb.SchedulerCode_OK, snapshot.Code)
+		values := snapshot.Meta.Value.GetListValue().Values
+		assert.Equal(t, "ok", values[0].GetStringValue())
+		assert.Equal(t, `<soap:Body><s:Status xmlns:s="urn:status">ok</s:Status></soap:Body>`, values[1].GetStringValue())
+	})
+	t.Run("Should: parse values from html by xpath and css", func(t *testing.T) {
+		snapshot := exec(monitoring_api.ValueFormatHTML, htmlBody,
+			&scheduler_config_storage.Selectors{
+				Type: apiPb.HttpJsonValueConfig_STRING,
+				Path: "//span[@id='state']",
+				Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleEquals, Value: "operational"},
+			},
+		)
+		assert.Equal(t, apiPb.SchedulerCode_OK, snapshot.Code)
+		snapshot = exec(monitoring_api.ValueFormatHTMLCSS, htmlBody,
+			&scheduler_config_storage.Selectors{
+				Type: apiPb.HttpJsonValueConfig_NUMBER,
+				Path: ".status .incidents",
+				Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleGreater, Number: 0},
+			},
+		)
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+		assert.Equal(t, "rule gt failed for value by path=`.status .incidents`: 0 is not greater than 0", snapshot.Error.Message)
+	})
+	t.Run("Should: return error because value not exist", func(t *testing.T) {
+		snapshot := exec(monitoring_api.ValueFormatXML, xmlBody, &scheduler_config_storage.Selectors{Type: apiPb.HttpJsonValueConfig_STRING, Path: "//guid"})
+		assert.Equal(t, valueNotExistErrorFn("//guid").Error(), snapshot.Error.Message)
+	})
+	t.Run("Should: return error because path is invalid", func(t *testing.T) {
+		snapshot := exec(monitoring_api.ValueFormatHTMLCSS, htmlBody, &scheduler_config_storage.Selectors{Type: apiPb.HttpJsonValueConfig_STRING, Path: "span["})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+	})
+	t.Run("Should: return error because xml is invalid", func(t *testing.T) {
+		snapshot := exec(monitoring_api.ValueFormatXML, htmlBody+"<", &scheduler_config_storage.Selectors{Type: apiPb.HttpJsonValueConfig_STRING, Path: "//span"})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+	})
+	t.Run("Should: return error because format is invalid", func(t *testing.T) {
+		snapshot := exec("yaml", xmlBody, &scheduler_config_storage.Selectors{Type: apiPb.HttpJsonValueConfig_STRING, Path: "title"})
+		assert.Equal(t, errInvalidValueFormat.Error(), snapshot.Error.Message)
+	})
+}
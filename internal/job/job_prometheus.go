@@ -0,0 +1,288 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	structType "github.com/golang/protobuf/ptypes/struct"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"math"
+	"net/http"
+	"regexp"
+	"squzy/internal/helpers"
+	"squzy/internal/httptools"
+	monitoring_api "squzy/internal/monitoring-api"
+	"squzy/internal/parsers"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+)
+
+const (
+	// Asks exporters which support negotiation to answer in text format
+	prometheusAcceptHeader = "text/plain;version=0.0.4;q=1,*/*;q=0.1"
+)
+
+var (
+	errInvalidMetricMatchType   = errors.New("INVALID_METRIC_MATCH_TYPE")
+	errInvalidMetricAggregation = errors.New("INVALID_METRIC_AGGREGATION")
+	metricNotExistErrorFn       = func(name string) error {
+		return fmt.Errorf("series of metric `%s` not exist", name)
+	}
+	metricRuleErrorFn = func(name string, ruleType monitoring_api.SelectorRuleType, err error) error {
+		return fmt.Errorf("rule %s failed for metric `%s`: %s", ruleType, name, err.Error())
+	}
+)
+
+type prometheusError struct {
+	schedulerID string
+	startTime   *timestamp.Timestamp
+	endTime     *timestamp.Timestamp
+	code        apiPb.SchedulerCode
+	description string
+	value       *structType.Value
+}
+
+func (e *prometheusError) GetLogData() *apiPb.SchedulerResponse {
+	var err *apiPb.SchedulerSnapshot_Error
+	if e.code == apiPb.SchedulerCode_ERROR {
+		err = &apiPb.SchedulerSnapshot_Error{
+			Message: e.description,
+		}
+	}
+	return &apiPb.SchedulerResponse{
+		SchedulerId: e.schedulerID,
+		Snapshot: &apiPb.SchedulerSnapshot{
+			Code:  e.code,
+			Error: err,
+			Type:  monitoring_api.SchedulerTypePrometheus,
+			Meta: &apiPb.SchedulerSnapshot_MetaData{
+				StartTime: e.startTime,
+				EndTime:   e.endTime,
+				Value:     e.value,
+			},
+		},
+	}
+}
+
+func newPrometheusError(schedulerID string, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string, value *structType.Value) CheckError {
+	return &prometheusError{
+		schedulerID: schedulerID,
+		startTime:   startTime,
+		endTime:     endTime,
+		code:        code,
+		description: description,
+		value:       value,
+	}
+}
+
+// Label matcher with compiled regex
+type metricMatcher struct {
+	config  *scheduler_config_storage.MetricLabelMatcher
+	pattern *regexp.Regexp
+}
+
+func ExecPrometheus(schedulerID string, timeout int32, config *scheduler_config_storage.PrometheusConfig, httpTool httptools.HTTPTool) CheckError {
+	startTime := ptypes.TimestampNow()
+
+	headers := map[string]string{}
+	for name, value := range config.Headers {
+		headers[name] = value
+	}
+	if _, ok := headers["Accept"]; !ok {
+		headers["Accept"] = prometheusAcceptHeader
+	}
+	req := httpTool.CreateRequest(http.MethodGet, config.URL, &headers, schedulerID)
+	req, timings := httptools.WithTimings(httptools.WithClientConfig(req, httpClientConfig(config.Client)))
+	_, data, err := httpTool.SendRequestTimeoutStatusCode(req, helpers.DurationFromSecond(timeout), http.StatusOK)
+	if err != nil {
+		return newPrometheusError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), httpMetaValue(nil, timings))
+	}
+
+	samples, err := parsers.ParseMetrics(data)
+	if err != nil {
+		return newPrometheusError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), httpMetaValue(nil, timings))
+	}
+
+	results := []*structType.Value{}
+	var ruleErr error
+	for _, selector := range config.Selectors {
+		matched, err := selectSamples(selector, samples)
+		if err != nil {
+			return newPrometheusError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), httpMetaValue(nil, timings))
+		}
+		if len(matched) == 0 {
+			return newPrometheusError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, metricNotExistErrorFn(selector.Name).Error(), httpMetaValue(nil, timings))
+		}
+		result, err := checkMetricSelector(selector, matched)
+		if err != nil && ruleErr == nil {
+			// Values of all selectors are saved even when first of them does not satisfy rule
+			ruleErr = err
+		}
+		results = append(results, result)
+	}
+
+	value := httpMetaValue(&structType.Value{
+		Kind: &structType.Value_ListValue{
+			ListValue: &structType.ListValue{
+				Values: results,
+			},
+		},
+	}, timings)
+	if ruleErr != nil {
+		return newPrometheusError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, ruleErr.Error(), value)
+	}
+	return newPrometheusError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", value)
+}
+
+func selectSamples(selector *scheduler_config_storage.MetricSelector, samples []*parsers.MetricSample) ([]*parsers.MetricSample, error) {
+	matchers := []*metricMatcher{}
+	for _, label := range selector.Labels {
+		matcher, err := newMetricMatcher(label)
+		if err != nil {
+			return nil, err
+		}
+		matchers = append(matchers, matcher)
+	}
+	result := []*parsers.MetricSample{}
+	for _, sample := range samples {
+		if sample.Name != selector.Name {
+			continue
+		}
+		matched := true
+		for _, matcher := range matchers {
+			if !matcher.match(sample.Labels[matcher.config.Name]) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			result = append(result, sample)
+		}
+	}
+	return result, nil
+}
+
+func newMetricMatcher(config *scheduler_config_storage.MetricLabelMatcher) (*metricMatcher, error) {
+	switch config.Type {
+	case monitoring_api.MetricMatchEqual, monitoring_api.MetricMatchNotEqual:
+		return &metricMatcher{config: config}, nil
+	case monitoring_api.MetricMatchRegex, monitoring_api.MetricMatchNotRegex:
+		pattern, err := regexp.Compile("^(?:" + config.Value + ")$")
+		if err != nil {
+			return nil, invalidPatternErrorFn(config.Value, err)
+		}
+		return &metricMatcher{config: config, pattern: pattern}, nil
+	default:
+		return nil, errInvalidMetricMatchType
+	}
+}
+
+// Missing label is matched as empty value same as in PromQL
+func (m *metricMatcher) match(value string) bool {
+	switch m.config.Type {
+	case monitoring_api.MetricMatchEqual:
+		return value == m.config.Value
+	case monitoring_api.MetricMatchNotEqual:
+		return value != m.config.Value
+	case monitoring_api.MetricMatchRegex:
+		return m.pattern.MatchString(value)
+	default:
+		return !m.pattern.MatchString(value)
+	}
+}
+
+// Returns values of series and checks rule against aggregated value or against every series
+func checkMetricSelector(selector *scheduler_config_storage.MetricSelector, samples []*parsers.MetricSample) (*structType.Value, error) {
+	series := []*structType.Value{}
+	for _, sample := range samples {
+		series = append(series, metricSampleValue(sample))
+	}
+	fields := map[string]*structType.Value{
+		"name": stringValue(selector.Name),
+		"series": {
+			Kind: &structType.Value_ListValue{
+				ListValue: &structType.ListValue{Values: series},
+			},
+		},
+	}
+	result := &structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{Fields: fields},
+		},
+	}
+
+	values := []float64{}
+	if selector.Aggregate != "" {
+		aggregated, err := aggregateSamples(selector.Aggregate, samples)
+		if err != nil {
+			return result, err
+		}
+		fields["value"] = numberValue(aggregated)
+		values = append(values, aggregated)
+	} else {
+		for _, sample := range samples {
+			values = append(values, sample.Value)
+		}
+	}
+	if selector.Rule == nil {
+		return result, nil
+	}
+	for _, value := range values {
+		err := checkNumberRule(selector.Rule, value)
+		if err != nil {
+			return result, metricRuleErrorFn(selector.Name, selector.Rule.Type, err)
+		}
+	}
+	return result, nil
+}
+
+func aggregateSamples(aggregation monitoring_api.MetricAggregation, samples []*parsers.MetricSample) (float64, error) {
+	switch aggregation {
+	case monitoring_api.MetricAggregationCount:
+		return float64(len(samples)), nil
+	case monitoring_api.MetricAggregationSum, monitoring_api.MetricAggregationAvg:
+		sum := float64(0)
+		for _, sample := range samples {
+			sum += sample.Value
+		}
+		if aggregation == monitoring_api.MetricAggregationAvg {
+			return sum / float64(len(samples)), nil
+		}
+		return sum, nil
+	case monitoring_api.MetricAggregationMin:
+		min := math.Inf(1)
+		for _, sample := range samples {
+			min = math.Min(min, sample.Value)
+		}
+		return min, nil
+	case monitoring_api.MetricAggregationMax:
+		max := math.Inf(-1)
+		for _, sample := range samples {
+			max = math.Max(max, sample.Value)
+		}
+		return max, nil
+	default:
+		return 0, errInvalidMetricAggregation
+	}
+}
+
+func metricSampleValue(sample *parsers.MetricSample) *structType.Value {
+	labels := map[string]*structType.Value{}
+	for name, value := range sample.Labels {
+		labels[name] = stringValue(value)
+	}
+	return &structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{
+				Fields: map[string]*structType.Value{
+					"labels": {
+						Kind: &structType.Value_StructValue{
+							StructValue: &structType.Struct{Fields: labels},
+						},
+					},
+					"value": numberValue(sample.Value),
+				},
+			},
+		},
+	}
+}
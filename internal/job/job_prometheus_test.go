@@ -0,0 +1,184 @@
+package job
+
+import (
+	"fmt"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"squzy/internal/httptools"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"testing"
+)
+
+const metricsFixture = `# HELP queue_size Size of queue.
+# TYPE queue_size gauge
+queue_size{queue="emails",region="eu"} 12
+queue_size{queue="emails",region="us"} 30
+queue_size{queue="sms",region="eu"} 3
+# TYPE up gauge
+up 1
+`
+
+func newMetricsServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer squzy" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/invalid" {
+			_, _ = fmt.Fprint(w, "up{job=squzy} 1")
+			return
+		}
+		_, _ = fmt.Fprint(w, metricsFixture)
+	}))
+}
+
+type metricResult struct {
+	name   string
+	series int
+	value  float64
+}
+
+func metricResults(job CheckError) []*metricResult {
+	result := []*metricResult{}
+	value := job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields[httpMetaValueKey]
+	for _, item := range value.GetListValue().Values {
+		fields := item.GetStructValue().Fields
+		result = append(result, &metricResult{
+			name:   fields["name"].GetStringValue(),
+			series: len(fields["series"].GetListValue().Values),
+			value:  fields["value"].GetNumberValue(),
+		})
+	}
+	return result
+}
+
+func TestExecPrometheus(t *testing.T) {
+	server := newMetricsServer()
+	defer server.Close()
+	headers := map[string]string{"Authorization": "Bearer squzy"}
+
+	t.Run("Should: return ok because rules satisfied", func(t *testing.T) {
+		job := ExecPrometheus("", 1, &scheduler_config_storage.PrometheusConfig{
+			URL:     server.URL + "/metrics",
+			Headers: headers,
+			Selectors: []*scheduler_config_storage.MetricSelector{
+				{
+					Name: "up",
+					Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleEquals, Number: 1},
+				},
+				{
+					Name: "queue_size",
+					Labels: []*scheduler_config_storage.MetricLabelMatcher{
+						{Name: "queue", Type: monitoring_api.MetricMatchEqual, Value: "emails"},
+					},
+					Aggregate: monitoring_api.MetricAggregationSum,
+					Rule:      &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleLess, Number: 100},
+				},
+			},
+		}, httptools.New(""))
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		assert.Equal(t, monitoring_api.SchedulerTypePrometheus, job.GetLogData().Snapshot.Type)
+		assert.Equal(t, []*metricResult{
+			{name: "up", series: 1},
+			{name: "queue_size", series: 2, value: 42},
+		}, metricResults(job))
+	})
+	t.Run("Should: return error because one of series does not satisfy rule", func(t *testing.T) {
+		job := ExecPrometheus("", 1, &scheduler_config_storage.PrometheusConfig{
+			URL:     server.URL + "/metrics",
+			Headers: headers,
+			Selectors: []*scheduler_config_storage.MetricSelector{
+				{
+					Name: "queue_size",
+					Labels: []*scheduler_config_storage.MetricLabelMatcher{
+						{Name: "region", Type: monitoring_api.MetricMatchRegex, Value: "eu|us"},
+					},
+					Rule: &scheduler_config_storage.SelectorRule{Type: monitoring_api.SelectorRuleLess, Number: 20},
+				},
+			},
+		}, httptools.New(""))
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		assert.Equal(t, "rule lt failed for metric `queue_size`: 30 is not less than 20", job.GetLogData().Snapshot.Error.Message)
+		assert.Equal(t, []*metricResult{{name: "queue_size", series: 3}}, metricResults(job))
+	})
+	t.Run("Should: aggregate series", func(t *testing.T) {
+		for aggregation, expected := range map[monitoring_api.MetricAggregation]float64{
+			monitoring_api.MetricAggregationMin:   3,
+			monitoring_api.MetricAggregationMax:   12,
+			monitoring_api.MetricAggregationAvg:   7.5,
+			monitoring_api.MetricAggregationCount: 2,
+		} {
+			job := ExecPrometheus("", 1, &scheduler_config_storage.PrometheusConfig{
+				URL:     server.URL + "/metrics",
+				Headers: headers,
+				Selectors: []*scheduler_config_storage.MetricSelector{
+					{
+						Name: "queue_size",
+						Labels: []*scheduler_config_storage.MetricLabelMatcher{
+							{Name: "region", Type: monitoring_api.MetricMatchNotEqual, Value: "us"},
+						},
+						Aggregate: aggregation,
+					},
+				},
+			}, httptools.New(""))
+			assert.Equal(t, expected, metricResults(job)[0].value)
+		}
+	})
+	t.Run("Should: return error because series not exist", func(t *testing.T) {
+		job := ExecPrometheus("", 1, &scheduler_config_storage.PrometheusConfig{
+			URL:     server.URL + "/metrics",
+			Headers: headers,
+			Selectors: []*scheduler_config_storage.MetricSelector{
+				{
+					Name: "queue_size",
+					Labels: []*scheduler_config_storage.MetricLabelMatcher{
+						{Name: "queue", Type: monitoring_api.MetricMatchNotRegex, Value: "emails|sms"},
+					},
+				},
+			},
+		}, httptools.New(""))
+		assert.Equal(t, metricNotExistErrorFn("queue_size").Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: return error because match type is invalid", func(t *testing.T) {
+		job := ExecPrometheus("", 1, &scheduler_config_storage.PrometheusConfig{
+			URL:     server.URL + "/metrics",
+			Headers: headers,
+			Selectors: []*scheduler_config_storage.MetricSelector{
+				{
+					Name: "up",
+					Labels: []*scheduler_config_storage.MetricLabelMatcher{
+						{Name: "job", Type: "~"},
+					},
+				},
+			},
+		}, httptools.New(""))
+		assert.Equal(t, errInvalidMetricMatchType.Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: return error because aggregation is invalid", func(t *testing.T) {
+		job := ExecPrometheus("", 1, &scheduler_config_storage.PrometheusConfig{
+			URL:     server.URL + "/metrics",
+			Headers: headers,
+			Selectors: []*scheduler_config_storage.MetricSelector{
+				{Name: "up", Aggregate: "median"},
+			},
+		}, httptools.New(""))
+		assert.Equal(t, errInvalidMetricAggregation.Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: return error because metrics are invalid", func(t *testing.T) {
+		job := ExecPrometheus("", 1, &scheduler_config_storage.PrometheusConfig{
+			URL:     server.URL + "/invalid",
+			Headers: headers,
+		}, httptools.New(""))
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		assert.Contains(t, job.GetLogData().Snapshot.Error.Message, "parsing error in line 1")
+	})
+	t.Run("Should: return error because status code is not ok", func(t *testing.T) {
+		job := ExecPrometheus("", 1, &scheduler_config_storage.PrometheusConfig{
+			URL: server.URL + "/metrics",
+		}, httptools.New(""))
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+	})
+}
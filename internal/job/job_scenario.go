@@ -0,0 +1,288 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	structType "github.com/golang/protobuf/ptypes/struct"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/tidwall/gjson"
+	"net/http"
+	"regexp"
+	"squzy/internal/helpers"
+	"squzy/internal/httptools"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"time"
+)
+
+var (
+	errScenarioEmpty       = errors.New("SCENARIO_HAS_NO_STEPS")
+	errScenarioTimeout     = errors.New("SCENARIO_TIMEOUT")
+	scenarioVariableRegexp = regexp.MustCompile(`{{\s*([\w.-]+)\s*}}`)
+	scenarioStepErrorFn    = func(index int, step *scheduler_config_storage.ScenarioStep, err error) error {
+		if step.Name == "" {
+			return fmt.Errorf("step %d failed: %s", index+1, err.Error())
+		}
+		return fmt.Errorf("step %d (%s) failed: %s", index+1, step.Name, err.Error())
+	}
+	variableNotCapturedErrorFn = func(variable string) error {
+		return fmt.Errorf("variable %s is not captured", variable)
+	}
+	captureNotExistErrorFn = func(capture *scheduler_config_storage.ScenarioCapture) error {
+		if capture.Path != "" {
+			return fmt.Errorf("can't capture %s, %s", capture.Variable, valueNotExistErrorFn(capture.Path).Error())
+		}
+		return fmt.Errorf("can't capture %s, header %s is missing", capture.Variable, capture.Header)
+	}
+)
+
+type scenarioError struct {
+	schedulerID string
+	startTime   *timestamp.Timestamp
+	endTime     *timestamp.Timestamp
+	code        apiPb.SchedulerCode
+	description string
+	value       *structType.Value
+}
+
+func (e *scenarioError) GetLogData() *apiPb.SchedulerResponse {
+	var err *apiPb.SchedulerSnapshot_Error
+	if e.code == apiPb.SchedulerCode_ERROR {
+		err = &apiPb.SchedulerSnapshot_Error{
+			Message: e.description,
+		}
+	}
+	return &apiPb.SchedulerResponse{
+		SchedulerId: e.schedulerID,
+		Snapshot: &apiPb.SchedulerSnapshot{
+			Code:  e.code,
+			Error: err,
+			Type:  monitoring_api.SchedulerTypeScenario,
+			Meta: &apiPb.SchedulerSnapshot_MetaData{
+				StartTime: e.startTime,
+				EndTime:   e.endTime,
+				Value:     e.value,
+			},
+		},
+	}
+}
+
+func newScenarioError(schedulerID string, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string, value *structType.Value) CheckError {
+	return &scenarioError{
+		schedulerID: schedulerID,
+		startTime:   startTime,
+		endTime:     endTime,
+		code:        code,
+		description: description,
+		value:       value,
+	}
+}
+
+type scenarioStepResult struct {
+	name       string
+	statusCode int
+	latency    time.Duration
+	err        error
+}
+
+// Steps are executed one by one, scenario stops on first failed step
+func ExecScenario(schedulerID string, timeout int32, config *scheduler_config_storage.ScenarioConfig, httpTool httptools.HTTPTool) CheckError {
+	startTime := ptypes.TimestampNow()
+
+	if len(config.Steps) == 0 {
+		return newScenarioError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errScenarioEmpty.Error(), nil)
+	}
+
+	// Timeout is shared between all steps
+	total := helpers.DurationFromSecond(timeout)
+	if total <= 0 {
+		total = helpers.DurationFromSecond(httptools.RequestTimeout)
+	}
+	deadline := time.Now().Add(total)
+
+	clientConfig := httpClientConfig(config.Client)
+	variables := map[string]string{}
+	results := []*scenarioStepResult{}
+	for index, step := range config.Steps {
+		result := execScenarioStep(schedulerID, step, clientConfig, variables, time.Until(deadline), httpTool)
+		results = append(results, result)
+		if result.err != nil {
+			return newScenarioError(
+				schedulerID,
+				startTime,
+				ptypes.TimestampNow(),
+				apiPb.SchedulerCode_ERROR,
+				scenarioStepErrorFn(index, step, result.err).Error(),
+				scenarioResultsToValue(results),
+			)
+		}
+	}
+
+	return newScenarioError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", scenarioResultsToValue(results))
+}
+
+func execScenarioStep(
+	schedulerID string,
+	step *scheduler_config_storage.ScenarioStep,
+	clientConfig *httptools.ClientConfig,
+	variables map[string]string,
+	timeout time.Duration,
+	httpTool httptools.HTTPTool,
+) *scenarioStepResult {
+	result := &scenarioStepResult{
+		name: step.Name,
+	}
+	if timeout <= 0 {
+		result.err = errScenarioTimeout
+		return result
+	}
+
+	url, err := interpolate(step.URL, variables)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	headers := map[string]string{}
+	for k, v := range step.Headers {
+		headers[k], err = interpolate(v, variables)
+		if err != nil {
+			result.err = err
+			return result
+		}
+	}
+	body, err := interpolateBody(step.Body, variables)
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	req := httptools.WithClientConfig(httpTool.CreateRequest(step.Method, url, &headers, schedulerID), clientConfig)
+	err = setRequestBody(req, body)
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	expectedCode := int(step.StatusCode)
+	if expectedCode == 0 {
+		expectedCode = http.StatusOK
+	}
+
+	requestStart := time.Now()
+	statusCode, responseHeaders, responseBody, err := httpTool.SendRequestTimeoutStatusCodeWithHeaders(req, timeout, expectedCode)
+	result.latency = time.Since(requestStart)
+	result.statusCode = statusCode
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	for _, assertion := range step.Assertions {
+		err = checkHTTPAssertion(assertion, responseHeaders, responseBody, result.latency)
+		if err != nil {
+			result.err = httpAssertionErrorFn(assertion.Type, err)
+			return result
+		}
+	}
+
+	for _, capture := range step.Captures {
+		value, ok := captureValue(capture, responseHeaders, responseBody)
+		if !ok {
+			result.err = captureNotExistErrorFn(capture)
+			return result
+		}
+		variables[capture.Variable] = value
+	}
+	return result
+}
+
+func captureValue(capture *scheduler_config_storage.ScenarioCapture, headers http.Header, body []byte) (string, bool) {
+	if capture.Path != "" {
+		res := gjson.GetBytes(body, capture.Path)
+		return res.String(), res.Exists()
+	}
+	values, ok := headers[http.CanonicalHeaderKey(capture.Header)]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[0], true
+}
+
+// Replaces {{variable}} by captured values, returns error when variable was not captured by previous steps
+func interpolate(value string, variables map[string]string) (string, error) {
+	var err error
+	res := scenarioVariableRegexp.ReplaceAllStringFunc(value, func(match string) string {
+		name := scenarioVariableRegexp.FindStringSubmatch(match)[1]
+		v, ok := variables[name]
+		if !ok && err == nil {
+			err = variableNotCapturedErrorFn(name)
+		}
+		return v
+	})
+	if err != nil {
+		return "", err
+	}
+	return res, nil
+}
+
+func interpolateBody(body *scheduler_config_storage.RequestBody, variables map[string]string) (*scheduler_config_storage.RequestBody, error) {
+	if body == nil {
+		return nil, nil
+	}
+	content, err := interpolate(body.Content, variables)
+	if err != nil {
+		return nil, err
+	}
+	form := map[string]string{}
+	for k, v := range body.Form {
+		form[k], err = interpolate(v, variables)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &scheduler_config_storage.RequestBody{
+		Type:        body.Type,
+		Content:     content,
+		Form:        form,
+		ContentType: body.ContentType,
+	}, nil
+}
+
+func scenarioResultsToValue(results []*scenarioStepResult) *structType.Value {
+	steps := []*structType.Value{}
+	for _, result := range results {
+		fields := map[string]*structType.Value{
+			"name":       stringValue(result.name),
+			"statusCode": numberValue(float64(result.statusCode)),
+			"latencyMs":  numberValue(float64(result.latency) / float64(time.Millisecond)),
+			"success":    boolValue(result.err == nil),
+		}
+		if result.err != nil {
+			fields["error"] = stringValue(result.err.Error())
+		}
+		steps = append(steps, &structType.Value{
+			Kind: &structType.Value_StructValue{
+				StructValue: &structType.Struct{
+					Fields: fields,
+				},
+			},
+		})
+	}
+	return &structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{
+				Fields: map[string]*structType.Value{
+					"steps": {
+						Kind: &structType.Value_ListValue{
+							ListValue: &structType.ListValue{
+								Values: steps,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,203 @@
+package job
+
+import (
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"squzy/internal/httptools"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"testing"
+	"time"
+)
+
+func scenarioServer() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.FormValue("user") != "squzy" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-Session", "session-1")
+		_, _ = w.Write([]byte(`{"data":{"token":"token-1"}}`))
+	})
+	mux.HandleFunc("/me", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token-1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"squzy"}`))
+	})
+	mux.HandleFunc("/logout/session-1", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(time.Second * 2)
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestExecScenario(t *testing.T) {
+	t.Run("Test: Testing scenario check:", func(t *testing.T) {
+		server := scenarioServer()
+		defer server.Close()
+		httpTool := httptools.New("")
+		login := &scheduler_config_storage.ScenarioStep{
+			Name:   "login",
+			Method: http.MethodPost,
+			URL:    server.URL + "/login",
+			Body: &scheduler_config_storage.RequestBody{
+				Type: monitoring_api.RequestBodyForm,
+				Form: map[string]string{
+					"user": "squzy",
+				},
+			},
+			Captures: []*scheduler_config_storage.ScenarioCapture{
+				{Variable: "token", Path: "data.token"},
+				{Variable: "session", Header: "x-session"},
+			},
+		}
+		me := &scheduler_config_storage.ScenarioStep{
+			Name:   "me",
+			Method: http.MethodGet,
+			URL:    server.URL + "/me",
+			Headers: map[string]string{
+				"Authorization": "Bearer {{ token }}",
+			},
+			Assertions: []*scheduler_config_storage.HTTPAssertion{
+				{Type: monitoring_api.HTTPAssertionBodyContains, Value: "squzy"},
+			},
+		}
+		logout := &scheduler_config_storage.ScenarioStep{
+			Method:     http.MethodPost,
+			URL:        server.URL + "/logout/{{session}}",
+			StatusCode: http.StatusNoContent,
+		}
+
+		t.Run("Should: return ok with result of every step", func(t *testing.T) {
+			snapshot := ExecScenario("", 1, &scheduler_config_storage.ScenarioConfig{
+				Steps: []*scheduler_config_storage.ScenarioStep{login, me, logout},
+			}, httpTool).GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_OK, snapshot.Code)
+			assert.Equal(t, monitoring_api.SchedulerTypeScenario, snapshot.Type)
+			steps := snapshot.Meta.Value.GetStructValue().Fields["steps"].GetListValue().Values
+			assert.Equal(t, 3, len(steps))
+			assert.Equal(t, "login", steps[0].GetStructValue().Fields["name"].GetStringValue())
+			assert.Equal(t, float64(http.StatusNoContent), steps[2].GetStructValue().Fields["statusCode"].GetNumberValue())
+			assert.Equal(t, true, steps[2].GetStructValue().Fields["success"].GetBoolValue())
+		})
+		t.Run("Should: return error of first failed step", func(t *testing.T) {
+			snapshot := ExecScenario("", 1, &scheduler_config_storage.ScenarioConfig{
+				Steps: []*scheduler_config_storage.ScenarioStep{
+					login,
+					{
+						Name:   "me",
+						Method: http.MethodGet,
+						URL:    server.URL + "/me",
+					},
+					logout,
+				},
+			}, httpTool).GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Contains(t, snapshot.Error.Message, "step 2 (me) failed")
+			steps := snapshot.Meta.Value.GetStructValue().Fields["steps"].GetListValue().Values
+			assert.Equal(t, 2, len(steps))
+			assert.Equal(t, false, steps[1].GetStructValue().Fields["success"].GetBoolValue())
+		})
+		t.Run("Should: return error because assertion failed", func(t *testing.T) {
+			snapshot := ExecScenario("", 1, &scheduler_config_storage.ScenarioConfig{
+				Steps: []*scheduler_config_storage.ScenarioStep{
+					{
+						Method: http.MethodGet,
+						URL:    server.URL + "/me",
+						Headers: map[string]string{
+							"Authorization": "Bearer token-1",
+						},
+						Assertions: []*scheduler_config_storage.HTTPAssertion{
+							{Type: monitoring_api.HTTPAssertionBodyContains, Value: "admin"},
+						},
+					},
+				},
+			}, httpTool).GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Equal(t, `step 1 failed: assertion body_contains failed: body does not contain "admin"`, snapshot.Error.Message)
+		})
+		t.Run("Should: return error because variable is not captured", func(t *testing.T) {
+			snapshot := ExecScenario("", 1, &scheduler_config_storage.ScenarioConfig{
+				Steps: []*scheduler_config_storage.ScenarioStep{me},
+			}, httpTool).GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Equal(t, scenarioStepErrorFn(0, me, variableNotCapturedErrorFn("token")).Error(), snapshot.Error.Message)
+		})
+		t.Run("Should: return error because value can't be captured", func(t *testing.T) {
+			step := &scheduler_config_storage.ScenarioStep{
+				Method: http.MethodPost,
+				URL:    server.URL + "/login",
+				Body:   login.Body,
+				Captures: []*scheduler_config_storage.ScenarioCapture{
+					{Variable: "refresh", Path: "data.refresh"},
+				},
+			}
+			snapshot := ExecScenario("", 1, &scheduler_config_storage.ScenarioConfig{
+				Steps: []*scheduler_config_storage.ScenarioStep{step},
+			}, httpTool).GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Equal(t, scenarioStepErrorFn(0, step, captureNotExistErrorFn(step.Captures[0])).Error(), snapshot.Error.Message)
+		})
+		t.Run("Should: return error because header can't be captured", func(t *testing.T) {
+			step := &scheduler_config_storage.ScenarioStep{
+				Method: http.MethodPost,
+				URL:    server.URL + "/login",
+				Body:   login.Body,
+				Captures: []*scheduler_config_storage.ScenarioCapture{
+					{Variable: "csrf", Header: "X-Csrf"},
+				},
+			}
+			snapshot := ExecScenario("", 1, &scheduler_config_storage.ScenarioConfig{
+				Steps: []*scheduler_config_storage.ScenarioStep{step},
+			}, httpTool).GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+		})
+		t.Run("Should: return error because body variable is not captured", func(t *testing.T) {
+			snapshot := ExecScenario("", 1, &scheduler_config_storage.ScenarioConfig{
+				Steps: []*scheduler_config_storage.ScenarioStep{
+					{
+						Method: http.MethodPost,
+						URL:    server.URL + "/login",
+						Body: &scheduler_config_storage.RequestBody{
+							Type: monitoring_api.RequestBodyForm,
+							Form: map[string]string{
+								"user": "{{user}}",
+							},
+						},
+					},
+				},
+			}, httpTool).GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+		})
+		t.Run("Should: return error because scenario timeout", func(t *testing.T) {
+			snapshot := ExecScenario("", 1, &scheduler_config_storage.ScenarioConfig{
+				Steps: []*scheduler_config_storage.ScenarioStep{
+					{
+						Method: http.MethodGet,
+						URL:    server.URL + "/slow",
+					},
+					login,
+				},
+			}, httpTool).GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			steps := snapshot.Meta.Value.GetStructValue().Fields["steps"].GetListValue().Values
+			assert.Equal(t, 1, len(steps))
+		})
+	})
+	t.Run("Should: return error because scenario has no steps", func(t *testing.T) {
+		snapshot := ExecScenario("", 1, &scheduler_config_storage.ScenarioConfig{}, nil).GetLogData().Snapshot
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+		assert.Equal(t, errScenarioEmpty.Error(), snapshot.Error.Message)
+	})
+	t.Run("Should: return error because no time left", func(t *testing.T) {
+		res := execScenarioStep("", &scheduler_config_storage.ScenarioStep{}, nil, map[string]string{}, 0, nil)
+		assert.Equal(t, errScenarioTimeout, res.err)
+	})
+}
@@ -4,15 +4,24 @@ import (
 	"context"
 	"fmt"
 	"github.com/golang/protobuf/ptypes"
+	structType "github.com/golang/protobuf/ptypes/struct"
 	"github.com/golang/protobuf/ptypes/timestamp"
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
-	"golang.org/x/sync/errgroup"
 	"net/http"
 	"squzy/internal/helpers"
 	"squzy/internal/httptools"
+	monitoring_api "squzy/internal/monitoring-api"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
 	"squzy/internal/semaphore"
 	sitemap_storage "squzy/internal/sitemap-storage"
+	"sync"
+	"time"
+)
+
+var (
+	siteMapFailedErrorFn = func(report *monitoring_api.SiteMapReport) error {
+		return fmt.Errorf("%d of %d urls failed", report.Failed, report.Total)
+	}
 )
 
 type siteMapError struct {
@@ -22,6 +31,7 @@ type siteMapError struct {
 	code        apiPb.SchedulerCode
 	description string
 	location    string
+	value       *structType.Value
 }
 
 func (s *siteMapError) GetLogData() *apiPb.SchedulerResponse {
@@ -40,12 +50,13 @@ func (s *siteMapError) GetLogData() *apiPb.SchedulerResponse {
 			Meta: &apiPb.SchedulerSnapshot_MetaData{
 				StartTime: s.startTime,
 				EndTime:   s.endTime,
+				Value:     s.value,
 			},
 		},
 	}
 }
 
-func newSiteMapError(schedulerID string, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string, location string) CheckError {
+func newSiteMapError(schedulerID string, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string, location string, value *structType.Value) CheckError {
 	return &siteMapError{
 		schedulerID: schedulerID,
 		startTime:   startTime,
@@ -53,20 +64,22 @@ func newSiteMapError(schedulerID string, startTime *timestamp.Timestamp, endTime
 		code:        code,
 		description: description,
 		location:    location,
+		value:       value,
 	}
 }
 
 func ExecSiteMap(schedulerID string, timeout int32, config *scheduler_config_storage.SiteMapConfig, siteMapStorage sitemap_storage.SiteMapStorage, httpTools httptools.HTTPTool, semaphoreFactoryFn func(n int) semaphore.Semaphore) CheckError {
 	startTime := ptypes.TimestampNow()
-	siteMap, err := siteMapStorage.Get(config.URL)
+	clientConfig := httpClientConfig(config.Client)
+	siteMap, err := siteMapStorage.Get(config.URL, clientConfig, helpers.DurationFromSecond(config.CacheTTL))
 	if err != nil {
-		return newSiteMapError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), config.URL)
+		return newSiteMapError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), config.URL, nil)
 	}
 
 	count := len(siteMap.URLSet)
 
 	if count == 0 {
-		return newSiteMapError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", "")
+		return newSiteMapError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", "", nil)
 	}
 
 	concurrency := int(config.Concurrency)
@@ -76,36 +89,139 @@ func ExecSiteMap(schedulerID string, timeout int32, config *scheduler_config_sto
 	}
 
 	sem := semaphoreFactoryFn(concurrency)
+	slowThreshold := float64(config.SlowThresholdMs)
 
-	group, ctx := errgroup.WithContext(context.Background())
-	for _, v := range siteMap.URLSet {
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	urlTimings := []*httptools.Timings{}
+	results := make([]*monitoring_api.SiteMapURLResult, len(siteMap.URLSet))
+
+	for i, v := range siteMap.URLSet {
 		if v.Ignore {
 			continue
 		}
+		index := i
 		location := v.Location
 
-		group.Go(func() error {
-			errSem := sem.Acquire(ctx)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := &monitoring_api.SiteMapURLResult{
+				Location: location,
+			}
+			results[index] = result
 
+			errSem := sem.Acquire(context.Background())
 			if errSem != nil {
-				return err
+				result.Error = errSem.Error()
+				return
 			}
 
 			defer sem.Release()
 
-			rq := httpTools.CreateRequest(http.MethodGet, location, nil, schedulerID)
-			_, _, errSem = httpTools.SendRequestTimeoutStatusCode(rq, helpers.DurationFromSecond(timeout), http.StatusOK)
+			rq := httptools.WithClientConfig(httpTools.CreateRequest(http.MethodGet, location, nil, schedulerID), clientConfig)
+			rq, timings := httptools.WithTimings(rq)
+			requestStart := time.Now()
+			code, _, errRq := httpTools.SendRequestTimeoutStatusCode(rq, helpers.DurationFromSecond(timeout), http.StatusOK)
 
-			if errSem != nil {
-				return errSem
+			result.Code = code
+			result.LatencyMs = durationToMs(time.Since(requestStart))
+			result.Slow = slowThreshold > 0 && result.LatencyMs > slowThreshold
+			if errRq != nil {
+				result.Error = errRq.Error()
 			}
 
-			return nil
-		})
+			mutex.Lock()
+			urlTimings = append(urlTimings, timings)
+			mutex.Unlock()
+		}()
 	}
-	err = group.Wait()
-	if err != nil {
-		return newSiteMapError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), config.URL)
+	wg.Wait()
+
+	report := newSiteMapReport(results)
+	value := siteMapMetaValue(urlTimings, report)
+	if report.Failed > 0 && report.FailureRatio() > config.MaxFailureRatio {
+		return newSiteMapError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, siteMapFailedErrorFn(report).Error(), config.URL, value)
+	}
+	return newSiteMapError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", "", value)
+}
+
+// Snapshot is sent to storage in one message, so list of urls is limited
+const maxSiteMapReportURLs = 100
+
+// Counts results of requested urls and lists failed or slow of them, ignored urls have not result
+func newSiteMapReport(results []*monitoring_api.SiteMapURLResult) *monitoring_api.SiteMapReport {
+	report := &monitoring_api.SiteMapReport{
+		MaxURLs: maxSiteMapReportURLs,
+		URLs:    []*monitoring_api.SiteMapURLResult{},
+	}
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+		report.Total++
+		if result.Error != "" {
+			report.Failed++
+		} else {
+			report.Ok++
+		}
+		if result.Slow {
+			report.Slow++
+		}
+		if result.Error == "" && !result.Slow {
+			continue
+		}
+		if len(report.URLs) >= maxSiteMapReportURLs {
+			report.Omitted++
+			report.Truncated = true
+			continue
+		}
+		report.URLs = append(report.URLs, result)
+	}
+	return report
+}
+
+// Latency breakdown of sitemap is average of all requested urls
+func siteMapMetaValue(urlTimings []*httptools.Timings, report *monitoring_api.SiteMapReport) *structType.Value {
+	fields := map[string]*structType.Value{}
+	if report != nil && report.Total > 0 {
+		fields[monitoring_api.SnapshotSiteMapReportKey] = report.ToValue()
+	}
+	if len(urlTimings) > 0 {
+		fields[monitoring_api.SnapshotTimingsKey] = averageTimings(urlTimings).ToValue()
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return &structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{
+				Fields: fields,
+			},
+		},
+	}
+}
+
+func averageTimings(urlTimings []*httptools.Timings) *monitoring_api.HTTPTimings {
+	average := &monitoring_api.HTTPTimings{
+		ReusedConnection: true,
+	}
+	for _, timings := range urlTimings {
+		t := httpTimings(timings)
+		average.DNSLookupMs += t.DNSLookupMs
+		average.TCPConnectMs += t.TCPConnectMs
+		average.TLSHandshakeMs += t.TLSHandshakeMs
+		average.TimeToFirstByteMs += t.TimeToFirstByteMs
+		average.ContentTransferMs += t.ContentTransferMs
+		average.TotalMs += t.TotalMs
+		average.ReusedConnection = average.ReusedConnection && t.ReusedConnection
 	}
-	return newSiteMapError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", "")
+	count := float64(len(urlTimings))
+	average.DNSLookupMs /= count
+	average.TCPConnectMs /= count
+	average.TLSHandshakeMs /= count
+	average.TimeToFirstByteMs /= count
+	average.ContentTransferMs /= count
+	average.TotalMs /= count
+	return average
 }
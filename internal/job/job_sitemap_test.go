@@ -6,6 +6,8 @@ import (
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"squzy/internal/httptools"
+	monitoring_api "squzy/internal/monitoring-api"
 	"squzy/internal/parsers"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
 	"squzy/internal/semaphore"
@@ -24,6 +26,14 @@ func (m mockHttpTools) SendRequestTimeout(req *http.Request, timeout time.Durati
 	return 200, nil, nil
 }
 
+func (m mockHttpTools) SendRequestTimeoutStatusCodeWithHeaders(req *http.Request, timeout time.Duration, expectedCode int) (int, http.Header, []byte, error) {
+	panic("implement me")
+}
+
+func (m mockHttpTools) SendRequestTimeoutWithHeaders(req *http.Request, timeout time.Duration) (int, http.Header, []byte, error) {
+	panic("implement me")
+}
+
 func (m mockHttpTools) CreateRequest(method string, url string, headers *map[string]string, log string) *http.Request {
 	rq, _ := http.NewRequest(method, url, nil)
 	return rq
@@ -43,7 +53,7 @@ type siteMapStorage struct {
 type siteMapStorageIgnore struct {
 }
 
-func (s siteMapStorageIgnore) Get(url string) (*parsers.SiteMap, error) {
+func (s siteMapStorageIgnore) Get(url string, clientConfig *httptools.ClientConfig, ttl time.Duration) (*parsers.SiteMap, error) {
 	return &parsers.SiteMap{
 		URLSet: []parsers.SiteMapURL{
 			{
@@ -58,7 +68,7 @@ func (s siteMapStorageIgnore) Get(url string) (*parsers.SiteMap, error) {
 	}, nil
 }
 
-func (s siteMapStorage) Get(url string) (*parsers.SiteMap, error) {
+func (s siteMapStorage) Get(url string, clientConfig *httptools.ClientConfig, ttl time.Duration) (*parsers.SiteMap, error) {
 	return &parsers.SiteMap{
 		URLSet: []parsers.SiteMapURL{
 			{
@@ -76,14 +86,14 @@ func (s siteMapStorage) Get(url string) (*parsers.SiteMap, error) {
 type siteMapStorageError struct {
 }
 
-func (s siteMapStorageError) Get(url string) (*parsers.SiteMap, error) {
+func (s siteMapStorageError) Get(url string, clientConfig *httptools.ClientConfig, ttl time.Duration) (*parsers.SiteMap, error) {
 	return nil, errors.New("SAFafs")
 }
 
 type siteMapStorageEmptyIgnore struct {
 }
 
-func (s siteMapStorageEmptyIgnore) Get(url string) (*parsers.SiteMap, error) {
+func (s siteMapStorageEmptyIgnore) Get(url string, clientConfig *httptools.ClientConfig, ttl time.Duration) (*parsers.SiteMap, error) {
 	return &parsers.SiteMap{
 		URLSet: []parsers.SiteMapURL{},
 	}, nil
@@ -108,6 +118,14 @@ func (m mockHttpToolsWithError) GetWithRedirects(url string) (int, []byte, error
 	return 500, nil, errors.New("Wrong code")
 }
 
+func (m mockHttpToolsWithError) SendRequestTimeoutStatusCodeWithHeaders(req *http.Request, timeout time.Duration, expectedCode int) (int, http.Header, []byte, error) {
+	panic("implement me")
+}
+
+func (m mockHttpToolsWithError) SendRequestTimeoutWithHeaders(req *http.Request, timeout time.Duration) (int, http.Header, []byte, error) {
+	panic("implement me")
+}
+
 func (m mockHttpToolsWithError) CreateRequest(method string, url string, headers *map[string]string, log string) *http.Request {
 	rq, _ := http.NewRequest(method, url, nil)
 	return rq
@@ -176,6 +194,15 @@ func TestExecSiteMap(t *testing.T) {
 			}, &siteMapStorage{}, &mockHttpToolsWithError{}, successFactory)
 			assert.IsType(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
 		})
+		t.Run("Because failure ratio is greater than max", func(t *testing.T) {
+			job := ExecSiteMap("", 0, &scheduler_config_storage.SiteMapConfig{
+				URL:             "",
+				Concurrency:     5,
+				MaxFailureRatio: 0.4,
+			}, &siteMapStoragePartial{}, &mockHttpToolsPartial{}, successFactory)
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+			assert.Equal(t, "Error: 2 of 4 urls failed, URL: ", job.GetLogData().Snapshot.Error.Message)
+		})
 		t.Run("Because sitemapError", func(t *testing.T) {
 			job := ExecSiteMap("", 0, &scheduler_config_storage.SiteMapConfig{
 				URL:         "",
@@ -185,3 +212,102 @@ func TestExecSiteMap(t *testing.T) {
 		})
 	})
 }
+
+type siteMapStoragePartial struct {
+}
+
+func (s siteMapStoragePartial) Get(url string, clientConfig *httptools.ClientConfig, ttl time.Duration) (*parsers.SiteMap, error) {
+	return &parsers.SiteMap{
+		URLSet: []parsers.SiteMapURL{
+			{Location: "https://squzy.app/"},
+			{Location: "https://squzy.app/slow"},
+			{Location: "https://squzy.app/404"},
+			{Location: "https://squzy.app/500"},
+			{Location: "https://squzy.app/ignore", Ignore: true},
+		},
+	}, nil
+}
+
+type mockHttpToolsPartial struct {
+	mockHttpTools
+}
+
+func (m mockHttpToolsPartial) SendRequestTimeoutStatusCode(req *http.Request, timeout time.Duration, expectedCode int) (int, []byte, error) {
+	switch req.URL.Path {
+	case "/404":
+		return http.StatusNotFound, nil, errors.New("Wrong code")
+	case "/500":
+		return http.StatusInternalServerError, nil, errors.New("Wrong code")
+	case "/slow":
+		time.Sleep(time.Millisecond * 50)
+	}
+	return http.StatusOK, nil, nil
+}
+
+func TestExecSiteMapReport(t *testing.T) {
+	t.Run("Should: report every url and pass because failure ratio is allowed", func(t *testing.T) {
+		job := ExecSiteMap("", 0, &scheduler_config_storage.SiteMapConfig{
+			URL:             "",
+			Concurrency:     2,
+			MaxFailureRatio: 0.5,
+			SlowThresholdMs: 30,
+		}, &siteMapStoragePartial{}, &mockHttpToolsPartial{}, successFactory)
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		report := monitoring_api.SiteMapReportFromMetaValue(job.GetLogData().Snapshot.Meta.Value)
+		assert.Equal(t, 4, report.Total)
+		assert.Equal(t, 2, report.Ok)
+		assert.Equal(t, 2, report.Failed)
+		assert.Equal(t, 1, report.Slow)
+		assert.Equal(t, 3, len(report.URLs))
+		assert.False(t, report.Truncated)
+		assert.Equal(t, "https://squzy.app/slow", report.URLs[0].Location)
+		assert.Equal(t, true, report.URLs[0].Slow)
+		assert.Equal(t, http.StatusNotFound, report.URLs[1].Code)
+		assert.Equal(t, "Wrong code", report.URLs[1].Error)
+		assert.Equal(t, http.StatusInternalServerError, report.URLs[2].Code)
+	})
+	t.Run("Should: report urls which were not requested because of acquire error", func(t *testing.T) {
+		job := ExecSiteMap("", 0, &scheduler_config_storage.SiteMapConfig{
+			URL:         "",
+			Concurrency: 2,
+		}, &siteMapStoragePartial{}, &mockHttpToolsPartial{}, errorFactory)
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		report := monitoring_api.SiteMapReportFromMetaValue(job.GetLogData().Snapshot.Meta.Value)
+		assert.Equal(t, 4, report.Failed)
+		assert.Equal(t, "Acquire error", report.URLs[0].Error)
+	})
+}
+
+func TestNewSiteMapReport(t *testing.T) {
+	t.Run("Should: limit list of failed urls", func(t *testing.T) {
+		results := []*monitoring_api.SiteMapURLResult{nil}
+		for i := 0; i < maxSiteMapReportURLs+10; i++ {
+			results = append(results, &monitoring_api.SiteMapURLResult{Error: "Wrong code"})
+		}
+		results = append(results, &monitoring_api.SiteMapURLResult{Code: http.StatusOK})
+		report := newSiteMapReport(results)
+		assert.Equal(t, maxSiteMapReportURLs+11, report.Total)
+		assert.Equal(t, 1, report.Ok)
+		assert.Equal(t, maxSiteMapReportURLs+10, report.Failed)
+		assert.Equal(t, 10, report.Omitted)
+		assert.True(t, report.Truncated)
+		assert.Equal(t, maxSiteMapReportURLs, report.MaxURLs)
+		assert.Equal(t, maxSiteMapReportURLs, len(report.URLs))
+	})
+}
+
+func TestSiteMapMetaValue(t *testing.T) {
+	t.Run("Should: return average timings of urls", func(t *testing.T) {
+		value := siteMapMetaValue([]*httptools.Timings{
+			{TCPConnect: time.Millisecond, Total: time.Millisecond * 10, ReusedConnection: true},
+			{TCPConnect: time.Millisecond * 3, Total: time.Millisecond * 20},
+		}, nil)
+		timings := monitoring_api.HTTPTimingsFromMetaValue(value)
+		assert.Equal(t, float64(2), timings.TCPConnectMs)
+		assert.Equal(t, float64(15), timings.TotalMs)
+		assert.Equal(t, false, timings.ReusedConnection)
+	})
+	t.Run("Should: return nil because urls were not requested", func(t *testing.T) {
+		assert.Nil(t, siteMapMetaValue(nil, &monitoring_api.SiteMapReport{}))
+	})
+}
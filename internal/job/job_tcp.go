@@ -1,21 +1,52 @@
 package job
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"github.com/golang/protobuf/ptypes"
+	structType "github.com/golang/protobuf/ptypes/struct"
 	"github.com/golang/protobuf/ptypes/timestamp"
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"io"
 	"net"
+	"regexp"
 	"squzy/internal/helpers"
+	monitoring_api "squzy/internal/monitoring-api"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"time"
+)
+
+const (
+	defaultSocketReadTimeout = time.Second * 10
+	// Response is not read further than that size
+	maxSocketResponseSize = 64 * 1024
+	// Part of response which is saved to snapshot
+	maxSocketResponseMetaSize = 1024
+)
+
+var (
+	errUDPMissingPayload       = errors.New("MISSING_UDP_PAYLOAD")
+	errSocketInvalidExpectType = errors.New("INVALID_EXPECT_TYPE")
+	socketWriteErrorFn         = func(err error) error {
+		return fmt.Errorf("send failed: %s", err.Error())
+	}
+	socketReadErrorFn = func(err error) error {
+		return fmt.Errorf("read failed: %s", err.Error())
+	}
+	socketUnexpectedResponseErrorFn = func(response []byte) error {
+		return fmt.Errorf("unexpected response %q", truncateResponse(response))
+	}
 )
 
 type tcpError struct {
-	schedulerID string
-	startTime   *timestamp.Timestamp
-	endTime     *timestamp.Timestamp
-	code        apiPb.SchedulerCode
-	description string
+	schedulerID   string
+	schedulerType apiPb.SchedulerType
+	startTime     *timestamp.Timestamp
+	endTime       *timestamp.Timestamp
+	code          apiPb.SchedulerCode
+	description   string
+	value         *structType.Value
 }
 
 func (s *tcpError) GetLogData() *apiPb.SchedulerResponse {
@@ -30,35 +61,188 @@ func (s *tcpError) GetLogData() *apiPb.SchedulerResponse {
 		Snapshot: &apiPb.SchedulerSnapshot{
 			Code:  s.code,
 			Error: err,
-			Type:  apiPb.SchedulerType_TCP,
+			Type:  s.schedulerType,
 			Meta: &apiPb.SchedulerSnapshot_MetaData{
 				StartTime: s.startTime,
 				EndTime:   s.endTime,
+				Value:     s.value,
 			},
 		},
 	}
 }
 
-func newTCPError(schedulerID string, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string) CheckError {
+func newTCPError(schedulerID string, schedulerType apiPb.SchedulerType, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string, value *structType.Value) CheckError {
 	return &tcpError{
-		schedulerID: schedulerID,
-		startTime:   startTime,
-		endTime:     endTime,
-		code:        code,
-		description: description,
+		schedulerID:   schedulerID,
+		schedulerType: schedulerType,
+		startTime:     startTime,
+		endTime:       endTime,
+		code:          code,
+		description:   description,
+		value:         value,
 	}
 }
 
+// Checks expected response of socket, nil matcher means response is not read
+type socketMatcher struct {
+	expect  *scheduler_config_storage.SocketExpect
+	pattern *regexp.Regexp
+}
+
 func ExecTCP(schedulerID string, timeout int32, config *scheduler_config_storage.TCPConfig) CheckError {
 	startTime := ptypes.TimestampNow()
-	conn, err := net.DialTimeout("tcp", net.JoinHostPort(config.Host, fmt.Sprintf("%d", config.Port)), helpers.DurationFromSecond(timeout))
+	conn, err := net.DialTimeout("tcp", socketAddress(config), helpers.DurationFromSecond(timeout))
+	if err != nil {
+		return newTCPError(schedulerID, apiPb.SchedulerType_TCP, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errWrongConnectConfigError.Error(), nil)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	if config.Send == "" && config.Expect == nil {
+		return newTCPError(schedulerID, apiPb.SchedulerType_TCP, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", nil)
+	}
+	return execSocketExchange(schedulerID, apiPb.SchedulerType_TCP, startTime, conn, timeout, config)
+}
+
+// Sends payload as datagram, response datagram is awaited only when expect is set
+func ExecUDP(schedulerID string, timeout int32, config *scheduler_config_storage.TCPConfig) CheckError {
+	startTime := ptypes.TimestampNow()
+	if config.Send == "" {
+		return newTCPError(schedulerID, monitoring_api.SchedulerTypeUDP, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errUDPMissingPayload.Error(), nil)
+	}
+	conn, err := net.DialTimeout("udp", socketAddress(config), helpers.DurationFromSecond(timeout))
+	if err != nil {
+		return newTCPError(schedulerID, monitoring_api.SchedulerTypeUDP, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errWrongConnectConfigError.Error(), nil)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	return execSocketExchange(schedulerID, monitoring_api.SchedulerTypeUDP, startTime, conn, timeout, config)
+}
+
+func execSocketExchange(schedulerID string, schedulerType apiPb.SchedulerType, startTime *timestamp.Timestamp, conn net.Conn, timeout int32, config *scheduler_config_storage.TCPConfig) CheckError {
+	matcher, err := newSocketMatcher(config.Expect)
 	if err != nil {
-		return newTCPError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errWrongConnectConfigError.Error())
+		return newTCPError(schedulerID, schedulerType, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), nil)
+	}
+	_ = conn.SetDeadline(time.Now().Add(socketReadTimeout(timeout, config.ReadTimeoutMs)))
+
+	if config.Send != "" {
+		_, err = conn.Write([]byte(config.Send))
+		if err != nil {
+			return newTCPError(schedulerID, schedulerType, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, socketWriteErrorFn(err).Error(), nil)
+		}
 	}
-	if conn != nil {
-		defer func() {
-			_ = conn.Close()
-		}()
+	if matcher == nil {
+		return newTCPError(schedulerID, schedulerType, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", nil)
+	}
+
+	_, datagram := conn.(*net.UDPConn)
+	response, err := readSocketResponse(conn, matcher, datagram)
+	if err != nil {
+		return newTCPError(schedulerID, schedulerType, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, socketReadErrorFn(err).Error(), nil)
+	}
+	value := socketMetaValue(response)
+	if !matcher.match(response) {
+		return newTCPError(schedulerID, schedulerType, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, socketUnexpectedResponseErrorFn(response).Error(), value)
+	}
+	return newTCPError(schedulerID, schedulerType, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", value)
+}
+
+// Reads single datagram or stream until response is enough to check it, connection is closed or deadline is reached
+func readSocketResponse(conn net.Conn, matcher *socketMatcher, datagram bool) ([]byte, error) {
+	buf := make([]byte, maxSocketResponseSize)
+	if datagram {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+	response := []byte{}
+	for len(response) < maxSocketResponseSize {
+		n, err := conn.Read(buf[:maxSocketResponseSize-len(response)])
+		response = append(response, buf[:n]...)
+		if matcher.complete(response) {
+			return response, nil
+		}
+		if err != nil {
+			// Partial response is checked, so error shows what was received
+			if err == io.EOF || len(response) > 0 {
+				return response, nil
+			}
+			return nil, err
+		}
+	}
+	return response, nil
+}
+
+func newSocketMatcher(expect *scheduler_config_storage.SocketExpect) (*socketMatcher, error) {
+	if expect == nil {
+		return nil, nil
+	}
+	switch expect.Type {
+	case monitoring_api.SocketExpectExact, monitoring_api.SocketExpectPrefix:
+		return &socketMatcher{expect: expect}, nil
+	case monitoring_api.SocketExpectRegex:
+		pattern, err := regexp.Compile(expect.Value)
+		if err != nil {
+			return nil, invalidPatternErrorFn(expect.Value, err)
+		}
+		return &socketMatcher{expect: expect, pattern: pattern}, nil
+	default:
+		return nil, errSocketInvalidExpectType
+	}
+}
+
+// Response is complete when more data can't change result of match
+func (m *socketMatcher) complete(response []byte) bool {
+	if m.pattern != nil {
+		return m.pattern.Match(response)
+	}
+	return len(response) >= len(m.expect.Value)
+}
+
+func (m *socketMatcher) match(response []byte) bool {
+	switch m.expect.Type {
+	case monitoring_api.SocketExpectExact:
+		return bytes.Equal(response, []byte(m.expect.Value))
+	case monitoring_api.SocketExpectPrefix:
+		return bytes.HasPrefix(response, []byte(m.expect.Value))
+	default:
+		return m.pattern.Match(response)
+	}
+}
+
+func socketAddress(config *scheduler_config_storage.TCPConfig) string {
+	return net.JoinHostPort(config.Host, fmt.Sprintf("%d", config.Port))
+}
+
+func socketReadTimeout(timeout int32, readTimeoutMs int32) time.Duration {
+	if readTimeoutMs > 0 {
+		return time.Duration(readTimeoutMs) * time.Millisecond
+	}
+	if timeout > 0 {
+		return helpers.DurationFromSecond(timeout)
+	}
+	return defaultSocketReadTimeout
+}
+
+func truncateResponse(response []byte) string {
+	if len(response) > maxSocketResponseMetaSize {
+		return string(response[:maxSocketResponseMetaSize])
+	}
+	return string(response)
+}
+
+func socketMetaValue(response []byte) *structType.Value {
+	return &structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{
+				Fields: map[string]*structType.Value{
+					"response": stringValue(truncateResponse(response)),
+				},
+			},
+		},
 	}
-	return newTCPError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "")
 }
@@ -1,14 +1,65 @@
 package job
 
 import (
+	"bufio"
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
 	"github.com/stretchr/testify/assert"
 	"net"
+	monitoring_api "squzy/internal/monitoring-api"
 	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"strings"
 	"testing"
 	"time"
 )
 
+// Sends banner, then answers every line with its upper case
+func newLineServer(t *testing.T, banner string) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				_, _ = conn.Write([]byte(banner))
+				reader := bufio.NewReader(conn)
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return
+					}
+					_, _ = conn.Write([]byte(strings.ToUpper(line)))
+				}
+			}()
+		}
+	}()
+	return listener
+}
+
+// Answers every datagram with its upper case
+func newUDPEchoServer(t *testing.T) net.PacketConn {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			_, _ = conn.WriteTo([]byte(strings.ToUpper(string(buf[:n]))), addr)
+		}
+	}()
+	return conn
+}
+
 func TestExecTcp(t *testing.T) {
 	t.Run("Test: Testing tcp health_check:", func(t *testing.T) {
 		t.Run("Should: return errWrongConnectConfigError", func(t *testing.T) {
@@ -48,3 +99,144 @@ func TestExecTcp(t *testing.T) {
 		})
 	})
 }
+
+func TestExecTCPExchange(t *testing.T) {
+	server := newLineServer(t, "220 smtp.squzy.app ESMTP\r\n")
+	defer server.Close()
+	host, port := listenerAddr(server)
+
+	t.Run("Should: return ok because banner has prefix", func(t *testing.T) {
+		job := ExecTCP("", 1, &scheduler_config_storage.TCPConfig{
+			Host: host,
+			Port: port,
+			Expect: &scheduler_config_storage.SocketExpect{
+				Type:  monitoring_api.SocketExpectPrefix,
+				Value: "220 ",
+			},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		assert.Equal(t, "220 ", job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields["response"].GetStringValue()[:4])
+	})
+	t.Run("Should: return ok because answer on payload matches regex", func(t *testing.T) {
+		job := ExecTCP("", 1, &scheduler_config_storage.TCPConfig{
+			Host: host,
+			Port: port,
+			Send: "stats\n",
+			Expect: &scheduler_config_storage.SocketExpect{
+				Type:  monitoring_api.SocketExpectRegex,
+				Value: "(?m)^STATS$",
+			},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+	})
+	t.Run("Should: return error because response is not exact", func(t *testing.T) {
+		job := ExecTCP("", 1, &scheduler_config_storage.TCPConfig{
+			Host: host,
+			Port: port,
+			Expect: &scheduler_config_storage.SocketExpect{
+				Type:  monitoring_api.SocketExpectExact,
+				Value: "220 ready\r\n",
+			},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		assert.Contains(t, job.GetLogData().Snapshot.Error.Message, "unexpected response")
+	})
+	t.Run("Should: return error because regex does not match before read timeout", func(t *testing.T) {
+		job := ExecTCP("", 1, &scheduler_config_storage.TCPConfig{
+			Host:          host,
+			Port:          port,
+			ReadTimeoutMs: 100,
+			Expect: &scheduler_config_storage.SocketExpect{
+				Type:  monitoring_api.SocketExpectRegex,
+				Value: "^\\+OK",
+			},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+	})
+	t.Run("Should: return error because expect type is invalid", func(t *testing.T) {
+		job := ExecTCP("", 1, &scheduler_config_storage.TCPConfig{
+			Host: host,
+			Port: port,
+			Expect: &scheduler_config_storage.SocketExpect{
+				Type: "suffix",
+			},
+		})
+		assert.Equal(t, errSocketInvalidExpectType.Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: return error because pattern is invalid", func(t *testing.T) {
+		job := ExecTCP("", 1, &scheduler_config_storage.TCPConfig{
+			Host: host,
+			Port: port,
+			Expect: &scheduler_config_storage.SocketExpect{
+				Type:  monitoring_api.SocketExpectRegex,
+				Value: "(",
+			},
+		})
+		assert.Contains(t, job.GetLogData().Snapshot.Error.Message, "invalid pattern (")
+	})
+}
+
+func TestExecUDP(t *testing.T) {
+	server := newUDPEchoServer(t)
+	defer server.Close()
+	addr := server.LocalAddr().(*net.UDPAddr)
+
+	t.Run("Should: return ok because answer is exact", func(t *testing.T) {
+		job := ExecUDP("", 1, &scheduler_config_storage.TCPConfig{
+			Host: addr.IP.String(),
+			Port: int32(addr.Port),
+			Send: "ping",
+			Expect: &scheduler_config_storage.SocketExpect{
+				Type:  monitoring_api.SocketExpectExact,
+				Value: "PING",
+			},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		assert.Equal(t, monitoring_api.SchedulerTypeUDP, job.GetLogData().Snapshot.Type)
+	})
+	t.Run("Should: return ok because payload was sent", func(t *testing.T) {
+		job := ExecUDP("", 1, &scheduler_config_storage.TCPConfig{
+			Host: addr.IP.String(),
+			Port: int32(addr.Port),
+			Send: "<14>squzy: test",
+		})
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+	})
+	t.Run("Should: return error because answer is unexpected", func(t *testing.T) {
+		job := ExecUDP("", 1, &scheduler_config_storage.TCPConfig{
+			Host: addr.IP.String(),
+			Port: int32(addr.Port),
+			Send: "ping",
+			Expect: &scheduler_config_storage.SocketExpect{
+				Type:  monitoring_api.SocketExpectPrefix,
+				Value: "PONG",
+			},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+	})
+	t.Run("Should: return error because nobody answered", func(t *testing.T) {
+		silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+		assert.Nil(t, err)
+		defer silent.Close()
+		silentAddr := silent.LocalAddr().(*net.UDPAddr)
+		job := ExecUDP("", 1, &scheduler_config_storage.TCPConfig{
+			Host:          silentAddr.IP.String(),
+			Port:          int32(silentAddr.Port),
+			Send:          "ping",
+			ReadTimeoutMs: 100,
+			Expect: &scheduler_config_storage.SocketExpect{
+				Type:  monitoring_api.SocketExpectExact,
+				Value: "PING",
+			},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		assert.Contains(t, job.GetLogData().Snapshot.Error.Message, "read failed")
+	})
+	t.Run("Should: return error because payload is missing", func(t *testing.T) {
+		job := ExecUDP("", 1, &scheduler_config_storage.TCPConfig{
+			Host: addr.IP.String(),
+			Port: int32(addr.Port),
+		})
+		assert.Equal(t, errUDPMissingPayload.Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+}
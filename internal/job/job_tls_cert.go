@@ -0,0 +1,181 @@
+package job
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	structType "github.com/golang/protobuf/ptypes/struct"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"net"
+	"squzy/internal/helpers"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"time"
+)
+
+const (
+	day = time.Hour * 24
+)
+
+var (
+	errNoPeerCertificates  = errors.New("NO_PEER_CERTIFICATES")
+	certExpiresSoonErrorFn = func(notAfter time.Time, days int32) error {
+		return fmt.Errorf("certificate expires at %s, less than %d days left", notAfter.Format(time.RFC3339), days)
+	}
+	hostnameMismatchErrorFn = func(err error) error {
+		return fmt.Errorf("hostname mismatch: %s", err.Error())
+	}
+	chainInvalidErrorFn = func(err error) error {
+		return fmt.Errorf("certificate chain invalid: %s", err.Error())
+	}
+)
+
+type tlsCertError struct {
+	schedulerID string
+	startTime   *timestamp.Timestamp
+	endTime     *timestamp.Timestamp
+	code        apiPb.SchedulerCode
+	description string
+	value       *structType.Value
+}
+
+func (e *tlsCertError) GetLogData() *apiPb.SchedulerResponse {
+	var err *apiPb.SchedulerSnapshot_Error
+	if e.code == apiPb.SchedulerCode_ERROR {
+		err = &apiPb.SchedulerSnapshot_Error{
+			Message: e.description,
+		}
+	}
+	return &apiPb.SchedulerResponse{
+		SchedulerId: e.schedulerID,
+		Snapshot: &apiPb.SchedulerSnapshot{
+			Code:  e.code,
+			Error: err,
+			Type:  monitoring_api.SchedulerTypeTLSCert,
+			Meta: &apiPb.SchedulerSnapshot_MetaData{
+				StartTime: e.startTime,
+				EndTime:   e.endTime,
+				Value:     e.value,
+			},
+		},
+	}
+}
+
+func newTLSCertError(schedulerID string, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string, value *structType.Value) CheckError {
+	return &tlsCertError{
+		schedulerID: schedulerID,
+		startTime:   startTime,
+		endTime:     endTime,
+		code:        code,
+		description: description,
+		value:       value,
+	}
+}
+
+func ExecTLSCert(schedulerID string, timeout int32, config *scheduler_config_storage.TLSCertConfig) CheckError {
+	startTime := ptypes.TimestampNow()
+
+	ctx, cancel := helpers.TimeoutContext(context.Background(), helpers.DurationFromSecond(timeout))
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+
+	serverName := config.ServerName
+	if serverName == "" {
+		serverName = config.Host
+	}
+
+	// Verification is done manually after handshake, so we are able to report broken certificates
+	conn, err := tls.DialWithDialer(
+		&net.Dialer{Deadline: deadline},
+		"tcp",
+		net.JoinHostPort(config.Host, fmt.Sprintf("%d", config.Port)),
+		&tls.Config{
+			ServerName:         serverName,
+			InsecureSkipVerify: true, //nolint:gosec
+		},
+	)
+	if err != nil {
+		return newTLSCertError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), nil)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+
+	certs := conn.ConnectionState().PeerCertificates
+	if len(certs) == 0 {
+		return newTLSCertError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, errNoPeerCertificates.Error(), nil)
+	}
+
+	now := time.Now()
+	leaf := certs[0]
+	intermediates := x509.NewCertPool()
+	// Chain expires together with the first expired certificate in it
+	notAfter := leaf.NotAfter
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+		if cert.NotAfter.Before(notAfter) {
+			notAfter = cert.NotAfter
+		}
+	}
+
+	_, chainErr := leaf.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		CurrentTime:   now,
+	})
+	hostnameErr := leaf.VerifyHostname(serverName)
+
+	value := certInfoToValue(leaf, notAfter, now, chainErr, hostnameErr)
+
+	if hostnameErr != nil {
+		return newTLSCertError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, hostnameMismatchErrorFn(hostnameErr).Error(), value)
+	}
+
+	if chainErr != nil && !config.SkipChainValidation {
+		return newTLSCertError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, chainInvalidErrorFn(chainErr).Error(), value)
+	}
+
+	if notAfter.Before(now.AddDate(0, 0, int(config.ExpiryWarningDays))) {
+		return newTLSCertError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, certExpiresSoonErrorFn(notAfter, config.ExpiryWarningDays).Error(), value)
+	}
+
+	return newTLSCertError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", value)
+}
+
+func certInfoToValue(leaf *x509.Certificate, notAfter time.Time, now time.Time, chainErr error, hostnameErr error) *structType.Value {
+	sans := []*structType.Value{}
+	for _, name := range leaf.DNSNames {
+		sans = append(sans, stringValue(name))
+	}
+	for _, ip := range leaf.IPAddresses {
+		sans = append(sans, stringValue(ip.String()))
+	}
+	fields := map[string]*structType.Value{
+		"daysUntilExpiry": numberValue(float64(int64(notAfter.Sub(now) / day))),
+		"notAfter":        stringValue(notAfter.Format(time.RFC3339)),
+		"issuer":          stringValue(leaf.Issuer.String()),
+		"subject":         stringValue(leaf.Subject.String()),
+		"sans": {
+			Kind: &structType.Value_ListValue{
+				ListValue: &structType.ListValue{
+					Values: sans,
+				},
+			},
+		},
+		"chainValid":    boolValue(chainErr == nil),
+		"hostnameValid": boolValue(hostnameErr == nil),
+	}
+	if chainErr != nil {
+		fields["chainError"] = stringValue(chainErr.Error())
+	}
+	return &structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{
+				Fields: fields,
+			},
+		},
+	}
+}
@@ -0,0 +1,95 @@
+package job
+
+import (
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"strconv"
+	"testing"
+)
+
+func tlsServerAddress(t *testing.T, server *httptest.Server) (string, int32) {
+	u, err := url.Parse(server.URL)
+	assert.Equal(t, nil, err)
+	host, port, err := net.SplitHostPort(u.Host)
+	assert.Equal(t, nil, err)
+	p, err := strconv.ParseInt(port, 10, 32)
+	assert.Equal(t, nil, err)
+	return host, int32(p)
+}
+
+func TestExecTLSCert(t *testing.T) {
+	t.Run("Test: Testing tls certificate check:", func(t *testing.T) {
+		// Test certificate is valid for example.com and 127.0.0.1 and signed by unknown authority
+		server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+		host, port := tlsServerAddress(t, server)
+
+		t.Run("Should: return ok with certificate info", func(t *testing.T) {
+			job := ExecTLSCert("", 1, &scheduler_config_storage.TLSCertConfig{
+				Host:                host,
+				Port:                port,
+				ServerName:          "example.com",
+				ExpiryWarningDays:   30,
+				SkipChainValidation: true,
+			})
+			snapshot := job.GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_OK, snapshot.Code)
+			fields := snapshot.Meta.Value.GetStructValue().Fields
+			assert.Equal(t, false, fields["chainValid"].GetBoolValue())
+			assert.Equal(t, true, fields["hostnameValid"].GetBoolValue())
+			assert.Less(t, float64(30), fields["daysUntilExpiry"].GetNumberValue())
+			assert.NotEqual(t, 0, len(fields["sans"].GetListValue().Values))
+		})
+		t.Run("Should: use host as server name", func(t *testing.T) {
+			job := ExecTLSCert("", 1, &scheduler_config_storage.TLSCertConfig{
+				Host:                host,
+				Port:                port,
+				SkipChainValidation: true,
+			})
+			assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		})
+		t.Run("Should: return error because chain invalid", func(t *testing.T) {
+			job := ExecTLSCert("", 1, &scheduler_config_storage.TLSCertConfig{
+				Host:       host,
+				Port:       port,
+				ServerName: "example.com",
+			})
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		})
+		t.Run("Should: return error because hostname mismatch", func(t *testing.T) {
+			job := ExecTLSCert("", 1, &scheduler_config_storage.TLSCertConfig{
+				Host:                host,
+				Port:                port,
+				ServerName:          "squzy.app",
+				SkipChainValidation: true,
+			})
+			snapshot := job.GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Equal(t, false, snapshot.Meta.Value.GetStructValue().Fields["hostnameValid"].GetBoolValue())
+		})
+		t.Run("Should: return error because certificate expires soon", func(t *testing.T) {
+			job := ExecTLSCert("", 1, &scheduler_config_storage.TLSCertConfig{
+				Host:                host,
+				Port:                port,
+				ServerName:          "example.com",
+				ExpiryWarningDays:   1000000,
+				SkipChainValidation: true,
+			})
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		})
+		t.Run("Should: return error because cant connect", func(t *testing.T) {
+			job := ExecTLSCert("", 1, &scheduler_config_storage.TLSCertConfig{
+				Host: "localhost",
+				Port: 10005,
+			})
+			snapshot := job.GetLogData().Snapshot
+			assert.Equal(t, apiPb.SchedulerCode_ERROR, snapshot.Code)
+			assert.Nil(t, snapshot.Meta.Value)
+		})
+	})
+}
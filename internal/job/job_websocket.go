@@ -0,0 +1,212 @@
+package job
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/golang/protobuf/ptypes"
+	structType "github.com/golang/protobuf/ptypes/struct"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"golang.org/x/net/websocket"
+	"net"
+	"net/url"
+	"squzy/internal/helpers"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"time"
+)
+
+var (
+	errWebSocketInvalidURL    = errors.New("INVALID_WEBSOCKET_URL")
+	webSocketHandshakeErrorFn = func(err error) error {
+		return fmt.Errorf("handshake failed: %s", err.Error())
+	}
+	webSocketNoMatchErrorFn = func(message []byte, err error) error {
+		if message == nil {
+			return fmt.Errorf("no message matched: %s", err.Error())
+		}
+		return fmt.Errorf("no message matched, last message %q", truncateResponse(message))
+	}
+)
+
+type webSocketError struct {
+	schedulerID string
+	startTime   *timestamp.Timestamp
+	endTime     *timestamp.Timestamp
+	code        apiPb.SchedulerCode
+	description string
+	value       *structType.Value
+}
+
+func (e *webSocketError) GetLogData() *apiPb.SchedulerResponse {
+	var err *apiPb.SchedulerSnapshot_Error
+	if e.code == apiPb.SchedulerCode_ERROR {
+		err = &apiPb.SchedulerSnapshot_Error{
+			Message: e.description,
+		}
+	}
+	return &apiPb.SchedulerResponse{
+		SchedulerId: e.schedulerID,
+		Snapshot: &apiPb.SchedulerSnapshot{
+			Code:  e.code,
+			Error: err,
+			Type:  monitoring_api.SchedulerTypeWebSocket,
+			Meta: &apiPb.SchedulerSnapshot_MetaData{
+				StartTime: e.startTime,
+				EndTime:   e.endTime,
+				Value:     e.value,
+			},
+		},
+	}
+}
+
+func newWebSocketError(schedulerID string, startTime *timestamp.Timestamp, endTime *timestamp.Timestamp, code apiPb.SchedulerCode, description string, value *structType.Value) CheckError {
+	return &webSocketError{
+		schedulerID: schedulerID,
+		startTime:   startTime,
+		endTime:     endTime,
+		code:        code,
+		description: description,
+		value:       value,
+	}
+}
+
+// Makes upgrade handshake, sends message and waits message which matches expectation, whole check is limited by timeout
+func ExecWebSocket(schedulerID string, timeout int32, config *scheduler_config_storage.WebSocketConfig) CheckError {
+	startTime := ptypes.TimestampNow()
+
+	matcher, err := newSocketMatcher(config.Expect)
+	if err != nil {
+		return newWebSocketError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), nil)
+	}
+	wsConfig, err := webSocketConfig(config)
+	if err != nil {
+		return newWebSocketError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), nil)
+	}
+
+	deadline := time.Now().Add(socketReadTimeout(timeout, 0))
+	handshakeStart := time.Now()
+	conn, err := dialWebSocket(wsConfig, deadline)
+	if err != nil {
+		return newWebSocketError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, err.Error(), nil)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	handshake := time.Since(handshakeStart)
+
+	if config.Send == "" && matcher == nil {
+		return newWebSocketError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", webSocketMetaValue(handshake, nil, nil))
+	}
+
+	roundTripStart := time.Now()
+	if config.Send != "" {
+		err = websocket.Message.Send(conn, config.Send)
+		if err != nil {
+			return newWebSocketError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, socketWriteErrorFn(err).Error(), webSocketMetaValue(handshake, nil, nil))
+		}
+	}
+	if matcher == nil {
+		return newWebSocketError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", webSocketMetaValue(handshake, nil, nil))
+	}
+
+	message, err := receiveWebSocketMessage(conn, matcher)
+	if err != nil {
+		return newWebSocketError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_ERROR, webSocketNoMatchErrorFn(message, err).Error(), webSocketMetaValue(handshake, nil, message))
+	}
+	roundTrip := time.Since(roundTripStart)
+	return newWebSocketError(schedulerID, startTime, ptypes.TimestampNow(), apiPb.SchedulerCode_OK, "", webSocketMetaValue(handshake, &roundTrip, message))
+}
+
+func webSocketConfig(config *scheduler_config_storage.WebSocketConfig) (*websocket.Config, error) {
+	location, err := url.Parse(config.URL)
+	if err != nil || location.Host == "" || (location.Scheme != "ws" && location.Scheme != "wss") {
+		return nil, errWebSocketInvalidURL
+	}
+	origin := config.Origin
+	if origin == "" {
+		scheme := "http"
+		if location.Scheme == "wss" {
+			scheme = "https"
+		}
+		origin = fmt.Sprintf("%s://%s", scheme, location.Host)
+	}
+	wsConfig, err := websocket.NewConfig(config.URL, origin)
+	if err != nil {
+		return nil, errWebSocketInvalidURL
+	}
+	wsConfig.Protocol = config.Subprotocols
+	for name, value := range config.Headers {
+		wsConfig.Header.Set(name, value)
+	}
+	if location.Scheme == "wss" {
+		wsConfig.TlsConfig, err = helpers.NewTLSConfig(config.InsecureSkipVerify, "", "", "", location.Hostname())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return wsConfig, nil
+}
+
+// Connection has deadline, so handshake and messages can't hang longer than timeout of check
+func dialWebSocket(config *websocket.Config, deadline time.Time) (*websocket.Conn, error) {
+	address := config.Location.Host
+	if config.Location.Port() == "" {
+		port := "80"
+		if config.Location.Scheme == "wss" {
+			port = "443"
+		}
+		address = net.JoinHostPort(config.Location.Hostname(), port)
+	}
+	dialer := &net.Dialer{Deadline: deadline}
+	conn, err := dialer.Dial("tcp", address)
+	if err != nil {
+		return nil, errWrongConnectConfigError
+	}
+	_ = conn.SetDeadline(deadline)
+	if config.TlsConfig != nil {
+		conn = tls.Client(conn, config.TlsConfig)
+	}
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, webSocketHandshakeErrorFn(err)
+	}
+	return ws, nil
+}
+
+// Skips messages which do not match, last message is returned when nothing matched before deadline
+func receiveWebSocketMessage(conn *websocket.Conn, matcher *socketMatcher) ([]byte, error) {
+	var last []byte
+	for {
+		var message []byte
+		err := websocket.Message.Receive(conn, &message)
+		if err != nil {
+			return last, err
+		}
+		if matcher.match(message) {
+			return message, nil
+		}
+		last = message
+	}
+}
+
+func webSocketMetaValue(handshake time.Duration, roundTrip *time.Duration, message []byte) *structType.Value {
+	fields := map[string]*structType.Value{
+		"handshakeMs": numberValue(durationToMs(handshake)),
+	}
+	if roundTrip != nil {
+		fields["roundTripMs"] = numberValue(durationToMs(*roundTrip))
+	}
+	if message != nil {
+		fields["message"] = stringValue(truncateResponse(message))
+	}
+	return &structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{
+				Fields: fields,
+			},
+		},
+	}
+}
@@ -0,0 +1,116 @@
+package job
+
+import (
+	"errors"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"net/http/httptest"
+	monitoring_api "squzy/internal/monitoring-api"
+	scheduler_config_storage "squzy/internal/scheduler-config-storage"
+	"strings"
+	"testing"
+)
+
+// Greets client, then answers every message with its upper case, handshake requires authorization header
+func newWebSocketServer() *httptest.Server {
+	return httptest.NewServer(websocket.Server{
+		Handshake: func(config *websocket.Config, r *http.Request) error {
+			if r.Header.Get("Authorization") != "Bearer squzy" {
+				return errors.New("unauthorized")
+			}
+			return nil
+		},
+		Handler: func(conn *websocket.Conn) {
+			_ = websocket.Message.Send(conn, "welcome")
+			for {
+				var message string
+				err := websocket.Message.Receive(conn, &message)
+				if err != nil {
+					return
+				}
+				_ = websocket.Message.Send(conn, strings.ToUpper(message))
+			}
+		},
+	})
+}
+
+func TestExecWebSocket(t *testing.T) {
+	server := newWebSocketServer()
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/notifications"
+	headers := map[string]string{"Authorization": "Bearer squzy"}
+
+	t.Run("Should: return ok because handshake succeeded", func(t *testing.T) {
+		job := ExecWebSocket("", 1, &scheduler_config_storage.WebSocketConfig{
+			URL:     url,
+			Headers: headers,
+		})
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		assert.Equal(t, monitoring_api.SchedulerTypeWebSocket, job.GetLogData().Snapshot.Type)
+		assert.NotNil(t, job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields["handshakeMs"])
+	})
+	t.Run("Should: return ok because answer on message matches", func(t *testing.T) {
+		job := ExecWebSocket("", 1, &scheduler_config_storage.WebSocketConfig{
+			URL:     url,
+			Headers: headers,
+			Send:    "ping",
+			Expect: &scheduler_config_storage.SocketExpect{
+				Type:  monitoring_api.SocketExpectExact,
+				Value: "PING",
+			},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+		fields := job.GetLogData().Snapshot.Meta.Value.GetStructValue().Fields
+		assert.Equal(t, "PING", fields["message"].GetStringValue())
+		assert.NotNil(t, fields["roundTripMs"])
+	})
+	t.Run("Should: return error because no message matched", func(t *testing.T) {
+		job := ExecWebSocket("", 1, &scheduler_config_storage.WebSocketConfig{
+			URL:     url,
+			Headers: headers,
+			Send:    "ping",
+			Expect: &scheduler_config_storage.SocketExpect{
+				Type:  monitoring_api.SocketExpectPrefix,
+				Value: "PONG",
+			},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		assert.Equal(t, `no message matched, last message "PING"`, job.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: return error because handshake is rejected", func(t *testing.T) {
+		job := ExecWebSocket("", 1, &scheduler_config_storage.WebSocketConfig{
+			URL: url,
+		})
+		assert.Equal(t, apiPb.SchedulerCode_ERROR, job.GetLogData().Snapshot.Code)
+		assert.Contains(t, job.GetLogData().Snapshot.Error.Message, "handshake failed")
+	})
+	t.Run("Should: return error because url is not websocket", func(t *testing.T) {
+		job := ExecWebSocket("", 1, &scheduler_config_storage.WebSocketConfig{
+			URL: server.URL,
+		})
+		assert.Equal(t, errWebSocketInvalidURL.Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: return error because server is not available", func(t *testing.T) {
+		job := ExecWebSocket("", 1, &scheduler_config_storage.WebSocketConfig{
+			URL: "ws://localhost:1/",
+		})
+		assert.Equal(t, errWrongConnectConfigError.Error(), job.GetLogData().Snapshot.Error.Message)
+	})
+	t.Run("Should: connect over tls", func(t *testing.T) {
+		tlsServer := httptest.NewTLSServer(websocket.Handler(func(conn *websocket.Conn) {
+			_ = websocket.Message.Send(conn, "welcome")
+		}))
+		defer tlsServer.Close()
+		job := ExecWebSocket("", 1, &scheduler_config_storage.WebSocketConfig{
+			URL:                "wss" + strings.TrimPrefix(tlsServer.URL, "https"),
+			InsecureSkipVerify: true,
+			Expect: &scheduler_config_storage.SocketExpect{
+				Type:  monitoring_api.SocketExpectRegex,
+				Value: "^wel",
+			},
+		})
+		assert.Equal(t, apiPb.SchedulerCode_OK, job.GetLogData().Snapshot.Code)
+	})
+}
@@ -0,0 +1,25 @@
+package monitoring_api
+
+import (
+	"encoding/json"
+)
+
+const (
+	codecName = "json"
+)
+
+// Messages of extension are plain go structures, so they are sent as json instead of protobuf
+type codec struct {
+}
+
+func (c codec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (c codec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (c codec) Name() string {
+	return codecName
+}
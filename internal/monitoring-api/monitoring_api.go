@@ -0,0 +1,536 @@
+package monitoring_api
+
+import (
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"time"
+)
+
+// Scheduler types which squzy_monitoring can execute, but which are not
+// described in squzy_generated yet. Values are reserved: they are stored with
+// schedulers and snapshots, so they must not be reused for other types, and
+// squzy_generated should take them over with the same values
+const (
+	SchedulerTypeTLSCert    apiPb.SchedulerType = 6
+	SchedulerTypeDNS        apiPb.SchedulerType = 7
+	SchedulerTypeScenario   apiPb.SchedulerType = 8
+	SchedulerTypeCrawler    apiPb.SchedulerType = 9
+	SchedulerTypePostgres   apiPb.SchedulerType = 10
+	SchedulerTypeMySQL      apiPb.SchedulerType = 11
+	SchedulerTypeRedis      apiPb.SchedulerType = 12
+	SchedulerTypeMongo      apiPb.SchedulerType = 13
+	SchedulerTypeUDP        apiPb.SchedulerType = 14
+	SchedulerTypeWebSocket  apiPb.SchedulerType = 15
+	SchedulerTypePrometheus apiPb.SchedulerType = 16
+	SchedulerTypeContent    apiPb.SchedulerType = 17
+	SchedulerTypeCommand    apiPb.SchedulerType = 18
+)
+
+// Scheduler codes which are not described in squzy_generated yet,
+// snapshots with that codes are not counted as successful. Values are reserved
+// in the same way as values of scheduler types
+const (
+	SchedulerCodeWarning apiPb.SchedulerCode = 3
+	SchedulerCodeUnknown apiPb.SchedulerCode = 4
+	// Tick is skipped because previous execution of scheduler is still running
+	SchedulerCodeSkipped apiPb.SchedulerCode = 5
+)
+
+// What happens with tick when previous execution of scheduler is still running
+type OverlapPolicy string
+
+const (
+	// Tick is skipped and skipped snapshot is saved, without policy tick is skipped without snapshot
+	OverlapSkip OverlapPolicy = "skip"
+	// Only one tick waits for running execution, others are skipped
+	OverlapQueueOne OverlapPolicy = "queue_one"
+	// Ticks are executed in parallel
+	OverlapAllowParallel OverlapPolicy = "allow_parallel"
+)
+
+type DNSRecordType string
+
+const (
+	DNSRecordTypeA     DNSRecordType = "A"
+	DNSRecordTypeAAAA  DNSRecordType = "AAAA"
+	DNSRecordTypeCNAME DNSRecordType = "CNAME"
+	DNSRecordTypeMX    DNSRecordType = "MX"
+	DNSRecordTypeTXT   DNSRecordType = "TXT"
+)
+
+type DNSAssertion string
+
+const (
+	// Resolved values should be equal to expected values, order does not matter
+	DNSAssertionExact DNSAssertion = "exact"
+	// Every expected value should be resolved
+	DNSAssertionContains DNSAssertion = "contains"
+	// At least min count of records should be resolved, used by default
+	DNSAssertionMinCount DNSAssertion = "min_count"
+)
+
+type HTTPAssertionType string
+
+const (
+	HTTPAssertionBodyContains    HTTPAssertionType = "body_contains"
+	HTTPAssertionBodyNotContains HTTPAssertionType = "body_not_contains"
+	HTTPAssertionBodyRegex       HTTPAssertionType = "body_regex"
+	HTTPAssertionMaxSize         HTTPAssertionType = "max_size"
+	HTTPAssertionHeader          HTTPAssertionType = "header"
+	HTTPAssertionMaxLatency      HTTPAssertionType = "max_latency"
+)
+
+type HTTPAssertion struct {
+	Type HTTPAssertionType `json:"type"`
+	// Substring or regexp for body assertions, expected value for header assertion
+	Value string `json:"value,omitempty"`
+	// Name of response header, header should only present when value is empty
+	Header string `json:"header,omitempty"`
+	// Bytes for max size and milliseconds for max latency
+	Max int64 `json:"max,omitempty"`
+}
+
+type RequestBodyType string
+
+const (
+	RequestBodyRaw  RequestBodyType = "raw"
+	RequestBodyJSON RequestBodyType = "json"
+	RequestBodyForm RequestBodyType = "form"
+)
+
+type RequestBody struct {
+	Type RequestBodyType `json:"type"`
+	// Content of raw and json body
+	Content string `json:"content,omitempty"`
+	// Fields of form body
+	Form map[string]string `json:"form,omitempty"`
+	// Overrides content type which is selected by type of body
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// Transport and redirect settings of http checks
+type HTTPClientConfig struct {
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+	// PEM encoded certificates which are trusted in addition to system certificates
+	CACert string `json:"ca_cert,omitempty"`
+	// PEM encoded certificate and key for mutual tls
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+	// Overrides name which is sent as SNI and verified against certificate
+	ServerName string `json:"server_name,omitempty"`
+	// Proxy from environment is used when empty
+	ProxyURL         string `json:"proxy_url,omitempty"`
+	DisableRedirects bool   `json:"disable_redirects,omitempty"`
+	// 10 redirects are followed when not set
+	MaxRedirects int32 `json:"max_redirects,omitempty"`
+}
+
+// Extends http config of squzy_generated, json of that config is compatible with original one
+type HTTPConfig struct {
+	*apiPb.HttpConfig
+	Assertions []*HTTPAssertion  `json:"assertions,omitempty"`
+	Body       *RequestBody      `json:"body,omitempty"`
+	Client     *HTTPClientConfig `json:"client,omitempty"`
+}
+
+type SelectorRuleType string
+
+const (
+	// Number value should be greater than number
+	SelectorRuleGreater SelectorRuleType = "gt"
+	// Number value should be less than number
+	SelectorRuleLess SelectorRuleType = "lt"
+	// Number value should be between min and max inclusive
+	SelectorRuleBetween SelectorRuleType = "between"
+	// String, bool or number value should be equal to value, bool or number
+	SelectorRuleEquals SelectorRuleType = "equals"
+	// String value should match value
+	SelectorRuleRegex SelectorRuleType = "regex"
+	// Time value should not be older than seconds
+	SelectorRuleNotOlder SelectorRuleType = "not_older"
+)
+
+type SelectorRule struct {
+	Type    SelectorRuleType `json:"type"`
+	Number  float64          `json:"number,omitempty"`
+	Min     float64          `json:"min,omitempty"`
+	Max     float64          `json:"max,omitempty"`
+	Value   string           `json:"value,omitempty"`
+	Bool    bool             `json:"bool,omitempty"`
+	Seconds int64            `json:"seconds,omitempty"`
+}
+
+type HTTPValueSelector struct {
+	*apiPb.HttpJsonValueConfig_Selectors
+	// Check fails when value does not satisfy rule
+	Rule *SelectorRule `json:"rule,omitempty"`
+}
+
+type ValueFormat string
+
+const (
+	// Paths are gjson paths
+	ValueFormatJSON ValueFormat = "json"
+	// Paths are xpath
+	ValueFormatXML  ValueFormat = "xml"
+	ValueFormatHTML ValueFormat = "html"
+	// Paths are css selectors
+	ValueFormatHTMLCSS ValueFormat = "html_css"
+)
+
+// Extends http value config of squzy_generated, selectors are replaced by selectors with rules
+type HTTPValueConfig struct {
+	*apiPb.HttpJsonValueConfig
+	// Format of response, json by default
+	Format    ValueFormat          `json:"format,omitempty"`
+	Selectors []*HTTPValueSelector `json:"selectors,omitempty"`
+	Body      *RequestBody         `json:"body,omitempty"`
+	Client    *HTTPClientConfig    `json:"client,omitempty"`
+}
+
+// Extends sitemap config of squzy_generated, client settings are used for sitemap and its urls
+type SiteMapConfig struct {
+	*apiPb.SiteMapConfig
+	Client *HTTPClientConfig `json:"client,omitempty"`
+	// Check fails when part of failed urls is greater, any failed url fails check by default
+	MaxFailureRatio float64 `json:"max_failure_ratio,omitempty"`
+	// Urls which respond longer are reported as slow
+	SlowThresholdMs int32 `json:"slow_threshold_ms,omitempty"`
+	// Seconds while downloaded sitemap is used, default of squzy_monitoring is used when empty
+	CacheTTL int32 `json:"cache_ttl,omitempty"`
+}
+
+type GrpcTLSConfig struct {
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty"`
+	// PEM encoded certificates which are trusted in addition to system certificates
+	CACert string `json:"ca_cert,omitempty"`
+	// PEM encoded certificate and key for mutual tls
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+	// Overrides name which is sent as SNI and verified against certificate
+	ServerName string `json:"server_name,omitempty"`
+}
+
+// Unary method which is called instead of health check, method is found by server reflection
+type GrpcCallConfig struct {
+	// Full name of method, package.Service/Method
+	Method string `json:"method"`
+	// Request message in json
+	Request string `json:"request,omitempty"`
+	// Values are selected from json of response message
+	Selectors []*HTTPValueSelector `json:"selectors,omitempty"`
+}
+
+type SocketExpectType string
+
+const (
+	// Response should be equal to value
+	SocketExpectExact SocketExpectType = "exact"
+	// Response should start with value
+	SocketExpectPrefix SocketExpectType = "prefix"
+	// Response should match value
+	SocketExpectRegex SocketExpectType = "regex"
+)
+
+type SocketExpect struct {
+	Type  SocketExpectType `json:"type"`
+	Value string           `json:"value"`
+}
+
+// Extends tcp config of squzy_generated, payload is sent after connection and response is read when expect is set
+type TCPConfig struct {
+	*apiPb.TcpConfig
+	Send   string        `json:"send,omitempty"`
+	Expect *SocketExpect `json:"expect,omitempty"`
+	// How long response is awaited, timeout of scheduler is used when not set
+	ReadTimeoutMs int32 `json:"read_timeout_ms,omitempty"`
+}
+
+// Payload is sent as single datagram, response is single datagram too
+type UDPConfig struct {
+	Host          string        `json:"host"`
+	Port          int32         `json:"port"`
+	Send          string        `json:"send"`
+	Expect        *SocketExpect `json:"expect,omitempty"`
+	ReadTimeoutMs int32         `json:"read_timeout_ms,omitempty"`
+}
+
+// Handshake is checked, message is sent after it and response is awaited when expect is set
+type WebSocketConfig struct {
+	// ws:// or wss:// url
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Origin header of handshake, origin of url is used when empty
+	Origin       string   `json:"origin,omitempty"`
+	Subprotocols []string `json:"subprotocols,omitempty"`
+	Send         string   `json:"send,omitempty"`
+	// Messages are read until one of them matches or timeout is reached
+	Expect             *SocketExpect `json:"expect,omitempty"`
+	InsecureSkipVerify bool          `json:"insecure_skip_verify,omitempty"`
+}
+
+type MetricMatchType string
+
+const (
+	MetricMatchEqual    MetricMatchType = "="
+	MetricMatchNotEqual MetricMatchType = "!="
+	// Label value should match regex, regex is anchored same as in PromQL
+	MetricMatchRegex    MetricMatchType = "=~"
+	MetricMatchNotRegex MetricMatchType = "!~"
+)
+
+type MetricAggregation string
+
+const (
+	MetricAggregationSum   MetricAggregation = "sum"
+	MetricAggregationMin   MetricAggregation = "min"
+	MetricAggregationMax   MetricAggregation = "max"
+	MetricAggregationAvg   MetricAggregation = "avg"
+	MetricAggregationCount MetricAggregation = "count"
+)
+
+type MetricLabelMatcher struct {
+	Name  string          `json:"name"`
+	Type  MetricMatchType `json:"type"`
+	Value string          `json:"value"`
+}
+
+// Selects series by name and labels, rule is checked against every series or against aggregated value when aggregation is set
+type MetricSelector struct {
+	Name      string                `json:"name"`
+	Labels    []*MetricLabelMatcher `json:"labels,omitempty"`
+	Aggregate MetricAggregation     `json:"aggregate,omitempty"`
+	Rule      *SelectorRule         `json:"rule,omitempty"`
+}
+
+// Scrapes endpoint in prometheus text format, like http value check for json
+type PrometheusConfig struct {
+	URL       string            `json:"url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Selectors []*MetricSelector `json:"selectors"`
+	Client    *HTTPClientConfig `json:"client,omitempty"`
+}
+
+type ContentRegionType string
+
+const (
+	ContentRegionCSS   ContentRegionType = "css"
+	ContentRegionXPath ContentRegionType = "xpath"
+	// Region is selected by gjson path
+	ContentRegionJSON ContentRegionType = "json"
+)
+
+type ContentRegion struct {
+	Type ContentRegionType `json:"type"`
+	Path string            `json:"path"`
+}
+
+type ContentStatus string
+
+// Result of content check relative to previous run, saved to snapshot
+const (
+	ContentStatusChanged   ContentStatus = "CHANGED"
+	ContentStatusUnchanged ContentStatus = "UNCHANGED"
+)
+
+// Hash of response body or its region is compared with hash of previous run
+type ContentConfig struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Whole body is hashed when region is not set
+	Region *ContentRegion `json:"region,omitempty"`
+	// Regexes of dynamic fragments(timestamps, tokens) which are removed before hashing
+	Ignore []string          `json:"ignore,omitempty"`
+	Client *HTTPClientConfig `json:"client,omitempty"`
+}
+
+// Runs local executable, exit codes are mapped same as for nagios plugins:
+// 0 - OK, 1 - warning, 2 - error, 3 and others - unknown
+type CommandConfig struct {
+	// Absolute path, executable should be inside of directories allowed by squzy_monitoring
+	Path string            `json:"path"`
+	Args []string          `json:"args,omitempty"`
+	Env  map[string]string `json:"env,omitempty"`
+	// Working directory, directory of squzy_monitoring is used when empty
+	Dir string `json:"dir,omitempty"`
+	// Stdout is saved to snapshot as json value instead of string
+	ParseJSON bool `json:"parse_json,omitempty"`
+}
+
+// Extends grpc config of squzy_generated, json of that config is compatible with original one
+type GrpcConfig struct {
+	*apiPb.GrpcConfig
+	// Connection is insecure when tls is not set
+	TLS *GrpcTLSConfig `json:"tls,omitempty"`
+	// Outgoing metadata, for example authorization header
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Call     *GrpcCallConfig   `json:"call,omitempty"`
+}
+
+type TLSCertConfig struct {
+	Host string `json:"host"`
+	Port int32  `json:"port"`
+	// Name which should be sent as SNI and matched against certificate, host is used when empty
+	ServerName string `json:"server_name,omitempty"`
+	// Check will fail when certificate expires in less than that amount of days
+	ExpiryWarningDays   int32 `json:"expiry_warning_days"`
+	SkipChainValidation bool  `json:"skip_chain_validation"`
+}
+
+type DNSConfig struct {
+	Host       string        `json:"host"`
+	RecordType DNSRecordType `json:"record_type"`
+	// Address of resolver which should be asked, 53 port is used when port is not set
+	Resolver       string       `json:"resolver"`
+	Assertion      DNSAssertion `json:"assertion,omitempty"`
+	ExpectedValues []string     `json:"expected_values,omitempty"`
+	MinCount       int32        `json:"min_count,omitempty"`
+}
+
+// Captures value from response of step into variable, which can be used as {{variable}} by next steps
+type ScenarioCapture struct {
+	Variable string `json:"variable"`
+	// Path of value in json body(https://github.com/tidwall/gjson)
+	Path string `json:"path,omitempty"`
+	// Name of response header, used when path is empty
+	Header string `json:"header,omitempty"`
+}
+
+type ScenarioStep struct {
+	Name    string            `json:"name,omitempty"`
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    *RequestBody      `json:"body,omitempty"`
+	// 200 is expected when not set
+	StatusCode int32              `json:"status_code,omitempty"`
+	Assertions []*HTTPAssertion   `json:"assertions,omitempty"`
+	Captures   []*ScenarioCapture `json:"captures,omitempty"`
+}
+
+type ScenarioConfig struct {
+	Steps []*ScenarioStep `json:"steps"`
+	// Used by every step
+	Client *HTTPClientConfig `json:"client,omitempty"`
+}
+
+// Crawler starts from url and follows links to pages of same origin
+type CrawlerConfig struct {
+	URL string `json:"url"`
+	// How many links are followed from url, 2 by default
+	MaxDepth int32 `json:"max_depth,omitempty"`
+	// Limit of requested pages, 100 by default
+	MaxPages    int32 `json:"max_pages,omitempty"`
+	Concurrency int32 `json:"concurrency,omitempty"`
+	// Regexps of urls, only matched urls are requested when include is set
+	Include []string `json:"include,omitempty"`
+	// Regexps of urls which are ignored
+	Exclude []string          `json:"exclude,omitempty"`
+	Client  *HTTPClientConfig `json:"client,omitempty"`
+}
+
+// Connection to database of postgres, mysql, redis or mongo scheduler
+type DatabaseConfig struct {
+	Host     string `json:"host"`
+	Port     int32  `json:"port"`
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+	// Name of database, number for redis, authentication database for mongo
+	Database string `json:"database,omitempty"`
+	// Probe which is executed after connection: sql query or mongo command in json.
+	// SELECT 1 and {"hello": 1} by default
+	Query string `json:"query,omitempty"`
+	// Redis command with arguments, PING by default
+	Command []string `json:"command,omitempty"`
+	// Postgres sslmode, disable by default
+	SSLMode string `json:"ssl_mode,omitempty"`
+	// Checks single value returned by query, path is used only to select value from mongo command result
+	Value *HTTPValueSelector `json:"value,omitempty"`
+}
+
+// Failed check is repeated inside one run before it is saved
+type RetryPolicy struct {
+	// Total attempts of run, 1 means check is not repeated
+	Attempts  int32 `json:"attempts"`
+	BackoffMs int32 `json:"backoff_ms"`
+	// Backoff is multiplied after each attempt, 1 by default
+	BackoffMultiplier float64 `json:"backoff_multiplier,omitempty"`
+}
+
+// Failure is confirmed after N consecutive failed runs and recovery after M successful runs
+type FlapPolicy struct {
+	ConfirmAfter int32 `json:"confirm_after"`
+	RecoverAfter int32 `json:"recover_after"`
+}
+
+type AddRequest struct {
+	Interval   int32               `json:"interval"`
+	Cron       string              `json:"cron,omitempty"` // with seconds field, interval is not used when it is set
+	Timezone   string              `json:"timezone,omitempty"`
+	Timeout    int32               `json:"timeout"`
+	Name       string              `json:"name"`
+	Type       apiPb.SchedulerType `json:"type"`
+	Tcp        *TCPConfig          `json:"tcp,omitempty"`
+	Grpc       *GrpcConfig         `json:"grpc,omitempty"`
+	Http       *HTTPConfig         `json:"http,omitempty"`
+	Sitemap    *SiteMapConfig      `json:"sitemap,omitempty"`
+	HttpValue  *HTTPValueConfig    `json:"http_value,omitempty"`
+	TLSCert    *TLSCertConfig      `json:"tls_cert,omitempty"`
+	DNS        *DNSConfig          `json:"dns,omitempty"`
+	Scenario   *ScenarioConfig     `json:"scenario,omitempty"`
+	Crawler    *CrawlerConfig      `json:"crawler,omitempty"`
+	Database   *DatabaseConfig     `json:"database,omitempty"`
+	UDP        *UDPConfig          `json:"udp,omitempty"`
+	WebSocket  *WebSocketConfig    `json:"websocket,omitempty"`
+	Prometheus *PrometheusConfig   `json:"prometheus,omitempty"`
+	Content    *ContentConfig      `json:"content,omitempty"`
+	Command    *CommandConfig      `json:"command,omitempty"`
+	Retry      *RetryPolicy        `json:"retry,omitempty"`
+	Flap       *FlapPolicy         `json:"flap,omitempty"`
+	Overlap    OverlapPolicy       `json:"overlap,omitempty"`
+	// Hard deadline of check execution with all retry attempts in seconds, timeout snapshot is saved when it is exceeded
+	Deadline int32 `json:"deadline,omitempty"`
+}
+
+// Replaces config of scheduler, its id and history are kept
+type UpdateRequest struct {
+	ID     string      `json:"id"`
+	Config *AddRequest `json:"config"`
+}
+
+type UpdateResponse struct {
+}
+
+type GetSchedulesRequest struct {
+	// Schedules of all schedulers are returned when empty
+	IDs []string `json:"ids,omitempty"`
+}
+
+// When scheduler runs, it is not described by squzy_generated
+type Schedule struct {
+	ID       string `json:"id"`
+	Interval int32  `json:"interval"`
+	Cron     string `json:"cron,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+	// Empty when scheduler is stopped
+	NextRunTime *time.Time `json:"next_run_time,omitempty"`
+}
+
+type GetSchedulesResponse struct {
+	Schedules []*Schedule `json:"schedules"`
+}
+
+type GetDiagnosticsRequest struct {
+}
+
+// Current load of job executor
+type Diagnostics struct {
+	// Check executions which wait for free worker
+	QueueDepth  int32 `json:"queue_depth"`
+	RunningJobs int32 `json:"running_jobs"`
+	// 0 when concurrent executions are not limited
+	MaxConcurrentJobs int32 `json:"max_concurrent_jobs"`
+	// Check executions which wait for rate limit of target host
+	RateLimitedJobs  int32            `json:"rate_limited_jobs"`
+	RateLimitedHosts map[string]int32 `json:"rate_limited_hosts,omitempty"`
+}
@@ -0,0 +1,41 @@
+package monitoring_api
+
+import (
+	"encoding/json"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHTTPConfig(t *testing.T) {
+	t.Run("Should: be compatible with json of original config", func(t *testing.T) {
+		config := &HTTPConfig{}
+		err := json.Unmarshal([]byte(`{"method":"GET","url":"https://squzy.app","status_code":200,"assertions":[{"type":"max_size","max":10}]}`), config)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "https://squzy.app", config.Url)
+		assert.Equal(t, int32(200), config.StatusCode)
+		assert.Equal(t, HTTPAssertionMaxSize, config.Assertions[0].Type)
+		assert.Equal(t, int64(10), config.Assertions[0].Max)
+	})
+	t.Run("Should: marshal without extension fields", func(t *testing.T) {
+		data, err := json.Marshal(&HTTPConfig{
+			HttpConfig: &apiPb.HttpConfig{
+				Method: "GET",
+			},
+		})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, `{"method":"GET"}`, string(data))
+	})
+}
+
+func TestSiteMapConfig(t *testing.T) {
+	t.Run("Should: be compatible with json of original config", func(t *testing.T) {
+		config := &SiteMapConfig{}
+		err := json.Unmarshal([]byte(`{"url":"https://squzy.app/sitemap.xml","concurrency":5,"client":{"insecure_skip_verify":true,"max_redirects":2}}`), config)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "https://squzy.app/sitemap.xml", config.Url)
+		assert.Equal(t, int32(5), config.Concurrency)
+		assert.Equal(t, true, config.Client.InsecureSkipVerify)
+		assert.Equal(t, int32(2), config.Client.MaxRedirects)
+	})
+}
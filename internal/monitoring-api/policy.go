@@ -0,0 +1,88 @@
+package monitoring_api
+
+import (
+	structType "github.com/golang/protobuf/ptypes/struct"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+)
+
+// Key of snapshot meta value under which retries and confirmed state of check are saved
+const SnapshotPolicyKey = "policy"
+
+const (
+	policyAttemptsKey             = "attempts"
+	policyAttemptCodeKey          = "code"
+	policyAttemptMessageKey       = "message"
+	policyRawCodeKey              = "rawCode"
+	policyConfirmedCodeKey        = "confirmedCode"
+	policyConsecutiveFailuresKey  = "consecutiveFailures"
+	policyConsecutiveSuccessesKey = "consecutiveSuccesses"
+)
+
+type CheckAttempt struct {
+	Code    apiPb.SchedulerCode `json:"code"`
+	Message string              `json:"message,omitempty"`
+}
+
+// Raw attempts of run and state which is confirmed by flap suppression,
+// snapshot code is confirmed code
+type CheckPolicyState struct {
+	Attempts []*CheckAttempt `json:"attempts"`
+	// Code of last attempt
+	RawCode              apiPb.SchedulerCode `json:"rawCode"`
+	ConfirmedCode        apiPb.SchedulerCode `json:"confirmedCode"`
+	ConsecutiveFailures  int32               `json:"consecutiveFailures"`
+	ConsecutiveSuccesses int32               `json:"consecutiveSuccesses"`
+}
+
+func (s *CheckPolicyState) ToValue() *structType.Value {
+	attempts := []*structType.Value{}
+	for _, attempt := range s.Attempts {
+		attempts = append(attempts, &structType.Value{
+			Kind: &structType.Value_StructValue{
+				StructValue: &structType.Struct{
+					Fields: map[string]*structType.Value{
+						policyAttemptCodeKey:    numberValue(float64(attempt.Code)),
+						policyAttemptMessageKey: {Kind: &structType.Value_StringValue{StringValue: attempt.Message}},
+					},
+				},
+			},
+		})
+	}
+	return &structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{
+				Fields: map[string]*structType.Value{
+					policyAttemptsKey:             {Kind: &structType.Value_ListValue{ListValue: &structType.ListValue{Values: attempts}}},
+					policyRawCodeKey:              numberValue(float64(s.RawCode)),
+					policyConfirmedCodeKey:        numberValue(float64(s.ConfirmedCode)),
+					policyConsecutiveFailuresKey:  numberValue(float64(s.ConsecutiveFailures)),
+					policyConsecutiveSuccessesKey: numberValue(float64(s.ConsecutiveSuccesses)),
+				},
+			},
+		},
+	}
+}
+
+// Returns policy state which was saved in snapshot meta value, nil if snapshot has not it
+func CheckPolicyStateFromMetaValue(value *structType.Value) *CheckPolicyState {
+	state := value.GetStructValue().GetFields()[SnapshotPolicyKey].GetStructValue()
+	if state == nil {
+		return nil
+	}
+	fields := state.GetFields()
+	attempts := []*CheckAttempt{}
+	for _, attempt := range fields[policyAttemptsKey].GetListValue().GetValues() {
+		attemptFields := attempt.GetStructValue().GetFields()
+		attempts = append(attempts, &CheckAttempt{
+			Code:    apiPb.SchedulerCode(attemptFields[policyAttemptCodeKey].GetNumberValue()),
+			Message: attemptFields[policyAttemptMessageKey].GetStringValue(),
+		})
+	}
+	return &CheckPolicyState{
+		Attempts:             attempts,
+		RawCode:              apiPb.SchedulerCode(fields[policyRawCodeKey].GetNumberValue()),
+		ConfirmedCode:        apiPb.SchedulerCode(fields[policyConfirmedCodeKey].GetNumberValue()),
+		ConsecutiveFailures:  int32(fields[policyConsecutiveFailuresKey].GetNumberValue()),
+		ConsecutiveSuccesses: int32(fields[policyConsecutiveSuccessesKey].GetNumberValue()),
+	}
+}
@@ -0,0 +1,38 @@
+package monitoring_api
+
+import (
+	structType "github.com/golang/protobuf/ptypes/struct"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCheckPolicyStateFromMetaValue(t *testing.T) {
+	t.Run("Should: return policy state saved in meta value", func(t *testing.T) {
+		state := &CheckPolicyState{
+			Attempts: []*CheckAttempt{
+				{Code: apiPb.SchedulerCode_ERROR, Message: "timeout"},
+				{Code: apiPb.SchedulerCode_OK},
+			},
+			RawCode:              apiPb.SchedulerCode_OK,
+			ConfirmedCode:        apiPb.SchedulerCode_ERROR,
+			ConsecutiveSuccesses: 1,
+		}
+		value := &structType.Value{
+			Kind: &structType.Value_StructValue{
+				StructValue: &structType.Struct{
+					Fields: map[string]*structType.Value{
+						SnapshotPolicyKey: state.ToValue(),
+					},
+				},
+			},
+		}
+		assert.Equal(t, state, CheckPolicyStateFromMetaValue(value))
+	})
+	t.Run("Should: return nil because meta value has not policy state", func(t *testing.T) {
+		assert.Nil(t, CheckPolicyStateFromMetaValue(nil))
+		assert.Nil(t, CheckPolicyStateFromMetaValue(&structType.Value{
+			Kind: &structType.Value_StringValue{StringValue: "value"},
+		}))
+	})
+}
@@ -0,0 +1,182 @@
+package monitoring_api
+
+import (
+	"context"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+const (
+	serviceName              = "squzy.v1.monitoring.SchedulersExtension"
+	addMethodName            = "/" + serviceName + "/Add"
+	updateMethodName         = "/" + serviceName + "/Update"
+	getSchedulesMethodName   = "/" + serviceName + "/GetSchedules"
+	getDiagnosticsMethodName = "/" + serviceName + "/GetDiagnostics"
+)
+
+type SchedulersExtensionServer interface {
+	Add(context.Context, *AddRequest) (*apiPb.AddResponse, error)
+	Update(context.Context, *UpdateRequest) (*UpdateResponse, error)
+	GetSchedules(context.Context, *GetSchedulesRequest) (*GetSchedulesResponse, error)
+	GetDiagnostics(context.Context, *GetDiagnosticsRequest) (*Diagnostics, error)
+}
+
+type SchedulersExtensionClient interface {
+	Add(ctx context.Context, in *AddRequest, opts ...grpc.CallOption) (*apiPb.AddResponse, error)
+	Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*UpdateResponse, error)
+	GetSchedules(ctx context.Context, in *GetSchedulesRequest, opts ...grpc.CallOption) (*GetSchedulesResponse, error)
+	GetDiagnostics(ctx context.Context, in *GetDiagnosticsRequest, opts ...grpc.CallOption) (*Diagnostics, error)
+}
+
+type schedulersExtensionClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSchedulersExtensionClient(cc grpc.ClientConnInterface) SchedulersExtensionClient {
+	return &schedulersExtensionClient{
+		cc: cc,
+	}
+}
+
+func (c *schedulersExtensionClient) Add(ctx context.Context, in *AddRequest, opts ...grpc.CallOption) (*apiPb.AddResponse, error) {
+	out := new(apiPb.AddResponse)
+	err := c.cc.Invoke(ctx, addMethodName, in, out, withCodec(opts)...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *schedulersExtensionClient) Update(ctx context.Context, in *UpdateRequest, opts ...grpc.CallOption) (*UpdateResponse, error) {
+	out := new(UpdateResponse)
+	err := c.cc.Invoke(ctx, updateMethodName, in, out, withCodec(opts)...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *schedulersExtensionClient) GetSchedules(ctx context.Context, in *GetSchedulesRequest, opts ...grpc.CallOption) (*GetSchedulesResponse, error) {
+	out := new(GetSchedulesResponse)
+	err := c.cc.Invoke(ctx, getSchedulesMethodName, in, out, withCodec(opts)...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *schedulersExtensionClient) GetDiagnostics(ctx context.Context, in *GetDiagnosticsRequest, opts ...grpc.CallOption) (*Diagnostics, error) {
+	out := new(Diagnostics)
+	err := c.cc.Invoke(ctx, getDiagnosticsMethodName, in, out, withCodec(opts)...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func withCodec(opts []grpc.CallOption) []grpc.CallOption {
+	// Content-subtype tells server which codec should be used for request
+	return append(opts, grpc.ForceCodec(codec{}), grpc.CallContentSubtype(codecName))
+}
+
+func RegisterSchedulersExtensionServer(s *grpc.Server, srv SchedulersExtensionServer) {
+	encoding.RegisterCodec(codec{})
+	s.RegisterService(&serviceDesc, srv)
+}
+
+func addHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AddRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchedulersExtensionServer).Add(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: addMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchedulersExtensionServer).Add(ctx, req.(*AddRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func updateHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchedulersExtensionServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: updateMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchedulersExtensionServer).Update(ctx, req.(*UpdateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func getSchedulesHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSchedulesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchedulersExtensionServer).GetSchedules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: getSchedulesMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchedulersExtensionServer).GetSchedules(ctx, req.(*GetSchedulesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func getDiagnosticsHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetDiagnosticsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SchedulersExtensionServer).GetDiagnostics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: getDiagnosticsMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SchedulersExtensionServer).GetDiagnostics(ctx, req.(*GetDiagnosticsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*SchedulersExtensionServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Add",
+			Handler:    addHandler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    updateHandler,
+		},
+		{
+			MethodName: "GetSchedules",
+			Handler:    getSchedulesHandler,
+		},
+		{
+			MethodName: "GetDiagnostics",
+			Handler:    getDiagnosticsHandler,
+		},
+	},
+	Streams: []grpc.StreamDesc{},
+}
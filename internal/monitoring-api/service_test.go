@@ -0,0 +1,147 @@
+package monitoring_api
+
+import (
+	"context"
+	"errors"
+	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"net"
+	"testing"
+	"time"
+)
+
+type serverMock struct {
+	rq       *AddRequest
+	updateRq *UpdateRequest
+}
+
+func (s *serverMock) Add(ctx context.Context, rq *AddRequest) (*apiPb.AddResponse, error) {
+	if rq.Name == "" {
+		return nil, errors.New("empty name")
+	}
+	s.rq = rq
+	return &apiPb.AddResponse{
+		Id: rq.Name,
+	}, nil
+}
+
+func (s *serverMock) Update(ctx context.Context, rq *UpdateRequest) (*UpdateResponse, error) {
+	if rq.ID == "" {
+		return nil, errors.New("empty id")
+	}
+	s.updateRq = rq
+	return &UpdateResponse{}, nil
+}
+
+func (s *serverMock) GetSchedules(ctx context.Context, rq *GetSchedulesRequest) (*GetSchedulesResponse, error) {
+	if len(rq.IDs) == 0 {
+		return nil, errors.New("empty ids")
+	}
+	next := time.Date(2020, 6, 1, 3, 0, 0, 0, time.UTC)
+	return &GetSchedulesResponse{
+		Schedules: []*Schedule{
+			{
+				ID:          rq.IDs[0],
+				Cron:        "0 0 3 * * *",
+				NextRunTime: &next,
+			},
+		},
+	}, nil
+}
+
+func (s *serverMock) GetDiagnostics(ctx context.Context, rq *GetDiagnosticsRequest) (*Diagnostics, error) {
+	return &Diagnostics{
+		QueueDepth:        2,
+		RunningJobs:       10,
+		MaxConcurrentJobs: 10,
+		RateLimitedJobs:   1,
+		RateLimitedHosts: map[string]int32{
+			"squzy.app": 1,
+		},
+	}, nil
+}
+
+func TestNewSchedulersExtensionClient(t *testing.T) {
+	t.Run("Should: implement interface", func(t *testing.T) {
+		c := NewSchedulersExtensionClient(nil)
+		assert.Implements(t, (*SchedulersExtensionClient)(nil), c)
+	})
+}
+
+func TestSchedulersExtensionClient_Add(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:11101")
+	assert.Equal(t, nil, err)
+	srv := &serverMock{}
+	grpcServer := grpc.NewServer()
+	RegisterSchedulersExtensionServer(grpcServer, srv)
+	go func() {
+		_ = grpcServer.Serve(lis)
+	}()
+	defer grpcServer.Stop()
+	conn, err := grpc.Dial("localhost:11101", grpc.WithInsecure())
+	assert.Equal(t, nil, err)
+	defer conn.Close()
+	client := NewSchedulersExtensionClient(conn)
+	t.Run("Should: send request as json", func(t *testing.T) {
+		res, err := client.Add(context.Background(), &AddRequest{
+			Interval: 10,
+			Name:     "cert",
+			Type:     SchedulerTypeTLSCert,
+			TLSCert: &TLSCertConfig{
+				Host:              "localhost",
+				Port:              443,
+				ExpiryWarningDays: 14,
+			},
+		})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "cert", res.Id)
+		assert.Equal(t, SchedulerTypeTLSCert, srv.rq.Type)
+		assert.Equal(t, int32(14), srv.rq.TLSCert.ExpiryWarningDays)
+	})
+	t.Run("Should: return error from server", func(t *testing.T) {
+		_, err := client.Add(context.Background(), &AddRequest{})
+		assert.NotEqual(t, nil, err)
+	})
+	t.Run("Should: send update request as json", func(t *testing.T) {
+		_, err := client.Update(context.Background(), &UpdateRequest{
+			ID: "scheduler",
+			Config: &AddRequest{
+				Interval: 30,
+				Type:     SchedulerTypeTLSCert,
+				TLSCert: &TLSCertConfig{
+					Host: "localhost",
+					Port: 443,
+				},
+			},
+		})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "scheduler", srv.updateRq.ID)
+		assert.Equal(t, int32(30), srv.updateRq.Config.Interval)
+		assert.Equal(t, "localhost", srv.updateRq.Config.TLSCert.Host)
+	})
+	t.Run("Should: return error of update from server", func(t *testing.T) {
+		_, err := client.Update(context.Background(), &UpdateRequest{})
+		assert.NotEqual(t, nil, err)
+	})
+	t.Run("Should: return schedules", func(t *testing.T) {
+		res, err := client.GetSchedules(context.Background(), &GetSchedulesRequest{
+			IDs: []string{"scheduler"},
+		})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "scheduler", res.Schedules[0].ID)
+		assert.Equal(t, "0 0 3 * * *", res.Schedules[0].Cron)
+		assert.True(t, time.Date(2020, 6, 1, 3, 0, 0, 0, time.UTC).Equal(*res.Schedules[0].NextRunTime))
+	})
+	t.Run("Should: return error of schedules from server", func(t *testing.T) {
+		_, err := client.GetSchedules(context.Background(), &GetSchedulesRequest{})
+		assert.NotEqual(t, nil, err)
+	})
+	t.Run("Should: return diagnostics", func(t *testing.T) {
+		res, err := client.GetDiagnostics(context.Background(), &GetDiagnosticsRequest{})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, int32(2), res.QueueDepth)
+		assert.Equal(t, int32(10), res.RunningJobs)
+		assert.Equal(t, map[string]int32{"squzy.app": 1}, res.RateLimitedHosts)
+	})
+}
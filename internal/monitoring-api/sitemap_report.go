@@ -0,0 +1,129 @@
+package monitoring_api
+
+import (
+	structType "github.com/golang/protobuf/ptypes/struct"
+)
+
+// Key of snapshot meta value under which sitemap check saves results of urls
+const SnapshotSiteMapReportKey = "report"
+
+const (
+	reportTotalKey     = "total"
+	reportOkKey        = "ok"
+	reportFailedKey    = "failed"
+	reportSlowKey      = "slow"
+	reportOmittedKey   = "omitted"
+	reportTruncatedKey = "truncated"
+	reportMaxURLsKey   = "maxUrls"
+	reportURLsKey      = "urls"
+	reportLocationKey  = "location"
+	reportCodeKey      = "code"
+	reportLatencyKey   = "latencyMs"
+	reportErrorKey     = "error"
+	reportSlowFieldKey = "slow"
+)
+
+// Totals of requested urls of sitemap with results of failed or slow urls
+type SiteMapReport struct {
+	Total  int `json:"total"`
+	Ok     int `json:"ok"`
+	Failed int `json:"failed"`
+	Slow   int `json:"slow"`
+	// Failed or slow urls which are not listed because of limit of snapshot size
+	Omitted int `json:"omitted"`
+	// True when some failed or slow urls are omitted, so urls are not full list of them
+	Truncated bool `json:"truncated"`
+	// Limit of listed urls
+	MaxURLs int                 `json:"maxUrls"`
+	URLs    []*SiteMapURLResult `json:"urls"`
+}
+
+type SiteMapURLResult struct {
+	Location  string  `json:"location"`
+	Code      int     `json:"code"`
+	LatencyMs float64 `json:"latencyMs"`
+	Slow      bool    `json:"slow,omitempty"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Part of urls which failed, 0 when nothing was requested
+func (r *SiteMapReport) FailureRatio() float64 {
+	if r.Total == 0 {
+		return 0
+	}
+	return float64(r.Failed) / float64(r.Total)
+}
+
+func (r *SiteMapReport) ToValue() *structType.Value {
+	urls := make([]*structType.Value, 0, len(r.URLs))
+	for _, url := range r.URLs {
+		fields := map[string]*structType.Value{
+			reportLocationKey: {Kind: &structType.Value_StringValue{StringValue: url.Location}},
+			reportCodeKey:     numberValue(float64(url.Code)),
+			reportLatencyKey:  numberValue(url.LatencyMs),
+		}
+		if url.Slow {
+			fields[reportSlowFieldKey] = &structType.Value{Kind: &structType.Value_BoolValue{BoolValue: true}}
+		}
+		if url.Error != "" {
+			fields[reportErrorKey] = &structType.Value{Kind: &structType.Value_StringValue{StringValue: url.Error}}
+		}
+		urls = append(urls, &structType.Value{
+			Kind: &structType.Value_StructValue{
+				StructValue: &structType.Struct{Fields: fields},
+			},
+		})
+	}
+	return &structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{
+				Fields: map[string]*structType.Value{
+					reportTotalKey:   numberValue(float64(r.Total)),
+					reportOkKey:      numberValue(float64(r.Ok)),
+					reportFailedKey:  numberValue(float64(r.Failed)),
+					reportSlowKey:    numberValue(float64(r.Slow)),
+					reportOmittedKey: numberValue(float64(r.Omitted)),
+					reportTruncatedKey: {
+						Kind: &structType.Value_BoolValue{BoolValue: r.Truncated},
+					},
+					reportMaxURLsKey: numberValue(float64(r.MaxURLs)),
+					reportURLsKey: {
+						Kind: &structType.Value_ListValue{
+							ListValue: &structType.ListValue{Values: urls},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// Returns report which was saved in snapshot meta value, nil if snapshot has not it
+func SiteMapReportFromMetaValue(value *structType.Value) *SiteMapReport {
+	report := value.GetStructValue().GetFields()[SnapshotSiteMapReportKey].GetStructValue()
+	if report == nil {
+		return nil
+	}
+	fields := report.GetFields()
+	result := &SiteMapReport{
+		Total:     int(fields[reportTotalKey].GetNumberValue()),
+		Ok:        int(fields[reportOkKey].GetNumberValue()),
+		Failed:    int(fields[reportFailedKey].GetNumberValue()),
+		Slow:      int(fields[reportSlowKey].GetNumberValue()),
+		Omitted:   int(fields[reportOmittedKey].GetNumberValue()),
+		Truncated: fields[reportTruncatedKey].GetBoolValue(),
+		MaxURLs:   int(fields[reportMaxURLsKey].GetNumberValue()),
+		URLs:      []*SiteMapURLResult{},
+	}
+	for _, url := range fields[reportURLsKey].GetListValue().GetValues() {
+		urlFields := url.GetStructValue().GetFields()
+		result.URLs = append(result.URLs, &SiteMapURLResult{
+			Location:  urlFields[reportLocationKey].GetStringValue(),
+			Code:      int(urlFields[reportCodeKey].GetNumberValue()),
+			LatencyMs: urlFields[reportLatencyKey].GetNumberValue(),
+			Slow:      urlFields[reportSlowFieldKey].GetBoolValue(),
+			Error:     urlFields[reportErrorKey].GetStringValue(),
+		})
+	}
+	return result
+}
@@ -0,0 +1,47 @@
+package monitoring_api
+
+import (
+	structType "github.com/golang/protobuf/ptypes/struct"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSiteMapReportFromMetaValue(t *testing.T) {
+	t.Run("Should: return report saved in meta value", func(t *testing.T) {
+		report := &SiteMapReport{
+			Total:     2,
+			Ok:        1,
+			Failed:    1,
+			Slow:      1,
+			Omitted:   3,
+			Truncated: true,
+			MaxURLs:   2,
+			URLs: []*SiteMapURLResult{
+				{Location: "https://squzy.app", Code: 200, LatencyMs: 1500, Slow: true},
+				{Location: "https://squzy.app/404", Code: 404, LatencyMs: 10, Error: "wrong code"},
+			},
+		}
+		value := &structType.Value{
+			Kind: &structType.Value_StructValue{
+				StructValue: &structType.Struct{
+					Fields: map[string]*structType.Value{
+						SnapshotSiteMapReportKey: report.ToValue(),
+					},
+				},
+			},
+		}
+		assert.Equal(t, report, SiteMapReportFromMetaValue(value))
+	})
+	t.Run("Should: return nil because meta value has not report", func(t *testing.T) {
+		assert.Nil(t, SiteMapReportFromMetaValue(nil))
+	})
+}
+
+func TestSiteMapReport_FailureRatio(t *testing.T) {
+	t.Run("Should: return part of failed urls", func(t *testing.T) {
+		assert.Equal(t, 0.25, (&SiteMapReport{Total: 4, Failed: 1}).FailureRatio())
+	})
+	t.Run("Should: return 0 because urls were not requested", func(t *testing.T) {
+		assert.Equal(t, float64(0), (&SiteMapReport{}).FailureRatio())
+	})
+}
@@ -0,0 +1,73 @@
+package monitoring_api
+
+import (
+	structType "github.com/golang/protobuf/ptypes/struct"
+)
+
+// Key of snapshot meta value under which http checks save latency breakdown
+const SnapshotTimingsKey = "timings"
+
+const (
+	timingsDNSLookupKey        = "dnsLookupMs"
+	timingsTCPConnectKey       = "tcpConnectMs"
+	timingsTLSHandshakeKey     = "tlsHandshakeMs"
+	timingsTimeToFirstByteKey  = "timeToFirstByteMs"
+	timingsContentTransferKey  = "contentTransferMs"
+	timingsTotalKey            = "totalMs"
+	timingsReusedConnectionKey = "reusedConnection"
+)
+
+// Latency breakdown of http request in milliseconds
+type HTTPTimings struct {
+	DNSLookupMs       float64 `json:"dnsLookupMs"`
+	TCPConnectMs      float64 `json:"tcpConnectMs"`
+	TLSHandshakeMs    float64 `json:"tlsHandshakeMs"`
+	TimeToFirstByteMs float64 `json:"timeToFirstByteMs"`
+	ContentTransferMs float64 `json:"contentTransferMs"`
+	TotalMs           float64 `json:"totalMs"`
+	ReusedConnection  bool    `json:"reusedConnection"`
+}
+
+func (t *HTTPTimings) ToValue() *structType.Value {
+	return &structType.Value{
+		Kind: &structType.Value_StructValue{
+			StructValue: &structType.Struct{
+				Fields: map[string]*structType.Value{
+					timingsDNSLookupKey:        numberValue(t.DNSLookupMs),
+					timingsTCPConnectKey:       numberValue(t.TCPConnectMs),
+					timingsTLSHandshakeKey:     numberValue(t.TLSHandshakeMs),
+					timingsTimeToFirstByteKey:  numberValue(t.TimeToFirstByteMs),
+					timingsContentTransferKey:  numberValue(t.ContentTransferMs),
+					timingsTotalKey:            numberValue(t.TotalMs),
+					timingsReusedConnectionKey: {Kind: &structType.Value_BoolValue{BoolValue: t.ReusedConnection}},
+				},
+			},
+		},
+	}
+}
+
+// Returns timings which were saved in snapshot meta value, nil if snapshot has not them
+func HTTPTimingsFromMetaValue(value *structType.Value) *HTTPTimings {
+	timings := value.GetStructValue().GetFields()[SnapshotTimingsKey].GetStructValue()
+	if timings == nil {
+		return nil
+	}
+	fields := timings.GetFields()
+	return &HTTPTimings{
+		DNSLookupMs:       fields[timingsDNSLookupKey].GetNumberValue(),
+		TCPConnectMs:      fields[timingsTCPConnectKey].GetNumberValue(),
+		TLSHandshakeMs:    fields[timingsTLSHandshakeKey].GetNumberValue(),
+		TimeToFirstByteMs: fields[timingsTimeToFirstByteKey].GetNumberValue(),
+		ContentTransferMs: fields[timingsContentTransferKey].GetNumberValue(),
+		TotalMs:           fields[timingsTotalKey].GetNumberValue(),
+		ReusedConnection:  fields[timingsReusedConnectionKey].GetBoolValue(),
+	}
+}
+
+func numberValue(value float64) *structType.Value {
+	return &structType.Value{
+		Kind: &structType.Value_NumberValue{
+			NumberValue: value,
+		},
+	}
+}
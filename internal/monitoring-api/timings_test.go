@@ -0,0 +1,37 @@
+package monitoring_api
+
+import (
+	structType "github.com/golang/protobuf/ptypes/struct"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHTTPTimingsFromMetaValue(t *testing.T) {
+	t.Run("Should: return timings saved in meta value", func(t *testing.T) {
+		timings := &HTTPTimings{
+			DNSLookupMs:       1,
+			TCPConnectMs:      2,
+			TLSHandshakeMs:    3,
+			TimeToFirstByteMs: 4,
+			ContentTransferMs: 5,
+			TotalMs:           15,
+			ReusedConnection:  true,
+		}
+		value := &structType.Value{
+			Kind: &structType.Value_StructValue{
+				StructValue: &structType.Struct{
+					Fields: map[string]*structType.Value{
+						SnapshotTimingsKey: timings.ToValue(),
+					},
+				},
+			},
+		}
+		assert.Equal(t, timings, HTTPTimingsFromMetaValue(value))
+	})
+	t.Run("Should: return nil because meta value has not timings", func(t *testing.T) {
+		assert.Nil(t, HTTPTimingsFromMetaValue(nil))
+		assert.Nil(t, HTTPTimingsFromMetaValue(&structType.Value{
+			Kind: &structType.Value_StringValue{StringValue: "value"},
+		}))
+	})
+}
@@ -0,0 +1,20 @@
+package parsers
+
+import (
+	"fmt"
+	"github.com/andybalholm/cascadia"
+	"golang.org/x/net/html"
+)
+
+// Selects descendant elements of root in document order by CSS selector or group of selectors
+func SelectCSS(root *html.Node, selector string) ([]*html.Node, error) {
+	group, err := cascadia.ParseGroup(selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid css selector `%s`: %s", selector, err.Error())
+	}
+	result := cascadia.QueryAll(root, group)
+	if result == nil {
+		return []*html.Node{}, nil
+	}
+	return result, nil
+}
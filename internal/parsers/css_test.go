@@ -0,0 +1,99 @@
+package parsers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const htmlFixture = `<!DOCTYPE html>
+<html>
+<head><title>Squzy</title></head>
+<body>
+	<div id="main" class="content page">
+		<h1>Status</h1>
+		<ul class="services">
+			<li class="service ok" data-name="api">Api</li>
+			<li class="service failed" data-name="api-gateway">Gateway</li>
+			<li class="service ok" data-name="storage">Storage <b>v2</b></li>
+		</ul>
+		<p lang="en-US">Updated <span class="time">12:00</span></p>
+	</div>
+	<footer><a href="https://squzy.app/terms">Terms</a></footer>
+</body>
+</html>`
+
+func selectCSSText(t *testing.T, selector string) []string {
+	root, err := ParseHTML([]byte(htmlFixture))
+	assert.Nil(t, err)
+	nodes, err := SelectCSS(root, selector)
+	assert.Nil(t, err)
+	result := []string{}
+	for _, node := range nodes {
+		result = append(result, NodeText(node))
+	}
+	return result
+}
+
+func TestSelectCSS(t *testing.T) {
+	t.Run("Should: select by tag, id and class", func(t *testing.T) {
+		assert.Equal(t, []string{"Status"}, selectCSSText(t, "h1"))
+		assert.Equal(t, []string{"Status"}, selectCSSText(t, "#main h1"))
+		assert.Equal(t, []string{"Api", "Storage v2"}, selectCSSText(t, "li.service.ok"))
+		assert.Equal(t, []string{"Terms"}, selectCSSText(t, "footer > *"))
+	})
+	t.Run("Should: select by attributes", func(t *testing.T) {
+		assert.Equal(t, []string{"Api", "Gateway", "Storage v2"}, selectCSSText(t, "li[data-name]"))
+		assert.Equal(t, []string{"Api"}, selectCSSText(t, `li[data-name="api"]`))
+		assert.Equal(t, []string{"Api", "Gateway"}, selectCSSText(t, "li[data-name^=api]"))
+		assert.Equal(t, []string{"Storage v2"}, selectCSSText(t, "li[data-name$='age']"))
+		assert.Equal(t, []string{"Gateway"}, selectCSSText(t, "li[data-name*=gate]"))
+		assert.Equal(t, []string{"Gateway"}, selectCSSText(t, "li[class~=failed]"))
+		assert.Equal(t, []string{"Updated 12:00"}, selectCSSText(t, "p[lang|=en]"))
+	})
+	t.Run("Should: select by combinators and pseudo-classes", func(t *testing.T) {
+		assert.Equal(t, []string{"Updated 12:00"}, selectCSSText(t, "ul + p"))
+		assert.Equal(t, []string{"Updated 12:00"}, selectCSSText(t, "h1 ~ p"))
+		assert.Equal(t, []string{"12:00"}, selectCSSText(t, "div span.time"))
+		assert.Equal(t, []string{"Api"}, selectCSSText(t, "li:first-child"))
+		assert.Equal(t, []string{"Storage v2"}, selectCSSText(t, "li:last-child"))
+		assert.Equal(t, []string{"Gateway"}, selectCSSText(t, "li:nth-child(2)"))
+		assert.Equal(t, []string{"Api", "Storage v2"}, selectCSSText(t, "li:nth-child(odd)"))
+		assert.Equal(t, []string{"Gateway"}, selectCSSText(t, "li:nth-child(even)"))
+		assert.Equal(t, []string{"v2"}, selectCSSText(t, "b:only-child"))
+	})
+	t.Run("Should: select by structural pseudo-classes", func(t *testing.T) {
+		assert.Equal(t, []string{"Gateway", "Storage v2"}, selectCSSText(t, "li:nth-child(n+2)"))
+		assert.Equal(t, []string{"Api", "Gateway"}, selectCSSText(t, "li:nth-last-child(n+2)"))
+		assert.Equal(t, []string{"Gateway"}, selectCSSText(t, "li:nth-of-type(2)"))
+		assert.Equal(t, []string{"Terms"}, selectCSSText(t, "a:only-of-type"))
+		assert.Equal(t, []string{"Status", "Updated 12:00"}, selectCSSText(t, "div > :first-of-type:not(ul)"))
+		assert.Equal(t, []string{"Storage v2"}, selectCSSText(t, "li:last-of-type"))
+		assert.Equal(t, []string{}, selectCSSText(t, "li:nth-child(0)"))
+		assert.Equal(t, []string{"Squzy"}, selectCSSText(t, ":root > head > title"))
+	})
+	t.Run("Should: select by logical pseudo-classes", func(t *testing.T) {
+		assert.Equal(t, []string{"Gateway"}, selectCSSText(t, "li:not(.ok)"))
+		assert.Equal(t, []string{"Storage v2"}, selectCSSText(t, "li:has(b)"))
+		assert.Equal(t, []string{"Gateway"}, selectCSSText(t, "li:contains(Gate)"))
+		assert.Equal(t, []string{"Gateway", "Storage v2"}, selectCSSText(t, "li[data-name!=api]"))
+	})
+	t.Run("Should: select by nested combinators", func(t *testing.T) {
+		assert.Equal(t, []string{"v2"}, selectCSSText(t, "div > ul li > b"))
+		assert.Equal(t, []string{"Gateway", "Storage v2"}, selectCSSText(t, "li + li"))
+		assert.Equal(t, []string{"Storage v2"}, selectCSSText(t, "li.failed ~ li"))
+		assert.Equal(t, []string{"12:00"}, selectCSSText(t, "h1 ~ p > .time"))
+	})
+	t.Run("Should: select groups in document order", func(t *testing.T) {
+		assert.Equal(t, []string{"Status", "Terms"}, selectCSSText(t, "a, h1"))
+	})
+	t.Run("Should: return empty list", func(t *testing.T) {
+		assert.Equal(t, []string{}, selectCSSText(t, "table"))
+	})
+	t.Run("Should: return error because selector is invalid", func(t *testing.T) {
+		root, _ := ParseHTML([]byte(htmlFixture))
+		for _, selector := range []string{"", "li,", "li[", "li[data-name=", "li[data-name%=api]", "li:hover", "li:nth-child(x)", "div > > p", "#"} {
+			_, err := SelectCSS(root, selector)
+			assert.NotNil(t, err, selector)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package parsers
+
+import (
+	"bytes"
+	"golang.org/x/net/html"
+	"strings"
+)
+
+func ParseHTML(data []byte) (*html.Node, error) {
+	return html.Parse(bytes.NewReader(data))
+}
+
+// Returns concatenated text of node and its descendants, text of attribute selected by xpath is its value
+func NodeText(node *html.Node) string {
+	if node.Type == html.TextNode {
+		return node.Data
+	}
+	var text strings.Builder
+	var walk func(node *html.Node)
+	walk = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			switch child.Type {
+			case html.TextNode:
+				text.WriteString(child.Data)
+			case html.ElementNode:
+				walk(child)
+			}
+		}
+	}
+	walk(node)
+	return text.String()
+}
+
+// Returns markup of node with its descendants, so changes of attributes are visible too
+func RenderNode(node *html.Node) (string, error) {
+	if node.Type == html.TextNode {
+		return node.Data, nil
+	}
+	var buf bytes.Buffer
+	err := html.Render(&buf, node)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,91 @@
+package parsers
+
+import (
+	"bytes"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"sort"
+	"strconv"
+)
+
+// Single series of prometheus text exposition format
+type MetricSample struct {
+	Name   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Parses prometheus text exposition format by expfmt.TextParser, series are ordered by metric name.
+// Histograms and summaries are returned as their series(`_bucket`, `_sum`, `_count`), timestamps are skipped
+func ParseMetrics(data []byte) ([]*MetricSample, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	samples := []*MetricSample{}
+	for _, name := range names {
+		family := families[name]
+		for _, metric := range family.Metric {
+			samples = append(samples, metricSamples(name, family.GetType(), metric)...)
+		}
+	}
+	return samples, nil
+}
+
+func metricSamples(name string, metricType dto.MetricType, metric *dto.Metric) []*MetricSample {
+	labels := map[string]string{}
+	for _, label := range metric.Label {
+		labels[label.GetName()] = label.GetValue()
+	}
+	switch metricType {
+	case dto.MetricType_COUNTER:
+		return []*MetricSample{{Name: name, Labels: labels, Value: metric.GetCounter().GetValue()}}
+	case dto.MetricType_GAUGE:
+		return []*MetricSample{{Name: name, Labels: labels, Value: metric.GetGauge().GetValue()}}
+	case dto.MetricType_SUMMARY:
+		summary := metric.GetSummary()
+		samples := []*MetricSample{}
+		for _, quantile := range summary.Quantile {
+			samples = append(samples, &MetricSample{
+				Name:   name,
+				Labels: withLabel(labels, "quantile", quantile.GetQuantile()),
+				Value:  quantile.GetValue(),
+			})
+		}
+		return append(samples,
+			&MetricSample{Name: name + "_sum", Labels: labels, Value: summary.GetSampleSum()},
+			&MetricSample{Name: name + "_count", Labels: labels, Value: float64(summary.GetSampleCount())},
+		)
+	case dto.MetricType_HISTOGRAM:
+		histogram := metric.GetHistogram()
+		samples := []*MetricSample{}
+		for _, bucket := range histogram.Bucket {
+			samples = append(samples, &MetricSample{
+				Name:   name + "_bucket",
+				Labels: withLabel(labels, "le", bucket.GetUpperBound()),
+				Value:  float64(bucket.GetCumulativeCount()),
+			})
+		}
+		return append(samples,
+			&MetricSample{Name: name + "_sum", Labels: labels, Value: histogram.GetSampleSum()},
+			&MetricSample{Name: name + "_count", Labels: labels, Value: float64(histogram.GetSampleCount())},
+		)
+	default:
+		return []*MetricSample{{Name: name, Labels: labels, Value: metric.GetUntyped().GetValue()}}
+	}
+}
+
+// Copy of labels with label of bucket or quantile, value is formatted same as in exposition format
+func withLabel(labels map[string]string, name string, value float64) map[string]string {
+	result := map[string]string{name: strconv.FormatFloat(value, 'g', -1, 64)}
+	for key, labelValue := range labels {
+		result[key] = labelValue
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+package parsers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestParseMetrics(t *testing.T) {
+	t.Run("Should: parse samples with labels", func(t *testing.T) {
+		samples, err := ParseMetrics([]byte(`
+# HELP http_requests_total The total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",code="200"} 1027 1395066363000
+http_requests_total{method="post", code="400",} 3
+msdos_file_access_time_seconds{path="C:\\DIR\\FILE.TXT",error="Cannot find file:\n\"FILE.TXT\""} 1.458255915e9
+go_goroutines 42
+queue_oldest_seconds +Inf
+# EOF
+`))
+		assert.Nil(t, err)
+		assert.Equal(t, []*MetricSample{
+			{Name: "go_goroutines", Labels: map[string]string{}, Value: 42},
+			{Name: "http_requests_total", Labels: map[string]string{"method": "post", "code": "200"}, Value: 1027},
+			{Name: "http_requests_total", Labels: map[string]string{"method": "post", "code": "400"}, Value: 3},
+			{Name: "msdos_file_access_time_seconds", Labels: map[string]string{"path": `C:\DIR\FILE.TXT`, "error": "Cannot find file:\n\"FILE.TXT\""}, Value: 1.458255915e9},
+			{Name: "queue_oldest_seconds", Labels: map[string]string{}, Value: math.Inf(1)},
+		}, samples)
+	})
+	t.Run("Should: return series of histogram and summary", func(t *testing.T) {
+		samples, err := ParseMetrics([]byte(`
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{handler="/",le="0.1"} 8
+http_request_duration_seconds_bucket{handler="/",le="+Inf"} 10
+http_request_duration_seconds_sum{handler="/"} 1.5
+http_request_duration_seconds_count{handler="/"} 10
+# TYPE rpc_duration_seconds summary
+rpc_duration_seconds{quantile="0.99"} 0.25
+rpc_duration_seconds_sum 12
+rpc_duration_seconds_count 40
+`))
+		assert.Nil(t, err)
+		assert.Equal(t, []*MetricSample{
+			{Name: "http_request_duration_seconds_bucket", Labels: map[string]string{"handler": "/", "le": "0.1"}, Value: 8},
+			{Name: "http_request_duration_seconds_bucket", Labels: map[string]string{"handler": "/", "le": "+Inf"}, Value: 10},
+			{Name: "http_request_duration_seconds_sum", Labels: map[string]string{"handler": "/"}, Value: 1.5},
+			{Name: "http_request_duration_seconds_count", Labels: map[string]string{"handler": "/"}, Value: 10},
+			{Name: "rpc_duration_seconds", Labels: map[string]string{"quantile": "0.99"}, Value: 0.25},
+			{Name: "rpc_duration_seconds_sum", Labels: map[string]string{}, Value: 12},
+			{Name: "rpc_duration_seconds_count", Labels: map[string]string{}, Value: 40},
+		}, samples)
+	})
+	t.Run("Should: return error because value is missing", func(t *testing.T) {
+		_, err := ParseMetrics([]byte("go_goroutines\n"))
+		assert.NotNil(t, err)
+	})
+	t.Run("Should: return error because value is invalid", func(t *testing.T) {
+		_, err := ParseMetrics([]byte("go_goroutines many\n"))
+		assert.Contains(t, err.Error(), "line 1")
+	})
+	t.Run("Should: return error because labels are not closed", func(t *testing.T) {
+		_, err := ParseMetrics([]byte(`up{job="squzy" 1` + "\n"))
+		assert.NotNil(t, err)
+	})
+	t.Run("Should: return error because label value is not quoted", func(t *testing.T) {
+		_, err := ParseMetrics([]byte(`up{job=squzy} 1` + "\n"))
+		assert.NotNil(t, err)
+	})
+}
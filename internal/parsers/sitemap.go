@@ -1,12 +1,19 @@
 package parsers
 
 import (
+	"bytes"
+	"compress/gzip"
 	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
 )
 
 type SiteMap struct {
 	XMLName xml.Name     `xml:"urlset"`
 	URLSet  []SiteMapURL `xml:"url"`
+	// Child sitemaps, filled only when parsed file is sitemap index
+	SiteMaps []SiteMapIndexItem `xml:"-"`
 }
 
 type SiteMapURL struct {
@@ -15,22 +22,103 @@ type SiteMapURL struct {
 	Ignore   bool     `xml:"ignore"`
 }
 
+type SiteMapIndex struct {
+	XMLName  xml.Name           `xml:"sitemapindex"`
+	SiteMaps []SiteMapIndexItem `xml:"sitemap"`
+}
+
+type SiteMapIndexItem struct {
+	XMLName  xml.Name `xml:"sitemap"`
+	Location string   `xml:"loc"`
+}
+
+const (
+	siteMapIndexElement = "sitemapindex"
+)
+
+var (
+	gzipMagic          = []byte{0x1f, 0x8b}
+	errSiteMapTooLarge = errors.New("SITEMAP_TOO_LARGE")
+)
+
 type siteMapParser struct {
+	maxSize int64
 }
 
 type SiteMapParser interface {
+	// Parses urlset or sitemap index, gzip compressed files are decompressed
 	Parse(xmlBytes []byte) (*SiteMap, error)
 }
 
-func NewSiteMapParser() SiteMapParser {
-	return &siteMapParser{}
+// Sitemap which is bigger than max size after decompression is not parsed, 0 is without limit
+func NewSiteMapParser(maxSize int64) SiteMapParser {
+	return &siteMapParser{
+		maxSize: maxSize,
+	}
 }
 
 func (parser *siteMapParser) Parse(xmlBytes []byte) (*SiteMap, error) {
+	xmlBytes, err := decompress(xmlBytes, parser.maxSize)
+	if err != nil {
+		return nil, err
+	}
+	isIndex, err := isSiteMapIndex(xmlBytes)
+	if err != nil {
+		return nil, err
+	}
+	if isIndex {
+		index := &SiteMapIndex{}
+		err = xml.Unmarshal(xmlBytes, index)
+		if err != nil {
+			return nil, err
+		}
+		return &SiteMap{
+			SiteMaps: index.SiteMaps,
+		}, nil
+	}
 	siteMap := &SiteMap{}
-	err := xml.Unmarshal(xmlBytes, siteMap)
+	err = xml.Unmarshal(xmlBytes, siteMap)
 	if err != nil {
 		return nil, err
 	}
 	return siteMap, nil
 }
+
+// Decompressed data is read up to max size and one byte, so small gzip can not expand to huge sitemap
+func decompress(data []byte, maxSize int64) ([]byte, error) {
+	if bytes.HasPrefix(data, gzipMagic) {
+		gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = gzipReader.Close()
+		}()
+		var reader io.Reader = gzipReader
+		if maxSize > 0 {
+			reader = io.LimitReader(gzipReader, maxSize+1)
+		}
+		data, err = ioutil.ReadAll(reader)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return nil, errSiteMapTooLarge
+	}
+	return data, nil
+}
+
+// Looks at name of root element
+func isSiteMapIndex(data []byte) (bool, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return false, err
+		}
+		if element, ok := token.(xml.StartElement); ok {
+			return element.Name.Local == siteMapIndexElement, nil
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package parsers
 
 import (
+	"bytes"
+	"compress/gzip"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"testing"
@@ -8,7 +10,7 @@ import (
 
 func TestNewSiteMapParser(t *testing.T) {
 	t.Run("Test: SiteMapParse create", func(t *testing.T) {
-		parser := NewSiteMapParser()
+		parser := NewSiteMapParser(0)
 		assert.IsType(t, &siteMapParser{}, parser)
 		assert.NotEqual(t, nil, parser)
 	})
@@ -17,7 +19,7 @@ func TestNewSiteMapParser(t *testing.T) {
 func TestSiteMapParser_Parse(t *testing.T) {
 	t.Run("Test: Parse", func(t *testing.T) {
 		t.Run("Should: parse without error", func(t *testing.T) {
-			parser := NewSiteMapParser()
+			parser := NewSiteMapParser(0)
 			f, _ := ioutil.ReadFile("valid.xml")
 			res, err := parser.Parse(f)
 			assert.Equal(t, nil, err)
@@ -25,10 +27,57 @@ func TestSiteMapParser_Parse(t *testing.T) {
 			assert.Equal(t, true, res.URLSet[0].Ignore)
 		})
 		t.Run("Should: parse with error", func(t *testing.T) {
-			parser := NewSiteMapParser()
+			parser := NewSiteMapParser(0)
 			f, _ := ioutil.ReadFile("invalid.xml")
 			_, err := parser.Parse(f)
 			assert.NotEqual(t, nil, err)
 		})
+		t.Run("Should: parse sitemap index", func(t *testing.T) {
+			parser := NewSiteMapParser(0)
+			f, _ := ioutil.ReadFile("index.xml")
+			res, err := parser.Parse(f)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, 0, len(res.URLSet))
+			assert.Equal(t, 2, len(res.SiteMaps))
+			assert.Equal(t, "http://www.example.com/sitemap1.xml.gz", res.SiteMaps[0].Location)
+		})
+		t.Run("Should: parse gzip compressed sitemap", func(t *testing.T) {
+			parser := NewSiteMapParser(0)
+			f, _ := ioutil.ReadFile("valid.xml")
+			buf := &bytes.Buffer{}
+			writer := gzip.NewWriter(buf)
+			_, _ = writer.Write(f)
+			_ = writer.Close()
+			res, err := parser.Parse(buf.Bytes())
+			assert.Equal(t, nil, err)
+			assert.Equal(t, 5, len(res.URLSet))
+		})
+		t.Run("Should: return error because decompressed sitemap is too large", func(t *testing.T) {
+			f, _ := ioutil.ReadFile("valid.xml")
+			buf := &bytes.Buffer{}
+			writer := gzip.NewWriter(buf)
+			_, _ = writer.Write(f)
+			_ = writer.Close()
+			_, err := NewSiteMapParser(int64(len(f) - 1)).Parse(buf.Bytes())
+			assert.Equal(t, errSiteMapTooLarge, err)
+			res, err := NewSiteMapParser(int64(len(f))).Parse(buf.Bytes())
+			assert.Equal(t, nil, err)
+			assert.Equal(t, 5, len(res.URLSet))
+		})
+		t.Run("Should: return error because sitemap is too large", func(t *testing.T) {
+			f, _ := ioutil.ReadFile("valid.xml")
+			_, err := NewSiteMapParser(10).Parse(f)
+			assert.Equal(t, errSiteMapTooLarge, err)
+		})
+		t.Run("Should: return error because gzip is broken", func(t *testing.T) {
+			parser := NewSiteMapParser(0)
+			_, err := parser.Parse([]byte{0x1f, 0x8b, 0x00})
+			assert.NotEqual(t, nil, err)
+		})
+		t.Run("Should: return error because file is empty", func(t *testing.T) {
+			parser := NewSiteMapParser(0)
+			_, err := parser.Parse([]byte{})
+			assert.NotEqual(t, nil, err)
+		})
 	})
 }
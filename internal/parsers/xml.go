@@ -0,0 +1,74 @@
+package parsers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+)
+
+var (
+	errEmptyXML = errors.New("xml document is empty")
+)
+
+// Parses xml document, charset from declaration is decoded by golang.org/x/net/html/charset.
+// Namespace prefixes are kept, so elements can be selected by `//soap:Body` or by `//*[local-name()='Body']`
+func ParseXML(data []byte) (*xmlquery.Node, error) {
+	root, err := xmlquery.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == xmlquery.ElementNode {
+			return root, nil
+		}
+	}
+	return nil, errEmptyXML
+}
+
+// Selects xml nodes by XPath 1.0 expression which result is node-set.
+// Attributes are returned as attribute nodes with value of attribute as text
+func SelectXMLXPath(root *xmlquery.Node, path string) (result []*xmlquery.Node, err error) {
+	// Errors of functions arguments are raised by xpath as panic while nodes are selected
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("invalid xpath `%s`: %v", path, r)
+		}
+	}()
+	expr, err := xpath.Compile(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xpath `%s`: %s", path, err.Error())
+	}
+	iterator, ok := expr.Evaluate(xmlquery.CreateXPathNavigator(root)).(*xpath.NodeIterator)
+	if !ok {
+		return nil, fmt.Errorf("invalid xpath `%s`: %s", path, errNotLocationPath.Error())
+	}
+	result = []*xmlquery.Node{}
+	for iterator.MoveNext() {
+		navigator := iterator.Current().(*xmlquery.NodeNavigator)
+		if navigator.NodeType() != xpath.AttributeNode {
+			result = append(result, navigator.Current())
+			continue
+		}
+		// Navigator stays on element while it is moved through attributes
+		text := &xmlquery.Node{Type: xmlquery.TextNode, Data: navigator.Value()}
+		result = append(result, &xmlquery.Node{
+			Type:       xmlquery.AttributeNode,
+			Data:       navigator.LocalName(),
+			Prefix:     navigator.Prefix(),
+			Parent:     navigator.Current(),
+			FirstChild: text,
+			LastChild:  text,
+		})
+	}
+	return result, nil
+}
+
+// Returns xml markup of node with its descendants, attribute selected by xpath is returned as its value
+func RenderXMLNode(node *xmlquery.Node) string {
+	if node.Type == xmlquery.AttributeNode {
+		return node.InnerText()
+	}
+	return node.OutputXML(true)
+}
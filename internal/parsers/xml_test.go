@@ -0,0 +1,95 @@
+package parsers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const xmlFixture = `<?xml version="1.0" encoding="UTF-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:s="urn:status">
+	<soap:Body>
+		<s:Status updated="2020-06-01T10:00:00Z">
+			<s:Service name="billing" healthy="true">
+				<s:Latency>120</s:Latency>
+			</s:Service>
+			<s:Service name="search" healthy="false">
+				<s:Latency>980</s:Latency>
+				<s:Message><![CDATA[Index is <stale>]]></s:Message>
+			</s:Service>
+		</s:Status>
+	</soap:Body>
+</soap:Envelope>`
+
+func TestParseXML(t *testing.T) {
+	t.Run("Should: select by xpath with namespace prefixes", func(t *testing.T) {
+		root, err := ParseXML([]byte(xmlFixture))
+		assert.Nil(t, err)
+		nodes, err := SelectXMLXPath(root, "//s:Service[@name='search']/s:Latency")
+		assert.Nil(t, err)
+		assert.Equal(t, "980", nodes[0].InnerText())
+		nodes, err = SelectXMLXPath(root, "/soap:Envelope/soap:Body/s:Status/@updated")
+		assert.Nil(t, err)
+		assert.Equal(t, "2020-06-01T10:00:00Z", nodes[0].InnerText())
+		nodes, err = SelectXMLXPath(root, "//s:Service[@healthy='false']/s:Message")
+		assert.Nil(t, err)
+		assert.Equal(t, "Index is <stale>", nodes[0].InnerText())
+	})
+	t.Run("Should: select by xpath with local names", func(t *testing.T) {
+		root, err := ParseXML([]byte(xmlFixture))
+		assert.Nil(t, err)
+		nodes, err := SelectXMLXPath(root, "//*[local-name()='Service'][@name='billing']/*[local-name()='Latency']")
+		assert.Nil(t, err)
+		assert.Equal(t, "120", nodes[0].InnerText())
+	})
+	t.Run("Should: parse latin-1 document", func(t *testing.T) {
+		root, err := ParseXML([]byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><status>Fran\xe7ais</status>"))
+		assert.Nil(t, err)
+		assert.Equal(t, "Français", root.InnerText())
+	})
+	t.Run("Should: parse koi8-r document", func(t *testing.T) {
+		root, err := ParseXML([]byte("<?xml version=\"1.0\" encoding=\"KOI8-R\"?><status>\xf0\xd2\xc9\xd7\xc5\xd4</status>"))
+		assert.Nil(t, err)
+		assert.Equal(t, "Привет", root.InnerText())
+	})
+	t.Run("Should: return error because charset is not supported", func(t *testing.T) {
+		_, err := ParseXML([]byte(`<?xml version="1.0" encoding="X-UNKNOWN"?><status/>`))
+		assert.NotNil(t, err)
+	})
+	t.Run("Should: return error because xml is invalid", func(t *testing.T) {
+		_, err := ParseXML([]byte(`<status><ok></status>`))
+		assert.NotNil(t, err)
+	})
+	t.Run("Should: return error because xml is empty", func(t *testing.T) {
+		_, err := ParseXML([]byte(`<?xml version="1.0"?>`))
+		assert.Equal(t, errEmptyXML, err)
+	})
+}
+
+func TestSelectXMLXPath(t *testing.T) {
+	root, _ := ParseXML([]byte(xmlFixture))
+	t.Run("Should: return error because xpath is invalid", func(t *testing.T) {
+		_, err := SelectXMLXPath(root, "//s:Service[")
+		assert.NotNil(t, err)
+	})
+	t.Run("Should: return error because result is not node-set", func(t *testing.T) {
+		_, err := SelectXMLXPath(root, "count(//s:Service)")
+		assert.NotNil(t, err)
+	})
+	t.Run("Should: return empty result", func(t *testing.T) {
+		nodes, err := SelectXMLXPath(root, "//s:Unknown")
+		assert.Nil(t, err)
+		assert.Empty(t, nodes)
+	})
+}
+
+func TestRenderXMLNode(t *testing.T) {
+	t.Run("Should: render element with prefix and attribute", func(t *testing.T) {
+		root, _ := ParseXML([]byte(xmlFixture))
+		nodes, _ := SelectXMLXPath(root, "//s:Service[2]/s:Message")
+		assert.Equal(t, "<s:Message>Index is &lt;stale&gt;</s:Message>", RenderXMLNode(nodes[0]))
+		nodes, _ = SelectXMLXPath(root, "//s:Service[1]")
+		assert.Contains(t, RenderXMLNode(nodes[0]), `<s:Service name="billing" healthy="true">`)
+		nodes, _ = SelectXMLXPath(root, "//s:Service[1]/@name")
+		assert.Equal(t, "billing", RenderXMLNode(nodes[0]))
+	})
+}
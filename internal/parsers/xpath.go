@@ -0,0 +1,48 @@
+package parsers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+)
+
+var (
+	errNotLocationPath = errors.New("result is not a node-set")
+)
+
+// Selects nodes by XPath 1.0 expression which result is node-set.
+// Attributes are returned as text nodes with value of attribute
+func SelectXPath(root *html.Node, path string) (result []*html.Node, err error) {
+	// Errors of functions arguments are raised by xpath as panic while nodes are selected
+	defer func() {
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("invalid xpath `%s`: %v", path, r)
+		}
+	}()
+	expr, err := xpath.Compile(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid xpath `%s`: %s", path, err.Error())
+	}
+	iterator, ok := expr.Evaluate(htmlquery.CreateXPathNavigator(root)).(*xpath.NodeIterator)
+	if !ok {
+		return nil, fmt.Errorf("invalid xpath `%s`: %s", path, errNotLocationPath.Error())
+	}
+	result = []*html.Node{}
+	for iterator.MoveNext() {
+		navigator := iterator.Current().(*htmlquery.NodeNavigator)
+		if navigator.NodeType() != xpath.AttributeNode {
+			result = append(result, navigator.Current())
+			continue
+		}
+		// Navigator stays on element while it is moved through attributes
+		result = append(result, &html.Node{
+			Type:   html.TextNode,
+			Data:   navigator.Value(),
+			Attr:   []html.Attribute{{Key: navigator.LocalName(), Val: navigator.Value()}},
+			Parent: navigator.Current(),
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,96 @@
+package parsers
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func selectXPathText(t *testing.T, path string) []string {
+	root, err := ParseHTML([]byte(htmlFixture))
+	assert.Nil(t, err)
+	nodes, err := SelectXPath(root, path)
+	assert.Nil(t, err)
+	result := []string{}
+	for _, node := range nodes {
+		result = append(result, NodeText(node))
+	}
+	return result
+}
+
+func TestSelectXPath(t *testing.T) {
+	t.Run("Should: select by location path", func(t *testing.T) {
+		assert.Equal(t, []string{"Squzy"}, selectXPathText(t, "/html/head/title"))
+		assert.Equal(t, []string{"Squzy"}, selectXPathText(t, "/html/head/title/text()"))
+		assert.Equal(t, []string{"Status"}, selectXPathText(t, "//div/h1"))
+		assert.Equal(t, []string{"Api", "Gateway", "Storage v2"}, selectXPathText(t, "//ul/*"))
+		assert.Equal(t, []string{"12:00"}, selectXPathText(t, "//span/."))
+		assert.Equal(t, []string{"Updated 12:00"}, selectXPathText(t, "//span/.."))
+	})
+	t.Run("Should: select attributes", func(t *testing.T) {
+		assert.Equal(t, []string{"https://squzy.app/terms"}, selectXPathText(t, "//a/@href"))
+		assert.Equal(t, []string{"api", "api-gateway", "storage"}, selectXPathText(t, "//@data-name"))
+		assert.Equal(t, []string{"https://squzy.app/terms"}, selectXPathText(t, "//footer/a/@*"))
+	})
+	t.Run("Should: filter by predicates", func(t *testing.T) {
+		assert.Equal(t, []string{"Gateway"}, selectXPathText(t, "//li[2]"))
+		assert.Equal(t, []string{"Storage v2"}, selectXPathText(t, "//li[last()]"))
+		assert.Equal(t, []string{"Gateway", "Storage v2"}, selectXPathText(t, "//li[position() > 1]"))
+		assert.Equal(t, []string{"Api"}, selectXPathText(t, "//li[@data-name='api']"))
+		assert.Equal(t, []string{"Gateway"}, selectXPathText(t, `//li[contains(@class, "failed")]`))
+		assert.Equal(t, []string{"Api", "Gateway"}, selectXPathText(t, "//li[starts-with(@data-name, 'api')]"))
+		assert.Equal(t, []string{"Storage v2"}, selectXPathText(t, "//li[b]"))
+		assert.Equal(t, []string{"Api", "Gateway"}, selectXPathText(t, "//li[not(b)]"))
+		assert.Equal(t, []string{"Api"}, selectXPathText(t, "//li[text()='Api' or @data-name='none']"))
+		assert.Equal(t, []string{"Storage v2"}, selectXPathText(t, "//li[normalize-space(.)='Storage v2' and @class!='failed']"))
+		assert.Equal(t, []string{"Status"}, selectXPathText(t, "//div[count(ul/li) = 3]/h1"))
+		assert.Equal(t, []string{"12:00"}, selectXPathText(t, "//p[string(span) = '12:00']/span"))
+		assert.Equal(t, []string{"Api"}, selectXPathText(t, "//ul[count(li) >= 3]/li[1]"))
+	})
+	t.Run("Should: select by axes", func(t *testing.T) {
+		assert.Equal(t, []string{"Gateway", "Storage v2"}, selectXPathText(t, "//li[1]/following-sibling::li"))
+		assert.Equal(t, []string{"Api"}, selectXPathText(t, "//li[2]/preceding-sibling::*"))
+		assert.Equal(t, []string{"services"}, selectXPathText(t, "//b/ancestor::ul/@class"))
+		assert.Equal(t, []string{"Status"}, selectXPathText(t, "//span/ancestor::div[1]/child::h1"))
+		assert.Equal(t, []string{"v2"}, selectXPathText(t, "//ul/descendant::b"))
+		assert.Equal(t, []string{"Updated 12:00"}, selectXPathText(t, "//ul/following::p"))
+		assert.Equal(t, []string{"Storage v2"}, selectXPathText(t, "//b/parent::li"))
+		assert.Equal(t, []string{"api", "api-gateway"}, selectXPathText(t, "//li[position() < 3]/attribute::data-name"))
+	})
+	t.Run("Should: filter by functions", func(t *testing.T) {
+		assert.Equal(t, []string{"Api", "Gateway"}, selectXPathText(t, "//li[substring(@data-name, 1, 3) = 'api']"))
+		assert.Equal(t, []string{"Gateway"}, selectXPathText(t, "//li[string-length(text()) = 7]"))
+		assert.Equal(t, []string{"Api"}, selectXPathText(t, "//li[translate(text(), 'API', 'api') = 'api']"))
+		assert.Equal(t, []string{"Gateway"}, selectXPathText(t, "//li[substring-after(@data-name, '-') = 'gateway']"))
+		assert.Equal(t, []string{"Api", "Gateway"}, selectXPathText(t, "//li[substring-before(@data-name, '-') = 'api' or @data-name = 'api']"))
+		assert.Equal(t, []string{"Terms"}, selectXPathText(t, "//a[concat('https://', 'squzy.app/terms') = @href]"))
+		assert.Equal(t, []string{"Storage v2"}, selectXPathText(t, "//li[count(*) > 0]"))
+		assert.Equal(t, []string{"Api", "Storage v2"}, selectXPathText(t, "//li[position() mod 2 = 1]"))
+		assert.Equal(t, []string{"Api"}, selectXPathText(t, "//li[local-name() = 'li' and @data-name = 'api']"))
+	})
+	t.Run("Should: select union", func(t *testing.T) {
+		assert.ElementsMatch(t, []string{"Status", "Terms"}, selectXPathText(t, "//a | //h1"))
+	})
+	t.Run("Should: return attribute as text node", func(t *testing.T) {
+		root, _ := ParseHTML([]byte(htmlFixture))
+		nodes, err := SelectXPath(root, "//a/@href")
+		assert.Nil(t, err)
+		assert.Equal(t, "https://squzy.app/terms", nodes[0].Data)
+		assert.Equal(t, "href", nodes[0].Attr[0].Key)
+		assert.Equal(t, "a", nodes[0].Parent.Data)
+	})
+	t.Run("Should: return empty list", func(t *testing.T) {
+		assert.Equal(t, []string{}, selectXPathText(t, "//table"))
+	})
+	t.Run("Should: return error because path is invalid", func(t *testing.T) {
+		root, _ := ParseHTML([]byte(htmlFixture))
+		for _, path := range []string{"", "//", "//li[", "//li[1", "//li[@]", "//li[foo()]", "//li[contains(@class)]", "//li['unclosed]", "//li[!]", "//li/#", "count(//li)", "'text'"} {
+			_, err := SelectXPath(root, path)
+			assert.NotNil(t, err, path)
+		}
+	})
+	t.Run("Should: return error because function argument is out of range", func(t *testing.T) {
+		root, _ := ParseHTML([]byte(htmlFixture))
+		_, err := SelectXPath(root, "//li[substring(@data-name, 5) = 'gateway']")
+		assert.NotNil(t, err)
+	})
+}
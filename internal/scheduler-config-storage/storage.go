@@ -2,16 +2,37 @@ package scheduler_config_storage
 
 import (
 	"context"
+	"errors"
 	"github.com/squzy/mongo_helper"
 	apiPb "github.com/squzy/squzy_generated/generated/proto/v1"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"reflect"
+	monitoring_api "squzy/internal/monitoring-api"
+	"strings"
 )
 
 type GrpcConfig struct {
-	Service string `bson:"service"`
-	Host    string `bson:"host"`
-	Port    int32  `bson:"port"`
+	Service  string            `bson:"service"`
+	Host     string            `bson:"host"`
+	Port     int32             `bson:"port"`
+	TLS      *GrpcTLSConfig    `bson:"tls,omitempty"`
+	Metadata map[string]string `bson:"metadata,omitempty"`
+	Call     *GrpcCallConfig   `bson:"call,omitempty"`
+}
+
+type GrpcTLSConfig struct {
+	InsecureSkipVerify bool   `bson:"insecureSkipVerify,omitempty"`
+	CACert             string `bson:"caCert,omitempty"`
+	ClientCert         string `bson:"clientCert,omitempty"`
+	ClientKey          string `bson:"clientKey,omitempty"`
+	ServerName         string `bson:"serverName,omitempty"`
+}
+
+type GrpcCallConfig struct {
+	Method    string       `bson:"method"`
+	Request   string       `bson:"request,omitempty"`
+	Selectors []*Selectors `bson:"selectors,omitempty"`
 }
 
 type HTTPConfig struct {
@@ -19,42 +40,238 @@ type HTTPConfig struct {
 	URL        string            `bson:"url"`
 	Headers    map[string]string `bson:"headers"`
 	StatusCode int32             `bson:"statusCode"`
+	Assertions []*HTTPAssertion  `bson:"assertions,omitempty"`
+	Body       *RequestBody      `bson:"body,omitempty"`
+	Client     *HTTPClientConfig `bson:"client,omitempty"`
+}
+
+type HTTPClientConfig struct {
+	InsecureSkipVerify bool   `bson:"insecureSkipVerify,omitempty"`
+	CACert             string `bson:"caCert,omitempty"`
+	ClientCert         string `bson:"clientCert,omitempty"`
+	ClientKey          string `bson:"clientKey,omitempty"`
+	ServerName         string `bson:"serverName,omitempty"`
+	ProxyURL           string `bson:"proxyUrl,omitempty"`
+	DisableRedirects   bool   `bson:"disableRedirects,omitempty"`
+	MaxRedirects       int32  `bson:"maxRedirects,omitempty"`
+}
+
+type RequestBody struct {
+	Type        monitoring_api.RequestBodyType `bson:"type"`
+	Content     string                         `bson:"content,omitempty"`
+	Form        map[string]string              `bson:"form,omitempty"`
+	ContentType string                         `bson:"contentType,omitempty"`
+}
+
+type HTTPAssertion struct {
+	Type   monitoring_api.HTTPAssertionType `bson:"type"`
+	Value  string                           `bson:"value,omitempty"`
+	Header string                           `bson:"header,omitempty"`
+	Max    int64                            `bson:"max,omitempty"`
 }
 
 type HTTPValueConfig struct {
-	Method    string            `bson:"method"`
-	URL       string            `bson:"url"`
-	Headers   map[string]string `bson:"headers"`
-	Selectors []*Selectors      `bson:"selectors"`
+	Method    string                     `bson:"method"`
+	URL       string                     `bson:"url"`
+	Headers   map[string]string          `bson:"headers"`
+	Format    monitoring_api.ValueFormat `bson:"format,omitempty"`
+	Selectors []*Selectors               `bson:"selectors"`
+	Body      *RequestBody               `bson:"body,omitempty"`
+	Client    *HTTPClientConfig          `bson:"client,omitempty"`
 }
 
 type Selectors struct {
 	Type apiPb.HttpJsonValueConfig_JsonValueParseType `bson:"type"`
 	Path string                                       `bson:"path"`
+	Rule *SelectorRule                                `bson:"rule,omitempty"`
+}
+
+type SelectorRule struct {
+	Type    monitoring_api.SelectorRuleType `bson:"type"`
+	Number  float64                         `bson:"number,omitempty"`
+	Min     float64                         `bson:"min,omitempty"`
+	Max     float64                         `bson:"max,omitempty"`
+	Value   string                          `bson:"value,omitempty"`
+	Bool    bool                            `bson:"bool,omitempty"`
+	Seconds int64                           `bson:"seconds,omitempty"`
+}
+
+type SocketExpect struct {
+	Type  monitoring_api.SocketExpectType `bson:"type"`
+	Value string                          `bson:"value"`
 }
 
+// Used by tcp and udp schedulers
 type TCPConfig struct {
-	Host string `bson:"host"`
-	Port int32  `bson:"port"`
+	Host          string        `bson:"host"`
+	Port          int32         `bson:"port"`
+	Send          string        `bson:"send,omitempty"`
+	Expect        *SocketExpect `bson:"expect,omitempty"`
+	ReadTimeoutMs int32         `bson:"readTimeoutMs,omitempty"`
+}
+
+type WebSocketConfig struct {
+	URL                string            `bson:"url"`
+	Headers            map[string]string `bson:"headers,omitempty"`
+	Origin             string            `bson:"origin,omitempty"`
+	Subprotocols       []string          `bson:"subprotocols,omitempty"`
+	Send               string            `bson:"send,omitempty"`
+	Expect             *SocketExpect     `bson:"expect,omitempty"`
+	InsecureSkipVerify bool              `bson:"insecureSkipVerify,omitempty"`
+}
+
+type MetricLabelMatcher struct {
+	Name  string                         `bson:"name"`
+	Type  monitoring_api.MetricMatchType `bson:"type"`
+	Value string                         `bson:"value"`
+}
+
+type MetricSelector struct {
+	Name      string                           `bson:"name"`
+	Labels    []*MetricLabelMatcher            `bson:"labels,omitempty"`
+	Aggregate monitoring_api.MetricAggregation `bson:"aggregate,omitempty"`
+	Rule      *SelectorRule                    `bson:"rule,omitempty"`
+}
+
+type PrometheusConfig struct {
+	URL       string            `bson:"url"`
+	Headers   map[string]string `bson:"headers,omitempty"`
+	Selectors []*MetricSelector `bson:"selectors"`
+	Client    *HTTPClientConfig `bson:"client,omitempty"`
+}
+
+type ContentRegion struct {
+	Type monitoring_api.ContentRegionType `bson:"type"`
+	Path string                           `bson:"path"`
+}
+
+type ContentConfig struct {
+	URL     string            `bson:"url"`
+	Method  string            `bson:"method,omitempty"`
+	Headers map[string]string `bson:"headers,omitempty"`
+	Region  *ContentRegion    `bson:"region,omitempty"`
+	Ignore  []string          `bson:"ignore,omitempty"`
+	Client  *HTTPClientConfig `bson:"client,omitempty"`
+	// Hash of content on last run, updated by check itself
+	LastHash string `bson:"lastHash,omitempty"`
+}
+
+type CommandConfig struct {
+	Path      string            `bson:"path"`
+	Args      []string          `bson:"args,omitempty"`
+	Env       map[string]string `bson:"env,omitempty"`
+	Dir       string            `bson:"dir,omitempty"`
+	ParseJSON bool              `bson:"parseJson,omitempty"`
 }
 
 type SiteMapConfig struct {
-	URL         string `bson:"url"`
-	Concurrency int32  `bson:"concurrency"`
+	URL             string            `bson:"url"`
+	Concurrency     int32             `bson:"concurrency"`
+	Client          *HTTPClientConfig `bson:"client,omitempty"`
+	MaxFailureRatio float64           `bson:"maxFailureRatio,omitempty"`
+	SlowThresholdMs int32             `bson:"slowThresholdMs,omitempty"`
+	CacheTTL        int32             `bson:"cacheTtl,omitempty"`
+}
+
+type TLSCertConfig struct {
+	Host                string `bson:"host"`
+	Port                int32  `bson:"port"`
+	ServerName          string `bson:"serverName,omitempty"`
+	ExpiryWarningDays   int32  `bson:"expiryWarningDays"`
+	SkipChainValidation bool   `bson:"skipChainValidation"`
+}
+
+type DNSConfig struct {
+	Host           string                       `bson:"host"`
+	RecordType     monitoring_api.DNSRecordType `bson:"recordType"`
+	Resolver       string                       `bson:"resolver"`
+	Assertion      monitoring_api.DNSAssertion  `bson:"assertion,omitempty"`
+	ExpectedValues []string                     `bson:"expectedValues,omitempty"`
+	MinCount       int32                        `bson:"minCount,omitempty"`
+}
+
+type ScenarioCapture struct {
+	Variable string `bson:"variable"`
+	Path     string `bson:"path,omitempty"`
+	Header   string `bson:"header,omitempty"`
+}
+
+type ScenarioStep struct {
+	Name       string             `bson:"name,omitempty"`
+	Method     string             `bson:"method"`
+	URL        string             `bson:"url"`
+	Headers    map[string]string  `bson:"headers,omitempty"`
+	Body       *RequestBody       `bson:"body,omitempty"`
+	StatusCode int32              `bson:"statusCode,omitempty"`
+	Assertions []*HTTPAssertion   `bson:"assertions,omitempty"`
+	Captures   []*ScenarioCapture `bson:"captures,omitempty"`
+}
+
+type ScenarioConfig struct {
+	Steps  []*ScenarioStep   `bson:"steps"`
+	Client *HTTPClientConfig `bson:"client,omitempty"`
+}
+
+type CrawlerConfig struct {
+	URL         string            `bson:"url"`
+	MaxDepth    int32             `bson:"maxDepth,omitempty"`
+	MaxPages    int32             `bson:"maxPages,omitempty"`
+	Concurrency int32             `bson:"concurrency,omitempty"`
+	Include     []string          `bson:"include,omitempty"`
+	Exclude     []string          `bson:"exclude,omitempty"`
+	Client      *HTTPClientConfig `bson:"client,omitempty"`
+}
+
+type DatabaseConfig struct {
+	Host     string     `bson:"host"`
+	Port     int32      `bson:"port"`
+	User     string     `bson:"user,omitempty"`
+	Password string     `bson:"password,omitempty"`
+	Database string     `bson:"database,omitempty"`
+	Query    string     `bson:"query,omitempty"`
+	Command  []string   `bson:"command,omitempty"`
+	SSLMode  string     `bson:"sslMode,omitempty"`
+	Value    *Selectors `bson:"value,omitempty"`
+}
+
+type RetryPolicy struct {
+	Attempts          int32   `bson:"attempts"`
+	BackoffMs         int32   `bson:"backoffMs"`
+	BackoffMultiplier float64 `bson:"backoffMultiplier,omitempty"`
+}
+
+type FlapPolicy struct {
+	ConfirmAfter int32 `bson:"confirmAfter"`
+	RecoverAfter int32 `bson:"recoverAfter"`
 }
 
 type SchedulerConfig struct {
-	ID              primitive.ObjectID    `bson:"_id"`
-	Name            string                `bson:"name,omitempty"`
-	Type            apiPb.SchedulerType   `bson:"type"`
-	Status          apiPb.SchedulerStatus `bson:"status"`
-	Interval        int32                 `bson:"interval"`
-	Timeout         int32                 `bson:"timeout"`
-	TCPConfig       *TCPConfig            `bson:"tcpConfig,omitempty"`
-	SiteMapConfig   *SiteMapConfig        `bson:"siteMapConfig,omitempty"`
-	GrpcConfig      *GrpcConfig           `bson:"grpcConfig,omitempty"`
-	HTTPConfig      *HTTPConfig           `bson:"httpConfig,omitempty"`
-	HTTPValueConfig *HTTPValueConfig      `bson:"httpValueConfig,omitempty"`
+	ID               primitive.ObjectID           `bson:"_id"`
+	Name             string                       `bson:"name,omitempty"`
+	Type             apiPb.SchedulerType          `bson:"type"`
+	Status           apiPb.SchedulerStatus        `bson:"status"`
+	Interval         int32                        `bson:"interval"`
+	Cron             string                       `bson:"cron,omitempty"` // with seconds field, interval is not used when it is set
+	Timezone         string                       `bson:"timezone,omitempty"`
+	Timeout          int32                        `bson:"timeout"`
+	TCPConfig        *TCPConfig                   `bson:"tcpConfig,omitempty"`
+	SiteMapConfig    *SiteMapConfig               `bson:"siteMapConfig,omitempty"`
+	GrpcConfig       *GrpcConfig                  `bson:"grpcConfig,omitempty"`
+	HTTPConfig       *HTTPConfig                  `bson:"httpConfig,omitempty"`
+	HTTPValueConfig  *HTTPValueConfig             `bson:"httpValueConfig,omitempty"`
+	TLSCertConfig    *TLSCertConfig               `bson:"tlsCertConfig,omitempty"`
+	DNSConfig        *DNSConfig                   `bson:"dnsConfig,omitempty"`
+	ScenarioConfig   *ScenarioConfig              `bson:"scenarioConfig,omitempty"`
+	CrawlerConfig    *CrawlerConfig               `bson:"crawlerConfig,omitempty"`
+	DatabaseConfig   *DatabaseConfig              `bson:"databaseConfig,omitempty"`
+	WebSocketConfig  *WebSocketConfig             `bson:"webSocketConfig,omitempty"`
+	PrometheusConfig *PrometheusConfig            `bson:"prometheusConfig,omitempty"`
+	ContentConfig    *ContentConfig               `bson:"contentConfig,omitempty"`
+	CommandConfig    *CommandConfig               `bson:"commandConfig,omitempty"`
+	Retry            *RetryPolicy                 `bson:"retry,omitempty"`
+	Flap             *FlapPolicy                  `bson:"flap,omitempty"`
+	Overlap          monitoring_api.OverlapPolicy `bson:"overlap,omitempty"`
+	Deadline         int32                        `bson:"deadline,omitempty"`
 }
 
 type Storage interface {
@@ -63,8 +280,11 @@ type Storage interface {
 	Remove(ctx context.Context, schedulerID primitive.ObjectID) error
 	Run(ctx context.Context, schedulerID primitive.ObjectID) error
 	Stop(ctx context.Context, schedulerID primitive.ObjectID) error
+	// Replaces config of scheduler, status is kept. ErrSchedulerNotFound is returned when scheduler does not exist or it is removed
+	Update(ctx context.Context, config *SchedulerConfig) error
 	GetAll(ctx context.Context) ([]*SchedulerConfig, error)
 	GetAllForSync(ctx context.Context) ([]*SchedulerConfig, error)
+	SetContentHash(ctx context.Context, schedulerID primitive.ObjectID, hash string) error
 }
 
 type storage struct {
@@ -72,6 +292,9 @@ type storage struct {
 }
 
 var (
+	// Scheduler does not exist or it is removed
+	ErrSchedulerNotFound = errors.New("scheduler is not found")
+
 	statusForAction = []apiPb.SchedulerStatus{
 		apiPb.SchedulerStatus_STOPPED,
 		apiPb.SchedulerStatus_RUNNED,
@@ -144,6 +367,66 @@ func (s *storage) Stop(ctx context.Context, schedulerID primitive.ObjectID) erro
 	return err
 }
 
+func (s *storage) Update(ctx context.Context, config *SchedulerConfig) error {
+	raw, err := bson.Marshal(config)
+	if err != nil {
+		return err
+	}
+	set := bson.M{}
+	err = bson.Unmarshal(raw, &set)
+	if err != nil {
+		return err
+	}
+	delete(set, "_id")
+	delete(set, "status")
+	// Fields which are omitted by new config must not stay from previous one
+	unset := bson.M{}
+	for _, key := range schedulerConfigKeys() {
+		if _, exist := set[key]; !exist && key != "_id" && key != "status" {
+			unset[key] = ""
+		}
+	}
+	update := bson.M{
+		"$set": set,
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	res, err := s.connector.UpdateOne(ctx, bson.M{
+		"_id": config.ID,
+		"status": bson.M{
+			"$in": statusForAction,
+		},
+	}, update)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrSchedulerNotFound
+	}
+	return nil
+}
+
+func schedulerConfigKeys() []string {
+	t := reflect.TypeOf(SchedulerConfig{})
+	keys := make([]string, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		keys = append(keys, strings.Split(t.Field(i).Tag.Get("bson"), ",")[0])
+	}
+	return keys
+}
+
+func (s *storage) SetContentHash(ctx context.Context, schedulerID primitive.ObjectID, hash string) error {
+	_, err := s.connector.UpdateOne(ctx, bson.M{
+		"_id": schedulerID,
+	}, bson.M{
+		"$set": bson.M{
+			"contentConfig.lastHash": hash,
+		},
+	})
+	return err
+}
+
 func (s *storage) Get(ctx context.Context, schedulerID primitive.ObjectID) (*SchedulerConfig, error) {
 	config := &SchedulerConfig{}
 	err := s.connector.FindOne(ctx, bson.M{
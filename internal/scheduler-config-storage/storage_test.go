@@ -31,7 +31,7 @@ func (m mockOk) FindAll(ctx context.Context, predicate bson.M, structToDeseriali
 }
 
 func (m mockOk) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
-	return nil, nil
+	return &mongo.UpdateResult{MatchedCount: 1}, nil
 }
 
 type mockError struct {
@@ -148,3 +148,71 @@ func TestStorage_Stop(t *testing.T) {
 		assert.NotEqual(t, nil, err)
 	})
 }
+
+func TestStorage_SetContentHash(t *testing.T) {
+	t.Run("Should: not return error", func(t *testing.T) {
+		s := New(&mockOk{})
+		err := s.SetContentHash(context.Background(), primitive.NewObjectID(), "hash")
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error", func(t *testing.T) {
+		s := New(&mockError{})
+		err := s.SetContentHash(context.Background(), primitive.NewObjectID(), "hash")
+		assert.NotEqual(t, nil, err)
+	})
+}
+
+type mockUpdate struct {
+	mockOk
+	matched int64
+	filter  interface{}
+	update  interface{}
+}
+
+func (m *mockUpdate) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	m.filter = filter
+	m.update = update
+	return &mongo.UpdateResult{MatchedCount: m.matched}, nil
+}
+
+func TestStorage_Update(t *testing.T) {
+	t.Run("Should: not return error", func(t *testing.T) {
+		s := New(&mockOk{})
+		err := s.Update(context.Background(), &SchedulerConfig{ID: primitive.NewObjectID()})
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error", func(t *testing.T) {
+		s := New(&mockError{})
+		err := s.Update(context.Background(), &SchedulerConfig{ID: primitive.NewObjectID()})
+		assert.NotEqual(t, nil, err)
+	})
+	t.Run("Should: replace config except id and status", func(t *testing.T) {
+		connector := &mockUpdate{matched: 1}
+		s := New(connector)
+		id := primitive.NewObjectID()
+		err := s.Update(context.Background(), &SchedulerConfig{
+			ID:        id,
+			Interval:  10,
+			TCPConfig: &TCPConfig{Host: "localhost", Port: 80},
+		})
+		assert.Equal(t, nil, err)
+		assert.Equal(t, id, connector.filter.(bson.M)["_id"])
+		update := connector.update.(bson.M)
+		set := update["$set"].(bson.M)
+		assert.Equal(t, int32(10), set["interval"])
+		assert.NotNil(t, set["tcpConfig"])
+		assert.NotContains(t, set, "_id")
+		assert.NotContains(t, set, "status")
+		unset := update["$unset"].(bson.M)
+		assert.Contains(t, unset, "httpConfig")
+		assert.Contains(t, unset, "cron")
+		assert.NotContains(t, unset, "tcpConfig")
+		assert.NotContains(t, unset, "interval")
+		assert.NotContains(t, unset, "status")
+	})
+	t.Run("Should: return error because scheduler is not found", func(t *testing.T) {
+		s := New(&mockUpdate{})
+		err := s.Update(context.Background(), &SchedulerConfig{ID: primitive.NewObjectID()})
+		assert.Equal(t, ErrSchedulerNotFound, err)
+	})
+}
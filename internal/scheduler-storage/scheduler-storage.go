@@ -14,6 +14,8 @@ var (
 type SchedulerStorage interface {
 	Get(string) (scheduler.Scheduler, error)
 	Set(scheduler.Scheduler) error
+	// Stops scheduler with same id and sets new one instead of it
+	Replace(scheduler.Scheduler) error
 	Remove(string) error
 }
 
@@ -45,6 +47,18 @@ func (s *storage) Set(schl scheduler.Scheduler) error {
 	return nil
 }
 
+func (s *storage) Replace(schl scheduler.Scheduler) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	id := schl.GetID()
+	value, exist := s.kv[id]
+	if exist {
+		value.Stop()
+	}
+	s.kv[id] = schl
+	return nil
+}
+
 func (s *storage) Remove(id string) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
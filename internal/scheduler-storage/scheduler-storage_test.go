@@ -4,6 +4,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"testing"
+	"time"
 )
 
 type schedulerMock struct {
@@ -28,6 +29,10 @@ func (s schedulerMock) IsRun() bool {
 	return true
 }
 
+func (s schedulerMock) GetNextRunTime() time.Time {
+	return time.Time{}
+}
+
 func TestNew(t *testing.T) {
 	t.Run("Shoudle: create storage", func(t *testing.T) {
 		s := New()
@@ -92,3 +97,36 @@ func TestStorage_Remove(t *testing.T) {
 		assert.Equal(t, nil, err)
 	})
 }
+
+type schedulerMockStopped struct {
+	schedulerMock
+	stopped bool
+}
+
+func (s *schedulerMockStopped) Stop() {
+	s.stopped = true
+}
+
+func TestStorage_Replace(t *testing.T) {
+	t.Run("Should: set scheduler if it does not exist", func(t *testing.T) {
+		s := New()
+		err := s.Replace(&schedulerMock{})
+		assert.Equal(t, nil, err)
+		_, err = s.Get("1")
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: stop previous scheduler and replace it", func(t *testing.T) {
+		s := New()
+		previous := &schedulerMockStopped{}
+		err := s.Set(previous)
+		assert.Equal(t, nil, err)
+		next := &schedulerMockStopped{}
+		err = s.Replace(next)
+		assert.Equal(t, nil, err)
+		assert.True(t, previous.stopped)
+		assert.False(t, next.stopped)
+		value, err := s.Get("1")
+		assert.Equal(t, nil, err)
+		assert.Equal(t, next, value)
+	})
+}
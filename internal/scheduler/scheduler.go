@@ -3,7 +3,10 @@ package scheduler
 import (
 	"errors"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"math/rand"
+	"squzy/internal/cron"
 	job_executor "squzy/internal/job-executor"
+	"sync"
 	"time"
 )
 
@@ -22,57 +25,131 @@ type Scheduler interface {
 	Stop()
 	// Return true/false depends from current state
 	IsRun() bool
+	// Return planned time of next run, zero time when Scheduler is stopped
+	GetNextRunTime() time.Time
 }
 
 type schl struct {
-	ticker      *time.Ticker
+	mutex       sync.Mutex
 	isStopped   bool
 	quitCh      chan bool
 	interval    time.Duration
+	startJitter time.Duration
+	schedule    cron.Schedule
+	nextRun     time.Time
 	id          primitive.ObjectID
 	jobExecutor job_executor.JobExecutor
 }
 
-func New(id primitive.ObjectID, interval time.Duration, jobExecutor job_executor.JobExecutor) (Scheduler, error) {
+// First tick happens earlier by random offset up to start jitter, so schedulers
+// which are started together do not tick at same time
+func New(id primitive.ObjectID, interval time.Duration, startJitter time.Duration, jobExecutor job_executor.JobExecutor) (Scheduler, error) {
 	if interval < time.Millisecond*500 {
 		return nil, errIntervalLessHalfSecondError
 	}
 	return &schl{
 		id:          id,
 		interval:    interval,
+		startJitter: startJitter,
+		isStopped:   true,
+		jobExecutor: jobExecutor,
+	}, nil
+}
+
+// Scheduler runs job at times of cron expression instead of fixed interval
+func NewCron(id primitive.ObjectID, spec string, timezone string, jobExecutor job_executor.JobExecutor) (Scheduler, error) {
+	schedule, err := cron.Parse(spec, timezone)
+	if err != nil {
+		return nil, err
+	}
+	return &schl{
+		id:          id,
+		schedule:    schedule,
 		isStopped:   true,
 		jobExecutor: jobExecutor,
 	}, nil
 }
 
 func (s *schl) Run() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	if !s.isStopped {
 		return
 	}
-	s.ticker = time.NewTicker(s.interval)
 	s.isStopped = false
 	s.quitCh = make(chan bool, 1)
-	s.observer()
+	s.nextRun = s.next(time.Now()).Add(-s.startOffset())
+	s.observer(s.nextRun, s.quitCh)
 }
 
-func (s *schl) observer() {
+// Observer owns quit channel of its run, so observer of previous run can't read channel of next one
+func (s *schl) observer(next time.Time, quitCh chan bool) {
 	go func() {
-	loop:
-		for {
+		// Cron expression can have no next run at all
+		for !next.IsZero() {
+			timer := time.NewTimer(time.Until(next))
 			select {
-			case <-s.ticker.C:
-				s.jobExecutor.Execute(s.id)
-			case <-s.quitCh:
-				break loop
+			case <-timer.C:
+				// Long execution should not delay next ticks, overlap is handled by job executor
+				go s.jobExecutor.Execute(s.id)
+			case <-quitCh:
+				timer.Stop()
+				return
+			}
+			next = s.next(next)
+			s.mutex.Lock()
+			if s.quitCh != quitCh {
+				s.mutex.Unlock()
+				return
 			}
+			s.nextRun = next
+			s.mutex.Unlock()
 		}
 	}()
 }
 
+func (s *schl) startOffset() time.Duration {
+	jitter := s.startJitter
+	if jitter > s.interval {
+		jitter = s.interval
+	}
+	if jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(jitter)))
+}
+
+// Runs which are missed are skipped as it was with ticker
+func (s *schl) next(previous time.Time) time.Time {
+	now := time.Now()
+	if now.Before(previous) {
+		now = previous
+	}
+	if s.schedule != nil {
+		return s.schedule.Next(now)
+	}
+	next := previous.Add(s.interval)
+	for next.Before(now) {
+		next = next.Add(s.interval)
+	}
+	return next
+}
+
 func (s *schl) IsRun() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	return !s.isStopped
 }
 
+func (s *schl) GetNextRunTime() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.isStopped {
+		return time.Time{}
+	}
+	return s.nextRun
+}
+
 func (s *schl) GetID() string {
 	return s.id.Hex()
 }
@@ -82,10 +159,11 @@ func (s *schl) GetIDBson() primitive.ObjectID {
 }
 
 func (s *schl) Stop() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 	if s.isStopped {
 		return
 	}
-	s.ticker.Stop()
 	s.quitCh <- true
 	close(s.quitCh)
 	s.isStopped = true
@@ -3,65 +3,191 @@ package scheduler
 import (
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	monitoring_api "squzy/internal/monitoring-api"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
 type jobExecutor struct {
-	count int
+	count int32
 }
 
 func (j *jobExecutor) Execute(schedulerId primitive.ObjectID) {
-	j.count += 1
+	atomic.AddInt32(&j.count, 1)
+}
+
+func (j *jobExecutor) getCount() int32 {
+	return atomic.LoadInt32(&j.count)
+}
+
+func (j *jobExecutor) Reset(schedulerId primitive.ObjectID) {
+}
+
+func (j *jobExecutor) GetDiagnostics() *monitoring_api.Diagnostics {
+	return &monitoring_api.Diagnostics{}
 }
 
 func TestNew(t *testing.T) {
 	t.Run("Tests: Scheduler.New()", func(t *testing.T) {
 		t.Run("Should: create new app without error", func(t *testing.T) {
-			_, err := New(primitive.NewObjectID(), time.Second, nil)
+			_, err := New(primitive.NewObjectID(), time.Second, 0, nil)
 			assert.Equal(t, nil, err)
 		})
 		t.Run("Should: create new app with 'intervalLessHalfSecondError' error", func(t *testing.T) {
-			_, err := New(primitive.NewObjectID(), time.Millisecond, nil)
+			_, err := New(primitive.NewObjectID(), time.Millisecond, 0, nil)
 			assert.Equal(t, errIntervalLessHalfSecondError, err)
 		})
 	})
 }
 
+func TestNewCron(t *testing.T) {
+	t.Run("Should: create new app without error", func(t *testing.T) {
+		_, err := NewCron(primitive.NewObjectID(), "0 */5 9-17 * * MON-FRI", "Europe/Moscow", nil)
+		assert.Equal(t, nil, err)
+	})
+	t.Run("Should: return error because expression is invalid", func(t *testing.T) {
+		_, err := NewCron(primitive.NewObjectID(), "*/5 * * *", "", nil)
+		assert.NotEqual(t, nil, err)
+	})
+}
+
 func TestSchl_Run(t *testing.T) {
 	t.Run("Tests: Scheduler.Run()", func(t *testing.T) {
 		t.Run("Should: run without error ", func(t *testing.T) {
-			i, _ := New(primitive.NewObjectID(), time.Second, &jobExecutor{})
+			i, _ := New(primitive.NewObjectID(), time.Second, 0, &jobExecutor{})
 			i.Run()
 			i.Run()
 			i.Stop()
 		})
 		t.Run("Should: run job every second ", func(t *testing.T) {
 			store := &jobExecutor{}
-			i, err := New(primitive.NewObjectID(), time.Second, store)
+			i, err := New(primitive.NewObjectID(), time.Second, 0, store)
 			assert.Equal(t, nil, err)
 			i.Run()
 			assert.Equal(t, nil, err)
 			ch := make(chan bool)
 			time.AfterFunc(time.Millisecond*1100, func() {
-				assert.Equal(t, 1, store.count)
+				assert.Equal(t, int32(1), store.getCount())
 				ch <- true
 			})
 			<-ch
 			time.AfterFunc(time.Millisecond*1100, func() {
-				assert.Equal(t, 2, store.count)
+				assert.Equal(t, int32(2), store.getCount())
 				ch <- true
 			})
 			<-ch
 			i.Stop()
 		})
+		t.Run("Should: run one observer after fast restart", func(t *testing.T) {
+			store := &jobExecutor{}
+			i, _ := New(primitive.NewObjectID(), time.Second, 0, store)
+			i.Run()
+			i.Stop()
+			i.Run()
+			time.Sleep(time.Millisecond * 1100)
+			i.Stop()
+			assert.Equal(t, int32(1), store.getCount())
+		})
+	})
+}
+
+type slowJobExecutor struct {
+	mutex sync.Mutex
+	count int
+}
+
+func (j *slowJobExecutor) Execute(schedulerId primitive.ObjectID) {
+	j.mutex.Lock()
+	j.count += 1
+	j.mutex.Unlock()
+	time.Sleep(time.Second * 2)
+}
+
+func (j *slowJobExecutor) Reset(schedulerId primitive.ObjectID) {
+}
+
+func (j *slowJobExecutor) GetDiagnostics() *monitoring_api.Diagnostics {
+	return &monitoring_api.Diagnostics{}
+}
+
+func (j *slowJobExecutor) getCount() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.count
+}
+
+func TestSchl_RunSlowJob(t *testing.T) {
+	t.Run("Should: not delay next tick by long execution", func(t *testing.T) {
+		store := &slowJobExecutor{}
+		i, _ := New(primitive.NewObjectID(), time.Second, 0, store)
+		i.Run()
+		time.Sleep(time.Millisecond * 2100)
+		i.Stop()
+		assert.Equal(t, 2, store.getCount())
+	})
+}
+
+func TestSchl_RunCron(t *testing.T) {
+	t.Run("Should: run job every second by cron expression", func(t *testing.T) {
+		store := &jobExecutor{}
+		i, err := NewCron(primitive.NewObjectID(), "* * * * * *", "", store)
+		assert.Equal(t, nil, err)
+		i.Run()
+		time.Sleep(time.Millisecond * 2100)
+		i.Stop()
+		assert.True(t, store.getCount() >= 2)
+	})
+	t.Run("Should: not run when expression has no next time", func(t *testing.T) {
+		i, _ := NewCron(primitive.NewObjectID(), "0 0 0 30 2 *", "", &jobExecutor{})
+		i.Run()
+		assert.True(t, i.GetNextRunTime().IsZero())
+		i.Stop()
+	})
+}
+
+func TestSchl_GetNextRunTime(t *testing.T) {
+	t.Run("Should: return zero time because scheduler is stopped", func(t *testing.T) {
+		i, _ := New(primitive.NewObjectID(), time.Second, 0, &jobExecutor{})
+		assert.True(t, i.GetNextRunTime().IsZero())
+		i.Run()
+		i.Stop()
+		assert.True(t, i.GetNextRunTime().IsZero())
+	})
+	t.Run("Should: return time of next tick", func(t *testing.T) {
+		i, _ := New(primitive.NewObjectID(), time.Hour, 0, &jobExecutor{})
+		i.Run()
+		assert.WithinDuration(t, time.Now().Add(time.Hour), i.GetNextRunTime(), time.Second)
+		i.Stop()
+	})
+	t.Run("Should: return time of first tick shifted by start jitter", func(t *testing.T) {
+		i, _ := New(primitive.NewObjectID(), time.Hour, time.Minute*10, &jobExecutor{})
+		i.Run()
+		next := i.GetNextRunTime()
+		assert.True(t, next.After(time.Now().Add(time.Minute*49)))
+		assert.True(t, next.Before(time.Now().Add(time.Hour)))
+		i.Stop()
+	})
+	t.Run("Should: limit start jitter by interval", func(t *testing.T) {
+		i, _ := New(primitive.NewObjectID(), time.Second, time.Hour, &jobExecutor{})
+		i.Run()
+		assert.True(t, i.GetNextRunTime().After(time.Now()))
+		i.Stop()
+	})
+	t.Run("Should: return time of next cron run", func(t *testing.T) {
+		i, _ := NewCron(primitive.NewObjectID(), "@daily", "", &jobExecutor{})
+		i.Run()
+		now := time.Now().UTC()
+		assert.True(t, time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC).Equal(i.GetNextRunTime()))
+		i.Stop()
 	})
 }
 
 func TestSchl_Stop(t *testing.T) {
 	t.Run("Tests: Scheduler.Stop()", func(t *testing.T) {
 		t.Run("Should: stop without error ", func(t *testing.T) {
-			i, _ := New(primitive.NewObjectID(), time.Second, &jobExecutor{})
+			i, _ := New(primitive.NewObjectID(), time.Second, 0, &jobExecutor{})
 			i.Run()
 			i.Stop()
 			i.Stop()
@@ -72,7 +198,7 @@ func TestSchl_Stop(t *testing.T) {
 func TestSchl_IsRun(t *testing.T) {
 	t.Run("Tests: Scheduler.IsRun()", func(t *testing.T) {
 		t.Run("Should: return true ", func(t *testing.T) {
-			i, _ := New(primitive.NewObjectID(), time.Second, &jobExecutor{})
+			i, _ := New(primitive.NewObjectID(), time.Second, 0, &jobExecutor{})
 			i.Run()
 			assert.Equal(t, true, i.IsRun())
 			i.Stop()
@@ -80,11 +206,11 @@ func TestSchl_IsRun(t *testing.T) {
 		})
 		t.Run("Should: return false", func(t *testing.T) {
 			t.Run("Suite: after creation", func(t *testing.T) {
-				i, _ := New(primitive.NewObjectID(), time.Second, &jobExecutor{})
+				i, _ := New(primitive.NewObjectID(), time.Second, 0, &jobExecutor{})
 				assert.Equal(t, false, i.IsRun())
 			})
 			t.Run("Suite: after stop", func(t *testing.T) {
-				i, _ := New(primitive.NewObjectID(), time.Second, &jobExecutor{})
+				i, _ := New(primitive.NewObjectID(), time.Second, 0, &jobExecutor{})
 				i.Run()
 				i.Stop()
 				assert.Equal(t, false, i.IsRun())
@@ -96,7 +222,7 @@ func TestSchl_IsRun(t *testing.T) {
 func TestSchl_GetId(t *testing.T) {
 	t.Run("Should: return id as string", func(t *testing.T) {
 		id := primitive.NewObjectID()
-		s, err := New(id, time.Second, &jobExecutor{})
+		s, err := New(id, time.Second, 0, &jobExecutor{})
 		assert.Equal(t, id.Hex(), s.GetID())
 		assert.IsType(t, "", s.GetID())
 		assert.Equal(t, nil, err)
@@ -106,7 +232,7 @@ func TestSchl_GetId(t *testing.T) {
 func TestSchl_GetIdBson(t *testing.T) {
 	t.Run("Should: return id as bson", func(t *testing.T) {
 		id := primitive.NewObjectID()
-		s, err := New(id, time.Second, &jobExecutor{})
+		s, err := New(id, time.Second, 0, &jobExecutor{})
 		assert.Equal(t, id, s.GetIDBson())
 		assert.IsType(t, primitive.ObjectID{}, s.GetIDBson())
 		assert.Equal(t, nil, err)
@@ -1,6 +1,9 @@
 package sitemap_storage
 
 import (
+	"container/list"
+	"fmt"
+	"golang.org/x/sync/singleflight"
 	"net/http"
 	"squzy/internal/httptools"
 	"squzy/internal/parsers"
@@ -8,51 +11,165 @@ import (
 	"time"
 )
 
+// Expired sitemap is returned while it is refreshed until it is older than that amount of ttls,
+// then it is downloaded again and error of download is returned, so broken sitemap is not hidden by cache
+const maxStaleTTLs = 3
+
 type SiteMapStorage interface {
-	Get(url string) (*parsers.SiteMap, error)
+	// Sitemap is cached for ttl, default ttl of storage is used when ttl <= 0
+	Get(url string, clientConfig *httptools.ClientConfig, ttl time.Duration) (*parsers.SiteMap, error)
 }
 
 type storage struct {
 	httpTools     httptools.HTTPTool
 	duration      time.Duration
-	kv            map[string]*StorageItem
-	mutex         sync.RWMutex
+	maxEntries    int
+	kv            map[string]*list.Element
+	lru           *list.List
+	mutex         sync.Mutex
+	group         singleflight.Group
 	siteMapParser parsers.SiteMapParser
+	maxDepth      int
+	maxURLs       int
+	now           func() time.Time
 }
 
 type StorageItem struct {
+	key      string
 	deadline time.Time
-	siteMap  *parsers.SiteMap
+	// Sitemap is not returned after that time even when it can't be refreshed
+	staleDeadline time.Time
+	siteMap       *parsers.SiteMap
 }
 
-func (s *storage) Get(url string) (*parsers.SiteMap, error) {
+// Different urls are downloaded in parallel, concurrent calls for same url wait for one download.
+// Expired sitemap is returned while it is refreshed in background, until it is older than maxStaleTTLs of ttl.
+func (s *storage) Get(url string, clientConfig *httptools.ClientConfig, ttl time.Duration) (*parsers.SiteMap, error) {
+	if ttl <= 0 {
+		ttl = s.duration
+	}
+	key := cacheKey(url, clientConfig)
+
+	s.mutex.Lock()
+	item := s.lookup(key)
+	s.mutex.Unlock()
+
+	if item == nil || s.now().After(item.staleDeadline) {
+		return s.fetch(key, url, clientConfig, ttl)
+	}
+	if s.now().After(item.deadline) {
+		go func() {
+			_, _ = s.fetch(key, url, clientConfig, ttl)
+		}()
+	}
+	return item.siteMap, nil
+}
+
+// Returns cached item and marks it as recently used
+func (s *storage) lookup(key string) *StorageItem {
+	element, exist := s.kv[key]
+	if !exist {
+		return nil
+	}
+	s.lru.MoveToFront(element)
+	return element.Value.(*StorageItem)
+}
+
+func (s *storage) fetch(key string, url string, clientConfig *httptools.ClientConfig, ttl time.Duration) (*parsers.SiteMap, error) {
+	value, err, _ := s.group.Do(key, func() (interface{}, error) {
+		siteMap := &parsers.SiteMap{}
+		err := s.collect(siteMap, url, clientConfig, 0, map[string]bool{})
+		if err != nil {
+			return nil, err
+		}
+		now := s.now()
+		s.save(&StorageItem{
+			key:           key,
+			deadline:      now.Add(ttl),
+			staleDeadline: now.Add(ttl * maxStaleTTLs),
+			siteMap:       siteMap,
+		})
+		return siteMap, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*parsers.SiteMap), nil
+}
+
+// Saves item, least recently used items are evicted when storage is full
+func (s *storage) save(item *StorageItem) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
-	value, exist := s.kv[url]
-	if exist && time.Now().Before(value.deadline) {
-		return value.siteMap, nil
+	if element, exist := s.kv[item.key]; exist {
+		element.Value = item
+		s.lru.MoveToFront(element)
+		return
+	}
+	s.kv[item.key] = s.lru.PushFront(item)
+	for s.maxEntries > 0 && s.lru.Len() > s.maxEntries {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.kv, oldest.Value.(*StorageItem).key)
+	}
+}
+
+// Same sitemap can be requested with different client settings
+func cacheKey(url string, clientConfig *httptools.ClientConfig) string {
+	if clientConfig == nil {
+		return url
 	}
-	req := s.httpTools.CreateRequest(http.MethodGet, url, nil, "")
+	return fmt.Sprintf("%s %+v", url, *clientConfig)
+}
+
+// Adds urls of sitemap to result, sitemap indexes are followed until maxDepth, urls after maxURLs are dropped
+func (s *storage) collect(result *parsers.SiteMap, url string, clientConfig *httptools.ClientConfig, depth int, visited map[string]bool) error {
+	visited[url] = true
+	req := httptools.WithClientConfig(s.httpTools.CreateRequest(http.MethodGet, url, nil, ""), clientConfig)
 	_, resp, err := s.httpTools.SendRequestWithStatusCode(req, http.StatusOK)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	siteMap, err := s.siteMapParser.Parse(resp)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	for _, item := range siteMap.URLSet {
+		if s.maxURLs > 0 && len(result.URLSet) >= s.maxURLs {
+			return nil
+		}
+		result.URLSet = append(result.URLSet, item)
+	}
+	if depth >= s.maxDepth {
+		return nil
 	}
-	s.kv[url] = &StorageItem{
-		deadline: time.Now().Add(s.duration),
-		siteMap:  siteMap,
+	for _, child := range siteMap.SiteMaps {
+		if s.maxURLs > 0 && len(result.URLSet) >= s.maxURLs {
+			return nil
+		}
+		if visited[child.Location] {
+			continue
+		}
+		err = s.collect(result, child.Location, clientConfig, depth+1, visited)
+		if err != nil {
+			return err
+		}
 	}
-	return siteMap, nil
+	return nil
 }
 
-func New(duration time.Duration, httpTools httptools.HTTPTool, siteMapParser parsers.SiteMapParser) SiteMapStorage {
+// duration is default ttl, maxEntries limits amount of cached sitemaps, 0 is without limit.
+// maxDepth is how many levels of nested sitemap indexes are followed, maxURLs limits merged urls, 0 is without limit
+func New(duration time.Duration, maxEntries int, httpTools httptools.HTTPTool, siteMapParser parsers.SiteMapParser, maxDepth int, maxURLs int) SiteMapStorage {
 	return &storage{
 		duration:      duration,
-		kv:            make(map[string]*StorageItem),
+		maxEntries:    maxEntries,
+		kv:            make(map[string]*list.Element),
+		lru:           list.New(),
 		httpTools:     httpTools,
 		siteMapParser: siteMapParser,
+		maxDepth:      maxDepth,
+		maxURLs:       maxURLs,
+		now:           time.Now,
 	}
 }
@@ -1,10 +1,17 @@
 package sitemap_storage
 
 import (
+	"bytes"
+	"compress/gzip"
 	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"net/http"
+	"net/http/httptest"
+	"squzy/internal/httptools"
 	"squzy/internal/parsers"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -28,6 +35,14 @@ func (m mockHttp) GetWithRedirects(url string) (int, []byte, error) {
 	return 200, nil, nil
 }
 
+func (m mockHttp) SendRequestTimeoutStatusCodeWithHeaders(req *http.Request, timeout time.Duration, expectedCode int) (int, http.Header, []byte, error) {
+	panic("implement me")
+}
+
+func (m mockHttp) SendRequestTimeoutWithHeaders(req *http.Request, timeout time.Duration) (int, http.Header, []byte, error) {
+	panic("implement me")
+}
+
 func (m mockHttp) CreateRequest(method string, url string, headers *map[string]string, log string) *http.Request {
 	return nil
 }
@@ -73,6 +88,14 @@ func (m mockHttpError) GetWithRedirects(url string) (int, []byte, error) {
 	return 0, nil, errors.New("ascss")
 }
 
+func (m mockHttpError) SendRequestTimeoutStatusCodeWithHeaders(req *http.Request, timeout time.Duration, expectedCode int) (int, http.Header, []byte, error) {
+	panic("implement me")
+}
+
+func (m mockHttpError) SendRequestTimeoutWithHeaders(req *http.Request, timeout time.Duration) (int, http.Header, []byte, error) {
+	panic("implement me")
+}
+
 func (m mockHttpError) CreateRequest(method string, url string, headers *map[string]string, log string) *http.Request {
 	return nil
 }
@@ -87,34 +110,260 @@ func (m mockHttpError) SendRequestWithStatusCode(req *http.Request, expectedCode
 
 func TestNew(t *testing.T) {
 	t.Run("Shoudle implement interface", func(t *testing.T) {
-		s := New(time.Second, &mockHttp{}, &mockSiteMapParser{})
+		s := New(time.Second, 0, &mockHttp{}, &mockSiteMapParser{}, 1, 0)
 		assert.Implements(t, (*SiteMapStorage)(nil), s)
 	})
 }
 
 func TestStorage_Get(t *testing.T) {
 	t.Run("Should: return error because httpError", func(t *testing.T) {
-		s := New(time.Second, &mockHttpError{}, &mockSiteMapParser{})
-		_, err := s.Get("evrerver")
+		s := New(time.Second, 0, &mockHttpError{}, &mockSiteMapParser{}, 1, 0)
+		_, err := s.Get("evrerver", nil, 0)
 		assert.NotEqual(t, nil, err)
 	})
 	t.Run("Should: return error because parseError", func(t *testing.T) {
-		s := New(time.Second, &mockHttp{}, &mockSiteMapParserError{})
-		_, err := s.Get("evrerver")
+		s := New(time.Second, 0, &mockHttp{}, &mockSiteMapParserError{}, 1, 0)
+		_, err := s.Get("evrerver", nil, 0)
 		assert.NotEqual(t, nil, err)
 	})
 	t.Run("Should: return sitemap", func(t *testing.T) {
-		s := New(time.Second, &mockHttp{}, &mockSiteMapParser{})
-		sm, err := s.Get("evrerver")
+		s := New(time.Second, 0, &mockHttp{}, &mockSiteMapParser{}, 1, 0)
+		sm, err := s.Get("evrerver", nil, 0)
 		assert.Equal(t, nil, err)
 		assert.NotEqual(t, sm, err)
 	})
 	t.Run("Should: return from cache", func(t *testing.T) {
-		s := New(time.Minute, &mockHttp{}, &mockSiteMapParser{})
-		sm, err := s.Get("evrerver")
+		s := New(time.Minute, 0, &mockHttp{}, &mockSiteMapParser{}, 1, 0)
+		sm, err := s.Get("evrerver", nil, 0)
 		assert.Equal(t, nil, err)
 		assert.NotEqual(t, sm, err)
-		sm2, _ := s.Get("evrerver")
+		sm2, _ := s.Get("evrerver", nil, 0)
 		assert.Equal(t, sm, sm2)
 	})
 }
+
+func newSiteMapServer() *httptest.Server {
+	mux := http.NewServeMux()
+	var url string
+	index := func(locations ...string) string {
+		body := `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`
+		for _, location := range locations {
+			body += fmt.Sprintf("<sitemap><loc>%s%s</loc></sitemap>", url, location)
+		}
+		return body + "</sitemapindex>"
+	}
+	urlSet := func(locations ...string) string {
+		body := `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`
+		for _, location := range locations {
+			body += fmt.Sprintf("<url><loc>%s</loc></url>", location)
+		}
+		return body + "</urlset>"
+	}
+	mux.HandleFunc("/index.xml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(index("/first.xml.gz", "/nested.xml", "/index.xml")))
+	})
+	mux.HandleFunc("/first.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		buf := &bytes.Buffer{}
+		writer := gzip.NewWriter(buf)
+		_, _ = writer.Write([]byte(urlSet("https://squzy.app/1", "https://squzy.app/2")))
+		_ = writer.Close()
+		_, _ = w.Write(buf.Bytes())
+	})
+	mux.HandleFunc("/nested.xml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(index("/second.xml")))
+	})
+	mux.HandleFunc("/second.xml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(urlSet("https://squzy.app/3")))
+	})
+	mux.HandleFunc("/broken.xml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(index("/missing.xml")))
+	})
+	server := httptest.NewServer(mux)
+	url = server.URL
+	return server
+}
+
+func TestStorage_GetSiteMapIndex(t *testing.T) {
+	server := newSiteMapServer()
+	defer server.Close()
+	locations := func(siteMap *parsers.SiteMap) []string {
+		result := []string{}
+		for _, item := range siteMap.URLSet {
+			result = append(result, item.Location)
+		}
+		return result
+	}
+
+	t.Run("Should: merge urls of nested and compressed sitemaps", func(t *testing.T) {
+		s := New(time.Second, 0, httptools.New(""), parsers.NewSiteMapParser(0), 2, 0)
+		sm, err := s.Get(server.URL+"/index.xml", nil, 0)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []string{"https://squzy.app/1", "https://squzy.app/2", "https://squzy.app/3"}, locations(sm))
+		assert.Equal(t, 0, len(sm.SiteMaps))
+	})
+	t.Run("Should: not follow indexes deeper than max depth", func(t *testing.T) {
+		s := New(time.Second, 0, httptools.New(""), parsers.NewSiteMapParser(0), 1, 0)
+		sm, err := s.Get(server.URL+"/index.xml", nil, 0)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []string{"https://squzy.app/1", "https://squzy.app/2"}, locations(sm))
+	})
+	t.Run("Should: drop urls after max urls", func(t *testing.T) {
+		s := New(time.Second, 0, httptools.New(""), parsers.NewSiteMapParser(0), 2, 1)
+		sm, err := s.Get(server.URL+"/index.xml", nil, 0)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, []string{"https://squzy.app/1"}, locations(sm))
+	})
+	t.Run("Should: return error because child sitemap is missing", func(t *testing.T) {
+		s := New(time.Second, 0, httptools.New(""), parsers.NewSiteMapParser(0), 2, 0)
+		_, err := s.Get(server.URL+"/broken.xml", nil, 0)
+		assert.NotEqual(t, nil, err)
+	})
+}
+
+// Counts requests of every path, requests of /slow.xml wait until release is closed,
+// only first request of /broken.xml succeeds
+type countingSiteMapServer struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	requests map[string]int
+	release  chan struct{}
+}
+
+func newCountingSiteMapServer() *countingSiteMapServer {
+	server := &countingSiteMapServer{
+		requests: map[string]int{},
+		release:  make(chan struct{}),
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		server.requests[r.URL.Path]++
+		server.mutex.Unlock()
+		if r.URL.Path == "/slow.xml" {
+			<-server.release
+		}
+		if r.URL.Path == "/broken.xml" && server.count(r.URL.Path) > 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `<urlset><url><loc>https://squzy.app%s</loc></url></urlset>`, r.URL.Path)
+	}))
+	return server
+}
+
+func (s *countingSiteMapServer) count(path string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.requests[path]
+}
+
+func TestStorage_GetCache(t *testing.T) {
+	t.Run("Should: download different urls in parallel", func(t *testing.T) {
+		server := newCountingSiteMapServer()
+		defer server.Close()
+		s := New(time.Minute, 0, httptools.New(""), parsers.NewSiteMapParser(0), 1, 0)
+		done := make(chan struct{})
+		go func() {
+			_, _ = s.Get(server.URL+"/slow.xml", nil, 0)
+			close(done)
+		}()
+		for server.count("/slow.xml") == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		sm, err := s.Get(server.URL+"/fast.xml", nil, 0)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, "https://squzy.app/fast.xml", sm.URLSet[0].Location)
+		close(server.release)
+		<-done
+	})
+	t.Run("Should: share one download between concurrent calls", func(t *testing.T) {
+		server := newCountingSiteMapServer()
+		defer server.Close()
+		s := New(time.Minute, 0, httptools.New(""), parsers.NewSiteMapParser(0), 1, 0)
+		var wg sync.WaitGroup
+		var succeeded int32
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := s.Get(server.URL+"/slow.xml", nil, 0); err == nil {
+					atomic.AddInt32(&succeeded, 1)
+				}
+			}()
+		}
+		for server.count("/slow.xml") == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		time.Sleep(time.Millisecond * 20)
+		close(server.release)
+		wg.Wait()
+		assert.Equal(t, int32(5), succeeded)
+		assert.Equal(t, 1, server.count("/slow.xml"))
+	})
+	t.Run("Should: return expired sitemap and refresh it in background", func(t *testing.T) {
+		server := newCountingSiteMapServer()
+		defer server.Close()
+		s := New(time.Minute, 0, httptools.New(""), parsers.NewSiteMapParser(0), 1, 0).(*storage)
+		now := time.Now()
+		s.now = func() time.Time {
+			return now
+		}
+		first, err := s.Get(server.URL+"/fast.xml", nil, time.Second)
+		assert.Equal(t, nil, err)
+		now = now.Add(time.Second * 2)
+		stale, err := s.Get(server.URL+"/fast.xml", nil, time.Second)
+		assert.Equal(t, nil, err)
+		assert.True(t, first == stale)
+		for server.count("/fast.xml") < 2 {
+			time.Sleep(time.Millisecond)
+		}
+	})
+	t.Run("Should: return error of refresh because expired sitemap is too old", func(t *testing.T) {
+		server := newCountingSiteMapServer()
+		defer server.Close()
+		s := New(time.Minute, 0, httptools.New(""), parsers.NewSiteMapParser(0), 1, 0).(*storage)
+		now := time.Now()
+		s.now = func() time.Time {
+			return now
+		}
+		_, err := s.Get(server.URL+"/broken.xml", nil, time.Second)
+		assert.Equal(t, nil, err)
+		now = now.Add(time.Second * 2)
+		_, err = s.Get(server.URL+"/broken.xml", nil, time.Second)
+		assert.Equal(t, nil, err)
+		for server.count("/broken.xml") < 2 {
+			time.Sleep(time.Millisecond)
+		}
+		now = now.Add(time.Second * 2)
+		_, err = s.Get(server.URL+"/broken.xml", nil, time.Second)
+		assert.NotEqual(t, nil, err)
+	})
+	t.Run("Should: use default ttl of storage", func(t *testing.T) {
+		server := newCountingSiteMapServer()
+		defer server.Close()
+		s := New(time.Minute, 0, httptools.New(""), parsers.NewSiteMapParser(0), 1, 0)
+		_, _ = s.Get(server.URL+"/fast.xml", nil, 0)
+		_, _ = s.Get(server.URL+"/fast.xml", nil, 0)
+		assert.Equal(t, 1, server.count("/fast.xml"))
+	})
+	t.Run("Should: evict least recently used sitemap", func(t *testing.T) {
+		server := newCountingSiteMapServer()
+		defer server.Close()
+		s := New(time.Minute, 2, httptools.New(""), parsers.NewSiteMapParser(0), 1, 0)
+		_, _ = s.Get(server.URL+"/first.xml", nil, 0)
+		_, _ = s.Get(server.URL+"/second.xml", nil, 0)
+		_, _ = s.Get(server.URL+"/first.xml", nil, 0)
+		_, _ = s.Get(server.URL+"/third.xml", nil, 0)
+		_, _ = s.Get(server.URL+"/first.xml", nil, 0)
+		_, _ = s.Get(server.URL+"/second.xml", nil, 0)
+		assert.Equal(t, 1, server.count("/first.xml"))
+		assert.Equal(t, 2, server.count("/second.xml"))
+	})
+	t.Run("Should: cache sitemap separately for client settings", func(t *testing.T) {
+		server := newCountingSiteMapServer()
+		defer server.Close()
+		s := New(time.Minute, 0, httptools.New(""), parsers.NewSiteMapParser(0), 1, 0)
+		_, _ = s.Get(server.URL+"/fast.xml", nil, 0)
+		_, _ = s.Get(server.URL+"/fast.xml", &httptools.ClientConfig{MaxRedirects: 1}, 0)
+		assert.Equal(t, 2, server.count("/fast.xml"))
+	})
+}